@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// Resources with a change history, see GetHistory
+const (
+	HistoryCars    = "cars"
+	HistoryDrivers = "drivers"
+	HistoryTrips   = "trips"
+	HistoryUsers   = "users"
+)
+
+// GetHistory lists the recorded changes of a car, driver, trip or user newest first
+func (c *Client) GetHistory(ctx context.Context, resource string, id string, opts models.ListOptions) (Page[models.AuditEntry], error) {
+	return list[models.AuditEntry](ctx, c, "GetHistory", itemPath("/api/v1/"+resource, id, "history"), nil, opts)
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// Login starts a session, the tokens are used by every following call
+func (c *Client) Login(ctx context.Context, userName string, password string) (models.TokenPair, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if err := c.login(ctx, models.Credentials{UserName: userName, Password: password}); err != nil {
+		return models.TokenPair{}, err
+	}
+	return c.Tokens(), nil
+}
+
+// RefreshToken rotates the tokens of the session. Calls refresh expired tokens by themselves, this is only
+// needed to rotate them early.
+func (c *Client) RefreshToken(ctx context.Context) (models.TokenPair, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	refreshToken := c.Tokens().RefreshToken
+	if refreshToken == "" {
+		return models.TokenPair{}, ErrNoSession
+	}
+	if err := c.refresh(ctx, refreshToken); err != nil {
+		return models.TokenPair{}, err
+	}
+	return c.Tokens(), nil
+}
+
+// Logout revokes the access and refresh tokens of the session and forgets them
+func (c *Client) Logout(ctx context.Context) error {
+	tokens := c.Tokens()
+	if tokens.AccessToken == "" {
+		return nil
+	}
+	_, err := c.do(ctx, "Logout", request{
+		method: http.MethodPost,
+		path:   "/api/v1/logout",
+		body:   models.RefreshRequest{RefreshToken: tokens.RefreshToken},
+	}, nil)
+
+	c.mu.Lock()
+	c.tokens = models.TokenPair{}
+	c.mu.Unlock()
+	return err
+}
+
+// JWKS returns the public keys tokens are signed with
+func (c *Client) JWKS(ctx context.Context) (models.JWKSet, error) {
+	var set models.JWKSet
+	_, err := c.do(ctx, "JWKS", request{method: http.MethodGet, path: "/.well-known/jwks.json", public: true}, &set)
+	return set, err
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const carsPath = "/api/v1/cars"
+
+func (c *Client) GetCar(ctx context.Context, id string) (models.Car, error) {
+	return call[models.Car](ctx, c, "GetCar", http.MethodGet, itemPath(carsPath, id), nil)
+}
+
+func (c *Client) SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) (Page[models.Car], error) {
+	query := newFilter().
+		string("q", filter.Query).
+		string("brand", filter.Brand).
+		string("fuel_type", filter.FuelType).
+		string("status", filter.Status).
+		int("year_from", filter.YearFrom).
+		int("year_to", filter.YearTo).
+		float("price_min", filter.PriceMin).
+		float("price_max", filter.PriceMax).
+		int("displacement_min", filter.DisplacementMin).
+		int("displacement_max", filter.DisplacementMax).
+		int("cylinders", filter.Cylinders).
+		int("range_min", filter.RangeMin).
+		bool("service_due", filter.ServiceDue)
+	if filter.WithEngine {
+		query.string("with_engine", strconv.FormatBool(filter.WithEngine))
+	}
+	return list[models.Car](ctx, c, "SearchCars", carsPath, query.values(), opts)
+}
+
+func (c *Client) CreateCar(ctx context.Context, carReq models.CarRequest) (models.Car, error) {
+	return call[models.Car](ctx, c, "CreateCar", http.MethodPost, carsPath, carReq)
+}
+
+func (c *Client) UpdateCar(ctx context.Context, id string, carReq models.CarRequest) (models.Car, error) {
+	return call[models.Car](ctx, c, "UpdateCar", http.MethodPut, itemPath(carsPath, id), carReq)
+}
+
+func (c *Client) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	return call[models.Car](ctx, c, "DeleteCar", http.MethodDelete, itemPath(carsPath, id), nil)
+}
+
+// GetCarTrips lists the trips of a car, filter.Status, From and To apply
+func (c *Client) GetCarTrips(ctx context.Context, id string, filter models.TripFilter, opts models.ListOptions) (Page[models.Trip], error) {
+	return list[models.Trip](ctx, c, "GetCarTrips", itemPath(carsPath, id, "trips"), tripQuery(filter), opts)
+}
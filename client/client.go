@@ -0,0 +1,382 @@
+// Package client is a typed Go client for the carzone API. A Client logs in with its credentials or resumes a
+// session from tokens, refreshes the access token as it expires, retries idempotent calls that failed on the
+// network or with 429, 502, 503 or 504, and propagates the trace context of the caller:
+//
+//	c, err := client.New("http://localhost:8080", client.WithCredentials("admin", "password"))
+//	cars, err := c.SearchCars(ctx, models.CarFilter{Status: models.CarStatusAvailable}, models.ListOptions{})
+//
+// Errors returned for a response are *Error and match the models error kinds, e.g.
+// errors.Is(err, models.ErrNotFound).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// expirySkew refreshes access tokens shortly before they expire rather than after a rejected request
+const expirySkew = 30 * time.Second
+
+// RetryPolicy controls the retries of idempotent calls, GET, PUT and DELETE. The wait doubles from MinBackoff
+// after every attempt up to MaxBackoff, with jitter, unless the server asks for longer with Retry-After.
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy retries three times within about two seconds
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+type Client struct {
+	baseURL     string
+	http        *http.Client
+	retry       RetryPolicy
+	credentials *models.Credentials
+	onTokens    func(models.TokenPair)
+
+	// mu guards the tokens, authMu serializes logins and refreshes since a refresh token is only good once
+	mu        sync.Mutex
+	authMu    sync.Mutex
+	tokens    models.TokenPair
+	expiresAt time.Time
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the given client instead of a client with a 30 second timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+// WithCredentials logs in on the first call, and again whenever the session can no longer be refreshed
+func WithCredentials(userName string, password string) Option {
+	return func(c *Client) {
+		c.credentials = &models.Credentials{UserName: userName, Password: password}
+	}
+}
+
+// WithTokens resumes a session, e.g. tokens saved from a previous run. ExpiresIn is taken as counting from now.
+func WithTokens(tokens models.TokenPair) Option {
+	return func(c *Client) {
+		c.setTokens(tokens)
+	}
+}
+
+// WithTokenCallback is called with every token pair the client obtains, so it can be saved and resumed with
+// WithTokens
+func WithTokenCallback(onTokens func(models.TokenPair)) Option {
+	return func(c *Client) {
+		c.onTokens = onTokens
+	}
+}
+
+// WithRetry replaces DefaultRetryPolicy, a policy with MaxRetries 0 turns retries off
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New creates a client for the carzone server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+		retry:   DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Tokens returns the tokens of the current session
+func (c *Client) Tokens() models.TokenPair {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+func (c *Client) setTokens(tokens models.TokenPair) {
+	if tokens.AccessToken == "" {
+		tokens.AccessToken = tokens.Token
+	}
+	c.mu.Lock()
+	c.tokens = tokens
+	c.expiresAt = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		c.expiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	c.mu.Unlock()
+
+	if c.onTokens != nil && tokens.AccessToken != "" {
+		c.onTokens(tokens)
+	}
+}
+
+// request is one API call. Body is sent as JSON unless contentType is set, then it must be a []byte.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        interface{}
+	contentType string
+	// public calls are sent without a token
+	public bool
+}
+
+// do sends a request and decodes the JSON response into out, when out is not nil. The response headers are
+// returned for the list metadata.
+func (c *Client) do(ctx context.Context, op string, req request, out interface{}) (http.Header, error) {
+	tracer := otel.Tracer("Client")
+	ctx, span := tracer.Start(ctx, op+"-Client", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", req.method), attribute.String("url.path", req.path))
+
+	header, err := c.send(ctx, req, out)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return header, err
+}
+
+func (c *Client) send(ctx context.Context, req request, out interface{}) (http.Header, error) {
+	var body []byte
+	switch b := req.body.(type) {
+	case nil:
+	case []byte:
+		body = b
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			return nil, err
+		}
+	}
+
+	var token string
+	if !req.public {
+		var err error
+		if token, err = c.accessToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reauthorized := false
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req, body, token)
+		if err != nil {
+			if ctx.Err() != nil || !c.retryable(req.method, attempt) {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 400 {
+			defer resp.Body.Close()
+			if out == nil || resp.StatusCode == http.StatusNoContent {
+				return resp.Header, nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return resp.Header, fmt.Errorf("invalid response from %s %s: %w", req.method, req.path, err)
+			}
+			return resp.Header, nil
+		}
+
+		apiErr := decodeError(resp)
+		resp.Body.Close()
+
+		// a token revoked before it expired is replaced once, the refresh does not count as a retry
+		if apiErr.StatusCode == http.StatusUnauthorized && !req.public && !reauthorized {
+			reauthorized = true
+			if token, err = c.reauthorize(ctx, token); err != nil {
+				if errors.Is(err, ErrNoSession) {
+					return resp.Header, apiErr
+				}
+				return nil, err
+			}
+			attempt--
+			continue
+		}
+
+		if isRetryableStatus(apiErr.StatusCode) && c.retryable(req.method, attempt) {
+			if err := c.wait(ctx, attempt, apiErr.RetryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return resp.Header, apiErr
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	return c.http.Do(httpReq)
+}
+
+// retryable reports whether another attempt is allowed. Only idempotent methods are retried, a POST that timed
+// out may still have been carried out.
+func (c *Client) retryable(method string, attempt int) bool {
+	if attempt >= c.retry.MaxRetries {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// wait sleeps before the next attempt, returning early with the context's error when it is cancelled
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	backoff := c.retry.MinBackoff << attempt
+	if backoff <= 0 || backoff > c.retry.MaxBackoff {
+		backoff = c.retry.MaxBackoff
+	}
+	// full jitter keeps clients that failed together from retrying together
+	if backoff > 0 {
+		backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	}
+	if retryAfter > backoff {
+		backoff = retryAfter
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// accessToken returns a token that has not expired, logging in or refreshing as needed
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expiresAt := c.tokens.AccessToken, c.expiresAt
+	c.mu.Unlock()
+
+	if token != "" && (expiresAt.IsZero() || time.Now().Add(expirySkew).Before(expiresAt)) {
+		return token, nil
+	}
+	return c.reauthorize(ctx, token)
+}
+
+// reauthorize replaces the rejected or expired token. A call that waited for another one to refresh uses the
+// token that call obtained.
+func (c *Client) reauthorize(ctx context.Context, rejected string) (string, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	tokens := c.Tokens()
+	if tokens.AccessToken != "" && tokens.AccessToken != rejected {
+		return tokens.AccessToken, nil
+	}
+
+	if tokens.RefreshToken != "" {
+		err := c.refresh(ctx, tokens.RefreshToken)
+		if err == nil {
+			return c.Tokens().AccessToken, nil
+		}
+		if !errors.Is(err, ErrUnauthorized) || c.credentials == nil {
+			return "", err
+		}
+	}
+
+	if c.credentials == nil {
+		return "", ErrNoSession
+	}
+	if err := c.login(ctx, *c.credentials); err != nil {
+		return "", err
+	}
+	return c.Tokens().AccessToken, nil
+}
+
+func (c *Client) login(ctx context.Context, credentials models.Credentials) error {
+	var tokens models.TokenPair
+	req := request{method: http.MethodPost, path: "/api/v1/login", body: credentials, public: true}
+	if _, err := c.do(ctx, "Login", req, &tokens); err != nil {
+		return err
+	}
+	c.setTokens(tokens)
+	return nil
+}
+
+func (c *Client) refresh(ctx context.Context, refreshToken string) error {
+	var tokens models.TokenPair
+	req := request{method: http.MethodPost, path: "/api/v1/token/refresh", body: models.RefreshRequest{RefreshToken: refreshToken}, public: true}
+	if _, err := c.do(ctx, "RefreshToken", req, &tokens); err != nil {
+		return err
+	}
+	c.setTokens(tokens)
+	return nil
+}
+
+// call sends a request and decodes the response into a T
+func call[T any](ctx context.Context, c *Client, op string, method string, path string, body interface{}) (T, error) {
+	var out T
+	_, err := c.do(ctx, op, request{method: method, path: path, body: body}, &out)
+	return out, err
+}
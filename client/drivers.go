@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const driversPath = "/api/v1/drivers"
+
+func (c *Client) GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) (Page[models.Driver], error) {
+	query := newFilter().
+		bool("active", filter.Active).
+		time("license_expires_before", filter.LicenseExpiresBefore).
+		time("license_expires_after", filter.LicenseExpiresAfter)
+	return list[models.Driver](ctx, c, "GetDrivers", driversPath, query.values(), opts)
+}
+
+// GetExpiringDrivers lists the active drivers whose license expires within the window, the server default of
+// 30 days when it is 0
+func (c *Client) GetExpiringDrivers(ctx context.Context, within time.Duration, opts models.ListOptions) (Page[models.Driver], error) {
+	query := newFilter().window("within", within)
+	return list[models.Driver](ctx, c, "GetExpiringDrivers", driversPath+"/expiring", query.values(), opts)
+}
+
+func (c *Client) GetDriver(ctx context.Context, id string) (models.Driver, error) {
+	return call[models.Driver](ctx, c, "GetDriver", http.MethodGet, itemPath(driversPath, id), nil)
+}
+
+func (c *Client) GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error) {
+	return call[[]models.DriverLicense](ctx, c, "GetDriverLicenses", http.MethodGet, itemPath(driversPath, id, "licenses"), nil)
+}
+
+func (c *Client) CreateDriver(ctx context.Context, driverReq models.DriverRequest) (models.Driver, error) {
+	return call[models.Driver](ctx, c, "CreateDriver", http.MethodPost, driversPath, driverReq)
+}
+
+func (c *Client) UpdateDriver(ctx context.Context, id string, driverReq models.DriverUpdateRequest) (models.Driver, error) {
+	return call[models.Driver](ctx, c, "UpdateDriver", http.MethodPut, itemPath(driversPath, id), driverReq)
+}
+
+// SoftDeleteDriver deactivates a driver and keeps the record
+func (c *Client) SoftDeleteDriver(ctx context.Context, id string) (models.Driver, error) {
+	return call[models.Driver](ctx, c, "SoftDeleteDriver", http.MethodDelete, itemPath(driversPath, id), nil)
+}
+
+// DeleteDriver removes a driver, only admins may
+func (c *Client) DeleteDriver(ctx context.Context, id string) (models.Driver, error) {
+	return call[models.Driver](ctx, c, "DeleteDriver", http.MethodDelete, itemPath(driversPath, id, "delete"), nil)
+}
+
+func (c *Client) ToggleDriverStatus(ctx context.Context, id string, active bool) (models.Driver, error) {
+	return toggleStatus[models.Driver](ctx, c, "ToggleDriverStatus", itemPath(driversPath, id, "toggle-status"), active)
+}
+
+// GetDriverTrips lists the trips of a driver, filter.Status, From and To apply
+func (c *Client) GetDriverTrips(ctx context.Context, id string, filter models.TripFilter, opts models.ListOptions) (Page[models.Trip], error) {
+	return list[models.Trip](ctx, c, "GetDriverTrips", itemPath(driversPath, id, "trips"), tripQuery(filter), opts)
+}
+
+// GetDriverFuelEntries lists the refuels of a driver, filter.From and To apply
+func (c *Client) GetDriverFuelEntries(ctx context.Context, id string, filter models.FuelFilter, opts models.ListOptions) (Page[models.FuelEntry], error) {
+	return list[models.FuelEntry](ctx, c, "GetDriverFuelEntries", itemPath(driversPath, id, "fuel"), fuelQuery(filter), opts)
+}
+
+func (c *Client) GetDriverFuelEfficiency(ctx context.Context, id string, filter models.FuelEfficiencyFilter) (models.FuelEfficiencyReport, error) {
+	return fuelEfficiency(ctx, c, "GetDriverFuelEfficiency", itemPath(driversPath, id, "fuel", "efficiency"), filter)
+}
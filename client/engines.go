@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const enginesPath = "/api/v1/engines"
+
+func (c *Client) GetEngine(ctx context.Context, id string) (models.Engine, error) {
+	return call[models.Engine](ctx, c, "GetEngine", http.MethodGet, itemPath(enginesPath, id), nil)
+}
+
+func (c *Client) CreateEngine(ctx context.Context, engineReq models.EngineRequest) (models.Engine, error) {
+	return call[models.Engine](ctx, c, "CreateEngine", http.MethodPost, enginesPath, engineReq)
+}
+
+func (c *Client) UpdateEngine(ctx context.Context, id string, engineReq models.EngineRequest) (models.Engine, error) {
+	return call[models.Engine](ctx, c, "UpdateEngine", http.MethodPut, itemPath(enginesPath, id), engineReq)
+}
+
+func (c *Client) DeleteEngine(ctx context.Context, id string) (models.Engine, error) {
+	return call[models.Engine](ctx, c, "DeleteEngine", http.MethodDelete, itemPath(enginesPath, id), nil)
+}
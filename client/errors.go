@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// Kinds of errors for the statuses the models error kinds do not cover. An *Error matches one of these or one of
+// models.ErrNotFound, ErrConflict, ErrValidation and ErrForbidden with errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("too many requests")
+	ErrLocked       = errors.New("account locked")
+	ErrServer       = errors.New("server error")
+	// ErrNoSession is returned when a call needs a token and the client has neither tokens nor credentials
+	ErrNoSession = errors.New("no session, log in or create the client with credentials")
+)
+
+// Error is a response with an error status, holding the problem document the server sent. Details is the
+// resource the problem is about if any, e.g. the time window of the trip a booking conflicts with; DecodeDetails reads it.
+type Error struct {
+	StatusCode int             `json:"status"`
+	Title      string          `json:"title"`
+	Detail     string          `json:"detail"`
+	Instance   string          `json:"instance"`
+	Details    json.RawMessage `json:"details"`
+	// RetryAfter is how long the server asked to wait, from the Retry-After header
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+// Is maps the status to the kind of error, so callers handle errors the same way in and out of process
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == models.ErrValidation
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == models.ErrForbidden
+	case http.StatusNotFound:
+		return target == models.ErrNotFound
+	case http.StatusConflict:
+		return target == models.ErrConflict
+	case http.StatusLocked:
+		return target == ErrLocked
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
+
+// DecodeDetails reads the details of the problem into v, e.g. a models.TripBooking for a booking conflict
+func (e *Error) DecodeDetails(v interface{}) error {
+	if len(e.Details) == 0 {
+		return errors.New("the problem has no details")
+	}
+	return json.Unmarshal(e.Details, v)
+}
+
+// decodeError reads an error response. Responses that are not problem documents, such as the plain text error
+// pages of a proxy in front of the server, keep their text as the detail.
+func decodeError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	apiErr := &Error{}
+	if err := json.Unmarshal(body, apiErr); err != nil {
+		apiErr = &Error{Detail: strings.TrimSpace(string(body))}
+	}
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const fuelPath = "/api/v1/fuel"
+
+func fuelQuery(filter models.FuelFilter) url.Values {
+	return newFilter().time("from", filter.From).time("to", filter.To).values()
+}
+
+func fuelEfficiency(ctx context.Context, c *Client, op string, path string, filter models.FuelEfficiencyFilter) (models.FuelEfficiencyReport, error) {
+	var report models.FuelEfficiencyReport
+	query := newFilter().time("from", filter.From).time("to", filter.To).string("interval", filter.Interval)
+	_, err := c.do(ctx, op, request{method: http.MethodGet, path: path, query: query.values()}, &report)
+	return report, err
+}
+
+// GetCarFuelEntries lists the refuels of a car, filter.From and To apply
+func (c *Client) GetCarFuelEntries(ctx context.Context, carID string, filter models.FuelFilter, opts models.ListOptions) (Page[models.FuelEntry], error) {
+	return list[models.FuelEntry](ctx, c, "GetCarFuelEntries", itemPath(carsPath, carID, "fuel"), fuelQuery(filter), opts)
+}
+
+func (c *Client) CreateFuelEntry(ctx context.Context, carID string, fuelReq models.FuelEntryRequest) (models.FuelEntry, error) {
+	return call[models.FuelEntry](ctx, c, "CreateFuelEntry", http.MethodPost, itemPath(carsPath, carID, "fuel"), fuelReq)
+}
+
+func (c *Client) GetFuelEntry(ctx context.Context, id string) (models.FuelEntry, error) {
+	return call[models.FuelEntry](ctx, c, "GetFuelEntry", http.MethodGet, itemPath(fuelPath, id), nil)
+}
+
+func (c *Client) DeleteFuelEntry(ctx context.Context, id string) (models.FuelEntry, error) {
+	return call[models.FuelEntry](ctx, c, "DeleteFuelEntry", http.MethodDelete, itemPath(fuelPath, id), nil)
+}
+
+func (c *Client) GetCarFuelEfficiency(ctx context.Context, carID string, filter models.FuelEfficiencyFilter) (models.FuelEfficiencyReport, error) {
+	return fuelEfficiency(ctx, c, "GetCarFuelEfficiency", itemPath(carsPath, carID, "fuel", "efficiency"), filter)
+}
+
+func (c *Client) GetFuelAnomalies(ctx context.Context, filter models.FuelAnomalyFilter, opts models.ListOptions) (Page[models.FuelAnomaly], error) {
+	query := newFilter().
+		uuid("car_id", filter.CarID).
+		time("from", filter.From).
+		time("to", filter.To).
+		float("threshold", filter.Threshold).
+		int("min_refuels", filter.MinSamples)
+	return list[models.FuelAnomaly](ctx, c, "GetFuelAnomalies", fuelPath+"/anomalies", query.values(), opts)
+}
+
+// ImportFuelCardStatement uploads a CSV fuel card statement. The mapping holds the columns that differ from the
+// server's mapping, a zero mapping uses the server's as is.
+func (c *Client) ImportFuelCardStatement(ctx context.Context, statement io.Reader, mapping models.FuelCardMapping) (models.FuelImportReport, error) {
+	var report models.FuelImportReport
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("statement", "statement.csv")
+	if err != nil {
+		return report, err
+	}
+	if _, err := io.Copy(part, statement); err != nil {
+		return report, err
+	}
+	if mapping != (models.FuelCardMapping{}) {
+		value, err := json.Marshal(mapping)
+		if err != nil {
+			return report, err
+		}
+		if err := form.WriteField("mapping", string(value)); err != nil {
+			return report, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return report, err
+	}
+
+	req := request{method: http.MethodPost, path: fuelPath + "/import", body: body.Bytes(), contentType: form.FormDataContentType()}
+	_, err = c.do(ctx, "ImportFuelCardStatement", req, &report)
+	return report, err
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const geofencesPath = "/api/v1/geofences"
+
+func (c *Client) GetGeofences(ctx context.Context, filter models.GeofenceFilter, opts models.ListOptions) (Page[models.Geofence], error) {
+	query := newFilter().string("kind", filter.Kind)
+	return list[models.Geofence](ctx, c, "GetGeofences", geofencesPath, query.values(), opts)
+}
+
+func (c *Client) GetGeofence(ctx context.Context, id string) (models.Geofence, error) {
+	return call[models.Geofence](ctx, c, "GetGeofence", http.MethodGet, itemPath(geofencesPath, id), nil)
+}
+
+func (c *Client) CreateGeofence(ctx context.Context, geofenceReq models.GeofenceRequest) (models.Geofence, error) {
+	return call[models.Geofence](ctx, c, "CreateGeofence", http.MethodPost, geofencesPath, geofenceReq)
+}
+
+func (c *Client) UpdateGeofence(ctx context.Context, id string, geofenceReq models.GeofenceRequest) (models.Geofence, error) {
+	return call[models.Geofence](ctx, c, "UpdateGeofence", http.MethodPut, itemPath(geofencesPath, id), geofenceReq)
+}
+
+func (c *Client) DeleteGeofence(ctx context.Context, id string) (models.Geofence, error) {
+	return call[models.Geofence](ctx, c, "DeleteGeofence", http.MethodDelete, itemPath(geofencesPath, id), nil)
+}
+
+func (c *Client) GetGeofenceEvents(ctx context.Context, filter models.GeofenceEventFilter, opts models.ListOptions) (Page[models.GeofenceEvent], error) {
+	query := newFilter().
+		uuid("geofence_id", filter.GeofenceID).
+		uuid("car_id", filter.CarID).
+		uuid("trip_id", filter.TripID).
+		string("event", filter.Event).
+		time("from", filter.From).
+		time("to", filter.To)
+	return list[models.GeofenceEvent](ctx, c, "GetGeofenceEvents", geofencesPath+"/events", query.values(), opts)
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// Page is one page of a list endpoint. Total counts the records matching the filters across every page.
+type Page[T any] struct {
+	Items []T
+	Total int
+}
+
+// list fetches one page of a list endpoint
+func list[T any](ctx context.Context, c *Client, op string, path string, query url.Values, opts models.ListOptions) (Page[T], error) {
+	page := Page[T]{Items: []T{}}
+	header, err := c.do(ctx, op, request{method: http.MethodGet, path: path, query: listQuery(query, opts)}, &page.Items)
+	if err != nil {
+		return page, err
+	}
+	page.Total, err = strconv.Atoi(header.Get("X-Total-Count"))
+	if err != nil {
+		page.Total = len(page.Items)
+	}
+	return page, nil
+}
+
+// listQuery adds the page and the order to the filters, the way handler.ParseListOptions reads them
+func listQuery(query url.Values, opts models.ListOptions) url.Values {
+	if query == nil {
+		query = url.Values{}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Sort != "" {
+		sort := opts.Sort
+		if opts.Desc {
+			sort = "-" + sort
+		}
+		query.Set("sort", sort)
+	}
+	return query
+}
+
+// filter builds the query of a list endpoint, leaving out zero values as the handlers ignore them
+type filter url.Values
+
+func (f filter) string(name string, value string) filter {
+	if value != "" {
+		url.Values(f).Set(name, value)
+	}
+	return f
+}
+
+func (f filter) uuid(name string, value uuid.UUID) filter {
+	if value != uuid.Nil {
+		url.Values(f).Set(name, value.String())
+	}
+	return f
+}
+
+func (f filter) time(name string, value time.Time) filter {
+	if !value.IsZero() {
+		url.Values(f).Set(name, value.Format(time.RFC3339Nano))
+	}
+	return f
+}
+
+func (f filter) int(name string, value int) filter {
+	if value != 0 {
+		url.Values(f).Set(name, strconv.Itoa(value))
+	}
+	return f
+}
+
+func (f filter) float(name string, value float64) filter {
+	if value != 0 {
+		url.Values(f).Set(name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return f
+}
+
+func (f filter) bool(name string, value *bool) filter {
+	if value != nil {
+		url.Values(f).Set(name, strconv.FormatBool(*value))
+	}
+	return f
+}
+
+// window sends a duration the way models.ParseWindow reads it
+func (f filter) window(name string, value time.Duration) filter {
+	if value > 0 {
+		url.Values(f).Set(name, value.String())
+	}
+	return f
+}
+
+func (f filter) values() url.Values {
+	return url.Values(f)
+}
+
+func newFilter() filter {
+	return filter(url.Values{})
+}
+
+// itemPath joins a collection and an id, e.g. itemPath("/api/v1/cars", id, "trips")
+func itemPath(collection string, id string, sub ...string) string {
+	path := collection + "/" + url.PathEscape(id)
+	for _, s := range sub {
+		path += "/" + s
+	}
+	return path
+}
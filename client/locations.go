@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const locationsPath = "/api/v1/locations"
+
+func (c *Client) GetLocations(ctx context.Context, filter models.LocationFilter, opts models.ListOptions) (Page[models.Location], error) {
+	query := newFilter().string("type", filter.Type).string("name", filter.Name)
+	return list[models.Location](ctx, c, "GetLocations", locationsPath, query.values(), opts)
+}
+
+func (c *Client) GetLocation(ctx context.Context, id string) (models.Location, error) {
+	return call[models.Location](ctx, c, "GetLocation", http.MethodGet, itemPath(locationsPath, id), nil)
+}
+
+func (c *Client) CreateLocation(ctx context.Context, locationReq models.LocationRequest) (models.Location, error) {
+	return call[models.Location](ctx, c, "CreateLocation", http.MethodPost, locationsPath, locationReq)
+}
+
+func (c *Client) UpdateLocation(ctx context.Context, id string, locationReq models.LocationRequest) (models.Location, error) {
+	return call[models.Location](ctx, c, "UpdateLocation", http.MethodPut, itemPath(locationsPath, id), locationReq)
+}
+
+func (c *Client) DeleteLocation(ctx context.Context, id string) (models.Location, error) {
+	return call[models.Location](ctx, c, "DeleteLocation", http.MethodDelete, itemPath(locationsPath, id), nil)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const maintenancePath = "/api/v1/maintenance"
+
+func (c *Client) GetMaintenanceRecords(ctx context.Context, carID string, opts models.ListOptions) (Page[models.MaintenanceRecord], error) {
+	return list[models.MaintenanceRecord](ctx, c, "GetMaintenanceRecords", itemPath(carsPath, carID, "maintenance"), nil, opts)
+}
+
+func (c *Client) GetMaintenanceRecord(ctx context.Context, id string) (models.MaintenanceRecord, error) {
+	return call[models.MaintenanceRecord](ctx, c, "GetMaintenanceRecord", http.MethodGet, itemPath(maintenancePath, id), nil)
+}
+
+func (c *Client) CreateMaintenanceRecord(ctx context.Context, carID string, recordReq models.MaintenanceRecordRequest) (models.MaintenanceRecord, error) {
+	return call[models.MaintenanceRecord](ctx, c, "CreateMaintenanceRecord", http.MethodPost, itemPath(carsPath, carID, "maintenance"), recordReq)
+}
+
+func (c *Client) UpdateMaintenanceRecord(ctx context.Context, id string, recordReq models.MaintenanceRecordRequest) (models.MaintenanceRecord, error) {
+	return call[models.MaintenanceRecord](ctx, c, "UpdateMaintenanceRecord", http.MethodPut, itemPath(maintenancePath, id), recordReq)
+}
+
+func (c *Client) DeleteMaintenanceRecord(ctx context.Context, id string) (models.MaintenanceRecord, error) {
+	return call[models.MaintenanceRecord](ctx, c, "DeleteMaintenanceRecord", http.MethodDelete, itemPath(maintenancePath, id), nil)
+}
+
+func (c *Client) GetMaintenancePlans(ctx context.Context, carID string) ([]models.MaintenancePlan, error) {
+	return call[[]models.MaintenancePlan](ctx, c, "GetMaintenancePlans", http.MethodGet, itemPath(carsPath, carID, "maintenance", "plans"), nil)
+}
+
+func (c *Client) CreateMaintenancePlan(ctx context.Context, carID string, planReq models.MaintenancePlanRequest) (models.MaintenancePlan, error) {
+	return call[models.MaintenancePlan](ctx, c, "CreateMaintenancePlan", http.MethodPost, itemPath(carsPath, carID, "maintenance", "plans"), planReq)
+}
+
+func (c *Client) UpdateMaintenancePlan(ctx context.Context, id string, planReq models.MaintenancePlanRequest) (models.MaintenancePlan, error) {
+	return call[models.MaintenancePlan](ctx, c, "UpdateMaintenancePlan", http.MethodPut, itemPath(maintenancePath+"/plans", id), planReq)
+}
+
+func (c *Client) DeleteMaintenancePlan(ctx context.Context, id string) (models.MaintenancePlan, error) {
+	return call[models.MaintenancePlan](ctx, c, "DeleteMaintenancePlan", http.MethodDelete, itemPath(maintenancePath+"/plans", id), nil)
+}
+
+// GetDueMaintenance lists the service plans that are overdue or come due within the window or the kilometers,
+// the server defaults apply to zero values
+func (c *Client) GetDueMaintenance(ctx context.Context, within time.Duration, withinKM float64, opts models.ListOptions) (Page[models.MaintenancePlan], error) {
+	query := newFilter().window("within", within).float("within_km", withinKM)
+	return list[models.MaintenancePlan](ctx, c, "GetDueMaintenance", maintenancePath+"/due", query.values(), opts)
+}
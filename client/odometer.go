@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+func (c *Client) GetOdometerReadings(ctx context.Context, carID string, filter models.OdometerFilter, opts models.ListOptions) (Page[models.OdometerReading], error) {
+	query := newFilter().
+		uuid("trip_id", filter.TripID).
+		string("source", filter.Source).
+		time("from", filter.From).
+		time("to", filter.To)
+	return list[models.OdometerReading](ctx, c, "GetOdometerReadings", itemPath(carsPath, carID, "odometer"), query.values(), opts)
+}
+
+func (c *Client) CreateOdometerReading(ctx context.Context, carID string, readingReq models.OdometerReadingRequest) (models.OdometerReading, error) {
+	return call[models.OdometerReading](ctx, c, "CreateOdometerReading", http.MethodPost, itemPath(carsPath, carID, "odometer"), readingReq)
+}
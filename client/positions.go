@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// GetPositions lists the GPS points of a car, filter.TripID, From and To apply
+func (c *Client) GetPositions(ctx context.Context, carID string, filter models.PositionFilter, opts models.ListOptions) (Page[models.Position], error) {
+	query := newFilter().
+		uuid("trip_id", filter.TripID).
+		time("from", filter.From).
+		time("to", filter.To)
+	return list[models.Position](ctx, c, "GetPositions", itemPath(carsPath, carID, "positions"), query.values(), opts)
+}
+
+// CreatePositions reports a batch of GPS points of a car, at most models.MaxPositionBatch
+func (c *Client) CreatePositions(ctx context.Context, carID string, batch models.PositionBatchRequest) (models.PositionBatch, error) {
+	return call[models.PositionBatch](ctx, c, "CreatePositions", http.MethodPost, itemPath(carsPath, carID, "positions"), batch)
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const tripsPath = "/api/v1/trips"
+
+func tripQuery(filter models.TripFilter) url.Values {
+	return newFilter().
+		string("status", filter.Status).
+		uuid("car_id", filter.CarID).
+		uuid("driver_id", filter.DriverID).
+		uuid("start_location_id", filter.StartLocationID).
+		uuid("end_location_id", filter.EndLocationID).
+		time("from", filter.From).
+		time("to", filter.To).
+		values()
+}
+
+func (c *Client) GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) (Page[models.Trip], error) {
+	return list[models.Trip](ctx, c, "GetTrips", tripsPath, tripQuery(filter), opts)
+}
+
+func (c *Client) GetTrip(ctx context.Context, id string) (models.Trip, error) {
+	return call[models.Trip](ctx, c, "GetTrip", http.MethodGet, itemPath(tripsPath, id), nil)
+}
+
+// CreateTrip books a trip. A booking that overlaps another trip of the car or the driver fails with
+// models.ErrConflict, and the *Error holds the other trip in its details.
+func (c *Client) CreateTrip(ctx context.Context, tripReq models.TripRequest) (models.Trip, error) {
+	return call[models.Trip](ctx, c, "CreateTrip", http.MethodPost, tripsPath, tripReq)
+}
+
+func (c *Client) UpdateTrip(ctx context.Context, id string, tripReq models.TripRequest) (models.Trip, error) {
+	return call[models.Trip](ctx, c, "UpdateTrip", http.MethodPut, itemPath(tripsPath, id), tripReq)
+}
+
+func (c *Client) UpdateTripStatus(ctx context.Context, id string, statusReq models.TripStatusRequest) (models.Trip, error) {
+	return call[models.Trip](ctx, c, "UpdateTripStatus", http.MethodPut, itemPath(tripsPath, id, "update-status"), statusReq)
+}
+
+func (c *Client) DeleteTrip(ctx context.Context, id string) (models.Trip, error) {
+	return call[models.Trip](ctx, c, "DeleteTrip", http.MethodDelete, itemPath(tripsPath, id), nil)
+}
+
+func (c *Client) GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error) {
+	return call[[]models.TripTransition](ctx, c, "GetTripTransitions", http.MethodGet, itemPath(tripsPath, id, "transitions"), nil)
+}
+
+// GetTripRoute returns the GPS track of a trip as a GeoJSON Feature
+func (c *Client) GetTripRoute(ctx context.Context, id string) (models.TripRoute, error) {
+	return call[models.TripRoute](ctx, c, "GetTripRoute", http.MethodGet, itemPath(tripsPath, id, "route"), nil)
+}
+
+func (c *Client) GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) (Page[models.RouteStats], error) {
+	query := newFilter().
+		uuid("car_id", filter.CarID).
+		uuid("driver_id", filter.DriverID).
+		uuid("origin_id", filter.OriginID).
+		uuid("destination_id", filter.DestinationID).
+		time("from", filter.From).
+		time("to", filter.To)
+	return list[models.RouteStats](ctx, c, "GetRouteStats", tripsPath+"/routes", query.values(), opts)
+}
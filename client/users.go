@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+const usersPath = "/api/v1/users"
+
+func (c *Client) GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) (Page[models.User], error) {
+	query := newFilter().string("role", filter.Role).bool("active", filter.Active)
+	return list[models.User](ctx, c, "GetUsers", usersPath, query.values(), opts)
+}
+
+func (c *Client) GetUser(ctx context.Context, id string) (models.User, error) {
+	return call[models.User](ctx, c, "GetUser", http.MethodGet, itemPath(usersPath, id), nil)
+}
+
+func (c *Client) CreateUser(ctx context.Context, userReq models.UserRequest) (models.User, error) {
+	return call[models.User](ctx, c, "CreateUser", http.MethodPost, usersPath, userReq)
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id string, userReq models.UserRequest) (models.User, error) {
+	return call[models.User](ctx, c, "UpdateUser", http.MethodPut, itemPath(usersPath, id), userReq)
+}
+
+func (c *Client) UpdateUserPassword(ctx context.Context, id string, passwordReq models.UpdatePasswordRequest) (models.User, error) {
+	return call[models.User](ctx, c, "UpdateUserPassword", http.MethodPut, itemPath(usersPath, id, "update-password"), passwordReq)
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id string) (models.User, error) {
+	return call[models.User](ctx, c, "DeleteUser", http.MethodDelete, itemPath(usersPath, id), nil)
+}
+
+func (c *Client) ToggleUserStatus(ctx context.Context, id string, active bool) (models.User, error) {
+	return toggleStatus[models.User](ctx, c, "ToggleUserStatus", itemPath(usersPath, id, "toggle-status"), active)
+}
+
+// toggleStatus activates or deactivates a user or a driver
+func toggleStatus[T any](ctx context.Context, c *Client, op string, path string, active bool) (T, error) {
+	var out T
+	req := request{method: http.MethodPut, path: path, query: map[string][]string{"active": {strconv.FormatBool(active)}}}
+	_, err := c.do(ctx, op, req, &out)
+	return out, err
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JulianaSau/carzone/client"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// the demo users of the in-memory store share this password
+const demoPassword = "admin123"
+
+// fastRetry keeps the retry tests quick
+var fastRetry = client.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+// newTestServer serves the API on a fresh in-memory store with the demo data loaded. wrap, when not nil, sits in
+// front of the router to fake failures.
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	var handler http.Handler = newRouter(memoryStores())
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, server *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	c, err := client.New(server.URL, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// failing answers status to the first n requests for path and counts every request for it
+func failing(path string, status int, n int32, requests *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if atomic.AddInt32(requests, 1) <= n {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestClientLoginAndRefresh(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, nil)
+	c := newTestClient(t, server)
+
+	if _, err := c.GetCar(ctx, "c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"); !errors.Is(err, client.ErrNoSession) {
+		t.Fatalf("GetCar without a session: got %v, want ErrNoSession", err)
+	}
+
+	tokens, err := c.Login(ctx, "admin", demoPassword)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("Login: got tokens %+v, want an access and a refresh token", tokens)
+	}
+
+	car, err := c.GetCar(ctx, "c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3")
+	if err != nil {
+		t.Fatalf("GetCar: %v", err)
+	}
+	if car.RegistrationNumber != "KCX 786T" {
+		t.Fatalf("GetCar: got registration %q, want KCX 786T", car.RegistrationNumber)
+	}
+
+	refreshed, err := c.RefreshToken(ctx)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if refreshed.AccessToken == tokens.AccessToken || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("RefreshToken: the tokens were not rotated")
+	}
+	if _, err := c.GetCar(ctx, "c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"); err != nil {
+		t.Fatalf("GetCar after the refresh: %v", err)
+	}
+
+	// a client resuming the replaced session cannot refresh it again
+	stale := newTestClient(t, server, client.WithTokens(models.TokenPair{RefreshToken: tokens.RefreshToken}))
+	if _, err := stale.RefreshToken(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("RefreshToken with a used refresh token: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClientProblemErrors(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, nil)
+
+	admin := newTestClient(t, server, client.WithCredentials("admin", demoPassword))
+
+	_, err := admin.GetCar(ctx, uuid.NewString())
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("GetCar of an unknown car: got %v, want ErrNotFound", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Title != "Not Found" || apiErr.Detail == "" {
+		t.Fatalf("GetCar of an unknown car: got %#v, want a 404 problem with a detail", err)
+	}
+
+	if _, err := admin.GetCar(ctx, "not-a-uuid"); !errors.Is(err, models.ErrValidation) {
+		t.Fatalf("GetCar with an invalid id: got %v, want ErrValidation", err)
+	}
+
+	// the demo drivers' licenses expired at the end of 2024, the conflict carries the license
+	_, err = admin.CreateTrip(ctx, models.TripRequest{
+		Description:   "Nairobi To Nakuru Route",
+		DriverID:      uuid.MustParse("a1b2c3d4-e5f6-7a8b-9c0d-e1f2a3b4c5d6"),
+		CarID:         uuid.MustParse("9d6a56f8-79c3-4931-a5c0-6b290c84ba2f"),
+		StartLocation: "Nairobi",
+		EndLocation:   "Nakuru",
+		StartTime:     time.Now().Add(24 * time.Hour),
+		Status:        models.TripStatusScheduled,
+	})
+	if !errors.Is(err, models.ErrConflict) || !errors.As(err, &apiErr) {
+		t.Fatalf("CreateTrip for an expired license: got %v, want ErrConflict", err)
+	}
+	var license models.DriverLicenseExpiry
+	if err := apiErr.DecodeDetails(&license); err != nil {
+		t.Fatalf("DecodeDetails: %v", err)
+	}
+	if license.DriverLicenseNo != "DL123456" {
+		t.Fatalf("DecodeDetails: got license %q, want DL123456", license.DriverLicenseNo)
+	}
+
+	driver := newTestClient(t, server, client.WithCredentials("driver", demoPassword))
+	if _, err := driver.DeleteCar(ctx, "c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"); !errors.Is(err, models.ErrForbidden) {
+		t.Fatalf("DeleteCar as a driver: got %v, want ErrForbidden", err)
+	}
+
+	// last, as a failed login slows down the next ones from the same address
+	if _, err := newTestClient(t, server).Login(ctx, "manager", "wrong"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Login with a wrong password: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClientRetriesUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	var requests int32
+	server := newTestServer(t, failing("/.well-known/jwks.json", http.StatusServiceUnavailable, 2, &requests))
+	c := newTestClient(t, server, client.WithRetry(fastRetry))
+
+	if _, err := c.JWKS(ctx); err != nil {
+		t.Fatalf("JWKS: %v", err)
+	}
+	if atomic.LoadInt32(&requests) != 3 {
+		t.Fatalf("JWKS: got %d requests, want 3", requests)
+	}
+
+	// a POST may have been carried out, it is not retried
+	var logins int32
+	server = newTestServer(t, failing("/api/v1/login", http.StatusServiceUnavailable, 1, &logins))
+	c = newTestClient(t, server, client.WithRetry(fastRetry))
+
+	_, err := c.Login(ctx, "admin", demoPassword)
+	if !errors.Is(err, client.ErrServer) {
+		t.Fatalf("Login: got %v, want ErrServer", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Login: got %v, want a 503", err)
+	}
+	if atomic.LoadInt32(&logins) != 1 {
+		t.Fatalf("Login: got %d requests, want 1", logins)
+	}
+}
+
+func TestClientContextCancellation(t *testing.T) {
+	// the server asks to come back after a minute, the caller gives up first
+	unavailable := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		})
+	}
+	c := newTestClient(t, newTestServer(t, unavailable), client.WithRetry(fastRetry))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.JWKS(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("JWKS waiting to retry: got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("JWKS waiting to retry: returned after %s, want it to stop at the deadline", elapsed)
+	}
+
+	// a request in flight is abandoned too
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		})
+	}
+	c = newTestClient(t, newTestServer(t, hanging), client.WithRetry(fastRetry))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.JWKS(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("JWKS in flight: got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+)
+
+// apiError is a problem returned by the server
+type apiError struct {
+	handler.Problem
+}
+
+func (e *apiError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.Status, e.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+var errNotLoggedIn = errors.New("not logged in, run `carzonectl login` first")
+
+// api calls the carzone REST API with the cached session, refreshing the access token when it has expired
+type api struct {
+	http    *http.Client
+	session *session
+}
+
+func newAPI(session *session) *api {
+	return &api{http: &http.Client{Timeout: 30 * time.Second}, session: session}
+}
+
+// do sends a request and decodes the JSON response into out, when out is not nil. The response headers are
+// returned for the list metadata.
+func (a *api) do(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) (http.Header, error) {
+	if a.session.AccessToken == "" {
+		return nil, errNotLoggedIn
+	}
+	if a.session.expired() {
+		if err := a.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := a.send(ctx, method, path, query, in, out, a.session.AccessToken)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized && a.session.RefreshToken != "" {
+		// the token may have been revoked before it expired, a refreshed one is tried once
+		if err := a.refresh(ctx); err != nil {
+			return nil, err
+		}
+		return a.send(ctx, method, path, query, in, out, a.session.AccessToken)
+	}
+	return header, err
+}
+
+func (a *api) send(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}, token string) (http.Header, error) {
+	target := strings.TrimSuffix(a.session.Server, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp.Header, decodeProblem(resp)
+	}
+	if out == nil {
+		return resp.Header, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.Header, fmt.Errorf("invalid response from %s %s: %w", method, path, err)
+	}
+	return resp.Header, nil
+}
+
+// decodeProblem reads an error response. Responses that are not problem documents, such as the plain text
+// 403 of the role checks, keep their text as the detail.
+func decodeProblem(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &apiError{}
+	if err := json.Unmarshal(body, &apiErr.Problem); err != nil || apiErr.Status == 0 {
+		apiErr.Problem = handler.Problem{Detail: strings.TrimSpace(string(body))}
+	}
+	apiErr.Status = resp.StatusCode
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		apiErr.Detail = strings.TrimSpace(apiErr.Detail + " (retry after " + retryAfter + "s)")
+	}
+	return apiErr
+}
+
+// login exchanges credentials for a token pair and stores it in the session
+func (a *api) login(ctx context.Context, credentials models.Credentials) error {
+	var tokens models.TokenPair
+	if _, err := a.send(ctx, http.MethodPost, "/api/v1/login", nil, credentials, &tokens, ""); err != nil {
+		return err
+	}
+	a.session.set(credentials.UserName, tokens)
+	return a.session.save()
+}
+
+// refresh rotates the refresh token. A refresh token that is no longer accepted ends the session.
+func (a *api) refresh(ctx context.Context) error {
+	if a.session.RefreshToken == "" {
+		return errors.New("session expired, run `carzonectl login` again")
+	}
+
+	var tokens models.TokenPair
+	_, err := a.send(ctx, http.MethodPost, "/api/v1/token/refresh", nil, models.RefreshRequest{RefreshToken: a.session.RefreshToken}, &tokens, "")
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized {
+		a.session.clear()
+		_ = a.session.save()
+		return errors.New("session expired, run `carzonectl login` again")
+	}
+	if err != nil {
+		return err
+	}
+	a.session.set(a.session.UserName, tokens)
+	return a.session.save()
+}
+
+// logout revokes the tokens of the session on the server and forgets them
+func (a *api) logout(ctx context.Context) error {
+	if a.session.AccessToken == "" {
+		return nil
+	}
+	_, err := a.do(ctx, http.MethodPost, "/api/v1/logout", nil, models.RefreshRequest{RefreshToken: a.session.RefreshToken}, nil)
+	a.session.clear()
+	if saveErr := a.session.save(); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// totalCount reads the X-Total-Count header of a list response, -1 when it is missing
+func totalCount(header http.Header) int {
+	total, err := strconv.Atoi(header.Get("X-Total-Count"))
+	if err != nil {
+		return -1
+	}
+	return total
+}
@@ -0,0 +1,140 @@
+// Command carzonectl manages a carzone fleet from the command line. It logs in once, caches the session and
+// then lists, shows, creates, updates and deletes cars, engines, drivers, users and trips:
+//
+//	carzonectl login -u admin
+//	carzonectl cars list -q status=Available -o csv
+//	carzonectl trips create -f trip.yaml
+//	carzonectl trips status <id> Completed -distance 120 -fuel 9.5
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/JulianaSau/carzone/models"
+	"golang.org/x/term"
+)
+
+const defaultServer = "http://localhost:8080"
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: carzonectl [-server url] <command> [arguments]
+
+commands:
+  login [-u username]    log in, the password is read from CARZONE_PASSWORD or prompted for
+  logout                 revoke the cached session`)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", name, strings.Join(commands[name].actions(), ", "))
+	}
+	fmt.Fprintln(os.Stderr, "\nrun `carzonectl <command> <action> -h` for the flags of an action")
+}
+
+func main() {
+	server := flag.String("server", "", "carzone URL, $CARZONE_SERVER or the server of the cached session by default")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, *server, flag.Args()); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "carzonectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, server string, args []string) error {
+	session, err := loadSession()
+	if err != nil {
+		return err
+	}
+	switch {
+	case server != "":
+		session.Server = server
+	case os.Getenv("CARZONE_SERVER") != "":
+		session.Server = os.Getenv("CARZONE_SERVER")
+	case session.Server == "":
+		session.Server = defaultServer
+	}
+	api := newAPI(session)
+
+	switch args[0] {
+	case "login":
+		return login(ctx, api, args[1:])
+	case "logout":
+		return api.logout(ctx)
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("%s needs an action: %s", args[0], strings.Join(cmd.actions(), ", "))
+	}
+	return cmd.run(ctx, api, args[1], args[2:])
+}
+
+func login(ctx context.Context, api *api, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	userName := fs.String("u", api.session.UserName, "username")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if *userName == "" {
+		fmt.Fprint(os.Stderr, "Username: ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		*userName = strings.TrimSpace(line)
+	}
+
+	password := os.Getenv("CARZONE_PASSWORD")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return err
+			}
+			password = string(secret)
+		} else {
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				return err
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	if err := api.login(ctx, models.Credentials{UserName: *userName, Password: password}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Logged in to %s as %s\n", api.session.Server, *userName)
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+func validateFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return nil
+	}
+	return fmt.Errorf("invalid output %q, expected table, json or csv", format)
+}
+
+// column is one field shown in table and CSV output, JSON output holds every field of the model
+type column[T any] struct {
+	header string
+	value  func(T) string
+}
+
+func printList[T any](w io.Writer, format string, columns []column[T], items []T) error {
+	switch format {
+	case formatJSON:
+		return printJSON(w, items)
+	case formatCSV:
+		return printCSV(w, columns, items)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c.header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(tw, strings.Join(row(columns, item), "\t"))
+	}
+	return tw.Flush()
+}
+
+// printItem shows a single record, as a field per line in a table
+func printItem[T any](w io.Writer, format string, columns []column[T], item T) error {
+	switch format {
+	case formatJSON:
+		return printJSON(w, item)
+	case formatCSV:
+		return printCSV(w, columns, []T{item})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range columns {
+		fmt.Fprintf(tw, "%s:\t%s\n", strings.ToUpper(c.header), c.value(item))
+	}
+	return tw.Flush()
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func printCSV[T any](w io.Writer, columns []column[T], items []T) error {
+	cw := csv.NewWriter(w)
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.header
+	}
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := cw.Write(row(columns, item)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func row[T any](columns []column[T], item T) []string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = c.value(item)
+	}
+	return values
+}
+
+// formatting helpers for column values, zero values show as empty cells
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatUUID(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+func formatUUIDPtr(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return formatUUID(*id)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// command is a resource the CLI manages, e.g. `carzonectl cars list`
+type command interface {
+	run(ctx context.Context, api *api, action string, args []string) error
+	actions() []string
+}
+
+// action is an extra subcommand of a resource, such as `trips status`
+type action func(ctx context.Context, api *api, args []string) error
+
+// resource runs the common subcommands against a REST collection. T is the model the server returns, C and U
+// the requests it takes on create and update.
+type resource[T any, C any, U any] struct {
+	name    string
+	path    string
+	columns []column[T]
+	// list is false for collections the API only serves by id
+	list bool
+	// toggle adds toggle-status for collections that can be deactivated
+	toggle bool
+	// hardDeletePath, when set, is appended to the item path by `delete --hard`
+	hardDeletePath string
+	extra          map[string]action
+}
+
+func (r *resource[T, C, U]) actions() []string {
+	actions := []string{"get", "create", "update", "delete"}
+	if r.list {
+		actions = append([]string{"list"}, actions...)
+	}
+	if r.toggle {
+		actions = append(actions, "toggle-status")
+	}
+	for name := range r.extra {
+		actions = append(actions, name)
+	}
+	sort.Strings(actions[len(actions)-len(r.extra):])
+	return actions
+}
+
+func (r *resource[T, C, U]) run(ctx context.Context, api *api, action string, args []string) error {
+	switch {
+	case action == "list" && r.list:
+		return r.listItems(ctx, api, args)
+	case action == "get":
+		return r.get(ctx, api, args)
+	case action == "create":
+		return r.create(ctx, api, args)
+	case action == "update":
+		return r.update(ctx, api, args)
+	case action == "delete":
+		return r.delete(ctx, api, args)
+	case action == "toggle-status" && r.toggle:
+		return r.toggleStatus(ctx, api, args)
+	}
+	if extra, ok := r.extra[action]; ok {
+		return extra(ctx, api, args)
+	}
+	return fmt.Errorf("unknown %s command %q, expected one of %s", r.name, action, strings.Join(r.actions(), ", "))
+}
+
+func (r *resource[T, C, U]) listItems(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " list")
+	limit := fs.Int("limit", 0, "number of records per page, the server default when 0")
+	offset := fs.Int("offset", 0, "number of records to skip")
+	sortBy := fs.String("sort", "", "field to sort by, prefixed with - for descending order")
+	query := url.Values{}
+	fs.Func("q", "filter as name=value, repeatable, e.g. -q status=Completed", func(filter string) error {
+		name, value, ok := strings.Cut(filter, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("expected name=value, got %q", filter)
+		}
+		query.Add(name, value)
+		return nil
+	})
+	if _, err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	if *offset > 0 {
+		query.Set("offset", strconv.Itoa(*offset))
+	}
+	if *sortBy != "" {
+		query.Set("sort", *sortBy)
+	}
+
+	var items []T
+	header, err := api.do(ctx, http.MethodGet, r.path, query, nil, &items)
+	if err != nil {
+		return err
+	}
+	if err := printList(os.Stdout, *output, r.columns, items); err != nil {
+		return err
+	}
+	if total := totalCount(header); total >= 0 && *output == formatTable {
+		fmt.Fprintf(os.Stderr, "%d of %d %s\n", len(items), total, r.name)
+	}
+	return nil
+}
+
+func (r *resource[T, C, U]) get(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " get <id>")
+	positional, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	var item T
+	if _, err := api.do(ctx, http.MethodGet, r.itemPath(positional[0]), nil, nil, &item); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, r.columns, item)
+}
+
+func (r *resource[T, C, U]) create(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " create -f <file>")
+	file := fs.String("f", "", "JSON or YAML file with the "+r.name+" request, - for stdin")
+	if _, err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var req C
+	if err := readRequest(*file, &req); err != nil {
+		return err
+	}
+	var item T
+	if _, err := api.do(ctx, http.MethodPost, r.path, nil, req, &item); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, r.columns, item)
+}
+
+func (r *resource[T, C, U]) update(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " update <id> -f <file>")
+	file := fs.String("f", "", "JSON or YAML file with the "+r.name+" request, - for stdin")
+	positional, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	var req U
+	if err := readRequest(*file, &req); err != nil {
+		return err
+	}
+	var item T
+	if _, err := api.do(ctx, http.MethodPut, r.itemPath(positional[0]), nil, req, &item); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, r.columns, item)
+}
+
+func (r *resource[T, C, U]) delete(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " delete <id>")
+	var hard *bool
+	if r.hardDeletePath != "" {
+		hard = fs.Bool("hard", false, "remove the record instead of deactivating it")
+	}
+	positional, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	path := r.itemPath(positional[0])
+	if hard != nil && *hard {
+		path += r.hardDeletePath
+	}
+	var item T
+	if _, err := api.do(ctx, http.MethodDelete, path, nil, nil, &item); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, r.columns, item)
+}
+
+func (r *resource[T, C, U]) toggleStatus(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet(r.name + " toggle-status <id> -active=true|false")
+	active := fs.String("active", "", "true to activate, false to deactivate")
+	positional, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	isActive, err := strconv.ParseBool(*active)
+	if err != nil {
+		return fmt.Errorf("-active must be true or false, got %q", *active)
+	}
+
+	var item T
+	query := url.Values{"active": {strconv.FormatBool(isActive)}}
+	if _, err := api.do(ctx, http.MethodPut, r.itemPath(positional[0])+"/toggle-status", query, nil, &item); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, r.columns, item)
+}
+
+func (r *resource[T, C, U]) itemPath(id string) string {
+	return r.path + "/" + url.PathEscape(id)
+}
+
+// newFlagSet creates the flags of a subcommand with the -o output flag every subcommand shares
+func newFlagSet(usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(usage, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: carzonectl %s\n", usage)
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", formatTable, "output format: table, json or csv")
+	return fs, output
+}
+
+// parseFlags parses flags given before and after the positional arguments, and checks that between min and max
+// positional arguments were given
+func parseFlags(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) < min || len(positional) > max {
+		fs.Usage()
+		return nil, fmt.Errorf("unexpected number of arguments: %d", len(positional))
+	}
+	if output := fs.Lookup("o"); output != nil {
+		if err := validateFormat(output.Value.String()); err != nil {
+			return nil, err
+		}
+	}
+	return positional, nil
+}
+
+// readRequest decodes a JSON or YAML request file into a models request. YAML is converted to JSON first so the
+// json tags of the models apply to both, and fields the request does not have are rejected.
+func readRequest(file string, req interface{}) error {
+	if file == "" {
+		return errors.New("a request file is required, pass -f <file>")
+	}
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	trimmed := bytes.TrimSpace(data)
+	isJSON := ext == ".json" || (ext != ".yaml" && ext != ".yml" && len(trimmed) > 0 && trimmed[0] == '{')
+	if !isJSON {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("invalid YAML in %s: %w", file, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("invalid YAML in %s: %w", file, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return fmt.Errorf("invalid request in %s: %w", file, err)
+	}
+	return nil
+}
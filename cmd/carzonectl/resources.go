@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// commands are the resources the CLI manages, keyed by the name used on the command line
+var commands = map[string]command{
+	"cars": &resource[models.Car, models.CarRequest, models.CarRequest]{
+		name: "cars",
+		path: "/api/v1/cars",
+		list: true,
+		columns: []column[models.Car]{
+			{"id", func(c models.Car) string { return formatUUID(c.ID) }},
+			{"registration_number", func(c models.Car) string { return c.RegistrationNumber }},
+			{"name", func(c models.Car) string { return c.Name }},
+			{"brand", func(c models.Car) string { return c.Brand }},
+			{"year", func(c models.Car) string { return c.Year }},
+			{"fuel_type", func(c models.Car) string { return c.FuelType }},
+			{"engine_id", func(c models.Car) string { return formatUUID(c.Engine.EngineID) }},
+			{"status", func(c models.Car) string { return c.Status }},
+			{"odometer_km", func(c models.Car) string { return formatFloat(c.OdometerKM) }},
+			{"service_due", func(c models.Car) string { return strconv.FormatBool(c.ServiceDue) }},
+		},
+	},
+	"engines": &resource[models.Engine, models.EngineRequest, models.EngineRequest]{
+		name: "engines",
+		path: "/api/v1/engines",
+		columns: []column[models.Engine]{
+			{"engine_id", func(e models.Engine) string { return formatUUID(e.EngineID) }},
+			{"displacement", func(e models.Engine) string { return formatInt(e.Displacement) }},
+			{"no_of_cylinders", func(e models.Engine) string { return formatInt(e.NoOfCylinders) }},
+			{"car_range", func(e models.Engine) string { return formatInt(e.CarRange) }},
+			{"updated_at", func(e models.Engine) string { return formatTime(e.UpdatedAt) }},
+		},
+	},
+	"drivers": &resource[models.Driver, models.DriverRequest, models.DriverUpdateRequest]{
+		name:           "drivers",
+		path:           "/api/v1/drivers",
+		list:           true,
+		toggle:         true,
+		hardDeletePath: "/delete",
+		columns: []column[models.Driver]{
+			{"id", func(d models.Driver) string { return formatUUID(d.ID) }},
+			{"user_id", func(d models.Driver) string { return formatUUID(d.UserID) }},
+			{"name", func(d models.Driver) string { return d.User.FirstName + " " + d.User.LastName }},
+			{"driver_license_number", func(d models.Driver) string { return d.DriverLicenseNo }},
+			{"license_expiry", func(d models.Driver) string { return formatTime(d.LicenseExpiry) }},
+			{"active", func(d models.Driver) string { return strconv.FormatBool(d.Active) }},
+		},
+	},
+	"users": &resource[models.User, models.UserRequest, models.UserRequest]{
+		name:   "users",
+		path:   "/api/v1/users",
+		list:   true,
+		toggle: true,
+		columns: []column[models.User]{
+			{"id", func(u models.User) string { return formatUUID(u.ID) }},
+			{"username", func(u models.User) string { return u.UserName }},
+			{"first_name", func(u models.User) string { return u.FirstName }},
+			{"last_name", func(u models.User) string { return u.LastName }},
+			{"email", func(u models.User) string { return u.Email }},
+			{"role", func(u models.User) string { return u.Role }},
+			{"active", func(u models.User) string { return strconv.FormatBool(u.Active) }},
+		},
+	},
+	"trips": &resource[models.Trip, models.TripRequest, models.TripRequest]{
+		name:    "trips",
+		path:    "/api/v1/trips",
+		list:    true,
+		columns: tripColumns,
+		extra: map[string]action{
+			"status": updateTripStatus,
+		},
+	},
+}
+
+var tripColumns = []column[models.Trip]{
+	{"id", func(t models.Trip) string { return formatUUID(t.ID) }},
+	{"description", func(t models.Trip) string { return t.Description }},
+	{"status", func(t models.Trip) string { return t.Status }},
+	{"car_id", func(t models.Trip) string { return formatUUID(t.CarID) }},
+	{"driver_id", func(t models.Trip) string { return formatUUID(t.DriverID) }},
+	{"start_location", func(t models.Trip) string { return t.StartLocation }},
+	{"end_location", func(t models.Trip) string { return t.EndLocation }},
+	{"start_location_id", func(t models.Trip) string { return formatUUIDPtr(t.StartLocationID) }},
+	{"end_location_id", func(t models.Trip) string { return formatUUIDPtr(t.EndLocationID) }},
+	{"start_time", func(t models.Trip) string { return formatTime(t.StartTime) }},
+	{"end_time", func(t models.Trip) string { return formatTime(t.EndTime) }},
+	{"distance_km", func(t models.Trip) string { return formatFloat(t.DistanceKM) }},
+}
+
+// updateTripStatus moves a trip to another status, e.g. `carzonectl trips status <id> Completed -distance 120
+// -fuel 9.5`. The request can also be given as a file with -f.
+func updateTripStatus(ctx context.Context, api *api, args []string) error {
+	fs, output := newFlagSet("trips status <id> <status>")
+	file := fs.String("f", "", "JSON or YAML file with the status request, - for stdin")
+	reason := fs.String("reason", "", "reason for the change, e.g. why a trip is cancelled")
+	endTime := fs.String("end-time", "", "time the trip ended, RFC 3339, now when completing without it")
+	distance := fs.Float64("distance", 0, "distance covered in kilometers")
+	fuel := fs.Float64("fuel", 0, "fuel consumed in liters")
+	odometer := fs.Float64("odometer", 0, "odometer reading in kilometers")
+
+	positional, err := parseFlags(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	var statusReq models.TripStatusRequest
+	if *file != "" {
+		if err := readRequest(*file, &statusReq); err != nil {
+			return err
+		}
+	}
+	if len(positional) == 2 {
+		statusReq.Status = positional[1]
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "reason":
+			statusReq.Reason = *reason
+		case "distance":
+			statusReq.DistanceKM = *distance
+		case "fuel":
+			statusReq.FuelConsumedLiters = *fuel
+		case "odometer":
+			statusReq.OdometerKM = *odometer
+		}
+	})
+	if *endTime != "" {
+		if statusReq.EndTime, err = time.Parse(time.RFC3339, *endTime); err != nil {
+			return err
+		}
+	}
+	if statusReq.Status == models.TripStatusCompleted && statusReq.EndTime.IsZero() {
+		statusReq.EndTime = time.Now().UTC().Truncate(time.Second)
+	}
+
+	var trip models.Trip
+	if _, err := api.do(ctx, http.MethodPut, "/api/v1/trips/"+url.PathEscape(positional[0])+"/update-status", nil, statusReq, &trip); err != nil {
+		return err
+	}
+	return printItem(os.Stdout, *output, tripColumns, trip)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// expirySkew refreshes access tokens shortly before they expire rather than after a rejected request
+const expirySkew = 30 * time.Second
+
+// session is the login cached between runs, in $CARZONE_SESSION or carzone/session.json under the user's
+// config directory. The file holds tokens and is only readable by its owner.
+type session struct {
+	Server       string    `json:"server"`
+	UserName     string    `json:"username"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	path string
+}
+
+func sessionPath() (string, error) {
+	if path := os.Getenv("CARZONE_SESSION"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "carzone", "session.json"), nil
+}
+
+// loadSession reads the cached session, an empty one when there is none
+func loadSession() (*session, error) {
+	path, err := sessionPath()
+	if err != nil {
+		return nil, err
+	}
+	s := &session{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.New("invalid session file " + path + ", run `carzonectl login` again")
+	}
+	return s, nil
+}
+
+func (s *session) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+func (s *session) set(userName string, tokens models.TokenPair) {
+	s.UserName = userName
+	s.AccessToken = tokens.AccessToken
+	if s.AccessToken == "" {
+		s.AccessToken = tokens.Token
+	}
+	s.RefreshToken = tokens.RefreshToken
+	s.ExpiresAt = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		s.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+}
+
+func (s *session) clear() {
+	s.UserName = ""
+	s.AccessToken = ""
+	s.RefreshToken = ""
+	s.ExpiresAt = time.Time{}
+}
+
+func (s *session) expired() bool {
+	return !s.ExpiresAt.IsZero() && time.Now().Add(expirySkew).After(s.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package driver
+
+import (
+	"database/sql"
+	"log"
+	"os"
+)
+
+var db *sql.DB
+
+// InitDB connects to the database named by DB_DRIVER: postgres, the default, or sqlite
+func InitDB() {
+	switch dbDriver := os.Getenv("DB_DRIVER"); dbDriver {
+	case "", "postgres":
+		initPostgres()
+	case "sqlite":
+		initSQLite()
+	default:
+		log.Fatalf("unknown DB_DRIVER %q, use postgres or sqlite", dbDriver)
+	}
+}
+
+func GetDB() *sql.DB {
+	return db
+}
+
+func CloseDB() {
+	if err := db.Close(); err != nil {
+		log.Fatalf("error closing database: %v", err)
+	}
+}
@@ -10,9 +10,9 @@ import (
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB
-
-func InitDB() {
+// initPostgres connects to the postgres database of the DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME
+// environment variables, waiting for it to come up
+func initPostgres() {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_PORT"),
@@ -62,13 +62,3 @@ func InitDB() {
 
 	fmt.Println("Successfully connected to the database")
 }
-
-func GetDB() *sql.DB {
-	return db
-}
-
-func CloseDB() {
-	if err := db.Close(); err != nil {
-		log.Fatalf("error closing database: %v", err)
-	}
-}
@@ -0,0 +1,26 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/JulianaSau/carzone/store"
+)
+
+// initSQLite opens the SQLite database in the file DB_PATH, carzone.db by default, creating it if needed
+func initSQLite() {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "carzone.db"
+	}
+
+	fmt.Println("Opening the SQLite database", path)
+	var err error
+	db, err = store.OpenSQLite(path)
+	if err != nil {
+		log.Fatalf("error opening database %s: %v", path, err)
+	}
+
+	fmt.Println("Successfully connected to the database")
+}
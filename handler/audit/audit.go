@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+// resources maps the collection names used in the url to the audited resources
+var resources = map[string]string{
+	"cars":    models.AuditResourceCar,
+	"drivers": models.AuditResourceDriver,
+	"trips":   models.AuditResourceTrip,
+	"users":   models.AuditResourceUser,
+}
+
+type AuditHandler struct {
+	service service.AuditServiceInterface
+}
+
+func NewAuditHandler(service service.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+// GetHistoryHandler godoc
+// @Summary Get the change history of a resource
+// @Description Get a page of the audit log of a car, driver, trip or user, newest change first. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Audit
+// @Accept  json
+// @Produce  json
+// @Param resource path string true "Resource" Enums(cars, drivers, trips, users)
+// @Param id path string true "Resource ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of entries to skip"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending (default -created_at)"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} handler.Problem "Invalid ID or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 404 {object} handler.Problem "Unknown resource"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/{resource}/{id}/history [get]
+// @Security Bearer
+func (h *AuditHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("AuditHandler")
+	ctx, span := tracer.Start(r.Context(), "GetHistory-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	resource, ok := resources[vars["resource"]]
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusNotFound, "Unknown resource")
+		return
+	}
+
+	resourceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, total, err := h.service.GetHistory(ctx, resource, resourceID.String(), opts)
+	if err != nil {
+		log.Println("Error getting history: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		log.Println("Error marshalling history response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
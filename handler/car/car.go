@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/JulianaSau/carzone/handler"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/service"
 	"github.com/gorilla/mux"
@@ -30,9 +31,11 @@ func NewCarHandler(service service.CarServiceInterface) *CarHandler {
 // @Produce  json
 // @Param id path string true "Car ID"
 // @Success 200 {object} models.Car
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Car not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars/{id} [get]
 // @Security Bearer
 func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
@@ -46,16 +49,16 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	// get the car by id from the car service
 	res, err := h.service.GetCarById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting car by id: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(res)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling car by id response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -69,43 +72,69 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GetCarByBrandHandler godoc
-// @Summary Get cars by brand
-// @Description Get cars by brand
+// SearchCarsHandler godoc
+// @Summary List and search cars
+// @Description Get a page of cars matching the filters. The total is returned in X-Total-Count and the next and previous pages in the Link header.
 // @Tags Car
 // @Accept  json
 // @Produce  json
-// @Param brand query string true "Car Brand"
-// @Param isEngine query boolean false "Car with Engine"
-// @Success 200 {object} []models.Car
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Car not found"
-// @Failure 500 {string} string "Internal server error"
+// @Param q query string false "Part of the name or registration number"
+// @Param brand query string false "Car Brand"
+// @Param fuel_type query string false "Fuel type" Enums(Petrol, Diesel, Electric, Hybrid)
+// @Param status query string false "Status" Enums(Available, In Use, Maintenance, Decommissioned)
+// @Param year_from query int false "Oldest model year"
+// @Param year_to query int false "Newest model year"
+// @Param price_min query number false "Minimum price"
+// @Param price_max query number false "Maximum price"
+// @Param displacement_min query int false "Minimum engine displacement"
+// @Param displacement_max query int false "Maximum engine displacement"
+// @Param cylinders query int false "Number of cylinders"
+// @Param range_min query int false "Minimum range"
+// @Param service_due query boolean false "Cars that passed their next service (true) or not (false)"
+// @Param with_engine query boolean false "Embed the engine details"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of cars to skip"
+// @Param sort query string false "Sort field: name, registration_number, brand, year, price, status, odometer_km, created_at, updated_at; prefix with - for descending"
+// @Success 200 {array} models.Car
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars [get]
 // @Security Bearer
-func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
+func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
 	tracer := otel.Tracer("CarHandler")
-	ctx, span := tracer.Start(r.Context(), "GetCarByBrand-Handler")
+	ctx, span := tracer.Start(r.Context(), "SearchCars-Handler")
 	defer span.End()
 
-	brand := r.URL.Query().Get("brand")
-	isEngine := r.URL.Query().Get("isEngine") == "true"
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	resp, err := h.service.GetCarByBrand(ctx, brand, isEngine)
+	filter, err := carFilter(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error getting car by brand: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, total, err := h.service.SearchCars(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error searching cars: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(resp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error marshalling car by brand response: ", err)
+		log.Println("Error marshalling cars response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
+	handler.WriteListHeaders(w, r, opts, total)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -116,6 +145,53 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// carFilter reads the car search filters from the query string
+func carFilter(r *http.Request) (models.CarFilter, error) {
+	query := r.URL.Query()
+	filter := models.CarFilter{
+		Query:    query.Get("q"),
+		Brand:    query.Get("brand"),
+		FuelType: query.Get("fuel_type"),
+		Status:   query.Get("status"),
+	}
+
+	var err error
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"year_from", &filter.YearFrom},
+		{"year_to", &filter.YearTo},
+		{"displacement_min", &filter.DisplacementMin},
+		{"displacement_max", &filter.DisplacementMax},
+		{"cylinders", &filter.Cylinders},
+		{"range_min", &filter.RangeMin},
+	}
+	for _, param := range ints {
+		if *param.value, err = handler.QueryInt(query, param.name); err != nil {
+			return filter, err
+		}
+	}
+	if filter.PriceMin, err = handler.QueryFloat(query, "price_min"); err != nil {
+		return filter, err
+	}
+	if filter.PriceMax, err = handler.QueryFloat(query, "price_max"); err != nil {
+		return filter, err
+	}
+
+	if filter.ServiceDue, err = handler.QueryBool(query, "service_due"); err != nil {
+		return filter, err
+	}
+
+	withEngine, err := handler.QueryBool(query, "with_engine")
+	if err != nil {
+		return filter, err
+	}
+	filter.WithEngine = withEngine != nil && *withEngine
+
+	return filter, nil
+}
+
 // CreateCarHandler godoc
 // @Summary Create a new car
 // @Description Create a new car
@@ -124,8 +200,11 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param car body models.CarRequest true "Car Request"
 // @Success 201 {object} models.Car
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 409 {object} handler.Problem "Registration number already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars [post]
 // @Security Bearer
 func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
@@ -135,8 +214,8 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -144,23 +223,23 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
 		log.Println("Error unmarshalling car request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// create the car
 	createdCar, err := h.service.CreateCar(ctx, &carReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating car: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(createdCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling created car response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -182,8 +261,12 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Car ID"
 // @Param car body models.CarRequest true "Car Request"
 // @Success 200 {object} models.Car
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 409 {object} handler.Problem "Registration number already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars/{id} [put]
 // @Security Bearer
 func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
@@ -196,32 +279,32 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	var carReq models.CarRequest
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error unmarshalling car request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the car
 	updatedCar, err := h.service.UpdateCar(ctx, id, &carReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating car: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated car response body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -243,7 +326,11 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Car ID"
 // @Success 200 {object} models.Car
-// @Failure 500 {string} string "Internal server error"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 409 {object} handler.Problem "Car is still referenced"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars/{id} [delete]
 // @Security Bearer
 func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
@@ -257,16 +344,16 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	// delete the car
 	deletedCar, err := h.service.DeleteCar(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error deleting car: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(deletedCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling deleted car response body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
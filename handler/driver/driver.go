@@ -6,7 +6,9 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/JulianaSau/carzone/handler"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/service"
 	"github.com/gorilla/mux"
@@ -25,12 +27,21 @@ func NewDriverHandler(service service.DriverServiceInterface) *DriverHandler {
 
 // GetDriversHandler godoc
 // @Summary Get all drivers
-// @Description Get all drivers
+// @Description Get a page of drivers. The total is returned in X-Total-Count and the next and previous pages in the Link header.
 // @Tags Driver
 // @Accept  json
 // @Produce  json
+// @Param active query bool false "Active drivers only (true) or deactivated drivers only (false)"
+// @Param license_expires_before query string false "Licenses expiring before this date or RFC 3339 time"
+// @Param license_expires_after query string false "Licenses expiring at or after this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of drivers to skip"
+// @Param sort query string false "Sort field: driver_license_number, license_expiry, username, created_at, updated_at; prefix with - for descending"
 // @Success 200 {array} models.Driver
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers [get]
 // @Security Bearer
 func (h *DriverHandler) GetDrivers(w http.ResponseWriter, r *http.Request) {
@@ -38,17 +49,155 @@ func (h *DriverHandler) GetDrivers(w http.ResponseWriter, r *http.Request) {
 	ctx, span := tracer.Start(r.Context(), "GetDrivers-Handler")
 	defer span.End()
 
-	drivers, err := h.service.GetDrivers(ctx)
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.DriverFilter
+	if filter.Active, err = handler.QueryBool(query, "active"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.LicenseExpiresBefore, err = handler.QueryTime(query, "license_expires_before"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.LicenseExpiresAfter, err = handler.QueryTime(query, "license_expires_after"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	drivers, total, err := h.service.GetDrivers(ctx, filter, opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting drivers: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	body, err := json.Marshal(drivers)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling drivers response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetExpiringDriversHandler godoc
+// @Summary Get drivers whose license expires soon
+// @Description Get a page of active drivers whose license has expired or expires within the window, soonest first. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Driver
+// @Accept  json
+// @Produce  json
+// @Param within query string false "Window in days or as a duration, e.g. 30d or 72h (default 30d)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of drivers to skip"
+// @Param sort query string false "Sort field: driver_license_number, license_expiry, username, created_at, updated_at; prefix with - for descending (default license_expiry)"
+// @Success 200 {array} models.Driver
+// @Failure 400 {object} handler.Problem "Invalid window or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/drivers/expiring [get]
+// @Security Bearer
+func (h *DriverHandler) GetExpiringDrivers(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DriverHandler")
+	ctx, span := tracer.Start(r.Context(), "GetExpiringDrivers-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Sort == "" {
+		opts.Sort = "license_expiry"
+	}
+
+	within := r.URL.Query().Get("within")
+	if within == "" {
+		within = "30d"
+	}
+	window, err := models.ParseWindow(within)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	active := true
+	filter := models.DriverFilter{
+		Active:               &active,
+		LicenseExpiresBefore: time.Now().Add(window),
+	}
+
+	drivers, total, err := h.service.GetDrivers(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting expiring drivers: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(drivers)
+	if err != nil {
+		log.Println("Error marshalling expiring drivers response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetDriverLicensesHandler godoc
+// @Summary Get the license history of a driver
+// @Description Get every license recorded for a driver, newest first, with the period it was on record and who recorded and replaced it
+// @Tags Driver
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Driver ID"
+// @Success 200 {array} models.DriverLicense
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/drivers/{id}/licenses [get]
+// @Security Bearer
+func (h *DriverHandler) GetDriverLicenses(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DriverHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDriverLicenses-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	licenses, err := h.service.GetDriverLicenses(ctx, id)
+	if err != nil {
+		log.Println("Error getting driver licenses: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(licenses)
+	if err != nil {
+		log.Println("Error marshalling driver licenses response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -69,9 +218,11 @@ func (h *DriverHandler) GetDrivers(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Driver ID"
 // @Success 200 {object} models.Driver
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Driver not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id} [get]
 // @Security Bearer
 func (h *DriverHandler) GetDriverById(w http.ResponseWriter, r *http.Request) {
@@ -85,16 +236,16 @@ func (h *DriverHandler) GetDriverById(w http.ResponseWriter, r *http.Request) {
 
 	driver, err := h.service.GetDriverById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting driver profile: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(driver)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling driver profile response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -116,8 +267,11 @@ func (h *DriverHandler) GetDriverById(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param driver body models.DriverRequest true "Driver object that needs to be created"
 // @Success 201 {object} models.Driver
-// @Failure 400 {string} string "Invalid request payload"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request payload"
+// @Failure 409 {object} handler.Problem "Driver already exists"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers [post]
 // @Security Bearer
 func (h *DriverHandler) CreateDriver(w http.ResponseWriter, r *http.Request) {
@@ -127,8 +281,8 @@ func (h *DriverHandler) CreateDriver(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -136,23 +290,23 @@ func (h *DriverHandler) CreateDriver(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &driverReq)
 	if err != nil {
 		log.Println("Error unmarshalling driver request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// create the car
 	createdCar, err := h.service.CreateDriver(ctx, &driverReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating driver: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(createdCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling created driver response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -174,9 +328,11 @@ func (h *DriverHandler) CreateDriver(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Driver ID"
 // @Param driver body models.DriverRequest true "Driver object that needs to be updated"
 // @Success 200 {object} models.Driver
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Driver not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id} [put]
 // @Security Bearer
 func (h *DriverHandler) UpdateDriver(w http.ResponseWriter, r *http.Request) {
@@ -190,8 +346,8 @@ func (h *DriverHandler) UpdateDriver(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -199,23 +355,23 @@ func (h *DriverHandler) UpdateDriver(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &driverReq)
 	if err != nil {
 		log.Println("Error unmarshalling driver request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the driver profile
 	updatedDriver, err := h.service.UpdateDriver(ctx, id, &driverReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating driver profile: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedDriver)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated driver response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -237,9 +393,11 @@ func (h *DriverHandler) UpdateDriver(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Driver ID"
 // @Success 200 {object} models.Driver
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Driver not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id}/delete [delete]
 // @Security Bearer
 func (h *DriverHandler) DeleteDriver(w http.ResponseWriter, r *http.Request) {
@@ -254,16 +412,16 @@ func (h *DriverHandler) DeleteDriver(w http.ResponseWriter, r *http.Request) {
 	// delete the driver
 	deletedDriver, err := h.service.DeleteDriver(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error deleting driver: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(deletedDriver)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling deleted driver response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -285,9 +443,11 @@ func (h *DriverHandler) DeleteDriver(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Driver ID"
 // @Success 200 {object} models.Driver
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Driver not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id} [delete]
 // @Security Bearer
 func (h *DriverHandler) SoftDeleteDriver(w http.ResponseWriter, r *http.Request) {
@@ -302,16 +462,16 @@ func (h *DriverHandler) SoftDeleteDriver(w http.ResponseWriter, r *http.Request)
 	// delete the driver
 	deletedDriver, err := h.service.SoftDeleteDriver(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error soft deleting driver: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(deletedDriver)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling soft deleted driver response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -334,9 +494,11 @@ func (h *DriverHandler) SoftDeleteDriver(w http.ResponseWriter, r *http.Request)
 // @Param id path string true "Driver ID"
 // @Param active query boolean true "Active status"
 // @Success 200 {object} models.Driver
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Driver not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Driver not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id}/toggle-status [put]
 // @Security Bearer
 func (h *DriverHandler) ToggleDriverStatus(w http.ResponseWriter, r *http.Request) {
@@ -352,24 +514,24 @@ func (h *DriverHandler) ToggleDriverStatus(w http.ResponseWriter, r *http.Reques
 	// parse the active status
 	isActive, err := strconv.ParseBool(active)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
 		log.Println("Invalid active status: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid active status")
 		return
 	}
 
 	// toggle the driver status
 	toggledDriver, err := h.service.ToggleDriverStatus(ctx, id, isActive)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error toggling driver status: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(toggledDriver)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling toggled driver response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -6,9 +6,9 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/JulianaSau/carzone/handler"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/service"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel"
 )
@@ -31,9 +31,11 @@ func NewEngineHandler(service service.EngineServiceInterface) *EngineHandler {
 // @Produce json
 // @Param id path string true "Engine ID"
 // @Success 200 {object} models.Engine
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Engine not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Engine not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/engines/{id} [get]
 // @Security Bearer
 func (h *EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
@@ -47,16 +49,16 @@ func (h *EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 	// get the engine by id from the engine service
 	res, err := h.service.GetEngineById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting engine by id: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(res)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling engine response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -78,8 +80,10 @@ func (h *EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param engine body models.EngineRequest true "Engine details"
 // @Success 201 {object} models.Engine
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/engines [post]
 // @Security Bearer
 func (h *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
@@ -89,8 +93,8 @@ func (h *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -99,24 +103,24 @@ func (h *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 	// unmarshal the request body
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
 		log.Println("Error unmarshalling engine request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// create the engine
 	createdEngine, err := h.service.CreateEngine(ctx, &engineReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating engine: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(createdEngine)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling engine response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -138,8 +142,11 @@ func (h *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Engine ID"
 // @Param engine body models.EngineRequest true "Engine details"
 // @Success 200 {object} models.Engine
-// @Failure 400 {string} string "Invalid ID or request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID or request body"
+// @Failure 404 {object} handler.Problem "Engine not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/engines/{id} [put]
 // @Security Bearer
 func (h *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
@@ -153,8 +160,8 @@ func (h *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -163,24 +170,24 @@ func (h *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	// unmarshal the request body
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
 		log.Println("Error unmarshalling engine request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the engine
 	updatedEngine, err := h.service.UpdateEngine(ctx, id, &engineReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating engine: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedEngine)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated engine response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -201,8 +208,11 @@ func (h *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Engine ID"
 // @Success 200 {object} models.Engine
-// @Failure 404 {string} string "Engine not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 404 {object} handler.Problem "Engine not found"
+// @Failure 409 {object} handler.Problem "Engine is still used by a car"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/engines/{id} [delete]
 // @Security Bearer
 func (h *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
@@ -217,32 +227,16 @@ func (h *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
 	// delete the engine
 	deletedEngine, err := h.service.DeleteEngine(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error deleting engine: ", err)
-		response := map[string]string{"error": "Invalid ID or engine not found"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
-		return
-	}
-
-	// check if engine was deleted properly
-	if deletedEngine.EngineID == uuid.Nil {
-		w.WriteHeader(http.StatusNotFound)
-		response := map[string]string{"error": "Engine not found"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(deletedEngine)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling deleted engine response: ", err)
-		response := map[string]string{"error": "Internal server error"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
-
+		handler.WriteError(w, r, err)
 		return
 	}
 
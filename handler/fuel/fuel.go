@@ -0,0 +1,554 @@
+package fuel
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type FuelHandler struct {
+	service service.FuelServiceInterface
+}
+
+func NewFuelHandler(service service.FuelServiceInterface) *FuelHandler {
+	return &FuelHandler{
+		service: service,
+	}
+}
+
+// GetFuelEntriesByCarIDHandler godoc
+// @Summary Get the refuels of a car
+// @Description Get a page of the fuel log of a car. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param from query string false "Refuels at or after this date or RFC 3339 time"
+// @Param to query string false "Refuels before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of refuels to skip"
+// @Param sort query string false "Sort field: fueled_at, liters, total_cost, odometer_km, created_at; prefix with - for descending"
+// @Success 200 {array} models.FuelEntry
+// @Failure 400 {object} handler.Problem "Invalid ID, filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/fuel [get]
+// @Security Bearer
+func (h *FuelHandler) GetFuelEntriesByCarID(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetFuelEntriesByCarID-Handler")
+	defer span.End()
+
+	filter, err := fuelFilter(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.CarID, err = uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	h.listEntries(w, r.WithContext(ctx), filter)
+}
+
+// GetFuelEntriesByDriverIDHandler godoc
+// @Summary Get the refuels of a driver
+// @Description Get a page of the refuels made by a driver. Accepts the same filters, pagination and sort parameters as GET /api/v1/cars/{id}/fuel.
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Driver ID"
+// @Param from query string false "Refuels at or after this date or RFC 3339 time"
+// @Param to query string false "Refuels before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of refuels to skip"
+// @Param sort query string false "Sort field, prefix with - for descending"
+// @Success 200 {array} models.FuelEntry
+// @Failure 400 {object} handler.Problem "Invalid ID, filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/drivers/{id}/fuel [get]
+// @Security Bearer
+func (h *FuelHandler) GetFuelEntriesByDriverID(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetFuelEntriesByDriverID-Handler")
+	defer span.End()
+
+	filter, err := fuelFilter(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.DriverID, err = uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	h.listEntries(w, r.WithContext(ctx), filter)
+}
+
+// listEntries writes one page of the refuels matching filter
+func (h *FuelHandler) listEntries(w http.ResponseWriter, r *http.Request, filter models.FuelFilter) {
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, total, err := h.service.GetFuelEntries(r.Context(), filter, opts)
+	if err != nil {
+		log.Println("Error getting fuel entries: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		log.Println("Error marshalling fuel entries response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// fuelFilter reads the refuel time range from the query string
+func fuelFilter(r *http.Request) (models.FuelFilter, error) {
+	query := r.URL.Query()
+	var filter models.FuelFilter
+
+	var err error
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// GetFuelEntryByIdHandler godoc
+// @Summary Get refuel by ID
+// @Description Get refuel by ID
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Fuel entry ID"
+// @Success 200 {object} models.FuelEntry
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Fuel entry not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/fuel/{id} [get]
+// @Security Bearer
+func (h *FuelHandler) GetFuelEntryById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetFuelEntryById-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	entry, err := h.service.GetFuelEntryById(ctx, id)
+	if err != nil {
+		log.Println("Error getting fuel entry: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Error marshalling fuel entry response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateFuelEntryHandler godoc
+// @Summary Record a refuel
+// @Description Record a refuel of a car. total_cost defaults to liters times price_per_liter. The odometer reading is added to the car's readings and answers 409 when it goes backwards.
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param entry body models.FuelEntryRequest true "Refuel"
+// @Success 201 {object} models.FuelEntry
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 409 {object} handler.Problem "Unknown driver or trip, or odometer reading goes backwards"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/fuel [post]
+// @Security Bearer
+func (h *FuelHandler) CreateFuelEntry(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateFuelEntry-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var fuelReq models.FuelEntryRequest
+	err = json.Unmarshal(body, &fuelReq)
+	if err != nil {
+		log.Println("Error unmarshalling fuel entry request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	entry, err := h.service.CreateFuelEntry(ctx, carID, &fuelReq)
+	if err != nil {
+		log.Println("Error creating fuel entry: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Error marshalling fuel entry response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// DeleteFuelEntryHandler godoc
+// @Summary Delete refuel by ID
+// @Description Delete refuel by ID together with its odometer reading
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Fuel entry ID"
+// @Success 200 {object} models.FuelEntry
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Fuel entry not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/fuel/{id} [delete]
+// @Security Bearer
+func (h *FuelHandler) DeleteFuelEntry(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteFuelEntry-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	entry, err := h.service.DeleteFuelEntry(ctx, id)
+	if err != nil {
+		log.Println("Error deleting fuel entry: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Error marshalling fuel entry response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetCarFuelEfficiencyHandler godoc
+// @Summary Get the fuel efficiency of a car
+// @Description Get the consumption in liters per 100 km and the cost per km of a car in total and per period. Consumption is measured from refuel to refuel.
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param from query string false "Refuels at or after this date or RFC 3339 time"
+// @Param to query string false "Refuels before this date or RFC 3339 time"
+// @Param interval query string false "Period length (default month)" Enums(day, week, month, year)
+// @Success 200 {object} models.FuelEfficiencyReport
+// @Failure 400 {object} handler.Problem "Invalid ID, range or interval"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/fuel/efficiency [get]
+// @Security Bearer
+func (h *FuelHandler) GetCarFuelEfficiency(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetCarFuelEfficiency-Handler")
+	defer span.End()
+
+	filter, err := efficiencyFilter(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.CarID, err = uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	h.writeEfficiency(w, r.WithContext(ctx), filter)
+}
+
+// GetDriverFuelEfficiencyHandler godoc
+// @Summary Get the fuel efficiency of a driver
+// @Description Get the consumption in liters per 100 km and the cost per km of the refuels made by a driver, in total and per period
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Driver ID"
+// @Param from query string false "Refuels at or after this date or RFC 3339 time"
+// @Param to query string false "Refuels before this date or RFC 3339 time"
+// @Param interval query string false "Period length (default month)" Enums(day, week, month, year)
+// @Success 200 {object} models.FuelEfficiencyReport
+// @Failure 400 {object} handler.Problem "Invalid ID, range or interval"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/drivers/{id}/fuel/efficiency [get]
+// @Security Bearer
+func (h *FuelHandler) GetDriverFuelEfficiency(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDriverFuelEfficiency-Handler")
+	defer span.End()
+
+	filter, err := efficiencyFilter(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.DriverID, err = uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	h.writeEfficiency(w, r.WithContext(ctx), filter)
+}
+
+// efficiencyFilter reads the range and interval of an efficiency report from the query string
+func efficiencyFilter(r *http.Request) (models.FuelEfficiencyFilter, error) {
+	query := r.URL.Query()
+	filter := models.FuelEfficiencyFilter{Interval: query.Get("interval")}
+
+	var err error
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func (h *FuelHandler) writeEfficiency(w http.ResponseWriter, r *http.Request, filter models.FuelEfficiencyFilter) {
+	report, err := h.service.GetFuelEfficiency(r.Context(), filter)
+	if err != nil {
+		log.Println("Error getting fuel efficiency: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Println("Error marshalling fuel efficiency response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetFuelAnomaliesHandler godoc
+// @Summary Get refuels with unusual consumption
+// @Description Get a page of the refuels whose consumption deviates from the car's other refuels, or from the other cars with the same engine, by at least the threshold. Defaults come from FUEL_ANOMALY_THRESHOLD and FUEL_BASELINE_MIN_REFUELS. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Fuel
+// @Accept  json
+// @Produce  json
+// @Param car_id query string false "Refuels of this car only"
+// @Param from query string false "Refuels at or after this date or RFC 3339 time"
+// @Param to query string false "Refuels before this date or RFC 3339 time"
+// @Param threshold query number false "Relative deviation to flag, e.g. 0.25 for 25% (default 0.25)"
+// @Param min_refuels query int false "Refuels a baseline needs before it is compared against (default 3)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of refuels to skip"
+// @Param sort query string false "Sort field: fueled_at, liters_per_100km, liters; prefix with - for descending"
+// @Success 200 {array} models.FuelAnomaly
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/fuel/anomalies [get]
+// @Security Bearer
+func (h *FuelHandler) GetFuelAnomalies(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "GetFuelAnomalies-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.FuelAnomalyFilter
+	if filter.CarID, err = handler.QueryUUID(query, "car_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Threshold, err = handler.QueryFloat(query, "threshold"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.MinSamples, err = handler.QueryInt(query, "min_refuels"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	anomalies, total, err := h.service.GetFuelAnomalies(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting fuel anomalies: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(anomalies)
+	if err != nil {
+		log.Println("Error marshalling fuel anomalies response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// maxStatementSize is the largest fuel card statement accepted by an import
+const maxStatementSize = 10 << 20
+
+// ImportFuelCardStatementHandler godoc
+// @Summary Import a fuel card statement
+// @Description Import the transactions of a fuel card CSV statement into the fuel log. Each row is matched to a car by registration number and to the trip the car was on at the time, whose driver is recorded as the buyer. Rows that are invalid, name an unknown car or are rejected by the fuel log (a transaction imported before, an odometer reading that goes backwards) are skipped; refuels without an active trip or above the car's tank capacity are imported and flagged. Both are listed in the exceptions. The mapping field overrides the statement columns of the FUEL_CARD_MAPPING default, e.g. {"registration_number": "Vehicle", "liters": "Quantity", "delimiter": ";"}.
+// @Tags Fuel
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param statement formData file true "CSV statement, at most 10 MB"
+// @Param mapping formData string false "Column mapping as a JSON object, see models.FuelCardMapping"
+// @Success 200 {object} models.FuelImportReport
+// @Failure 400 {object} handler.Problem "Invalid statement or mapping"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/fuel/import [post]
+// @Security Bearer
+func (h *FuelHandler) ImportFuelCardStatement(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelHandler")
+	ctx, span := tracer.Start(r.Context(), "ImportFuelCardStatement-Handler")
+	defer span.End()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementSize)
+	statement, _, err := r.FormFile("statement")
+	if err != nil {
+		log.Println("Error reading fuel card statement: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "A CSV statement of at most 10 MB is required in the statement field")
+		return
+	}
+	defer statement.Close()
+
+	var mapping models.FuelCardMapping
+	if value := r.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			log.Println("Error unmarshalling fuel card mapping: ", err)
+			handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid mapping")
+			return
+		}
+	}
+
+	report, err := h.service.ImportFuelCardStatement(ctx, statement, mapping)
+	if err != nil {
+		log.Println("Error importing fuel card statement: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Println("Error marshalling fuel import report: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
@@ -0,0 +1,368 @@
+package geofence
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type GeofenceHandler struct {
+	service service.GeofenceServiceInterface
+}
+
+func NewGeofenceHandler(service service.GeofenceServiceInterface) *GeofenceHandler {
+	return &GeofenceHandler{
+		service: service,
+	}
+}
+
+// GetGeofencesHandler godoc
+// @Summary Get geofences
+// @Description Get a page of the geofences, by name by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param kind query string false "Kind" Enums(depot, customer, restricted)
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of geofences to skip"
+// @Param sort query string false "Sort field: name, kind, created_at, updated_at; prefix with - for descending"
+// @Success 200 {array} models.Geofence
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences [get]
+// @Security Bearer
+func (h *GeofenceHandler) GetGeofences(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetGeofences-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.GeofenceFilter{Kind: r.URL.Query().Get("kind")}
+
+	geofences, total, err := h.service.GetGeofences(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting geofences: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(geofences)
+	if err != nil {
+		log.Println("Error marshalling geofences response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetGeofenceByIdHandler godoc
+// @Summary Get geofence by ID
+// @Description Get geofence by ID
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Geofence ID"
+// @Success 200 {object} models.Geofence
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Geofence not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences/{id} [get]
+// @Security Bearer
+func (h *GeofenceHandler) GetGeofenceById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetGeofenceById-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	geofence, err := h.service.GetGeofenceById(ctx, id)
+	if err != nil {
+		log.Println("Error getting geofence: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(geofence)
+	if err != nil {
+		log.Println("Error marshalling geofence response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateGeofenceHandler godoc
+// @Summary Create a geofence
+// @Description Create a circle (center and radius_m in meters) or a polygon (at least 3 vertices) marking a depot, customer site or restricted zone. Positions reported from then on are checked against it.
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param geofence body models.GeofenceRequest true "Geofence"
+// @Success 201 {object} models.Geofence
+// @Failure 400 {object} handler.Problem "Invalid request body or shape"
+// @Failure 409 {object} handler.Problem "Name already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences [post]
+// @Security Bearer
+func (h *GeofenceHandler) CreateGeofence(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateGeofence-Handler")
+	defer span.End()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var geofenceReq models.GeofenceRequest
+	err = json.Unmarshal(body, &geofenceReq)
+	if err != nil {
+		log.Println("Error unmarshalling geofence request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	geofence, err := h.service.CreateGeofence(ctx, &geofenceReq)
+	if err != nil {
+		log.Println("Error creating geofence: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(geofence)
+	if err != nil {
+		log.Println("Error marshalling geofence response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// UpdateGeofenceHandler godoc
+// @Summary Update geofence by ID
+// @Description Update geofence by ID. Events recorded before keep the boundary they were detected with.
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Geofence ID"
+// @Param geofence body models.GeofenceRequest true "Geofence"
+// @Success 200 {object} models.Geofence
+// @Failure 400 {object} handler.Problem "Invalid request body or shape"
+// @Failure 404 {object} handler.Problem "Geofence not found"
+// @Failure 409 {object} handler.Problem "Name already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences/{id} [put]
+// @Security Bearer
+func (h *GeofenceHandler) UpdateGeofence(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateGeofence-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var geofenceReq models.GeofenceRequest
+	err = json.Unmarshal(body, &geofenceReq)
+	if err != nil {
+		log.Println("Error unmarshalling geofence request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	geofence, err := h.service.UpdateGeofence(ctx, id, &geofenceReq)
+	if err != nil {
+		log.Println("Error updating geofence: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(geofence)
+	if err != nil {
+		log.Println("Error marshalling geofence response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// DeleteGeofenceHandler godoc
+// @Summary Delete geofence by ID
+// @Description Delete geofence by ID together with its events
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Geofence ID"
+// @Success 200 {object} models.Geofence
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Geofence not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences/{id} [delete]
+// @Security Bearer
+func (h *GeofenceHandler) DeleteGeofence(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteGeofence-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	geofence, err := h.service.DeleteGeofence(ctx, id)
+	if err != nil {
+		log.Println("Error deleting geofence: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(geofence)
+	if err != nil {
+		log.Println("Error marshalling geofence response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetGeofenceEventsHandler godoc
+// @Summary Get geofence events
+// @Description Get a page of the times cars entered or left a geofence, oldest first by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Geofences
+// @Accept  json
+// @Produce  json
+// @Param geofence_id query string false "Events of this geofence"
+// @Param car_id query string false "Events of this car"
+// @Param trip_id query string false "Events during this trip"
+// @Param event query string false "Event" Enums(enter, exit)
+// @Param from query string false "Events at or after this date or RFC 3339 time"
+// @Param to query string false "Events before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of events to skip"
+// @Param sort query string false "Sort field: occurred_at, event; prefix with - for descending"
+// @Success 200 {array} models.GeofenceEvent
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/geofences/events [get]
+// @Security Bearer
+func (h *GeofenceHandler) GetGeofenceEvents(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("GeofenceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetGeofenceEvents-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.GeofenceEventFilter{Event: query.Get("event")}
+	if filter.GeofenceID, err = handler.QueryUUID(query, "geofence_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.CarID, err = handler.QueryUUID(query, "car_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.TripID, err = handler.QueryUUID(query, "trip_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, total, err := h.service.GetGeofenceEvents(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting geofence events: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		log.Println("Error marshalling geofence events response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// ParseListOptions reads the limit, offset and sort query parameters shared by every list endpoint.
+// sort names a field, prefixed with "-" for descending order, e.g. sort=-start_time.
+func ParseListOptions(r *http.Request) (models.ListOptions, error) {
+	query := r.URL.Query()
+	var opts models.ListOptions
+
+	var err error
+	if opts.Limit, err = nonNegativeInt(query, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = nonNegativeInt(query, "offset"); err != nil {
+		return opts, err
+	}
+
+	sort := query.Get("sort")
+	if strings.HasPrefix(sort, "-") {
+		opts.Desc = true
+		sort = sort[1:]
+	}
+	opts.Sort = sort
+
+	return opts, nil
+}
+
+// WriteListHeaders sets X-Total-Count and a Link header pointing at the next and previous pages
+func WriteListHeaders(w http.ResponseWriter, r *http.Request, opts models.ListOptions, total int) {
+	opts.Normalize()
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	var links []string
+	if opts.Offset+opts.Limit < total {
+		links = append(links, pageLink(r, opts.Limit, opts.Offset+opts.Limit, "next"))
+	}
+	if opts.Offset > 0 {
+		prev := opts.Offset - opts.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(r, opts.Limit, prev, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(r *http.Request, limit int, offset int, rel string) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf("<%s>; rel=\"%s\"", link.String(), rel)
+}
+
+// QueryBool reads an optional boolean filter, nil when absent
+func QueryBool(query url.Values, name string) (*bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, models.Validation("invalid %s %q", name, value)
+	}
+	return &b, nil
+}
+
+// QueryTime reads an optional RFC 3339 timestamp or a plain date, zero when absent
+func QueryTime(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, models.Validation("invalid %s %q, expected a date or an RFC 3339 timestamp", name, value)
+}
+
+// QueryUUID reads an optional id filter, uuid.Nil when absent
+func QueryUUID(query url.Values, name string) (uuid.UUID, error) {
+	value := query.Get(name)
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, models.Validation("invalid %s %q", name, value)
+	}
+	return id, nil
+}
+
+// QueryInt reads an optional integer filter, zero when absent
+func QueryInt(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, models.Validation("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+// QueryFloat reads an optional decimal filter, zero when absent
+func QueryFloat(query url.Values, name string) (float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, models.Validation("invalid %s %q", name, value)
+	}
+	return f, nil
+}
+
+func nonNegativeInt(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, models.Validation("invalid %s %q", name, value)
+	}
+	return n, nil
+}
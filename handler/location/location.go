@@ -0,0 +1,291 @@
+package location
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type LocationHandler struct {
+	service service.LocationServiceInterface
+}
+
+func NewLocationHandler(service service.LocationServiceInterface) *LocationHandler {
+	return &LocationHandler{
+		service: service,
+	}
+}
+
+// GetLocationsHandler godoc
+// @Summary Get locations
+// @Description Get a page of the locations, by name by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Locations
+// @Accept  json
+// @Produce  json
+// @Param type query string false "Type" Enums(depot, customer, supplier, other)
+// @Param name query string false "Part of the name"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of locations to skip"
+// @Param sort query string false "Sort field: name, type, created_at, updated_at; prefix with - for descending"
+// @Success 200 {array} models.Location
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/locations [get]
+// @Security Bearer
+func (h *LocationHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LocationHandler")
+	ctx, span := tracer.Start(r.Context(), "GetLocations-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.LocationFilter{Type: query.Get("type"), Name: query.Get("name")}
+
+	locations, total, err := h.service.GetLocations(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting locations: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(locations)
+	if err != nil {
+		log.Println("Error marshalling locations response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetLocationByIdHandler godoc
+// @Summary Get location by ID
+// @Description Get location by ID
+// @Tags Locations
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Location ID"
+// @Success 200 {object} models.Location
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Location not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/locations/{id} [get]
+// @Security Bearer
+func (h *LocationHandler) GetLocationById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LocationHandler")
+	ctx, span := tracer.Start(r.Context(), "GetLocationById-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	location, err := h.service.GetLocationById(ctx, id)
+	if err != nil {
+		log.Println("Error getting location: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(location)
+	if err != nil {
+		log.Println("Error marshalling location response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateLocationHandler godoc
+// @Summary Create a location
+// @Description Create a place trips start and end at, such as a depot or a customer site. Trips created with start_location or end_location text matching its name are linked to it.
+// @Tags Locations
+// @Accept  json
+// @Produce  json
+// @Param location body models.LocationRequest true "Location"
+// @Success 201 {object} models.Location
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 409 {object} handler.Problem "Name already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/locations [post]
+// @Security Bearer
+func (h *LocationHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LocationHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateLocation-Handler")
+	defer span.End()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var locationReq models.LocationRequest
+	err = json.Unmarshal(body, &locationReq)
+	if err != nil {
+		log.Println("Error unmarshalling location request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	location, err := h.service.CreateLocation(ctx, &locationReq)
+	if err != nil {
+		log.Println("Error creating location: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(location)
+	if err != nil {
+		log.Println("Error marshalling location response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// UpdateLocationHandler godoc
+// @Summary Update location by ID
+// @Description Update location by ID. The start_location and end_location text of the trips linked to it is left as it was recorded.
+// @Tags Locations
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Location ID"
+// @Param location body models.LocationRequest true "Location"
+// @Success 200 {object} models.Location
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Location not found"
+// @Failure 409 {object} handler.Problem "Name already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/locations/{id} [put]
+// @Security Bearer
+func (h *LocationHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LocationHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateLocation-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var locationReq models.LocationRequest
+	err = json.Unmarshal(body, &locationReq)
+	if err != nil {
+		log.Println("Error unmarshalling location request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	location, err := h.service.UpdateLocation(ctx, id, &locationReq)
+	if err != nil {
+		log.Println("Error updating location: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(location)
+	if err != nil {
+		log.Println("Error marshalling location response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// DeleteLocationHandler godoc
+// @Summary Delete location by ID
+// @Description Delete a location no trip starts or ends at
+// @Tags Locations
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Location ID"
+// @Success 200 {object} models.Location
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Location not found"
+// @Failure 409 {object} handler.Problem "Location used by trips"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/locations/{id} [delete]
+// @Security Bearer
+func (h *LocationHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LocationHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteLocation-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	location, err := h.service.DeleteLocation(ctx, id)
+	if err != nil {
+		log.Println("Error deleting location: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(location)
+	if err != nil {
+		log.Println("Error marshalling location response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
@@ -0,0 +1,204 @@
+package login
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginLimiter tracks failed logins per username and per client IP. Every failure makes the
+// caller wait a little longer before the next attempt, and too many failures lock the
+// account (or the IP) for a while. The state is kept in memory, per instance.
+type LoginLimiter struct {
+	mu               sync.Mutex
+	maxAttempts      int
+	maxAttemptsPerIP int
+	lockout          time.Duration
+	window           time.Duration
+	users            map[string]*attempts
+	ips              map[string]*attempts
+	lastSweep        time.Time
+	now              func() time.Time
+}
+
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+const (
+	baseDelay = 500 * time.Millisecond
+	maxDelay  = 10 * time.Second
+)
+
+// NewLoginLimiter reads LOGIN_MAX_ATTEMPTS, LOGIN_MAX_ATTEMPTS_PER_IP, LOGIN_LOCKOUT and
+// LOGIN_ATTEMPT_WINDOW from the environment, falling back to 5, 20, 15m and 15m.
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		maxAttempts:      intFromEnv("LOGIN_MAX_ATTEMPTS", 5),
+		maxAttemptsPerIP: intFromEnv("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		lockout:          durationFromEnv("LOGIN_LOCKOUT", 15*time.Minute),
+		window:           durationFromEnv("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		users:            map[string]*attempts{},
+		ips:              map[string]*attempts{},
+		now:              time.Now,
+	}
+}
+
+// Check reports whether a login attempt may proceed. When it may not, it returns the status
+// code to answer with (423 for a locked account, 429 when throttled) and how long to wait.
+// An attempt that may proceed is counted as a failure right away, under the same lock, so
+// concurrent guesses cannot all pass before the first one fails. Success, Failure or Release
+// settle it once the password has been checked.
+func (l *LoginLimiter) Check(username, ip string) (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	user := l.users[normalizeUsername(username)]
+	client := l.ips[ip]
+
+	if user != nil && now.Before(user.lockedUntil) {
+		return http.StatusLocked, user.lockedUntil.Sub(now)
+	}
+	if client != nil && now.Before(client.lockedUntil) {
+		return http.StatusTooManyRequests, client.lockedUntil.Sub(now)
+	}
+
+	var wait time.Duration
+	for _, a := range []*attempts{user, client} {
+		if a == nil || a.failures == 0 {
+			continue
+		}
+		if next := a.lastFailure.Add(delay(a.failures)); now.Before(next) && next.Sub(now) > wait {
+			wait = next.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return http.StatusTooManyRequests, wait
+	}
+
+	// reserve the attempt
+	if l.record(l.users, normalizeUsername(username), l.maxAttempts, now) {
+		log.Printf("Login locked for username %q after %d failed attempts", username, l.maxAttempts)
+	}
+	if l.record(l.ips, ip, l.maxAttemptsPerIP, now) {
+		log.Printf("Login locked for client %s after %d failed attempts", ip, l.maxAttemptsPerIP)
+	}
+	return http.StatusOK, 0
+}
+
+// Failure settles an attempt Check let through as failed. It was counted already, the wait
+// before the next attempt starts over from now.
+func (l *LoginLimiter) Failure(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, a := range []*attempts{l.users[normalizeUsername(username)], l.ips[ip]} {
+		if a != nil {
+			a.lastFailure = now
+		}
+	}
+}
+
+// Success clears the failures of the username and gives the IP its attempt back. The IP keeps
+// the rest of its count so one valid account cannot be used to keep guessing the passwords of
+// others.
+func (l *LoginLimiter) Success(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.users, normalizeUsername(username))
+	release(l.ips[ip])
+}
+
+// Release gives back an attempt Check let through that ended before the password was checked,
+// e.g. because the user could not be loaded.
+func (l *LoginLimiter) Release(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	release(l.users[normalizeUsername(username)])
+	release(l.ips[ip])
+}
+
+// release uncounts one attempt. An attempt that triggered a lockout is not given back.
+func release(a *attempts) {
+	if a != nil && a.failures > 0 {
+		a.failures--
+	}
+}
+
+// record counts a failure and reports whether it triggered a lockout
+func (l *LoginLimiter) record(entries map[string]*attempts, key string, max int, now time.Time) bool {
+	a, ok := entries[key]
+	if !ok || now.Sub(a.lastFailure) > l.window {
+		a = &attempts{}
+		entries[key] = a
+	}
+
+	a.failures++
+	a.lastFailure = now
+	if a.failures >= max {
+		a.failures = 0
+		a.lockedUntil = now.Add(l.lockout)
+		return true
+	}
+	return false
+}
+
+// sweep drops entries that no longer affect anything so the maps do not grow without bound
+func (l *LoginLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for _, entries := range []map[string]*attempts{l.users, l.ips} {
+		for key, a := range entries {
+			if now.After(a.lockedUntil) && now.Sub(a.lastFailure) > l.window {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+// delay doubles with every failure: 0.5s, 1s, 2s, ... up to maxDelay
+func delay(failures int) time.Duration {
+	d := baseDelay
+	for i := 1; i < failures && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func intFromEnv(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
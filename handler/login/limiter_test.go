@@ -0,0 +1,72 @@
+package login
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	userService "github.com/JulianaSau/carzone/service/user"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lookupCounter is a user store that only knows one user and counts how often logins look it up
+type lookupCounter struct {
+	store.UserStoreInterface
+	user    models.User
+	lookups int32
+}
+
+func (s *lookupCounter) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	atomic.AddInt32(&s.lookups, 1)
+	// a slow lookup keeps every parallel attempt in flight at once
+	time.Sleep(100 * time.Millisecond)
+	return s.user, nil
+}
+
+func TestParallelBadLogins(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	limiter := NewLoginLimiter()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("right"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &lookupCounter{user: models.User{ID: uuid.New(), UserName: "fleet", Password: string(hash), Active: true}}
+	service := userService.NewUserService(users)
+
+	const attempts = 20
+	start := make(chan struct{})
+	statuses := make([]int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"username": "fleet", "password": "wrong"}`))
+			w := httptest.NewRecorder()
+			LoginHandler(w, r, service, nil, limiter)
+			statuses[i] = w.Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if lookups := atomic.LoadInt32(&users.lookups); lookups > 3 {
+		t.Fatalf("%d of %d parallel bad logins reached the password check, want at most LOGIN_MAX_ATTEMPTS (3)", lookups, attempts)
+	}
+	for _, status := range statuses {
+		if status != http.StatusUnauthorized && status != http.StatusTooManyRequests && status != http.StatusLocked {
+			t.Fatalf("got status %d, want 401, 429 or 423", status)
+		}
+	}
+}
@@ -1,35 +1,58 @@
 package login
 
 import (
-	// "database/sql"
+	"crypto/rand"
 	"encoding/json"
-	// "errors"
+	"errors"
 	"log"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
 
 	// "github.com/JulianaSau/carzone/driver"
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
 	userService "github.com/JulianaSau/carzone/service/user"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyPasswordHash is compared against when the username does not exist. It is the hash of random bytes generated
+// when the server starts, with the same cost as real hashes.
+var dummyPasswordHash = newDummyPasswordHash()
+
+func newDummyPasswordHash() string {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		log.Fatalf("generating the dummy password: %v", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, models.PasswordCost)
+	if err != nil {
+		log.Fatalf("hashing the dummy password: %v", err)
+	}
+	return string(hash)
+}
+
 // LoginHandler godoc
 // @Summary Authenticate user and generate a JWT token
-// @Description Validates user credentials and returns a JWT token on success
+// @Description Validates user credentials and returns a short-lived access token and a refresh token on success
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param credentials body models.Credentials true "User credentials"
-// @Success 200 {object} map[string]string
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 401 {string} string "Invalid credentials"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 401 {object} handler.Problem "Invalid credentials"
+// @Failure 403 {object} handler.Problem "Account is deactivated"
+// @Failure 423 {object} handler.Problem "Account temporarily locked"
+// @Failure 429 {object} handler.Problem "Too many login attempts"
 // @Router /api/v1/login [post]
-func LoginHandler(w http.ResponseWriter, r *http.Request, userService *userService.UserService) {
+func LoginHandler(w http.ResponseWriter, r *http.Request, userService *userService.UserService, tokenService service.TokenServiceInterface, limiter *LoginLimiter) {
 	var credentials models.Credentials
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid Request Body")
 		log.Println("Error decoding credentials: ", err)
 		return
 	}
@@ -42,59 +65,146 @@ func LoginHandler(w http.ResponseWriter, r *http.Request, userService *userServi
 	// 	return
 	// }
 
+	// refuse early while the username or the client is locked out or has to slow down, otherwise the attempt is
+	// counted until the password check settles it
+	ip := middleware.ClientIP(r)
+	if status, retryAfter := limiter.Check(credentials.UserName, ip); status != http.StatusOK {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		if status == http.StatusLocked {
+			handler.WriteProblem(w, r, status, "Account temporarily locked after too many failed login attempts")
+			return
+		}
+		handler.WriteProblem(w, r, status, "Too many login attempts, try again later")
+		return
+	}
+
 	// call GetUserByUsername service from user service
 
 	user, err := userService.GetUserByUsername(r.Context(), credentials.UserName)
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		limiter.Release(credentials.UserName, ip)
+		handler.WriteProblem(w, r, http.StatusInternalServerError, "Internal server error")
 		log.Println("Error fetching user: ", err)
 		return
 	}
 
-	// print user information
-	log.Println("User: ", user)
+	// unknown usernames still pay for a bcrypt comparison so timing does not reveal which usernames exist
+	if user == nil || user.ID == uuid.Nil {
+		user = &models.User{Password: dummyPasswordHash}
+	}
 
 	// Check if the password is correct
-	if err := user.CheckPassword(credentials.Password); err != nil {
-		http.Error(w, "Incorrect Username or Password", http.StatusUnauthorized)
+	if err := user.CheckPassword(credentials.Password); err != nil || user.ID == uuid.Nil {
+		limiter.Failure(credentials.UserName, ip)
+		handler.WriteProblem(w, r, http.StatusUnauthorized, "Incorrect Username or Password")
 		return
 	}
+	limiter.Success(credentials.UserName, ip)
 
-	// generate token
-	tokenString, err := GenerateToken(credentials.UserName)
+	if !user.Active {
+		handler.WriteProblem(w, r, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+
+	// generate the access and refresh tokens
+	tokens, err := tokenService.IssueTokens(r.Context(), user)
 	if err != nil {
-		http.Error(w, "Error generating token", http.StatusInternalServerError)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusInternalServerError, "Error generating token")
 		log.Println("Error generating token: ", err)
 		return
 	}
 
-	response := map[string]string{"token": tokenString}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(tokens)
 }
 
-func GenerateToken(username string) (string, error) {
-	// implement token generation logic here
-	expiration := time.Now().Add(24 * time.Hour)
+// RefreshHandler godoc
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new access token. The refresh token is rotated on every use.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 401 {object} handler.Problem "Invalid or expired refresh token"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/token/refresh [post]
+func RefreshHandler(w http.ResponseWriter, r *http.Request, tokenService service.TokenServiceInterface) {
+	var refreshReq models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&refreshReq); err != nil || refreshReq.RefreshToken == "" {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid Request Body")
+		return
+	}
 
-	// claims := &jwt.RegisteredClaims{
-	// 	ExpiresAt: jwt.NewNumericDate(expiration),
-	// 	Subject:   username,
-	// 	IssuedAt:  jwt.NewNumericDate(time.Now()),
-	// }
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expiration.Unix(),
-		Subject:   username,
-		IssuedAt:  time.Now().Unix(),
+	tokens, err := tokenService.RefreshTokens(r.Context(), refreshReq.RefreshToken)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidRefreshToken) ||
+			errors.Is(err, models.ErrRefreshTokenReused) ||
+			errors.Is(err, models.ErrUserInactive) {
+			handler.WriteProblem(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+		handler.WriteProblem(w, r, http.StatusInternalServerError, "Internal server error")
+		log.Println("Error refreshing token: ", err)
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
 
-	signedToken, err := token.SignedString([]byte("your-secret-key"))
-	if err != nil {
-		return "", err
+// LogoutHandler godoc
+// @Summary Log out
+// @Description Revokes the access token used for the request and, when given, the refresh token and every token rotated from it
+// @Tags Authentication
+// @Accept json
+// @Param refresh body models.RefreshRequest false "Refresh token"
+// @Success 204
+// @Failure 401 {object} handler.Problem "Invalid token"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/logout [post]
+// @Security Bearer
+func LogoutHandler(w http.ResponseWriter, r *http.Request, tokenService service.TokenServiceInterface) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	// the refresh token is optional, an empty body only revokes the access token
+	var refreshReq models.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&refreshReq); err != nil {
+			handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid Request Body")
+			return
+		}
 	}
 
-	return signedToken, nil
+	if err := tokenService.Logout(r.Context(), claims, refreshReq.RefreshToken); err != nil {
+		if errors.Is(err, models.ErrTokenRevoked) {
+			handler.WriteProblem(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+		handler.WriteProblem(w, r, http.StatusInternalServerError, "Internal server error")
+		log.Println("Error logging out: ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKSHandler godoc
+// @Summary Public token verification keys
+// @Description Publishes the public keys used to sign access tokens so other services can verify them offline
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} models.JWKSet
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(middleware.PublicJWKS()); err != nil {
+		log.Println("Error writing jwks response: ", err)
+	}
 }
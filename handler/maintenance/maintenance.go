@@ -0,0 +1,577 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type MaintenanceHandler struct {
+	service service.MaintenanceServiceInterface
+}
+
+func NewMaintenanceHandler(service service.MaintenanceServiceInterface) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		service: service,
+	}
+}
+
+// GetMaintenanceRecordsHandler godoc
+// @Summary Get the maintenance records of a car
+// @Description Get a page of the services carried out on a car, latest first by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of records to skip"
+// @Param sort query string false "Sort field: service_date, odometer_km, service_type, cost, created_at; prefix with - for descending (default -service_date)"
+// @Success 200 {array} models.MaintenanceRecord
+// @Failure 400 {object} handler.Problem "Invalid ID or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/maintenance [get]
+// @Security Bearer
+func (h *MaintenanceHandler) GetMaintenanceRecords(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetMaintenanceRecords-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Sort == "" {
+		opts.Sort = "service_date"
+		opts.Desc = true
+	}
+
+	carID := mux.Vars(r)["id"]
+
+	records, total, err := h.service.GetMaintenanceRecords(ctx, carID, opts)
+	if err != nil {
+		log.Println("Error getting maintenance records: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		log.Println("Error marshalling maintenance records response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetMaintenanceRecordByIdHandler godoc
+// @Summary Get maintenance record by ID
+// @Description Get maintenance record by ID
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Maintenance record ID"
+// @Success 200 {object} models.MaintenanceRecord
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Maintenance record not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/{id} [get]
+// @Security Bearer
+func (h *MaintenanceHandler) GetMaintenanceRecordById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetMaintenanceRecordById-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	record, err := h.service.GetMaintenanceRecordById(ctx, id)
+	if err != nil {
+		log.Println("Error getting maintenance record: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		log.Println("Error marshalling maintenance record response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateMaintenanceRecordHandler godoc
+// @Summary Record a service of a car
+// @Description Record a service carried out on a car. A record counts as the last service of the car's plan of the same service type.
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param record body models.MaintenanceRecordRequest true "Maintenance record"
+// @Success 201 {object} models.MaintenanceRecord
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 409 {object} handler.Problem "Car does not exist"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/maintenance [post]
+// @Security Bearer
+func (h *MaintenanceHandler) CreateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateMaintenanceRecord-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var recordReq models.MaintenanceRecordRequest
+	err = json.Unmarshal(body, &recordReq)
+	if err != nil {
+		log.Println("Error unmarshalling maintenance record request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	createdRecord, err := h.service.CreateMaintenanceRecord(ctx, carID, &recordReq)
+	if err != nil {
+		log.Println("Error creating maintenance record: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(createdRecord)
+	if err != nil {
+		log.Println("Error marshalling maintenance record response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// UpdateMaintenanceRecordHandler godoc
+// @Summary Update maintenance record by ID
+// @Description Update maintenance record by ID
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Maintenance record ID"
+// @Param record body models.MaintenanceRecordRequest true "Maintenance record"
+// @Success 200 {object} models.MaintenanceRecord
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Maintenance record not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/{id} [put]
+// @Security Bearer
+func (h *MaintenanceHandler) UpdateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateMaintenanceRecord-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var recordReq models.MaintenanceRecordRequest
+	err = json.Unmarshal(body, &recordReq)
+	if err != nil {
+		log.Println("Error unmarshalling maintenance record request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	updatedRecord, err := h.service.UpdateMaintenanceRecord(ctx, id, &recordReq)
+	if err != nil {
+		log.Println("Error updating maintenance record: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(updatedRecord)
+	if err != nil {
+		log.Println("Error marshalling maintenance record response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// DeleteMaintenanceRecordHandler godoc
+// @Summary Delete maintenance record by ID
+// @Description Delete maintenance record by ID
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Maintenance record ID"
+// @Success 200 {object} models.MaintenanceRecord
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Maintenance record not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/{id} [delete]
+// @Security Bearer
+func (h *MaintenanceHandler) DeleteMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteMaintenanceRecord-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	deletedRecord, err := h.service.DeleteMaintenanceRecord(ctx, id)
+	if err != nil {
+		log.Println("Error deleting maintenance record: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(deletedRecord)
+	if err != nil {
+		log.Println("Error marshalling maintenance record response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetMaintenancePlansHandler godoc
+// @Summary Get the service plans of a car
+// @Description Get the recurring services of a car with the last service, when each is next due and whether it is ok, due soon or overdue
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Success 200 {array} models.MaintenancePlan
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/maintenance/plans [get]
+// @Security Bearer
+func (h *MaintenanceHandler) GetMaintenancePlans(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetMaintenancePlans-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	plans, err := h.service.GetMaintenancePlans(ctx, carID)
+	if err != nil {
+		log.Println("Error getting maintenance plans: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(plans)
+	if err != nil {
+		log.Println("Error marshalling maintenance plans response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateMaintenancePlanHandler godoc
+// @Summary Add a service plan to a car
+// @Description Add a service that recurs every interval_km kilometers or interval_months months, whichever comes first. A car has one plan per service type.
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param plan body models.MaintenancePlanRequest true "Service plan"
+// @Success 201 {object} models.MaintenancePlan
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 409 {object} handler.Problem "Car does not exist or already has a plan for the service type"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/maintenance/plans [post]
+// @Security Bearer
+func (h *MaintenanceHandler) CreateMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateMaintenancePlan-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var planReq models.MaintenancePlanRequest
+	err = json.Unmarshal(body, &planReq)
+	if err != nil {
+		log.Println("Error unmarshalling maintenance plan request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	createdPlan, err := h.service.CreateMaintenancePlan(ctx, carID, &planReq)
+	if err != nil {
+		log.Println("Error creating maintenance plan: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(createdPlan)
+	if err != nil {
+		log.Println("Error marshalling maintenance plan response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// UpdateMaintenancePlanHandler godoc
+// @Summary Update service plan by ID
+// @Description Update service plan by ID. The baseline is kept when baseline_date is not given.
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Service plan ID"
+// @Param plan body models.MaintenancePlanRequest true "Service plan"
+// @Success 200 {object} models.MaintenancePlan
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Service plan not found"
+// @Failure 409 {object} handler.Problem "The car already has a plan for the service type"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/plans/{id} [put]
+// @Security Bearer
+func (h *MaintenanceHandler) UpdateMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateMaintenancePlan-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var planReq models.MaintenancePlanRequest
+	err = json.Unmarshal(body, &planReq)
+	if err != nil {
+		log.Println("Error unmarshalling maintenance plan request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	updatedPlan, err := h.service.UpdateMaintenancePlan(ctx, id, &planReq)
+	if err != nil {
+		log.Println("Error updating maintenance plan: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(updatedPlan)
+	if err != nil {
+		log.Println("Error marshalling maintenance plan response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// DeleteMaintenancePlanHandler godoc
+// @Summary Delete service plan by ID
+// @Description Delete service plan by ID, the maintenance records are kept
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Service plan ID"
+// @Success 200 {object} models.MaintenancePlan
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Service plan not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/plans/{id} [delete]
+// @Security Bearer
+func (h *MaintenanceHandler) DeleteMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteMaintenancePlan-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	deletedPlan, err := h.service.DeleteMaintenancePlan(ctx, id)
+	if err != nil {
+		log.Println("Error deleting maintenance plan: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(deletedPlan)
+	if err != nil {
+		log.Println("Error marshalling maintenance plan response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetDueMaintenanceHandler godoc
+// @Summary Get services that are due soon
+// @Description Get a page of the service plans of all cars that are overdue or come due within the window or the distance, soonest first. Both default to MAINTENANCE_DUE_WINDOW and MAINTENANCE_DUE_KM. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Maintenance
+// @Accept  json
+// @Produce  json
+// @Param within query string false "Window in days or as a duration, e.g. 30d or 72h (default 30d)"
+// @Param within_km query number false "Distance in kilometers (default 1000)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of plans to skip"
+// @Param sort query string false "Sort field: next_due_date, next_due_odometer_km, service_type, car_id; prefix with - for descending (default next_due_date)"
+// @Success 200 {array} models.MaintenancePlan
+// @Failure 400 {object} handler.Problem "Invalid window, distance or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/maintenance/due [get]
+// @Security Bearer
+func (h *MaintenanceHandler) GetDueMaintenance(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MaintenanceHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDueMaintenance-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+
+	var window time.Duration
+	if within := query.Get("within"); within != "" {
+		window, err = models.ParseWindow(within)
+		if err != nil {
+			handler.WriteError(w, r, err)
+			return
+		}
+	}
+
+	withinKM, err := handler.QueryFloat(query, "within_km")
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plans, total, err := h.service.GetDueMaintenance(ctx, window, withinKM, opts)
+	if err != nil {
+		log.Println("Error getting due maintenance: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(plans)
+	if err != nil {
+		log.Println("Error marshalling due maintenance response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
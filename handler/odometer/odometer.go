@@ -0,0 +1,159 @@
+package odometer
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type OdometerHandler struct {
+	service service.OdometerServiceInterface
+}
+
+func NewOdometerHandler(service service.OdometerServiceInterface) *OdometerHandler {
+	return &OdometerHandler{
+		service: service,
+	}
+}
+
+// GetOdometerReadingsHandler godoc
+// @Summary Get the odometer readings of a car
+// @Description Get a page of the odometer readings of a car, oldest first by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Odometer
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param trip_id query string false "Readings taken when this trip started or ended"
+// @Param source query string false "Source" Enums(manual, trip_start, trip_end, refuel)
+// @Param from query string false "Readings taken at or after this date or RFC 3339 time"
+// @Param to query string false "Readings taken before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of readings to skip"
+// @Param sort query string false "Sort field: recorded_at, reading_km; prefix with - for descending"
+// @Success 200 {array} models.OdometerReading
+// @Failure 400 {object} handler.Problem "Invalid ID, filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/odometer [get]
+// @Security Bearer
+func (h *OdometerHandler) GetOdometerReadings(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OdometerHandler")
+	ctx, span := tracer.Start(r.Context(), "GetOdometerReadings-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.OdometerFilter{Source: query.Get("source")}
+	if filter.TripID, err = handler.QueryUUID(query, "trip_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	carID := mux.Vars(r)["id"]
+
+	readings, total, err := h.service.GetOdometerReadings(ctx, carID, filter, opts)
+	if err != nil {
+		log.Println("Error getting odometer readings: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(readings)
+	if err != nil {
+		log.Println("Error marshalling odometer readings response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// CreateOdometerReadingHandler godoc
+// @Summary Record an odometer reading
+// @Description Record a manual odometer reading of a car, recorded_at defaults to now. Readings never go backwards: a reading below an earlier one or above a later one answers 409 with that reading in details.
+// @Tags Odometer
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param reading body models.OdometerReadingRequest true "Odometer reading"
+// @Success 201 {object} models.OdometerReading
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 409 {object} handler.Problem "Reading goes backwards"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/odometer [post]
+// @Security Bearer
+func (h *OdometerHandler) CreateOdometerReading(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OdometerHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateOdometerReading-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var readingReq models.OdometerReadingRequest
+	err = json.Unmarshal(body, &readingReq)
+	if err != nil {
+		log.Println("Error unmarshalling odometer reading request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	reading, err := h.service.CreateOdometerReading(ctx, carID, &readingReq)
+	if err != nil {
+		log.Println("Error creating odometer reading: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(reading)
+	if err != nil {
+		log.Println("Error marshalling odometer reading response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
@@ -0,0 +1,205 @@
+package position
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type PositionHandler struct {
+	service service.PositionServiceInterface
+}
+
+func NewPositionHandler(service service.PositionServiceInterface) *PositionHandler {
+	return &PositionHandler{
+		service: service,
+	}
+}
+
+// CreatePositionsHandler godoc
+// @Summary Report GPS points of a car
+// @Description Store a batch of at most 1000 GPS points from the tracker of a car. Every point is attached to the trip the car was under way on when it was taken; points already reported for the same timestamp are skipped as duplicates. The new points are checked against the geofences in the same transaction; when that fails none of the batch is stored and it can be sent again.
+// @Tags Positions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param batch body models.PositionBatchRequest true "GPS points, speed in km/h and heading in degrees"
+// @Success 201 {object} models.PositionBatch
+// @Failure 400 {object} handler.Problem "Invalid request body or point"
+// @Failure 404 {object} handler.Problem "Car not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/positions [post]
+// @Security Bearer
+func (h *PositionHandler) CreatePositions(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PositionHandler")
+	ctx, span := tracer.Start(r.Context(), "CreatePositions-Handler")
+	defer span.End()
+
+	carID := mux.Vars(r)["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var batchReq models.PositionBatchRequest
+	err = json.Unmarshal(body, &batchReq)
+	if err != nil {
+		log.Println("Error unmarshalling position batch request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	batch, err := h.service.CreatePositions(ctx, carID, &batchReq)
+	if err != nil {
+		log.Println("Error creating positions: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(batch)
+	if err != nil {
+		log.Println("Error marshalling position batch response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetPositionsHandler godoc
+// @Summary Get the GPS points of a car
+// @Description Get a page of the GPS points of a car, oldest first by default. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Positions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Car ID"
+// @Param trip_id query string false "Points taken on this trip"
+// @Param from query string false "Points taken at or after this date or RFC 3339 time"
+// @Param to query string false "Points taken before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of points to skip"
+// @Param sort query string false "Sort field: timestamp, received_at, speed; prefix with - for descending"
+// @Success 200 {array} models.Position
+// @Failure 400 {object} handler.Problem "Invalid ID, filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/cars/{id}/positions [get]
+// @Security Bearer
+func (h *PositionHandler) GetPositions(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PositionHandler")
+	ctx, span := tracer.Start(r.Context(), "GetPositions-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.PositionFilter
+	if filter.CarID, err = uuid.Parse(mux.Vars(r)["id"]); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	if filter.TripID, err = handler.QueryUUID(query, "trip_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	positions, total, err := h.service.GetPositions(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting positions: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(positions)
+	if err != nil {
+		log.Println("Error marshalling positions response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetTripRouteHandler godoc
+// @Summary Get the route of a trip
+// @Description Get the GPS track of a trip as a GeoJSON Feature with a LineString geometry of [longitude, latitude] pairs. The geometry is null until the trip has two points; distance_km is measured along the track with the haversine formula.
+// @Tags Positions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} models.TripRoute
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/trips/{id}/route [get]
+// @Security Bearer
+func (h *PositionHandler) GetTripRoute(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PositionHandler")
+	ctx, span := tracer.Start(r.Context(), "GetTripRoute-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	route, err := h.service.GetTripRoute(ctx, id)
+	if err != nil {
+		log.Println("Error getting trip route: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(route)
+	if err != nil {
+		log.Println("Error marshalling trip route response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
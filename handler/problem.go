@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// Problem is an RFC 7807 problem details body
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"car 4f9f1f0e-8a51-4d3c-9f39-2b8f4c1d6e7a not found"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/cars/4f9f1f0e-8a51-4d3c-9f39-2b8f4c1d6e7a"`
+	// Details holds the resource the problem is about, e.g. the id and time window of the trip a booking conflicts with
+	Details interface{} `json:"details,omitempty" swaggertype:"object"`
+}
+
+// WriteProblem writes an application/problem+json response with the given status and detail
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblem(w, r, status, detail, nil)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, details interface{}) {
+	body, err := json.Marshal(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Details:  details,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println("Error marshalling problem response: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// WriteError maps a domain error to its status code and writes it as a problem. The message of
+// unexpected errors is not shown to clients, the caller is expected to have logged it.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, models.ErrValidation):
+		status = http.StatusBadRequest
+	case errors.Is(err, models.ErrForbidden):
+		status = http.StatusForbidden
+	}
+
+	if status == http.StatusInternalServerError {
+		WriteProblem(w, r, status, "Internal server error")
+		return
+	}
+
+	var details interface{}
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		details = domainErr.Details
+	}
+	writeProblem(w, r, status, err.Error(), details)
+}
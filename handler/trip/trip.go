@@ -6,8 +6,10 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/JulianaSau/carzone/handler"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/service"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel"
 )
@@ -24,12 +26,25 @@ func NewTripHandler(service service.TripServiceInterface) *TripHandler {
 
 // GetTripsHandler godoc
 // @Summary Get all trips
-// @Description Get all trips
+// @Description Get a page of trips. The total is returned in X-Total-Count and the next and previous pages in the Link header.
 // @Tags Trip
 // @Accept  json
 // @Produce  json
+// @Param status query string false "Trip status"
+// @Param car_id query string false "Car ID"
+// @Param driver_id query string false "Driver ID"
+// @Param start_location_id query string false "Trips starting at this location"
+// @Param end_location_id query string false "Trips ending at this location"
+// @Param from query string false "Trips starting at or after this date or RFC 3339 time"
+// @Param to query string false "Trips starting before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of trips to skip"
+// @Param sort query string false "Sort field: start_time, end_time, status, distance_km, created_at, updated_at; prefix with - for descending"
 // @Success 200 {array} models.Trip
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips [get]
 // @Security Bearer
 func (h *TripHandler) GetTrips(w http.ResponseWriter, r *http.Request) {
@@ -37,27 +52,13 @@ func (h *TripHandler) GetTrips(w http.ResponseWriter, r *http.Request) {
 	ctx, span := tracer.Start(r.Context(), "GetTrips-Handler")
 	defer span.End()
 
-	trips, err := h.service.GetTrips(ctx)
+	filter, err := tripFilter(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error getting trips: ", err)
-		return
-	}
-
-	body, err := json.Marshal(trips)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error marshalling trips response: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	// write the response body
-	_, err = w.Write(body)
-	if err != nil {
-		log.Println("Error writing response body: ", err)
-	}
+	h.listTrips(w, r.WithContext(ctx), filter)
 }
 
 // GetTripByIDHandler godoc
@@ -68,9 +69,11 @@ func (h *TripHandler) GetTrips(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Trip ID"
 // @Success 200 {object} models.Trip
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Trip not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips/{id} [get]
 // @Security Bearer
 func (h *TripHandler) GetTripById(w http.ResponseWriter, r *http.Request) {
@@ -84,16 +87,16 @@ func (h *TripHandler) GetTripById(w http.ResponseWriter, r *http.Request) {
 	// get the trip by id from the trip service
 	res, err := h.service.GetTripById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting trip by id: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(res)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling trip by id response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -109,91 +112,111 @@ func (h *TripHandler) GetTripById(w http.ResponseWriter, r *http.Request) {
 
 // GetTripByCarIDHandler godoc
 // @Summary Get trips by Car ID
-// @Description Get trips by car ID
+// @Description Get a page of the trips of a car. Accepts the same filters, pagination and sort parameters as GET /api/v1/trips.
 // @Tags Trip
 // @Accept  json
 // @Produce  json
-// @Param id path string true "Trip ID"
-// @Success 200 {object} models.Trip
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Trip not found"
-// @Failure 500 {string} string "Internal server error"
+// @Param id path string true "Car ID"
+// @Param status query string false "Trip status"
+// @Param from query string false "Trips starting at or after this date or RFC 3339 time"
+// @Param to query string false "Trips starting before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of trips to skip"
+// @Param sort query string false "Sort field, prefix with - for descending"
+// @Success 200 {array} models.Trip
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/cars/{id}/trips [get]
 // @Security Bearer
 func (h *TripHandler) GetTripsByCarID(w http.ResponseWriter, r *http.Request) {
 	tracer := otel.Tracer("TripHandler")
 	ctx, span := tracer.Start(r.Context(), "GetTripByCarId-Handler")
 	defer span.End()
-	// get the request params
-	vars := mux.Vars(r)
-	id := vars["id"]
 
-	// get the trip by id from the trip service
-	res, err := h.service.GetTripsByCarID(ctx, id)
+	filter, err := tripFilter(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error getting trips by car id: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// marshal the response
-	body, err := json.Marshal(res)
+	// get the request params
+	filter.CarID, err = uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error marshalling trips by car id response: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid ID")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	// write the response body
-	_, err = w.Write(body)
-	if err != nil {
-		log.Println("Error writing response body: ", err)
-	}
+	h.listTrips(w, r.WithContext(ctx), filter)
 }
 
 // GetTripByDriverIDHandler godoc
 // @Summary Get trips by Driver ID
-// @Description Get trips by Driver ID
+// @Description Get a page of the trips of a driver. Accepts the same filters, pagination and sort parameters as GET /api/v1/trips.
 // @Tags Trip
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Driver ID"
-// @Success 200 {object} models.Trip
-// @Failure 400 {string} string "Invalid Driver ID"
-// @Failure 404 {string} string "Trips not found"
-// @Failure 500 {string} string "Internal server error"
+// @Param status query string false "Trip status"
+// @Param from query string false "Trips starting at or after this date or RFC 3339 time"
+// @Param to query string false "Trips starting before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of trips to skip"
+// @Param sort query string false "Sort field, prefix with - for descending"
+// @Success 200 {array} models.Trip
+// @Failure 400 {object} handler.Problem "Invalid Driver ID"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/drivers/{id}/trips [get]
 // @Security Bearer
 func (h *TripHandler) GetTripsByDriverID(w http.ResponseWriter, r *http.Request) {
 	tracer := otel.Tracer("TripHandler")
 	ctx, span := tracer.Start(r.Context(), "GetTripByDriverId-Handler")
 	defer span.End()
+
+	filter, err := tripFilter(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// get the request params
-	vars := mux.Vars(r)
-	id := vars["id"]
+	filter.DriverID, err = uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid Driver ID")
+		return
+	}
 
-	// get the trip by id from the trip service
-	res, err := h.service.GetTripsByDriverID(ctx, id)
+	h.listTrips(w, r.WithContext(ctx), filter)
+}
+
+// listTrips writes one page of the trips matching filter
+func (h *TripHandler) listTrips(w http.ResponseWriter, r *http.Request, filter models.TripFilter) {
+	opts, err := handler.ParseListOptions(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error getting trips by driver id: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// marshal the response
-	body, err := json.Marshal(res)
+	trips, total, err := h.service.GetTrips(r.Context(), filter, opts)
+	if err != nil {
+		log.Println("Error getting trips: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(trips)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error marshalling trips by driver id response: ", err)
+		log.Println("Error marshalling trips response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
+	handler.WriteListHeaders(w, r, opts, total)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-
 	// write the response body
 	_, err = w.Write(body)
 	if err != nil {
@@ -201,16 +224,46 @@ func (h *TripHandler) GetTripsByDriverID(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// tripFilter reads the trip filters from the query string
+func tripFilter(r *http.Request) (models.TripFilter, error) {
+	query := r.URL.Query()
+	filter := models.TripFilter{Status: query.Get("status")}
+
+	var err error
+	if filter.CarID, err = handler.QueryUUID(query, "car_id"); err != nil {
+		return filter, err
+	}
+	if filter.DriverID, err = handler.QueryUUID(query, "driver_id"); err != nil {
+		return filter, err
+	}
+	if filter.StartLocationID, err = handler.QueryUUID(query, "start_location_id"); err != nil {
+		return filter, err
+	}
+	if filter.EndLocationID, err = handler.QueryUUID(query, "end_location_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
 // CreateTripHandler godoc
 // @Summary Create a new trip
-// @Description Create a new trip
+// @Description Create a new trip. The endpoints are given as start_location_id and end_location_id, or as start_location and end_location text; text naming a location links the trip to it.
 // @Tags Trip
 // @Accept  json
 // @Produce  json
 // @Param trip body models.TripRequest true "Trip Request"
 // @Success 201 {object} models.Trip
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 409 {object} handler.Problem "Car or driver already booked at that time, or car unavailable"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips [post]
 // @Security Bearer
 func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request) {
@@ -220,8 +273,8 @@ func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -229,23 +282,23 @@ func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &tripReq)
 	if err != nil {
 		log.Println("Error unmarshalling trip request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// create the trip
 	createdTrip, err := h.service.CreateTrip(ctx, &tripReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating trip: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(createdTrip)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling created trip response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -267,8 +320,12 @@ func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Trip ID"
 // @Param trip body models.TripRequest true "Trip Request"
 // @Success 200 {object} models.Trip
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request body"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 409 {object} handler.Problem "Status changed or car or driver already booked at that time"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips/{id} [put]
 // @Security Bearer
 func (h *TripHandler) UpdateTrip(w http.ResponseWriter, r *http.Request) {
@@ -281,32 +338,32 @@ func (h *TripHandler) UpdateTrip(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	var tripReq models.TripRequest
 	err = json.Unmarshal(body, &tripReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error unmarshalling trip request: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the trip
 	updatedTrip, err := h.service.UpdateTrip(ctx, id, &tripReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating trip: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedTrip)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated trip response body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -328,7 +385,10 @@ func (h *TripHandler) UpdateTrip(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "Trip ID"
 // @Success 200 {object} models.Trip
-// @Failure 500 {string} string "Internal server error"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips/{id} [delete]
 // @Security Bearer
 func (h *TripHandler) DeleteTrip(w http.ResponseWriter, r *http.Request) {
@@ -342,16 +402,16 @@ func (h *TripHandler) DeleteTrip(w http.ResponseWriter, r *http.Request) {
 	// delete the trip
 	deletedTrip, err := h.service.DeleteTrip(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error deleting trip: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(deletedTrip)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling deleted trip response body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -367,16 +427,21 @@ func (h *TripHandler) DeleteTrip(w http.ResponseWriter, r *http.Request) {
 
 // UpdateTripStatusHandler godoc
 // @Summary Update trip status
-// @Description Update trip status by ID
+// @Description Move a trip through its lifecycle: Draft → Scheduled → In Progress → Completed, or Cancelled before it is completed. Starting a trip records the actual start time, completing it requires end_time and fuel_consumed_liters. odometer_km records the car's odometer when the trip starts or ends. With both odometer readings the distance is their difference and a distance_km given must agree with it; without them, a missing distance_km is measured along the GPS track of the trip. The status and reason may also be passed as query parameters.
 // @Tags Trip
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Trip ID"
-// @Param status query string true "Active status"
+// @Param trip body models.TripStatusRequest false "Status Request"
+// @Param status query string false "New status" Enums(Scheduled, In Progress, Completed, Cancelled)
+// @Param reason query string false "Reason for the change"
 // @Success 200 {object} models.Trip
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Trip not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID, status, missing completion details or a distance the odometer readings disagree with"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 409 {object} handler.Problem "Transition not allowed from the current status, car or driver already booked, or odometer reading goes backwards"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/trips/{id}/update-status [put]
 // @Security Bearer
 func (h *TripHandler) UpdateTripStatus(w http.ResponseWriter, r *http.Request) {
@@ -387,27 +452,181 @@ func (h *TripHandler) UpdateTripStatus(w http.ResponseWriter, r *http.Request) {
 	// get the request params
 	vars := mux.Vars(r)
 	id := vars["id"]
-	status := r.URL.Query().Get("status")
 
-	// toggle the trip status
-	toggledTrip, err := h.service.UpdateTripStatus(ctx, id, status)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	var statusReq models.TripStatusRequest
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &statusReq)
+		if err != nil {
+			log.Println("Error unmarshalling trip status request: ", err)
+			handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	query := r.URL.Query()
+	if statusReq.Status == "" {
+		statusReq.Status = query.Get("status")
+	}
+	if statusReq.Reason == "" {
+		statusReq.Reason = query.Get("reason")
+	}
+
+	// move the trip to the new status
+	updatedTrip, err := h.service.UpdateTripStatus(ctx, id, &statusReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating trip status: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
-	body, err := json.Marshal(toggledTrip)
+	responseBody, err := json.Marshal(updatedTrip)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated trip response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
+	// write the response body
+	_, err = w.Write(responseBody)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetTripTransitionsHandler godoc
+// @Summary Get the status changes of a trip
+// @Description Get every status change of a trip, oldest first, with the reason given and the user who made it
+// @Tags Trip
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Trip ID"
+// @Success 200 {array} models.TripTransition
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "Trip not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/trips/{id}/transitions [get]
+// @Security Bearer
+func (h *TripHandler) GetTripTransitions(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("TripHandler")
+	ctx, span := tracer.Start(r.Context(), "GetTripTransitions-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	transitions, err := h.service.GetTripTransitions(ctx, id)
+	if err != nil {
+		log.Println("Error getting trip transitions: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	// marshal the response
+	body, err := json.Marshal(transitions)
+	if err != nil {
+		log.Println("Error marshalling trip transitions response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// write the response body
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println("Error writing response body: ", err)
+	}
+}
+
+// GetRouteStatsHandler godoc
+// @Summary Get trip statistics per route
+// @Description Get a page of the origin–destination pairs of the completed trips with their trip count, average duration, distance and fuel. Trips linked to locations are grouped by location, the others by their start_location and end_location text. The total is returned in X-Total-Count and the next and previous pages in the Link header.
+// @Tags Trip
+// @Accept  json
+// @Produce  json
+// @Param car_id query string false "Trips of this car"
+// @Param driver_id query string false "Trips of this driver"
+// @Param origin_id query string false "Trips starting at this location"
+// @Param destination_id query string false "Trips ending at this location"
+// @Param from query string false "Trips starting at or after this date or RFC 3339 time"
+// @Param to query string false "Trips starting before this date or RFC 3339 time"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of routes to skip"
+// @Param sort query string false "Sort field: origin, destination, trips, avg_duration, avg_distance, avg_fuel; prefix with - for descending"
+// @Success 200 {array} models.RouteStats
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
+// @Router /api/v1/trips/routes [get]
+// @Security Bearer
+func (h *TripHandler) GetRouteStats(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("TripHandler")
+	ctx, span := tracer.Start(r.Context(), "GetRouteStats-Handler")
+	defer span.End()
+
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.RouteStatsFilter
+	if filter.CarID, err = handler.QueryUUID(query, "car_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.DriverID, err = handler.QueryUUID(query, "driver_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.OriginID, err = handler.QueryUUID(query, "origin_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.DestinationID, err = handler.QueryUUID(query, "destination_id"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = handler.QueryTime(query, "from"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = handler.QueryTime(query, "to"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	routes, total, err := h.service.GetRouteStats(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error getting route stats: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(routes)
+	if err != nil {
+		log.Println("Error marshalling route stats response: ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+
+	handler.WriteListHeaders(w, r, opts, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	// write the response body
 	_, err = w.Write(body)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/JulianaSau/carzone/handler"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/service"
 	"github.com/gorilla/mux"
@@ -25,12 +26,20 @@ func NewUserHandler(service service.UserServiceInterface) *UserHandler {
 
 // GetUsersHandler godoc
 // @Summary Get all users
-// @Description Get all users
+// @Description Get a page of users. The total is returned in X-Total-Count and the next and previous pages in the Link header.
 // @Tags User
 // @Accept  json
 // @Produce  json
+// @Param role query string false "Role" Enums(admin, manager, driver)
+// @Param active query bool false "Active users only (true) or deactivated users only (false)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of users to skip"
+// @Param sort query string false "Sort field: username, first_name, last_name, email, role, created_at, updated_at; prefix with - for descending"
 // @Success 200 {array} models.User
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid filter or pagination parameters"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users [get]
 // @Security Bearer
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
@@ -38,20 +47,34 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	ctx, span := tracer.Start(r.Context(), "GetUsers-Handler")
 	defer span.End()
 
-	users, err := h.service.GetUsers(ctx)
+	opts, err := handler.ParseListOptions(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.UserFilter{Role: query.Get("role")}
+	if filter.Active, err = handler.QueryBool(query, "active"); err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, total, err := h.service.GetUsers(ctx, filter, opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting users: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	body, err := json.Marshal(users)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling users response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
+	handler.WriteListHeaders(w, r, opts, total)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	// write the response body
@@ -69,9 +92,11 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param id path string true "User ID"
 // @Success 200 {object} models.User
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "User not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "User not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users/{id} [get]
 // @Security Bearer
 func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
@@ -85,16 +110,16 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.service.GetUserProfile(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error getting user profile: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(user)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling user profile response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -116,8 +141,11 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param user body models.UserRequest true "User object that needs to be created"
 // @Success 201 {object} models.User
-// @Failure 400 {string} string "Invalid request payload"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid request payload"
+// @Failure 409 {object} handler.Problem "Username or email already in use"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users [post]
 // @Security Bearer
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -127,8 +155,8 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -136,23 +164,23 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &userReq)
 	if err != nil {
 		log.Println("Error unmarshalling user request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// create the car
 	createdCar, err := h.service.CreateUser(ctx, &userReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating user: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(createdCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling created user response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -174,9 +202,11 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "User ID"
 // @Param user body models.UserRequest true "User object that needs to be updated"
 // @Success 200 {object} models.User
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "User not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "User not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users/{id} [put]
 // @Security Bearer
 func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
@@ -190,8 +220,8 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -199,23 +229,23 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	err = json.Unmarshal(body, &userReq)
 	if err != nil {
 		log.Println("Error unmarshalling user request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the user profile
 	updatedUser, err := h.service.UpdateUserProfile(ctx, id, &userReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating user profile: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedUser)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated user response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -238,9 +268,11 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 // @Param id path string true "User ID"
 // @Param user body models.UpdatePasswordRequest true "User object that needs to be updated"
 // @Success 200 {object} models.User
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "User not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "User not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users/{id}/update-password [put]
 // @Security Bearer
 func (h *UserHandler) UpdateUserPassword(w http.ResponseWriter, r *http.Request) {
@@ -255,8 +287,8 @@ func (h *UserHandler) UpdateUserPassword(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error reading request body: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -264,23 +296,23 @@ func (h *UserHandler) UpdateUserPassword(w http.ResponseWriter, r *http.Request)
 	err = json.Unmarshal(body, &userReq)
 	if err != nil {
 		log.Println("Error unmarshalling user request: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// update the user profile
 	updatedUser, err := h.service.UpdateUserPassword(ctx, id, &userReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error updating user profile: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	responseBody, err := json.Marshal(updatedUser)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling updated user response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -302,9 +334,11 @@ func (h *UserHandler) UpdateUserPassword(w http.ResponseWriter, r *http.Request)
 // @Produce  json
 // @Param id path string true "User ID"
 // @Success 200 {object} models.User
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "User not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "User not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users/{id}/delete [delete]
 // @Security Bearer
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
@@ -319,16 +353,16 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	// delete the user
 	deletedUser, err := h.service.DeleteUser(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error deleting user: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(deletedUser)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling deleted user response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -351,9 +385,11 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "User ID"
 // @Param active query boolean true "Active status"
 // @Success 200 {object} models.User
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "User not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} handler.Problem "Invalid ID"
+// @Failure 404 {object} handler.Problem "User not found"
+// @Failure 401 {object} handler.Problem "Unauthorized"
+// @Failure 403 {object} handler.Problem "Forbidden"
+// @Failure 500 {object} handler.Problem "Internal server error"
 // @Router /api/v1/users/{id}/toggle-status [put]
 // @Security Bearer
 func (h *UserHandler) ToggleUserStatus(w http.ResponseWriter, r *http.Request) {
@@ -369,24 +405,24 @@ func (h *UserHandler) ToggleUserStatus(w http.ResponseWriter, r *http.Request) {
 	// parse the active status
 	isActive, err := strconv.ParseBool(active)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
 		log.Println("Invalid active status: ", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "Invalid active status")
 		return
 	}
 
 	// toggle the user status
 	toggledUser, err := h.service.ToggleUserStatus(ctx, id, isActive)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error toggling user status: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	// marshal the response
 	body, err := json.Marshal(toggledUser)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error marshalling toggled user response: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 
@@ -1,50 +1,25 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/JulianaSau/carzone/driver"
-	carHandler "github.com/JulianaSau/carzone/handler/car"
-	driverHandler "github.com/JulianaSau/carzone/handler/driver"
-	engineHandler "github.com/JulianaSau/carzone/handler/engine"
-	tripHandler "github.com/JulianaSau/carzone/handler/trip"
-	userHandler "github.com/JulianaSau/carzone/handler/user"
-	carService "github.com/JulianaSau/carzone/service/car"
-	driverService "github.com/JulianaSau/carzone/service/driver"
-	engineService "github.com/JulianaSau/carzone/service/engine"
-	tripService "github.com/JulianaSau/carzone/service/trip"
-	userService "github.com/JulianaSau/carzone/service/user"
-	carStore "github.com/JulianaSau/carzone/store/car"
-	driverStore "github.com/JulianaSau/carzone/store/driver"
-	engineStore "github.com/JulianaSau/carzone/store/engine"
-	tripStore "github.com/JulianaSau/carzone/store/trip"
-	userStore "github.com/JulianaSau/carzone/store/user"
+	middleware "github.com/JulianaSau/carzone/middleware"
 
-	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
-	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
-
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-
-	loginHandler "github.com/JulianaSau/carzone/handler/login"
-	middleware "github.com/JulianaSau/carzone/middleware"
-
-	_ "github.com/JulianaSau/carzone/docs" // Import generated Swagger docs
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // @title Car Management System API
@@ -73,6 +48,26 @@ func main() {
 		log.Fatal("Error loading.env file")
 	}
 
+	// carzone migrate ... manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		driver.InitDB()
+		err := runMigrate(driver.GetDB(), os.Args[2:])
+		driver.CloseDB()
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	// --store=memory keeps the data in process instead of the database, for demos without one
+	storeBackend := flag.String("store", "database", "where the server keeps its data: database, the one DB_DRIVER names, or memory")
+	flag.Parse()
+
+	// load the token signing and verification keys
+	if err := middleware.LoadKeys(); err != nil {
+		log.Fatalf("failed to load jwt keys: %v", err)
+	}
+
 	// start tracing
 	traceProvider, err := startTracing()
 	if err != nil {
@@ -87,100 +82,29 @@ func main() {
 	}()
 
 	otel.SetTracerProvider(traceProvider)
+	// continue the traces of callers that send a traceparent header, such as the client package
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var stores stores
+	switch *storeBackend {
+	// postgres is the name the database had before DB_DRIVER could choose SQLite
+	case "database", "postgres":
+		driver.InitDB()
+		defer driver.CloseDB()
+
+		db := driver.GetDB()
+		if err := migrateOnStart(db); err != nil {
+			log.Fatalf("Error while migrating the database: %v", err)
+		}
+		stores = databaseStores(db)
+	case "memory":
+		log.Println("Keeping data in memory with the demo data loaded, changes are lost when the server stops")
+		stores = memoryStores()
+	default:
+		log.Fatalf("unknown store %q, expected database or memory", *storeBackend)
+	}
 
-	driver.InitDB()
-	defer driver.CloseDB()
-
-	db := driver.GetDB()
-	// if db == nil {
-	// 	log.Fatal("Could not connect to the database")
-	// }
-
-	// create a new car store instance and a new car service instance using the db instance
-	carStore := carStore.New(db)
-	carService := carService.NewCarService(carStore)
-
-	engineStore := engineStore.New(db)
-	engineService := engineService.NewEngineService(engineStore)
-
-	userStore := userStore.New(db)
-	userService := userService.NewUserService(userStore)
-
-	driverStore := driverStore.New(db)
-	driverService := driverService.NewDriverService(driverStore)
-
-	tripStore := tripStore.New(db)
-	tripService := tripService.NewTripService(tripStore)
-
-	carHandler := carHandler.NewCarHandler(carService)
-	engineHandler := engineHandler.NewEngineHandler(engineService)
-	userHandler := userHandler.NewUserHandler(userService)
-	driverHandler := driverHandler.NewDriverHandler(driverService)
-	tripHandler := tripHandler.NewTripHandler(tripService)
-
-	// initialise router
-	router := mux.NewRouter()
-
-	// // execute schema file to populate database
-	// schemaFile := "store/schema.sql"
-	// if err := executeSchemaFile(db, schemaFile); err != nil {
-	// 	log.Fatalf("Error while executing schema file: %v", err)
-	// }
-
-	// define routes
-	router.Use(otelmux.Middleware("carzone"))
-	router.Use(middleware.MetricsMiddleware)
-
-	router.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
-		loginHandler.LoginHandler(w, r, userService)
-	}).Methods("POST")
-
-	// Swagger documentation route
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-
-	// middleware
-	protected := router.PathPrefix("/").Subrouter()
-	protected.Use(middleware.AuthMIddleware)
-	// router.Use(middleware.AuthMIddleware)
-
-	protected.HandleFunc("/api/v1/users", userHandler.GetUsers).Methods("GET")
-	protected.HandleFunc("/api/v1/users/{id}", userHandler.GetUserProfile).Methods("GET")
-	protected.HandleFunc("/api/v1/users", userHandler.CreateUser).Methods("POST")
-	protected.HandleFunc("/api/v1/users/{id}", userHandler.UpdateUserProfile).Methods("PUT")
-	protected.HandleFunc("/api/v1/users/{id}/update-password", userHandler.UpdateUserPassword).Methods("PUT")
-	protected.HandleFunc("/api/v1/users/{id}", userHandler.DeleteUser).Methods("DELETE")
-	protected.HandleFunc("/api/v1/users/{id}/toggle-status", userHandler.ToggleUserStatus).Methods("PUT")
-
-	protected.HandleFunc("/api/v1/drivers", driverHandler.GetDrivers).Methods("GET")
-	protected.HandleFunc("/api/v1/drivers/{id}", driverHandler.GetDriverById).Methods("GET")
-	protected.HandleFunc("/api/v1/drivers", driverHandler.CreateDriver).Methods("POST")
-	protected.HandleFunc("/api/v1/drivers/{id}", driverHandler.UpdateDriver).Methods("PUT")
-	protected.HandleFunc("/api/v1/drivers/{id}/delete", driverHandler.DeleteDriver).Methods("DELETE")
-	protected.HandleFunc("/api/v1/drivers/{id}", driverHandler.SoftDeleteDriver).Methods("DELETE")
-	protected.HandleFunc("/api/v1/drivers/{id}/toggle-status", driverHandler.ToggleDriverStatus).Methods("PUT")
-
-	protected.HandleFunc("/api/v1/cars/{id}", carHandler.GetCarById).Methods("GET")
-	protected.HandleFunc("/api/v1/cars", carHandler.GetCarByBrand).Methods("GET")
-	protected.HandleFunc("/api/v1/cars", carHandler.CreateCar).Methods("POST")
-	protected.HandleFunc("/api/v1/cars/{id}", carHandler.UpdateCar).Methods("PUT")
-	protected.HandleFunc("/api/v1/cars/{id}", carHandler.DeleteCar).Methods("DELETE")
-
-	protected.HandleFunc("/api/v1/engines/{id}", engineHandler.GetEngineById).Methods("GET")
-	protected.HandleFunc("/api/v1/engines", engineHandler.CreateEngine).Methods("POST")
-	protected.HandleFunc("/api/v1/engines/{id}", engineHandler.UpdateEngine).Methods("PUT")
-	protected.HandleFunc("/api/v1/engines/{id}", engineHandler.DeleteEngine).Methods("DELETE")
-
-	protected.HandleFunc("/api/v1/trips", tripHandler.GetTrips).Methods("GET")
-	protected.HandleFunc("/api/v1/trips/{id}", tripHandler.GetTripById).Methods("GET")
-	protected.HandleFunc("/api/v1/cars/{id}/trips", tripHandler.GetTripsByCarID).Methods("GET")
-	protected.HandleFunc("/api/v1/drivers/{id}/trips", tripHandler.GetTripsByDriverID).Methods("GET")
-	protected.HandleFunc("/api/v1/trips", tripHandler.CreateTrip).Methods("POST")
-	protected.HandleFunc("/api/v1/trips/{id}", tripHandler.UpdateTrip).Methods("PUT")
-	protected.HandleFunc("/api/v1/trips/{id}/update-status", tripHandler.UpdateTripStatus).Methods("PUT")
-	protected.HandleFunc("/api/v1/trips/{id}", tripHandler.DeleteTrip).Methods("DELETE")
-
-	// metrics
-	router.Handle("/metrics", promhttp.Handler())
+	router := newRouter(stores)
 
 	// start the server
 	port := os.Getenv("PORT")
@@ -193,33 +117,6 @@ func main() {
 	log.Fatal(http.ListenAndServe(addr, router))
 }
 
-func executeSchemaFile(db *sql.DB, fileName string) error {
-	if db == nil {
-		return fmt.Errorf("database connection is nil")
-	}
-
-	// Use filepath for cross-platform compatibility
-	schemaPath := filepath.Clean(fileName)
-
-	// Read the schema file
-	sqlFile, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return fmt.Errorf("failed to read schema file '%s': %w", schemaPath, err)
-	}
-
-	// Normalize line endings
-	sqlFile = bytes.ReplaceAll(sqlFile, []byte("\r\n"), []byte("\n"))
-
-	// Execute the SQL commands
-	_, err = db.Exec(string(sqlFile))
-	if err != nil {
-		return fmt.Errorf("failed to execute schema file '%s': %w", schemaPath, err)
-	}
-
-	log.Printf("Successfully executed schema file: %s", schemaPath)
-	return nil
-}
-
 func startTracing() (*trace.TracerProvider, error) {
 	header := map[string]string{
 		"Content-Type": "application/json",
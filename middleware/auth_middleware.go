@@ -2,24 +2,45 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/JulianaSau/carzone/models"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtKey = []byte("your-secret-key") // Replace with your own secret key
 type Claims struct {
+	UserID   string `json:"user_id"`
 	UserName string `json:"username"`
+	Role     string `json:"role"`
 	jwt.StandardClaims
 }
 
+// claimsKey is the context key holding the parsed token claims
+type claimsKey struct{}
+
+// SessionValidator checks that a token with a valid signature still belongs to a live session,
+// i.e. it was not revoked on logout and its user has not been deactivated
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, claims *Claims) error
+}
+
+var sessions SessionValidator
+
+// SetSessionValidator installs the session check run by AuthMIddleware after the signature check
+func SetSessionValidator(validator SessionValidator) {
+	sessions = validator
+}
+
 func AuthMIddleware(next http.Handler) http.Handler {
 	// Alters request before it gets to application handler
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			handler.WriteProblem(w, r, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
@@ -27,16 +48,43 @@ func AuthMIddleware(next http.Handler) http.Handler {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
 
 		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			handler.WriteProblem(w, r, http.StatusUnauthorized, "Invalid token")
 			return
 		}
-		ctx := context.WithValue(r.Context(), "username", claims.UserName)
+
+		if sessions != nil {
+			if err := sessions.ValidateSession(r.Context(), claims); err != nil {
+				if errors.Is(err, models.ErrTokenRevoked) || errors.Is(err, models.ErrUserInactive) {
+					handler.WriteProblem(w, r, http.StatusUnauthorized, err.Error())
+					return
+				}
+				log.Println("Error validating session: ", err)
+				handler.WriteProblem(w, r, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+		}
+		ctx := WithIdentity(r.Context(), Identity{
+			UserID:   claims.UserID,
+			UserName: claims.UserName,
+			Role:     claims.Role,
+			IP:       ClientIP(r),
+		})
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 
 	})
 }
+
+// SignToken signs the claims with the current signing key and tags the token with its kid
+func SignToken(claims *Claims) (string, error) {
+	return keys.sign(claims)
+}
+
+// ClaimsFromContext returns the token claims stored by AuthMIddleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import "context"
+
+// Identity describes the authenticated caller of a request
+type Identity struct {
+	UserID   string
+	UserName string
+	Role     string
+	IP       string
+}
+
+// identityKey is the context key holding the Identity of the caller
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored by AuthMIddleware
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Actor returns the value recorded in the created_by and updated_by columns: the id of the caller,
+// or the username for tokens issued before the user id claim existed. It is empty outside a request.
+func Actor(ctx context.Context) string {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if identity.UserID != "" {
+		return identity.UserID
+	}
+	return identity.UserName
+}
@@ -0,0 +1,336 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	defaultTTL        = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// KeySet holds the key used to sign new tokens and every key that is still accepted when verifying.
+// Keeping retired keys in the verification set lets us rotate without logging everyone out.
+type KeySet struct {
+	method     jwt.SigningMethod
+	signingKID string
+	signingKey interface{}
+	verifyKeys map[string]verifyKey
+	ttl        time.Duration
+	refreshTTL time.Duration
+}
+
+// verifyKey is a key tokens are verified with and the one alg its tokens are signed with
+type verifyKey struct {
+	alg string
+	key interface{}
+}
+
+var keys = defaultKeys()
+
+// LoadKeys reads the signing configuration from the environment and installs it for
+// AuthMIddleware and SignToken. It is called once on startup after the .env file is loaded.
+//
+//	JWT_SIGNING_METHOD    HS256 (default), RS256 or ES256
+//	JWT_SECRET(_FILE)     HMAC secret for HS256, required unless JWT_RANDOM_SECRET is set
+//	JWT_RANDOM_SECRET     true signs HS256 tokens with a random secret of this process, for development only
+//	JWT_PRIVATE_KEY(_FILE) PEM private key for RS256/ES256
+//	JWT_KEY_ID            kid of the signing key, derived from the public key when empty
+//	JWT_VERIFY_KEYS       extra verification keys as kid=path pairs separated by commas
+//	JWT_TTL               access token lifetime, e.g. 15m
+//	JWT_REFRESH_TTL       refresh token lifetime, e.g. 720h
+func LoadKeys() error {
+	ks, err := keySetFromEnv()
+	if err != nil {
+		return err
+	}
+	keys = ks
+	return nil
+}
+
+// TokenTTL is the lifetime of newly issued access tokens
+func TokenTTL() time.Duration {
+	return keys.ttl
+}
+
+// RefreshTokenTTL is the lifetime of newly issued refresh tokens
+func RefreshTokenTTL() time.Duration {
+	return keys.refreshTTL
+}
+
+// PublicJWKS returns the public verification keys. HMAC secrets are never published.
+func PublicJWKS() models.JWKSet {
+	set := models.JWKSet{Keys: []models.JWK{}}
+	for kid, key := range keys.verifyKeys {
+		jwk, ok := toJWK(kid, key.alg, key.key)
+		if ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// keyFunc picks the verification key named by the kid header. Tokens without a kid were issued
+// before key ids existed and are checked against the current signing key.
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = k.signingKID
+	}
+
+	key, ok := k.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	// reject tokens whose alg is not exactly the one of their key to avoid algorithm confusion
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.key, nil
+}
+
+// defaultKeys signs with a random secret until LoadKeys installs the configured keys, so no token is ever signed
+// with a secret known outside the process
+func defaultKeys() *KeySet {
+	secret, err := randomSecret()
+	if err != nil {
+		panic(err)
+	}
+	return &KeySet{
+		method:     jwt.SigningMethodHS256,
+		signingKID: "default",
+		signingKey: secret,
+		verifyKeys: map[string]verifyKey{"default": {alg: jwt.SigningMethodHS256.Alg(), key: secret}},
+		ttl:        defaultTTL,
+		refreshTTL: defaultRefreshTTL,
+	}
+}
+
+// randomSecret returns a new 256 bit HMAC secret
+func randomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating a random secret: %w", err)
+	}
+	return secret, nil
+}
+
+func keySetFromEnv() (*KeySet, error) {
+	ks := &KeySet{
+		verifyKeys: map[string]verifyKey{},
+	}
+
+	var err error
+	if ks.ttl, err = durationFromEnv("JWT_TTL", defaultTTL); err != nil {
+		return nil, err
+	}
+	if ks.refreshTTL, err = durationFromEnv("JWT_REFRESH_TTL", defaultRefreshTTL); err != nil {
+		return nil, err
+	}
+
+	method := os.Getenv("JWT_SIGNING_METHOD")
+	if method == "" {
+		method = "HS256"
+	}
+
+	var public interface{}
+	switch method {
+	case "HS256":
+		secret, err := readValueOrFile("JWT_SECRET")
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			random, _ := strconv.ParseBool(os.Getenv("JWT_RANDOM_SECRET"))
+			if !random {
+				return nil, errors.New("JWT_SECRET or JWT_SECRET_FILE is required for HS256, or JWT_RANDOM_SECRET=true for development")
+			}
+			log.Println("JWT_SECRET is not set, signing with a random secret: tokens stop working when the server restarts")
+			if secret, err = randomSecret(); err != nil {
+				return nil, err
+			}
+		}
+		ks.method = jwt.SigningMethodHS256
+		ks.signingKey = secret
+		public = secret
+	case "RS256", "ES256":
+		pemBytes, err := readValueOrFile("JWT_PRIVATE_KEY")
+		if err != nil {
+			return nil, err
+		}
+		if pemBytes == nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_FILE is required for %s", method)
+		}
+		if method == "RS256" {
+			key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA private key: %w", err)
+			}
+			ks.method = jwt.SigningMethodRS256
+			ks.signingKey = key
+			public = &key.PublicKey
+		} else {
+			key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EC private key: %w", err)
+			}
+			if key.Curve != elliptic.P256() {
+				return nil, fmt.Errorf("ES256 needs a P-256 EC private key, got %s", key.Curve.Params().Name)
+			}
+			ks.method = jwt.SigningMethodES256
+			ks.signingKey = key
+			public = &key.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT_SIGNING_METHOD %q", method)
+	}
+
+	ks.signingKID = os.Getenv("JWT_KEY_ID")
+	if ks.signingKID == "" && ks.method == jwt.SigningMethodHS256 {
+		ks.signingKID = "default"
+	}
+	if ks.signingKID == "" {
+		kid, err := keyID(public)
+		if err != nil {
+			return nil, err
+		}
+		ks.signingKID = kid
+	}
+	ks.verifyKeys[ks.signingKID] = verifyKey{alg: ks.method.Alg(), key: public}
+
+	// previously used keys that should still verify until their tokens expire
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_VERIFY_KEYS entry %q, expected kid=path", entry)
+		}
+		key, err := loadVerifyKey(ks.method, path)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		ks.verifyKeys[kid] = key
+	}
+
+	return ks, nil
+}
+
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return d, nil
+}
+
+// readValueOrFile reads NAME from the environment, or the file named by NAME_FILE
+func readValueOrFile(name string) ([]byte, error) {
+	if value := os.Getenv(name); value != "" {
+		return []byte(value), nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return data, nil
+}
+
+// loadVerifyKey reads a retired key. Public keys may be RSA or EC regardless of the current
+// signing method so a rotation can also switch algorithms; the key type settles its alg.
+func loadVerifyKey(method jwt.SigningMethod, path string) (verifyKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return verifyKey{}, err
+	}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return verifyKey{alg: method.Alg(), key: []byte(strings.TrimSpace(string(data)))}, nil
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return verifyKey{alg: jwt.SigningMethodRS256.Alg(), key: key}, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		if key.Curve != elliptic.P256() {
+			return verifyKey{}, fmt.Errorf("expected a P-256 EC key for ES256, got %s", key.Curve.Params().Name)
+		}
+		return verifyKey{alg: jwt.SigningMethodES256.Alg(), key: key}, nil
+	}
+	return verifyKey{}, errors.New("expected a PEM encoded RSA or EC public key")
+}
+
+// keyID derives a stable kid from the public key so rotating keys always yields a new id
+func keyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:8]), nil
+}
+
+func toJWK(kid string, alg string, key interface{}) (models.JWK, bool) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return models.JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: alg,
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		point, err := k.ECDH()
+		if err != nil {
+			return models.JWK{}, false
+		}
+		// uncompressed point encoding: 0x04 || X || Y
+		raw := point.Bytes()
+		size := (len(raw) - 1) / 2
+		return models.JWK{
+			Kty: "EC",
+			Use: "sig",
+			Alg: alg,
+			Kid: kid,
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(raw[1 : 1+size]),
+			Y:   base64.RawURLEncoding.EncodeToString(raw[1+size:]),
+		}, true
+	}
+	return models.JWK{}, false
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestKeyFuncMatchesAlgExactly(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := &KeySet{
+		method:     jwt.SigningMethodRS256,
+		signingKID: "rsa",
+		signingKey: private,
+		verifyKeys: map[string]verifyKey{"rsa": {alg: jwt.SigningMethodRS256.Alg(), key: &private.PublicKey}},
+	}
+
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodRS256, jwt.SigningMethodRS384, jwt.SigningMethodPS256} {
+		token := jwt.NewWithClaims(method, jwt.RegisteredClaims{Subject: "fleet"})
+		token.Header["kid"] = "rsa"
+		signed, err := token.SignedString(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = jwt.Parse(signed, ks.keyFunc)
+		if method == jwt.SigningMethodRS256 && err != nil {
+			t.Errorf("%s token of an RS256 key: got error %v, want it accepted", method.Alg(), err)
+		}
+		if method != jwt.SigningMethodRS256 && err == nil {
+			t.Errorf("%s token of an RS256 key: accepted, want it rejected", method.Alg())
+		}
+	}
+}
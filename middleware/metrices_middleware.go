@@ -48,6 +48,28 @@ var (
 		},
 	)
 
+	fuelPurchasedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fuel_purchased_liters_total",
+			Help: "Total fuel bought across all refuels.",
+		},
+	)
+
+	fuelCostTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fuel_cost_total",
+			Help: "Total amount spent on fuel across all refuels.",
+		},
+	)
+
+	geofenceEventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "geofence_events_total",
+			Help: "Total number of cars entering and leaving geofences.",
+		},
+		[]string{"kind", "event"},
+	)
+
 	averageTripDuration = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "trip_duration_seconds",
@@ -63,7 +85,7 @@ type responseWriter struct {
 }
 
 func init() {
-	prometheus.MustRegister(requestCounter, requestDuration, statusCounter, fuelConsumedTotal, distanceTraveledTotal, averageTripDuration)
+	prometheus.MustRegister(requestCounter, requestDuration, statusCounter, fuelConsumedTotal, distanceTraveledTotal, averageTripDuration, fuelPurchasedTotal, fuelCostTotal, geofenceEventsTotal)
 }
 
 func MetricsMiddleware(next http.Handler) http.Handler {
@@ -98,6 +120,15 @@ func RecordTripMetrics(fuelConsumed float64, distanceTraveled float64, tripDurat
 	averageTripDuration.Observe(tripDuration.Seconds())
 }
 
+func RecordRefuelMetrics(liters float64, cost float64) {
+	fuelPurchasedTotal.Add(liters)
+	fuelCostTotal.Add(cost)
+}
+
+func RecordGeofenceEvent(kind string, event string) {
+	geofenceEventsTotal.WithLabelValues(kind, event).Inc()
+}
+
 func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.statusCode = statusCode
 	rw.ResponseWriter.WriteHeader(statusCode)
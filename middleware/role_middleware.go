@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/JulianaSau/carzone/handler"
+	"github.com/gorilla/mux"
+)
+
+// RequireRoles only lets the request through when the authenticated user has one of the given roles.
+// It must run behind AuthMIddleware so the token claims are available on the request context.
+func RequireRoles(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok || !hasRole(claims.Role, roles) {
+			forbidden(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// RequireSelfOrRoles lets users act on their own record (matched on the {id} route variable)
+// and otherwise falls back to the given roles.
+func RequireSelfOrRoles(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			forbidden(w, r)
+			return
+		}
+		if claims.UserID == "" || claims.UserID != mux.Vars(r)["id"] {
+			if !hasRole(claims.Role, roles) {
+				forbidden(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func hasRole(role string, roles []string) bool {
+	for _, allowed := range roles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// forbidden writes the problem response shared by every denied request
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	handler.WriteProblem(w, r, http.StatusForbidden, "You do not have permission to perform this action")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JulianaSau/carzone/store/migrations"
+)
+
+const migrateUsage = "usage: carzone migrate up | down [steps] | status | seed"
+
+// runMigrate carries out `carzone migrate`. up applies the pending migrations, down reverts the latest one or the
+// given number of them, status lists every migration and seed loads the demo data.
+func runMigrate(db *sql.DB, args []string) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations applied\n", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations reverted\n", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				applied += " (not in this build)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	case "seed":
+		if err := migrator.Seed(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Demo data loaded")
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+// migrateOnStart applies the pending migrations before the server starts when MIGRATE_ON_START=true. Replicas
+// starting together wait for each other on the migration lock.
+func migrateOnStart(db *sql.DB) error {
+	if os.Getenv("MIGRATE_ON_START") != "true" {
+		return nil
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
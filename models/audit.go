@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// audited resources, stored in audit_log.resource
+const (
+	AuditResourceCar    = "car"
+	AuditResourceDriver = "driver"
+	AuditResourceTrip   = "trip"
+	AuditResourceUser   = "user"
+)
+
+// audited actions, stored in audit_log.action
+const (
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// FieldChange holds the value of a column before and after a change
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditEntry is one immutable record of a change to a resource
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id"`
+	Resource   string                 `json:"resource"`
+	ResourceID string                 `json:"resource_id"`
+	Action     string                 `json:"action"`
+	Changes    map[string]FieldChange `json:"changes"`
+	ActorID    string                 `json:"actor_id"`
+	ActorName  string                 `json:"actor_name"`
+	IP         string                 `json:"ip"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -1,13 +1,19 @@
 package models
 
 import (
-	"errors"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	CarStatusAvailable      = "Available"
+	CarStatusInUse          = "In Use"
+	CarStatusMaintenance    = "Maintenance"
+	CarStatusDecommissioned = "Decommissioned"
+)
+
 type Car struct {
 	ID                 uuid.UUID `json:"id"`
 	RegistrationNumber string    `json:"registration_number"`
@@ -17,7 +23,10 @@ type Car struct {
 	FuelType           string    `json:"fuel_type"`
 	Engine             Engine    `json:"engine"`
 	Price              float64   `json:"price"`
+	TankCapacityLiters float64   `json:"tank_capacity_liters"`
 	Status             string    `json:"status"`
+	OdometerKM         float64   `json:"odometer_km"`
+	ServiceDue         bool      `json:"service_due"`
 	CreatedBy          string    `json:"created_by"`
 	UpdatedBy          string    `json:"updated_by"`
 	DeletedAt          time.Time `json:"deleted_at"`
@@ -34,6 +43,7 @@ type CarRequest struct {
 	Engine             Engine  `json:"engine"`
 	Status             string  `json:"status"`
 	Price              float64 `json:"price"`
+	TankCapacityLiters float64 `json:"tank_capacity_liters"`
 }
 
 func ValidateRequest(carReq CarRequest) error {
@@ -61,6 +71,9 @@ func ValidateRequest(carReq CarRequest) error {
 	if err := validatePrice(carReq.Price); err != nil {
 		return err
 	}
+	if err := validateTankCapacity(carReq.TankCapacityLiters); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -68,33 +81,33 @@ func ValidateRequest(carReq CarRequest) error {
 // validator functions
 func validateRegistrationNumber(registration_number string) error {
 	if registration_number == "" {
-		return errors.New("registration number is required")
+		return Validation("registration number is required")
 	}
 	return nil
 }
 func validateName(name string) error {
 	if name == "" {
-		return errors.New("name is required")
+		return Validation("name is required")
 	}
 	return nil
 }
 
 func validateYear(year string) error {
 	if year == "" {
-		return errors.New("year is required")
+		return Validation("year is required")
 	}
 
 	// convert year to int
 	_, err := strconv.Atoi(year)
 	if err != nil {
-		return errors.New("year must be a valid number")
+		return Validation("year must be a valid number")
 	}
 
 	currentYear := time.Now().Year()
 	yearInt, _ := strconv.Atoi(year)
 
 	if yearInt < 1886 || yearInt > currentYear {
-		return errors.New("year must be between 1886 and current year")
+		return Validation("year must be between 1886 and current year")
 	}
 
 	return nil
@@ -102,7 +115,7 @@ func validateYear(year string) error {
 
 func validateBrand(brand string) error {
 	if brand == "" {
-		return errors.New("brand is required")
+		return Validation("brand is required")
 	}
 	return nil
 }
@@ -114,7 +127,7 @@ func validateFuelType(fuelType string) error {
 			return nil
 		}
 	}
-	return errors.New("fuel type must be one of: Petrol, Diesel, Electric, or Hybrid")
+	return Validation("fuel type must be one of: Petrol, Diesel, Electric, or Hybrid")
 }
 
 func validateStatusType(status string) error {
@@ -124,31 +137,39 @@ func validateStatusType(status string) error {
 			return nil
 		}
 	}
-	return errors.New("status type must be one of: Available, In Use, Maintenance, or Decommissioned")
+	return Validation("status type must be one of: Available, In Use, Maintenance, or Decommissioned")
 }
 
 func validateEngine(engine Engine) error {
 	if engine.EngineID == uuid.Nil {
-		return errors.New("engine id is required")
+		return Validation("engine id is required")
 	}
 
 	if engine.Displacement <= 0 {
-		return errors.New("displacement must be greater than 0")
+		return Validation("displacement must be greater than 0")
 	}
 
 	if engine.NoOfCylinders <= 0 {
-		return errors.New("number of cylinders must be greater than 0")
+		return Validation("number of cylinders must be greater than 0")
 	}
 
 	if engine.CarRange <= 0 {
-		return errors.New("car range must be greater than 0")
+		return Validation("car range must be greater than 0")
 	}
 	return nil
 }
 
 func validatePrice(price float64) error {
 	if price <= 0 {
-		return errors.New("price must be greater than 0")
+		return Validation("price must be greater than 0")
+	}
+	return nil
+}
+
+// validateTankCapacity allows 0 for a car whose tank capacity is unknown
+func validateTankCapacity(liters float64) error {
+	if liters < 0 {
+		return Validation("tank capacity cannot be negative")
 	}
 	return nil
 }
@@ -15,6 +15,7 @@ type Driver struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 	CreatedBy       string    `json:"created_by"`
+	UpdatedBy       string    `json:"updated_by"`
 	DeletedAt       time.Time `json:"deleted_at"`
 	// Embedding the User struct to access user information like name, email, etc.
 	User User `json:"user"`
@@ -30,3 +31,24 @@ type DriverUpdateRequest struct {
 	DriverLicenseNo string    `json:"driver_license_number"`
 	LicenseExpiry   time.Time `json:"license_expiry"`
 }
+
+// DriverLicenseExpiry is the license of a driver that expires too soon for a trip, returned in the details of the
+// conflict.
+type DriverLicenseExpiry struct {
+	DriverID        uuid.UUID `json:"driver_id"`
+	DriverLicenseNo string    `json:"driver_license_number"`
+	LicenseExpiry   time.Time `json:"license_expiry"`
+}
+
+// DriverLicense is one license a driver held. ValidFrom and ValidTo bound the period it was on record,
+// ValidTo is zero for the current license.
+type DriverLicense struct {
+	ID              uuid.UUID `json:"id"`
+	DriverID        uuid.UUID `json:"driver_id"`
+	DriverLicenseNo string    `json:"driver_license_number"`
+	LicenseExpiry   time.Time `json:"license_expiry"`
+	ValidFrom       time.Time `json:"valid_from"`
+	ValidTo         time.Time `json:"valid_to"`
+	RecordedBy      string    `json:"recorded_by"`
+	ReplacedBy      string    `json:"replaced_by"`
+}
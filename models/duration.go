@@ -0,0 +1,23 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWindow parses a time window given in days, e.g. "30d", or as a duration, e.g. "72h"
+func ParseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, Validation("invalid window %q, expected e.g. 30d or 72h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, Validation("invalid window %q, expected e.g. 30d or 72h", value)
+	}
+	return d, nil
+}
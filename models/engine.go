@@ -1,7 +1,7 @@
 package models
 
 import (
-	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +11,10 @@ type Engine struct {
 	Displacement  int64     `json:"displacement"`
 	NoOfCylinders int64     `json:"no_of_cylinders"`
 	CarRange      int64     `json:"car_range"`
+	CreatedBy     string    `json:"created_by"`
+	UpdatedBy     string    `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type EngineRequest struct {
@@ -34,21 +38,21 @@ func ValidateEngineRequest(engineReq EngineRequest) error {
 
 func ValidateDisplacement(displacement int64) error {
 	if displacement <= 0 {
-		return errors.New("displacement must be greater than 0")
+		return Validation("displacement must be greater than 0")
 	}
 	return nil
 }
 
 func ValidateNoOfCylinders(noOfCylinders int64) error {
 	if noOfCylinders <= 0 {
-		return errors.New("number of cylinders must be greater than 0")
+		return Validation("number of cylinders must be greater than 0")
 	}
 	return nil
 }
 
 func ValidateCarRanges(carRange int64) error {
 	if carRange <= 0 {
-		return errors.New("car range must be greater than 0")
+		return Validation("car range must be greater than 0")
 	}
 	return nil
 }
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FuelIntervals are the periods fuel efficiency can be grouped by
+var FuelIntervals = []string{"day", "week", "month", "year"}
+
+// FuelEntry is a refuel of a car
+type FuelEntry struct {
+	ID            uuid.UUID  `json:"id"`
+	CarID         uuid.UUID  `json:"car_id"`
+	DriverID      *uuid.UUID `json:"driver_id,omitempty"`
+	TripID        *uuid.UUID `json:"trip_id,omitempty"`
+	FueledAt      time.Time  `json:"fueled_at"`
+	Liters        float64    `json:"liters"`
+	PricePerLiter float64    `json:"price_per_liter"`
+	TotalCost     float64    `json:"total_cost"`
+	Station       string     `json:"station"`
+	OdometerKM    float64    `json:"odometer_km"`
+	Notes         string     `json:"notes"`
+	Reference     string     `json:"reference,omitempty"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// FuelEntryRequest records a refuel, TotalCost defaults to Liters times PricePerLiter. Reference is the fuel card
+// transaction the refuel was imported from, a transaction is only imported once.
+type FuelEntryRequest struct {
+	DriverID      *uuid.UUID `json:"driver_id"`
+	TripID        *uuid.UUID `json:"trip_id"`
+	FueledAt      time.Time  `json:"fueled_at"`
+	Liters        float64    `json:"liters"`
+	PricePerLiter float64    `json:"price_per_liter"`
+	TotalCost     float64    `json:"total_cost"`
+	Station       string     `json:"station"`
+	OdometerKM    float64    `json:"odometer_km"`
+	Notes         string     `json:"notes"`
+	Reference     string     `json:"reference"`
+}
+
+// FuelFilter narrows GetFuelEntries, zero values are ignored. From and To bound the time of the refuel.
+type FuelFilter struct {
+	CarID    uuid.UUID
+	DriverID uuid.UUID
+	From     time.Time
+	To       time.Time
+}
+
+// FuelEfficiency is the fuel used over a distance. Consumption is measured from fill-up to fill-up: the liters
+// of a refuel are what the car burned over the kilometers since its previous refuel.
+type FuelEfficiency struct {
+	PeriodStart    time.Time `json:"period_start"`
+	Refuels        int       `json:"refuels"`
+	Liters         float64   `json:"liters"`
+	Cost           float64   `json:"cost"`
+	DistanceKM     float64   `json:"distance_km"`
+	LitersPer100KM float64   `json:"liters_per_100km"`
+	CostPerKM      float64   `json:"cost_per_km"`
+}
+
+// FuelEfficiencyReport is the fuel efficiency of a car or a driver in total and per period
+type FuelEfficiencyReport struct {
+	CarID    *uuid.UUID       `json:"car_id,omitempty"`
+	DriverID *uuid.UUID       `json:"driver_id,omitempty"`
+	Interval string           `json:"interval"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Total    FuelEfficiency   `json:"total"`
+	Periods  []FuelEfficiency `json:"periods"`
+}
+
+// FuelEfficiencyFilter selects the refuels a report covers, CarID or DriverID is set
+type FuelEfficiencyFilter struct {
+	CarID    uuid.UUID
+	DriverID uuid.UUID
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// FuelAnomaly is a refuel whose consumption deviates from the car's own baseline or from the other cars with the
+// same engine. Deviations are relative, e.g. 0.4 is 40% above the baseline; a baseline without enough refuels is 0.
+type FuelAnomaly struct {
+	FuelEntryID     uuid.UUID  `json:"fuel_entry_id"`
+	CarID           uuid.UUID  `json:"car_id"`
+	EngineID        uuid.UUID  `json:"engine_id"`
+	DriverID        *uuid.UUID `json:"driver_id,omitempty"`
+	FueledAt        time.Time  `json:"fueled_at"`
+	Liters          float64    `json:"liters"`
+	DistanceKM      float64    `json:"distance_km"`
+	LitersPer100KM  float64    `json:"liters_per_100km"`
+	CarBaseline     float64    `json:"car_baseline_liters_per_100km"`
+	CarDeviation    float64    `json:"car_deviation"`
+	EngineBaseline  float64    `json:"engine_baseline_liters_per_100km"`
+	EngineDeviation float64    `json:"engine_deviation"`
+}
+
+// FuelAnomalyFilter selects the refuels checked for anomalies. Threshold is the relative deviation that is
+// flagged, MinSamples the number of refuels a baseline needs before it is compared against.
+type FuelAnomalyFilter struct {
+	CarID      uuid.UUID
+	From       time.Time
+	To         time.Time
+	Threshold  float64
+	MinSamples int
+}
+
+// Rates fills in the consumption and the cost per kilometer, they stay 0 without distance
+func (e *FuelEfficiency) Rates() {
+	if e.DistanceKM <= 0 {
+		return
+	}
+	e.LitersPer100KM = round(e.Liters / e.DistanceKM * 100)
+	e.CostPerKM = round(e.Cost / e.DistanceKM)
+}
+
+// Deviations fills in how far the consumption is from the baselines it was compared against
+func (a *FuelAnomaly) Deviations() {
+	if a.CarBaseline > 0 {
+		a.CarDeviation = round(a.LitersPer100KM/a.CarBaseline - 1)
+		a.CarBaseline = round(a.CarBaseline)
+	}
+	if a.EngineBaseline > 0 {
+		a.EngineDeviation = round(a.LitersPer100KM/a.EngineBaseline - 1)
+		a.EngineBaseline = round(a.EngineBaseline)
+	}
+	a.LitersPer100KM = round(a.LitersPer100KM)
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+func ValidateFuelEntryRequest(fuelReq FuelEntryRequest) error {
+	if fuelReq.FueledAt.IsZero() {
+		return Validation("fueled_at is required")
+	}
+	if fuelReq.FueledAt.After(time.Now()) {
+		return Validation("fueled_at cannot be in the future")
+	}
+	if fuelReq.Liters <= 0 {
+		return Validation("liters must be greater than 0")
+	}
+	if fuelReq.PricePerLiter < 0 || fuelReq.TotalCost < 0 {
+		return Validation("price cannot be negative")
+	}
+	if fuelReq.OdometerKM <= 0 {
+		return Validation("odometer_km is required")
+	}
+	return nil
+}
+
+func ValidateFuelInterval(interval string) error {
+	if !slices.Contains(FuelIntervals, interval) {
+		return Validation("invalid interval %q, expected one of day, week, month, year", interval)
+	}
+	return nil
+}
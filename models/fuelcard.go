@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a fuel card transaction ends up in the exceptions of an import. Rows that are invalid, name an unknown
+// car or are rejected by the fuel log are skipped; refuels without an active trip or above the tank capacity are
+// imported and flagged.
+const (
+	FuelImportInvalidRow       = "invalid_row"
+	FuelImportUnknownCar       = "unknown_car"
+	FuelImportRejected         = "rejected"
+	FuelImportNoActiveTrip     = "no_active_trip"
+	FuelImportOverTankCapacity = "over_tank_capacity"
+)
+
+// FuelCardMapping names the statement columns each field is read from, column names are matched ignoring case.
+// Empty columns are not imported, except the required RegistrationNumber, FueledAt, Liters and OdometerKM.
+// TimeLayout is a Go time layout, RFC 3339 and "2006-01-02 15:04:05" are tried when it is empty.
+type FuelCardMapping struct {
+	Reference          string `json:"reference"`
+	RegistrationNumber string `json:"registration_number"`
+	FueledAt           string `json:"fueled_at"`
+	Liters             string `json:"liters"`
+	PricePerLiter      string `json:"price_per_liter"`
+	TotalCost          string `json:"total_cost"`
+	Station            string `json:"station"`
+	OdometerKM         string `json:"odometer_km"`
+	TimeLayout         string `json:"time_layout"`
+	Delimiter          string `json:"delimiter"`
+}
+
+// DefaultFuelCardMapping reads statements whose headers are the fuel entry fields
+var DefaultFuelCardMapping = FuelCardMapping{
+	Reference:          "reference",
+	RegistrationNumber: "registration_number",
+	FueledAt:           "fueled_at",
+	Liters:             "liters",
+	PricePerLiter:      "price_per_liter",
+	TotalCost:          "total_cost",
+	Station:            "station",
+	OdometerKM:         "odometer_km",
+	Delimiter:          ",",
+}
+
+// Merge returns m with the fields set in override replaced
+func (m FuelCardMapping) Merge(override FuelCardMapping) FuelCardMapping {
+	set := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	set(&m.Reference, override.Reference)
+	set(&m.RegistrationNumber, override.RegistrationNumber)
+	set(&m.FueledAt, override.FueledAt)
+	set(&m.Liters, override.Liters)
+	set(&m.PricePerLiter, override.PricePerLiter)
+	set(&m.TotalCost, override.TotalCost)
+	set(&m.Station, override.Station)
+	set(&m.OdometerKM, override.OdometerKM)
+	set(&m.TimeLayout, override.TimeLayout)
+	set(&m.Delimiter, override.Delimiter)
+	return m
+}
+
+// FuelImportException is a statement row that was skipped or imported with a warning. Row is the line in the
+// statement, FuelEntryID is set when the row was imported anyway.
+type FuelImportException struct {
+	Row                int        `json:"row"`
+	Reference          string     `json:"reference,omitempty"`
+	RegistrationNumber string     `json:"registration_number,omitempty"`
+	FueledAt           *time.Time `json:"fueled_at,omitempty"`
+	Liters             float64    `json:"liters,omitempty"`
+	Reason             string     `json:"reason" enums:"invalid_row,unknown_car,rejected,no_active_trip,over_tank_capacity"`
+	Message            string     `json:"message"`
+	FuelEntryID        *uuid.UUID `json:"fuel_entry_id,omitempty"`
+}
+
+// FuelImportReport is the outcome of a fuel card statement import. Rows counts the transactions in the statement,
+// Skipped those not imported; Exceptions lists every skipped or flagged row.
+type FuelImportReport struct {
+	Rows       int                   `json:"rows"`
+	Imported   int                   `json:"imported"`
+	Skipped    int                   `json:"skipped"`
+	Entries    []FuelEntry           `json:"entries"`
+	Exceptions []FuelImportException `json:"exceptions"`
+}
+
+func ValidateFuelCardMapping(mapping FuelCardMapping) error {
+	if mapping.RegistrationNumber == "" || mapping.FueledAt == "" || mapping.Liters == "" || mapping.OdometerKM == "" {
+		return Validation("the registration_number, fueled_at, liters and odometer_km columns are required")
+	}
+	if len([]rune(mapping.Delimiter)) != 1 {
+		return Validation("delimiter must be a single character")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GeofenceShapeCircle  = "circle"
+	GeofenceShapePolygon = "polygon"
+
+	GeofenceKindDepot      = "depot"
+	GeofenceKindCustomer   = "customer"
+	GeofenceKindRestricted = "restricted"
+
+	GeofenceEventEnter = "enter"
+	GeofenceEventExit  = "exit"
+)
+
+// GeofenceKinds are the kinds of places a geofence can mark
+var GeofenceKinds = []string{GeofenceKindDepot, GeofenceKindCustomer, GeofenceKindRestricted}
+
+// Coordinate is a point in degrees
+type Coordinate struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+}
+
+// Geofence is a named area cars are tracked in and out of. A circle has a Center and a RadiusM in meters, a
+// polygon its Vertices in order; the polygon is closed from the last vertex back to the first.
+type Geofence struct {
+	ID        uuid.UUID    `json:"id"`
+	Name      string       `json:"name"`
+	Kind      string       `json:"kind" enums:"depot,customer,restricted"`
+	Shape     string       `json:"shape" enums:"circle,polygon"`
+	Center    *Coordinate  `json:"center,omitempty"`
+	RadiusM   float64      `json:"radius_m,omitempty"`
+	Vertices  []Coordinate `json:"vertices,omitempty"`
+	CreatedBy string       `json:"created_by"`
+	UpdatedBy string       `json:"updated_by"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type GeofenceRequest struct {
+	Name     string       `json:"name"`
+	Kind     string       `json:"kind"`
+	Shape    string       `json:"shape"`
+	Center   *Coordinate  `json:"center"`
+	RadiusM  float64      `json:"radius_m"`
+	Vertices []Coordinate `json:"vertices"`
+}
+
+// GeofenceFilter narrows GetGeofences, zero values are ignored
+type GeofenceFilter struct {
+	Kind string
+}
+
+// GeofenceEvent is a car entering or leaving a geofence, detected from the position that was reported first on
+// the other side of its boundary
+type GeofenceEvent struct {
+	ID           uuid.UUID  `json:"id"`
+	GeofenceID   uuid.UUID  `json:"geofence_id"`
+	GeofenceName string     `json:"geofence_name"`
+	GeofenceKind string     `json:"geofence_kind"`
+	CarID        uuid.UUID  `json:"car_id"`
+	TripID       *uuid.UUID `json:"trip_id,omitempty"`
+	PositionID   int64      `json:"position_id"`
+	Event        string     `json:"event" enums:"enter,exit"`
+	Latitude     float64    `json:"lat"`
+	Longitude    float64    `json:"lon"`
+	OccurredAt   time.Time  `json:"occurred_at"`
+}
+
+// GeofenceEventFilter narrows GetGeofenceEvents, zero values are ignored. From and To bound the time of the event.
+type GeofenceEventFilter struct {
+	GeofenceID uuid.UUID
+	CarID      uuid.UUID
+	TripID     uuid.UUID
+	Event      string
+	From       time.Time
+	To         time.Time
+}
+
+// Contains reports whether a point lies inside the geofence. Polygons are tested by ray casting on the
+// longitude/latitude plane, which holds for areas that do not cross the antimeridian.
+func (g Geofence) Contains(lat, lon float64) bool {
+	switch g.Shape {
+	case GeofenceShapeCircle:
+		if g.Center == nil {
+			return false
+		}
+		return HaversineKM(g.Center.Latitude, g.Center.Longitude, lat, lon)*1000 <= g.RadiusM
+	case GeofenceShapePolygon:
+		inside := false
+		for i, j := 0, len(g.Vertices)-1; i < len(g.Vertices); j, i = i, i+1 {
+			a, b := g.Vertices[i], g.Vertices[j]
+			if (a.Latitude > lat) != (b.Latitude > lat) &&
+				lon < (b.Longitude-a.Longitude)*(lat-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+				inside = !inside
+			}
+		}
+		return inside
+	}
+	return false
+}
+
+// DetectGeofenceEvents checks positions of a car against every geofence, oldest first, and returns an event each
+// time the car crosses a boundary. inside holds the geofences the car is in before the first position and is updated
+// as the car moves.
+func DetectGeofenceEvents(carID uuid.UUID, geofences []Geofence, inside map[uuid.UUID]bool, positions []Position) []GeofenceEvent {
+	track := append([]Position(nil), positions...)
+	sort.SliceStable(track, func(i, j int) bool { return track[i].RecordedAt.Before(track[j].RecordedAt) })
+
+	events := []GeofenceEvent{}
+	for _, position := range track {
+		for _, geofence := range geofences {
+			contains := geofence.Contains(position.Latitude, position.Longitude)
+			if contains == inside[geofence.ID] {
+				continue
+			}
+			inside[geofence.ID] = contains
+
+			event := GeofenceEvent{
+				ID:           uuid.New(),
+				GeofenceID:   geofence.ID,
+				GeofenceName: geofence.Name,
+				GeofenceKind: geofence.Kind,
+				CarID:        carID,
+				TripID:       position.TripID,
+				PositionID:   position.ID,
+				Event:        GeofenceEventExit,
+				Latitude:     position.Latitude,
+				Longitude:    position.Longitude,
+				OccurredAt:   position.RecordedAt,
+			}
+			if contains {
+				event.Event = GeofenceEventEnter
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func ValidateGeofenceRequest(geofenceReq GeofenceRequest) error {
+	if strings.TrimSpace(geofenceReq.Name) == "" {
+		return Validation("name is required")
+	}
+	if !slices.Contains(GeofenceKinds, geofenceReq.Kind) {
+		return Validation("invalid kind %q, expected one of depot, customer, restricted", geofenceReq.Kind)
+	}
+
+	switch geofenceReq.Shape {
+	case GeofenceShapeCircle:
+		if geofenceReq.Center == nil {
+			return Validation("center is required for a circle")
+		}
+		if err := validateCoordinate(*geofenceReq.Center); err != nil {
+			return err
+		}
+		if geofenceReq.RadiusM <= 0 || math.IsInf(geofenceReq.RadiusM, 0) {
+			return Validation("radius_m must be greater than 0")
+		}
+		if len(geofenceReq.Vertices) > 0 {
+			return Validation("a circle has no vertices")
+		}
+	case GeofenceShapePolygon:
+		if len(geofenceReq.Vertices) < 3 {
+			return Validation("a polygon needs at least 3 vertices")
+		}
+		for _, vertex := range geofenceReq.Vertices {
+			if err := validateCoordinate(vertex); err != nil {
+				return err
+			}
+		}
+		if geofenceReq.Center != nil || geofenceReq.RadiusM != 0 {
+			return Validation("a polygon has no center or radius")
+		}
+	default:
+		return Validation("invalid shape %q, expected circle or polygon", geofenceReq.Shape)
+	}
+	return nil
+}
+
+func validateCoordinate(c Coordinate) error {
+	if c.Latitude < -90 || c.Latitude > 90 {
+		return Validation("lat must be between -90 and 90")
+	}
+	if c.Longitude < -180 || c.Longitude > 180 {
+		return Validation("lon must be between -180 and 180")
+	}
+	return nil
+}
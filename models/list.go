@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+var ErrInvalidSort = Validation("invalid sort field")
+
+// ListOptions selects one page of a list endpoint and its order. Sort names a field of the
+// listed model, e.g. "start_time"; every store maps the fields it allows to its own columns.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+}
+
+// Normalize applies the default page size and clamps out of range values
+func (o *ListOptions) Normalize() {
+	if o.Limit <= 0 {
+		o.Limit = DefaultListLimit
+	}
+	if o.Limit > MaxListLimit {
+		o.Limit = MaxListLimit
+	}
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+}
+
+// TripFilter narrows GetTrips, zero values are ignored. From and To bound the start time, ActiveAt keeps the
+// trips that were under way at that moment.
+type TripFilter struct {
+	Status          string
+	CarID           uuid.UUID
+	DriverID        uuid.UUID
+	StartLocationID uuid.UUID
+	EndLocationID   uuid.UUID
+	From            time.Time
+	To              time.Time
+	ActiveAt        time.Time
+}
+
+// UserFilter narrows GetUsers, zero values are ignored
+type UserFilter struct {
+	Role   string
+	Active *bool
+}
+
+// DriverFilter narrows GetDrivers, zero values are ignored
+type DriverFilter struct {
+	Active               *bool
+	LicenseExpiresBefore time.Time
+	LicenseExpiresAfter  time.Time
+}
+
+// CarFilter narrows SearchCars, zero values are ignored. Query matches part of the name or the
+// registration number. WithEngine embeds the engine details in every car.
+type CarFilter struct {
+	Query           string
+	Brand           string
+	FuelType        string
+	Status          string
+	YearFrom        int
+	YearTo          int
+	PriceMin        float64
+	PriceMax        float64
+	DisplacementMin int
+	DisplacementMax int
+	Cylinders       int
+	RangeMin        int
+	ServiceDue      *bool
+	WithEngine      bool
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LocationTypeDepot    = "depot"
+	LocationTypeCustomer = "customer"
+	LocationTypeSupplier = "supplier"
+	LocationTypeOther    = "other"
+)
+
+// LocationTypes are the kinds of places trips start and end at
+var LocationTypes = []string{LocationTypeDepot, LocationTypeCustomer, LocationTypeSupplier, LocationTypeOther}
+
+// Location is a named place trips start and end at. Names are unique regardless of case, trips that only name
+// their endpoints in start_location and end_location are linked to the location with that name.
+type Location struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Latitude  float64   `json:"lat"`
+	Longitude float64   `json:"lon"`
+	Type      string    `json:"type" enums:"depot,customer,supplier,other"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedBy string    `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type LocationRequest struct {
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Type      string  `json:"type"`
+}
+
+// LocationFilter narrows GetLocations, zero values are ignored. Name matches part of the name.
+type LocationFilter struct {
+	Type string
+	Name string
+}
+
+// RouteStats sums up the completed trips between an origin and a destination. Trips linked to a location are
+// grouped by it, the others by their start_location and end_location text; OriginID and DestinationID are only set
+// for the former.
+type RouteStats struct {
+	OriginID           *uuid.UUID `json:"origin_id,omitempty"`
+	Origin             string     `json:"origin"`
+	DestinationID      *uuid.UUID `json:"destination_id,omitempty"`
+	Destination        string     `json:"destination"`
+	Trips              int        `json:"trips"`
+	AvgDurationMinutes float64    `json:"avg_duration_minutes"`
+	AvgDistanceKM      float64    `json:"avg_distance_km"`
+	AvgFuelLiters      float64    `json:"avg_fuel_liters"`
+}
+
+// RouteStatsFilter narrows GetRouteStats, zero values are ignored. From and To bound the start time of the trips.
+type RouteStatsFilter struct {
+	CarID         uuid.UUID
+	DriverID      uuid.UUID
+	OriginID      uuid.UUID
+	DestinationID uuid.UUID
+	From          time.Time
+	To            time.Time
+}
+
+func ValidateLocationRequest(locationReq LocationRequest) error {
+	if strings.TrimSpace(locationReq.Name) == "" {
+		return Validation("name is required")
+	}
+	if !slices.Contains(LocationTypes, locationReq.Type) {
+		return Validation("invalid type %q, expected one of depot, customer, supplier, other", locationReq.Type)
+	}
+	return validateCoordinate(Coordinate{Latitude: locationReq.Latitude, Longitude: locationReq.Longitude})
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaintenanceStatusOK      = "ok"
+	MaintenanceStatusDueSoon = "due_soon"
+	MaintenanceStatusOverdue = "overdue"
+)
+
+// MaintenanceRecord is a service carried out on a car
+type MaintenanceRecord struct {
+	ID          uuid.UUID         `json:"id"`
+	CarID       uuid.UUID         `json:"car_id"`
+	ServiceDate time.Time         `json:"service_date"`
+	OdometerKM  float64           `json:"odometer_km"`
+	ServiceType string            `json:"service_type"`
+	Cost        float64           `json:"cost"`
+	Workshop    string            `json:"workshop"`
+	Notes       string            `json:"notes"`
+	Parts       []MaintenancePart `json:"parts"`
+	CreatedBy   string            `json:"created_by"`
+	UpdatedBy   string            `json:"updated_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// MaintenancePart is a part fitted during a service
+type MaintenancePart struct {
+	Name       string  `json:"name"`
+	PartNumber string  `json:"part_number"`
+	Quantity   int     `json:"quantity"`
+	UnitCost   float64 `json:"unit_cost"`
+}
+
+type MaintenanceRecordRequest struct {
+	ServiceDate time.Time         `json:"service_date"`
+	OdometerKM  float64           `json:"odometer_km"`
+	ServiceType string            `json:"service_type"`
+	Cost        float64           `json:"cost"`
+	Workshop    string            `json:"workshop"`
+	Notes       string            `json:"notes"`
+	Parts       []MaintenancePart `json:"parts"`
+}
+
+// MaintenancePlan is a service that recurs every IntervalKM kilometers or every IntervalMonths months, whichever
+// comes first. It counts from the latest record of the same service type, or from the baseline when there is none.
+// The last service, the current odometer and the next due point are worked out by the store.
+type MaintenancePlan struct {
+	ID                    uuid.UUID `json:"id"`
+	CarID                 uuid.UUID `json:"car_id"`
+	ServiceType           string    `json:"service_type"`
+	IntervalKM            int       `json:"interval_km"`
+	IntervalMonths        int       `json:"interval_months"`
+	BaselineDate          time.Time `json:"baseline_date"`
+	BaselineOdometerKM    float64   `json:"baseline_odometer_km"`
+	LastServiceDate       time.Time `json:"last_service_date"`
+	LastServiceOdometerKM float64   `json:"last_service_odometer_km"`
+	CurrentOdometerKM     float64   `json:"current_odometer_km"`
+	NextDueDate           time.Time `json:"next_due_date"`
+	NextDueOdometerKM     float64   `json:"next_due_odometer_km"`
+	Status                string    `json:"status" enums:"ok,due_soon,overdue"`
+	CreatedBy             string    `json:"created_by"`
+	UpdatedBy             string    `json:"updated_by"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+type MaintenancePlanRequest struct {
+	ServiceType    string `json:"service_type"`
+	IntervalKM     int    `json:"interval_km"`
+	IntervalMonths int    `json:"interval_months"`
+	// BaselineDate and BaselineOdometerKM are the last service before the car's records start, they default to now and 0
+	BaselineDate       time.Time `json:"baseline_date"`
+	BaselineOdometerKM float64   `json:"baseline_odometer_km"`
+}
+
+// MaintenanceDueFilter selects the plans that are overdue or come due before DueBy or within WithinKM kilometers
+type MaintenanceDueFilter struct {
+	DueBy    time.Time
+	WithinKM float64
+}
+
+// DueStatus tells whether the plan is overdue at now, comes due within the window or the kilometers, or neither
+func (p MaintenancePlan) DueStatus(now time.Time, window time.Duration, withinKM float64) string {
+	byDate := !p.NextDueDate.IsZero()
+	byKM := p.IntervalKM > 0
+
+	if (byDate && !now.Before(p.NextDueDate)) || (byKM && p.CurrentOdometerKM >= p.NextDueOdometerKM) {
+		return MaintenanceStatusOverdue
+	}
+	if (byDate && now.Add(window).After(p.NextDueDate)) || (byKM && p.CurrentOdometerKM+withinKM >= p.NextDueOdometerKM) {
+		return MaintenanceStatusDueSoon
+	}
+	return MaintenanceStatusOK
+}
+
+func ValidateMaintenanceRecordRequest(recordReq MaintenanceRecordRequest) error {
+	if recordReq.ServiceDate.IsZero() {
+		return Validation("service date is required")
+	}
+	if recordReq.ServiceDate.After(time.Now()) {
+		return Validation("service date cannot be in the future")
+	}
+	if recordReq.OdometerKM < 0 {
+		return Validation("odometer reading cannot be negative")
+	}
+	if recordReq.ServiceType == "" {
+		return Validation("service type is required")
+	}
+	if recordReq.Cost < 0 {
+		return Validation("cost cannot be negative")
+	}
+	for _, part := range recordReq.Parts {
+		if part.Name == "" {
+			return Validation("part name is required")
+		}
+		if part.Quantity <= 0 {
+			return Validation("quantity of part %q must be greater than 0", part.Name)
+		}
+		if part.UnitCost < 0 {
+			return Validation("unit cost of part %q cannot be negative", part.Name)
+		}
+	}
+	return nil
+}
+
+func ValidateMaintenancePlanRequest(planReq MaintenancePlanRequest) error {
+	if planReq.ServiceType == "" {
+		return Validation("service type is required")
+	}
+	if planReq.IntervalKM < 0 || planReq.IntervalMonths < 0 {
+		return Validation("service intervals cannot be negative")
+	}
+	if planReq.IntervalKM == 0 && planReq.IntervalMonths == 0 {
+		return Validation("interval_km or interval_months is required")
+	}
+	if planReq.BaselineOdometerKM < 0 {
+		return Validation("baseline odometer reading cannot be negative")
+	}
+	if planReq.BaselineDate.After(time.Now()) {
+		return Validation("baseline date cannot be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OdometerSourceManual    = "manual"
+	OdometerSourceTripStart = "trip_start"
+	OdometerSourceTripEnd   = "trip_end"
+	OdometerSourceRefuel    = "refuel"
+)
+
+// OdometerReading is the odometer of a car at a point in time. Readings taken when a trip starts
+// or ends carry the trip, readings taken at a refuel the fuel entry.
+type OdometerReading struct {
+	ID          uuid.UUID  `json:"id"`
+	CarID       uuid.UUID  `json:"car_id"`
+	TripID      *uuid.UUID `json:"trip_id,omitempty"`
+	FuelEntryID *uuid.UUID `json:"fuel_entry_id,omitempty"`
+	ReadingKM   float64    `json:"reading_km"`
+	Source      string     `json:"source" enums:"manual,trip_start,trip_end,refuel"`
+	Notes       string     `json:"notes"`
+	RecordedAt  time.Time  `json:"recorded_at"`
+	RecordedBy  string     `json:"recorded_by"`
+}
+
+// OdometerReadingRequest is a manual reading, RecordedAt defaults to now
+type OdometerReadingRequest struct {
+	ReadingKM  float64   `json:"reading_km"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Notes      string    `json:"notes"`
+}
+
+// OdometerFilter narrows GetOdometerReadings, zero values are ignored. From and To bound the time of the reading.
+type OdometerFilter struct {
+	TripID uuid.UUID
+	Source string
+	From   time.Time
+	To     time.Time
+}
+
+func ValidateOdometerReadingRequest(readingReq OdometerReadingRequest) error {
+	if err := ValidateOdometerReading(readingReq.ReadingKM); err != nil {
+		return err
+	}
+	if readingReq.RecordedAt.After(time.Now()) {
+		return Validation("recorded_at cannot be in the future")
+	}
+	return nil
+}
+
+func ValidateOdometerReading(readingKM float64) error {
+	if readingKM < 0 {
+		return Validation("odometer reading cannot be negative")
+	}
+	return nil
+}
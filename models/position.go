@@ -0,0 +1,157 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxPositionBatch is the number of points a tracker may report in one request
+	MaxPositionBatch = 1000
+	// earthRadiusKM is the mean radius used by HaversineKM
+	earthRadiusKM = 6371.0088
+)
+
+// Position is a GPS fix reported by the tracker of a car. TripID is the trip the car was under way on when the
+// fix was taken, if any. Speed is in km/h and Heading in degrees clockwise from north.
+type Position struct {
+	ID         int64      `json:"id"`
+	CarID      uuid.UUID  `json:"car_id"`
+	TripID     *uuid.UUID `json:"trip_id,omitempty"`
+	Latitude   float64    `json:"lat"`
+	Longitude  float64    `json:"lon"`
+	Speed      float64    `json:"speed"`
+	Heading    float64    `json:"heading"`
+	RecordedAt time.Time  `json:"timestamp"`
+	ReceivedAt time.Time  `json:"received_at"`
+}
+
+type PositionRequest struct {
+	Latitude  float64   `json:"lat"`
+	Longitude float64   `json:"lon"`
+	Speed     float64   `json:"speed"`
+	Heading   float64   `json:"heading"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PositionBatchRequest is what a tracker reports in one request, at most MaxPositionBatch points
+type PositionBatchRequest struct {
+	Points []PositionRequest `json:"points"`
+}
+
+// PositionBatch is the outcome of a batch. Points reported before are skipped as duplicates, Unassigned counts
+// the stored points taken while the car was not on a trip and GeofenceEvents the boundaries the car crossed.
+type PositionBatch struct {
+	Received       int `json:"received"`
+	Stored         int `json:"stored"`
+	Duplicates     int `json:"duplicates"`
+	Unassigned     int `json:"unassigned"`
+	GeofenceEvents int `json:"geofence_events"`
+}
+
+// PositionFilter narrows GetPositions, zero values are ignored. From and To bound the time of the fix.
+type PositionFilter struct {
+	CarID  uuid.UUID
+	TripID uuid.UUID
+	From   time.Time
+	To     time.Time
+}
+
+// LineString is a GeoJSON LineString, every coordinate is a [longitude, latitude] pair
+type LineString struct {
+	Type        string      `json:"type" example:"LineString"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// TripRoute is the GPS track of a trip as a GeoJSON Feature. The geometry is null until the trip has two points.
+type TripRoute struct {
+	Type       string              `json:"type" example:"Feature"`
+	Geometry   *LineString         `json:"geometry"`
+	Properties TripRouteProperties `json:"properties"`
+}
+
+// TripRouteProperties describe a route, DistanceKM is measured along the track
+type TripRouteProperties struct {
+	TripID     uuid.UUID  `json:"trip_id"`
+	CarID      uuid.UUID  `json:"car_id"`
+	Points     int        `json:"points"`
+	DistanceKM float64    `json:"distance_km"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+}
+
+// NewTripRoute turns the points of a trip, oldest first, into its route
+func NewTripRoute(trip Trip, points []Position) TripRoute {
+	route := TripRoute{
+		Type: "Feature",
+		Properties: TripRouteProperties{
+			TripID:     trip.ID,
+			CarID:      trip.CarID,
+			Points:     len(points),
+			DistanceKM: TrackDistanceKM(points),
+		},
+	}
+	if len(points) == 0 {
+		return route
+	}
+	route.Properties.StartedAt = &points[0].RecordedAt
+	route.Properties.EndedAt = &points[len(points)-1].RecordedAt
+	if len(points) < 2 {
+		return route
+	}
+
+	route.Geometry = &LineString{Type: "LineString", Coordinates: make([][]float64, len(points))}
+	for i, point := range points {
+		route.Geometry.Coordinates[i] = []float64{point.Longitude, point.Latitude}
+	}
+	return route
+}
+
+// TrackDistanceKM is the length of a track, the points ordered by time
+func TrackDistanceKM(points []Position) float64 {
+	var distance float64
+	for i := 1; i < len(points); i++ {
+		distance += HaversineKM(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+	return round(distance)
+}
+
+// HaversineKM is the great-circle distance between two coordinates in degrees
+func HaversineKM(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+func ValidatePositionBatch(batch PositionBatchRequest) error {
+	if len(batch.Points) == 0 {
+		return Validation("points are required")
+	}
+	if len(batch.Points) > MaxPositionBatch {
+		return Validation("a batch holds at most %d points, got %d", MaxPositionBatch, len(batch.Points))
+	}
+	// tracker clocks drift, fixes slightly ahead of the server are accepted
+	latest := time.Now().Add(5 * time.Minute)
+	for i, point := range batch.Points {
+		switch {
+		case point.Timestamp.IsZero():
+			return Validation("point %d: timestamp is required", i)
+		case point.Timestamp.After(latest):
+			return Validation("point %d: timestamp cannot be in the future", i)
+		case point.Latitude < -90 || point.Latitude > 90:
+			return Validation("point %d: lat must be between -90 and 90", i)
+		case point.Longitude < -180 || point.Longitude > 180:
+			return Validation("point %d: lon must be between -180 and 180", i)
+		case point.Speed < 0:
+			return Validation("point %d: speed cannot be negative", i)
+		case point.Heading < 0 || point.Heading >= 360:
+			return Validation("point %d: heading must be between 0 and 360", i)
+		}
+	}
+	return nil
+}
@@ -1,43 +1,55 @@
 package models
 
 import (
-	"errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-var ErrMissingField = errors.New("missing required field")
+var ErrMissingField = Validation("missing required field")
+
+const (
+	TripStatusDraft      = "Draft"
+	TripStatusScheduled  = "Scheduled"
+	TripStatusInProgress = "In Progress"
+	TripStatusCompleted  = "Completed"
+	TripStatusCancelled  = "Cancelled"
+)
 
 type Trip struct {
-	ID                 uuid.UUID `json:"id"`                   // Unique trip identifier
-	Description        string    `json:"description"`          //
-	DriverID           uuid.UUID `json:"driver_id"`            // Reference to the Driver model
-	CarID              uuid.UUID `json:"car_id"`               // Reference to the Car model
-	StartLocation      string    `json:"start_location"`       // Starting point of the trip
-	EndLocation        string    `json:"end_location"`         // Destination of the trip
-	StartTime          time.Time `json:"start_time"`           // Trip start time
-	EndTime            time.Time `json:"end_time"`             // Trip end time (nullable if still ongoing)
-	DistanceKM         float64   `json:"distance_km"`          // Distance covered in kilometers
-	FuelConsumedLiters float64   `json:"fuel_consumed_liters"` // Fuel consumed in liters
-	Status             string    `json:"status"`               // Trip status (e.g., Completed, In Progress, Cancelled, Draft, Scheduled)
-	CreatedAt          time.Time `json:"created_at"`           // Record creation timestamp
-	UpdatedAt          time.Time `json:"updated_at"`           // Record last update timestamp
-	CreatedBy          string    `json:"created_by"`           // User who created the record
-	UpdatedBy          string    `json:"updated_by"`           // User who last updated the record
+	ID                 uuid.UUID  `json:"id"`                          // Unique trip identifier
+	Description        string     `json:"description"`                 //
+	DriverID           uuid.UUID  `json:"driver_id"`                   // Reference to the Driver model
+	CarID              uuid.UUID  `json:"car_id"`                      // Reference to the Car model
+	StartLocation      string     `json:"start_location"`              // Starting point of the trip
+	EndLocation        string     `json:"end_location"`                // Destination of the trip
+	StartLocationID    *uuid.UUID `json:"start_location_id,omitempty"` // Reference to the Location the trip starts at
+	EndLocationID      *uuid.UUID `json:"end_location_id,omitempty"`   // Reference to the Location the trip ends at
+	StartTime          time.Time  `json:"start_time"`                  // Trip start time
+	EndTime            time.Time  `json:"end_time"`                    // Trip end time (nullable if still ongoing)
+	DistanceKM         float64    `json:"distance_km"`                 // Distance covered in kilometers
+	FuelConsumedLiters float64    `json:"fuel_consumed_liters"`        // Fuel consumed in liters
+	Status             string     `json:"status"`                      // Trip status (e.g., Completed, In Progress, Cancelled, Draft, Scheduled)
+	CreatedAt          time.Time  `json:"created_at"`                  // Record creation timestamp
+	UpdatedAt          time.Time  `json:"updated_at"`                  // Record last update timestamp
+	CreatedBy          string     `json:"created_by"`                  // User who created the record
+	UpdatedBy          string     `json:"updated_by"`                  // User who last updated the record
 }
 
 type TripRequest struct {
-	Description        string    `json:"description"`
-	DriverID           uuid.UUID `json:"driver_id"`
-	CarID              uuid.UUID `json:"car_id"`
-	StartLocation      string    `json:"start_location"`
-	EndLocation        string    `json:"end_location"`
-	StartTime          time.Time `json:"start_time"`
-	EndTime            time.Time `json:"end_time"`
-	DistanceKM         float64   `json:"distance_km"`
-	FuelConsumedLiters float64   `json:"fuel_consumed_liters"`
-	Status             string    `json:"status"`
+	Description        string     `json:"description"`
+	DriverID           uuid.UUID  `json:"driver_id"`
+	CarID              uuid.UUID  `json:"car_id"`
+	StartLocation      string     `json:"start_location"`
+	EndLocation        string     `json:"end_location"`
+	StartLocationID    *uuid.UUID `json:"start_location_id"`
+	EndLocationID      *uuid.UUID `json:"end_location_id"`
+	StartTime          time.Time  `json:"start_time"`
+	EndTime            time.Time  `json:"end_time"`
+	DistanceKM         float64    `json:"distance_km"`
+	FuelConsumedLiters float64    `json:"fuel_consumed_liters"`
+	Status             string     `json:"status"`
 }
 
 func ValidateTripRequest(tripReq TripRequest) error {
@@ -50,33 +62,89 @@ func ValidateTripRequest(tripReq TripRequest) error {
 	if tripReq.CarID == uuid.Nil {
 		return ErrMissingField
 	}
-	if tripReq.StartLocation == "" {
+	// trips name their endpoints by location, or by text as they did before locations existed
+	if tripReq.StartLocation == "" && tripReq.StartLocationID == nil {
 		return ErrMissingField
 	}
-	if tripReq.EndLocation == "" {
+	if tripReq.EndLocation == "" && tripReq.EndLocationID == nil {
 		return ErrMissingField
 	}
 	if tripReq.StartTime.IsZero() {
 		return ErrMissingField
 	}
-	if tripReq.DistanceKM == 0 {
-		return ErrMissingField
+	if !tripReq.EndTime.IsZero() && !tripReq.EndTime.After(tripReq.StartTime) {
+		return Validation("end_time must be after start_time")
 	}
-	if tripReq.FuelConsumedLiters == 0 {
-		return ErrMissingField
-	}
-	if err := validateTripStatus(tripReq.Status); err != nil {
+	if err := ValidateTripStatus(tripReq.Status); err != nil {
 		return err
 	}
 	return nil
 }
 
-func validateTripStatus(status string) error {
+func ValidateTripStatus(status string) error {
 	validateTripTypes := []string{"Completed", "Scheduled", "In Progress", "Cancelled", "Draft"}
 	for _, validType := range validateTripTypes {
 		if status == validType {
 			return nil
 		}
 	}
-	return errors.New("status type must be one of: Completed, Scheduled, In Progress, Cancelled, Draft")
+	return Validation("status type must be one of: Completed, Scheduled, In Progress, Cancelled, Draft")
+}
+
+// TripStatusRequest moves a trip to another status. Completing a trip requires EndTime, DistanceKM
+// and FuelConsumedLiters.
+type TripStatusRequest struct {
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	EndTime            time.Time `json:"end_time"`
+	DistanceKM         float64   `json:"distance_km"`
+	FuelConsumedLiters float64   `json:"fuel_consumed_liters"`
+	// OdometerKM is the odometer reading when the trip starts or ends
+	OdometerKM float64 `json:"odometer_km"`
+}
+
+// TripStatusChange is a checked transition applied by the trip store. StartTime, EndTime, DistanceKM
+// and FuelConsumedLiters are only written when set. OdometerKM, when set, is recorded as the car's
+// reading at the start or end of the trip.
+type TripStatusChange struct {
+	From               string
+	To                 string
+	Reason             string
+	StartTime          time.Time
+	EndTime            time.Time
+	DistanceKM         float64
+	FuelConsumedLiters float64
+	OdometerKM         float64
+}
+
+// TripTransition is one recorded status change of a trip
+type TripTransition struct {
+	ID         uuid.UUID `json:"id"`
+	TripID     uuid.UUID `json:"trip_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	Actor      string    `json:"actor"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TripBooking is the time window another trip holds its car or driver for, returned in the details of a booking
+// conflict. EndTime is left out for an open ended trip.
+type TripBooking struct {
+	ID        uuid.UUID  `json:"id"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+}
+
+// odometerToleranceKM is how far a distance given when completing a trip may be from the one between its odometer
+// readings, which are taken to a tenth of a kilometer
+const odometerToleranceKM = 0.1
+
+// CheckOdometerDistance rejects a distance given when completing a trip that disagrees with the distance between
+// the trip's odometer readings. A distance of zero was not given and always passes.
+func CheckOdometerDistance(tripID uuid.UUID, givenKM, readingsKM float64) error {
+	if givenKM > 0 && math.Abs(givenKM-readingsKM) > odometerToleranceKM {
+		return Validation("distance_km %g disagrees with the %g km between the odometer readings of trip %s", givenKM, readingsKM, tripID)
+	}
+	return nil
 }
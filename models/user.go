@@ -7,6 +7,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// roles recognised by the "user".role column
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleDriver  = "driver"
+	RoleTracker = "tracker"
+)
+
 type User struct {
 	UserName    string    `json:"username"`
 	Password    string    `json:"password"`
@@ -18,6 +26,7 @@ type User struct {
 	ID          uuid.UUID `json:"uuid"`
 	Active      bool      `json:"active"`
 	CreatedBy   string    `json:"created_by"`
+	UpdatedBy   string    `json:"updated_by"`
 	DeletedAt   time.Time `json:"deleted_at"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -40,9 +49,12 @@ type UpdatePasswordRequest struct {
 	ConfirmPassword  string `json:"confirm_password"`
 }
 
+// PasswordCost is the bcrypt cost of stored password hashes
+const PasswordCost = 14
+
 // create a function to hash the user's password as you are saving the user
 func (user *UserRequest) HashPassword(password string) error {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
 	if err != nil {
 		return err
 	}
@@ -51,7 +63,7 @@ func (user *UserRequest) HashPassword(password string) error {
 }
 
 func (user *UpdatePasswordRequest) HashPassword(password string) error {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+
+	auditHandler "github.com/JulianaSau/carzone/handler/audit"
+	carHandler "github.com/JulianaSau/carzone/handler/car"
+	driverHandler "github.com/JulianaSau/carzone/handler/driver"
+	engineHandler "github.com/JulianaSau/carzone/handler/engine"
+	fuelHandler "github.com/JulianaSau/carzone/handler/fuel"
+	geofenceHandler "github.com/JulianaSau/carzone/handler/geofence"
+	locationHandler "github.com/JulianaSau/carzone/handler/location"
+	loginHandler "github.com/JulianaSau/carzone/handler/login"
+	maintenanceHandler "github.com/JulianaSau/carzone/handler/maintenance"
+	odometerHandler "github.com/JulianaSau/carzone/handler/odometer"
+	positionHandler "github.com/JulianaSau/carzone/handler/position"
+	tripHandler "github.com/JulianaSau/carzone/handler/trip"
+	userHandler "github.com/JulianaSau/carzone/handler/user"
+	middleware "github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	auditService "github.com/JulianaSau/carzone/service/audit"
+	carService "github.com/JulianaSau/carzone/service/car"
+	driverService "github.com/JulianaSau/carzone/service/driver"
+	engineService "github.com/JulianaSau/carzone/service/engine"
+	fuelService "github.com/JulianaSau/carzone/service/fuel"
+	geofenceService "github.com/JulianaSau/carzone/service/geofence"
+	locationService "github.com/JulianaSau/carzone/service/location"
+	maintenanceService "github.com/JulianaSau/carzone/service/maintenance"
+	odometerService "github.com/JulianaSau/carzone/service/odometer"
+	positionService "github.com/JulianaSau/carzone/service/position"
+	tokenService "github.com/JulianaSau/carzone/service/token"
+	tripService "github.com/JulianaSau/carzone/service/trip"
+	userService "github.com/JulianaSau/carzone/service/user"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+
+	_ "github.com/JulianaSau/carzone/docs" // Import generated Swagger docs
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// newRouter builds the services and handlers of the API on stores and routes every endpoint to them
+func newRouter(stores stores) *mux.Router {
+	// create the services of the core features on the stores
+	carService := carService.NewCarService(stores.car)
+	engineService := engineService.NewEngineService(stores.engine)
+	userService := userService.NewUserService(stores.user)
+	driverService := driverService.NewDriverService(stores.driver)
+	tripService := tripService.NewTripService(stores.trip, stores.driver)
+	tokenService := tokenService.NewTokenService(stores.token, stores.user)
+	auditService := auditService.NewAuditService(stores.audit)
+
+	carHandler := carHandler.NewCarHandler(carService)
+	engineHandler := engineHandler.NewEngineHandler(engineService)
+	userHandler := userHandler.NewUserHandler(userService)
+	driverHandler := driverHandler.NewDriverHandler(driverService)
+	tripHandler := tripHandler.NewTripHandler(tripService)
+	auditHandler := auditHandler.NewAuditHandler(auditService)
+
+	// initialise router
+	router := mux.NewRouter()
+
+	// define routes
+	router.Use(otelmux.Middleware("carzone"))
+	router.Use(middleware.MetricsMiddleware)
+
+	// failed login tracking per username and client ip
+	loginLimiter := loginHandler.NewLoginLimiter()
+
+	router.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		loginHandler.LoginHandler(w, r, userService, tokenService, loginLimiter)
+	}).Methods("POST")
+	router.HandleFunc("/api/v1/token/refresh", func(w http.ResponseWriter, r *http.Request) {
+		loginHandler.RefreshHandler(w, r, tokenService)
+	}).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", loginHandler.JWKSHandler).Methods("GET")
+
+	// Swagger documentation route
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// middleware
+	protected := router.PathPrefix("/").Subrouter()
+	protected.Use(middleware.AuthMIddleware)
+	// router.Use(middleware.AuthMIddleware)
+
+	// reject revoked tokens and tokens of deactivated users
+	middleware.SetSessionValidator(tokenService)
+
+	protected.HandleFunc("/api/v1/logout", func(w http.ResponseWriter, r *http.Request) {
+		loginHandler.LogoutHandler(w, r, tokenService)
+	}).Methods("POST")
+
+	// route permission policy: admins manage users, managers manage the fleet, drivers can only read and
+	// trackers can only report positions
+	admins := []string{models.RoleAdmin}
+	managers := []string{models.RoleAdmin, models.RoleManager}
+	readers := []string{models.RoleAdmin, models.RoleManager, models.RoleDriver}
+	trackers := []string{models.RoleAdmin, models.RoleManager, models.RoleTracker}
+
+	protected.HandleFunc("/api/v1/users", middleware.RequireRoles(userHandler.GetUsers, managers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/users/{id}", middleware.RequireSelfOrRoles(userHandler.GetUserProfile, managers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/users", middleware.RequireRoles(userHandler.CreateUser, admins...)).Methods("POST")
+	protected.HandleFunc("/api/v1/users/{id}", middleware.RequireRoles(userHandler.UpdateUserProfile, admins...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/users/{id}/update-password", middleware.RequireSelfOrRoles(userHandler.UpdateUserPassword, admins...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/users/{id}", middleware.RequireRoles(userHandler.DeleteUser, admins...)).Methods("DELETE")
+	protected.HandleFunc("/api/v1/users/{id}/toggle-status", middleware.RequireRoles(userHandler.ToggleUserStatus, admins...)).Methods("PUT")
+
+	protected.HandleFunc("/api/v1/drivers", middleware.RequireRoles(driverHandler.GetDrivers, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/drivers/expiring", middleware.RequireRoles(driverHandler.GetExpiringDrivers, managers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/drivers/{id}", middleware.RequireRoles(driverHandler.GetDriverById, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/drivers/{id}/licenses", middleware.RequireRoles(driverHandler.GetDriverLicenses, managers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/drivers", middleware.RequireRoles(driverHandler.CreateDriver, managers...)).Methods("POST")
+	protected.HandleFunc("/api/v1/drivers/{id}", middleware.RequireRoles(driverHandler.UpdateDriver, managers...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/drivers/{id}/delete", middleware.RequireRoles(driverHandler.DeleteDriver, admins...)).Methods("DELETE")
+	protected.HandleFunc("/api/v1/drivers/{id}", middleware.RequireRoles(driverHandler.SoftDeleteDriver, managers...)).Methods("DELETE")
+	protected.HandleFunc("/api/v1/drivers/{id}/toggle-status", middleware.RequireRoles(driverHandler.ToggleDriverStatus, managers...)).Methods("PUT")
+
+	protected.HandleFunc("/api/v1/cars/{id}", middleware.RequireRoles(carHandler.GetCarById, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/cars", middleware.RequireRoles(carHandler.SearchCars, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/cars", middleware.RequireRoles(carHandler.CreateCar, managers...)).Methods("POST")
+	protected.HandleFunc("/api/v1/cars/{id}", middleware.RequireRoles(carHandler.UpdateCar, managers...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/cars/{id}", middleware.RequireRoles(carHandler.DeleteCar, managers...)).Methods("DELETE")
+
+	protected.HandleFunc("/api/v1/engines/{id}", middleware.RequireRoles(engineHandler.GetEngineById, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/engines", middleware.RequireRoles(engineHandler.CreateEngine, managers...)).Methods("POST")
+	protected.HandleFunc("/api/v1/engines/{id}", middleware.RequireRoles(engineHandler.UpdateEngine, managers...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/engines/{id}", middleware.RequireRoles(engineHandler.DeleteEngine, managers...)).Methods("DELETE")
+
+	protected.HandleFunc("/api/v1/trips", middleware.RequireRoles(tripHandler.GetTrips, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/trips/routes", middleware.RequireRoles(tripHandler.GetRouteStats, managers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/trips/{id}", middleware.RequireRoles(tripHandler.GetTripById, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/cars/{id}/trips", middleware.RequireRoles(tripHandler.GetTripsByCarID, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/drivers/{id}/trips", middleware.RequireRoles(tripHandler.GetTripsByDriverID, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/trips", middleware.RequireRoles(tripHandler.CreateTrip, managers...)).Methods("POST")
+	protected.HandleFunc("/api/v1/trips/{id}", middleware.RequireRoles(tripHandler.UpdateTrip, managers...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/trips/{id}/update-status", middleware.RequireRoles(tripHandler.UpdateTripStatus, managers...)).Methods("PUT")
+	protected.HandleFunc("/api/v1/trips/{id}/transitions", middleware.RequireRoles(tripHandler.GetTripTransitions, readers...)).Methods("GET")
+	protected.HandleFunc("/api/v1/trips/{id}", middleware.RequireRoles(tripHandler.DeleteTrip, managers...)).Methods("DELETE")
+
+	// the optional features are left out when the store backend does not keep them
+	if stores.location != nil {
+		locationHandler := locationHandler.NewLocationHandler(locationService.NewLocationService(stores.location))
+		protected.HandleFunc("/api/v1/locations", middleware.RequireRoles(locationHandler.GetLocations, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/locations", middleware.RequireRoles(locationHandler.CreateLocation, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/locations/{id}", middleware.RequireRoles(locationHandler.GetLocationById, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/locations/{id}", middleware.RequireRoles(locationHandler.UpdateLocation, managers...)).Methods("PUT")
+		protected.HandleFunc("/api/v1/locations/{id}", middleware.RequireRoles(locationHandler.DeleteLocation, managers...)).Methods("DELETE")
+	}
+
+	if stores.maintenance != nil {
+		maintenanceHandler := maintenanceHandler.NewMaintenanceHandler(maintenanceService.NewMaintenanceService(stores.maintenance))
+		protected.HandleFunc("/api/v1/cars/{id}/maintenance", middleware.RequireRoles(maintenanceHandler.GetMaintenanceRecords, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/maintenance", middleware.RequireRoles(maintenanceHandler.CreateMaintenanceRecord, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/cars/{id}/maintenance/plans", middleware.RequireRoles(maintenanceHandler.GetMaintenancePlans, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/maintenance/plans", middleware.RequireRoles(maintenanceHandler.CreateMaintenancePlan, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/maintenance/due", middleware.RequireRoles(maintenanceHandler.GetDueMaintenance, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/maintenance/plans/{id}", middleware.RequireRoles(maintenanceHandler.UpdateMaintenancePlan, managers...)).Methods("PUT")
+		protected.HandleFunc("/api/v1/maintenance/plans/{id}", middleware.RequireRoles(maintenanceHandler.DeleteMaintenancePlan, managers...)).Methods("DELETE")
+		protected.HandleFunc("/api/v1/maintenance/{id}", middleware.RequireRoles(maintenanceHandler.GetMaintenanceRecordById, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/maintenance/{id}", middleware.RequireRoles(maintenanceHandler.UpdateMaintenanceRecord, managers...)).Methods("PUT")
+		protected.HandleFunc("/api/v1/maintenance/{id}", middleware.RequireRoles(maintenanceHandler.DeleteMaintenanceRecord, managers...)).Methods("DELETE")
+	}
+
+	if stores.odometer != nil {
+		odometerHandler := odometerHandler.NewOdometerHandler(odometerService.NewOdometerService(stores.odometer))
+		protected.HandleFunc("/api/v1/cars/{id}/odometer", middleware.RequireRoles(odometerHandler.GetOdometerReadings, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/odometer", middleware.RequireRoles(odometerHandler.CreateOdometerReading, managers...)).Methods("POST")
+	}
+
+	// positions are checked against the geofences as they are reported
+	if stores.geofence != nil && stores.position != nil {
+		geofenceService := geofenceService.NewGeofenceService(stores.geofence)
+		geofenceHandler := geofenceHandler.NewGeofenceHandler(geofenceService)
+		positionHandler := positionHandler.NewPositionHandler(positionService.NewPositionService(stores.position, stores.trip))
+
+		protected.HandleFunc("/api/v1/trips/{id}/route", middleware.RequireRoles(positionHandler.GetTripRoute, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/positions", middleware.RequireRoles(positionHandler.GetPositions, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/positions", middleware.RequireRoles(positionHandler.CreatePositions, trackers...)).Methods("POST")
+
+		protected.HandleFunc("/api/v1/geofences", middleware.RequireRoles(geofenceHandler.GetGeofences, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/geofences", middleware.RequireRoles(geofenceHandler.CreateGeofence, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/geofences/events", middleware.RequireRoles(geofenceHandler.GetGeofenceEvents, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/geofences/{id}", middleware.RequireRoles(geofenceHandler.GetGeofenceById, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/geofences/{id}", middleware.RequireRoles(geofenceHandler.UpdateGeofence, managers...)).Methods("PUT")
+		protected.HandleFunc("/api/v1/geofences/{id}", middleware.RequireRoles(geofenceHandler.DeleteGeofence, managers...)).Methods("DELETE")
+	}
+
+	if stores.fuel != nil {
+		fuelHandler := fuelHandler.NewFuelHandler(fuelService.NewFuelService(stores.fuel, stores.car, stores.trip))
+		protected.HandleFunc("/api/v1/cars/{id}/fuel", middleware.RequireRoles(fuelHandler.GetFuelEntriesByCarID, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/cars/{id}/fuel", middleware.RequireRoles(fuelHandler.CreateFuelEntry, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/cars/{id}/fuel/efficiency", middleware.RequireRoles(fuelHandler.GetCarFuelEfficiency, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/drivers/{id}/fuel", middleware.RequireRoles(fuelHandler.GetFuelEntriesByDriverID, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/drivers/{id}/fuel/efficiency", middleware.RequireRoles(fuelHandler.GetDriverFuelEfficiency, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/fuel/anomalies", middleware.RequireRoles(fuelHandler.GetFuelAnomalies, managers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/fuel/import", middleware.RequireRoles(fuelHandler.ImportFuelCardStatement, managers...)).Methods("POST")
+		protected.HandleFunc("/api/v1/fuel/{id}", middleware.RequireRoles(fuelHandler.GetFuelEntryById, readers...)).Methods("GET")
+		protected.HandleFunc("/api/v1/fuel/{id}", middleware.RequireRoles(fuelHandler.DeleteFuelEntry, managers...)).Methods("DELETE")
+	}
+
+	protected.HandleFunc("/api/v1/{resource:cars|drivers|trips|users}/{id}/history", middleware.RequireRoles(auditHandler.GetHistory, managers...)).Methods("GET")
+
+	// metrics
+	router.Handle("/metrics", promhttp.Handler())
+
+	return router
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type AuditService struct {
+	store store.AuditStoreInterface
+}
+
+func NewAuditService(store store.AuditStoreInterface) *AuditService {
+	return &AuditService{
+		store: store,
+	}
+}
+
+// GetHistory returns a page of the change history of a resource, newest first unless a sort is given
+func (s *AuditService) GetHistory(ctx context.Context, resource string, resourceID string, opts models.ListOptions) ([]models.AuditEntry, int, error) {
+	tracer := otel.Tracer("AuditService")
+	ctx, span := tracer.Start(ctx, "GetHistory-Service")
+	defer span.End()
+
+	opts.Normalize()
+	if opts.Sort == "" {
+		opts.Desc = true
+	}
+
+	entries, total, err := s.store.GetHistory(ctx, resource, resourceID, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -3,6 +3,7 @@ package car
 import (
 	"context"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/store"
 	"go.opentelemetry.io/otel"
@@ -32,16 +33,17 @@ func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, er
 	return &car, nil
 }
 
-func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+func (s *CarService) SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) ([]models.Car, int, error) {
 	tracer := otel.Tracer("CarService")
-	ctx, span := tracer.Start(ctx, "GetCarByBrand-Service")
+	ctx, span := tracer.Start(ctx, "SearchCars-Service")
 	defer span.End()
 
-	cars, err := s.store.GetCarByBrand(ctx, brand, isEngine)
+	opts.Normalize()
+	cars, total, err := s.store.SearchCars(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return cars, nil
+	return cars, total, nil
 }
 
 func (s *CarService) CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error) {
@@ -53,7 +55,7 @@ func (s *CarService) CreateCar(ctx context.Context, carReq *models.CarRequest) (
 		return nil, err
 	}
 
-	createdCar, err := s.store.CreateCar(ctx, carReq)
+	createdCar, err := s.store.CreateCar(ctx, carReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +70,7 @@ func (s *CarService) UpdateCar(ctx context.Context, id string, carReq *models.Ca
 		return nil, err
 	}
 
-	updatedCar, err := s.store.UpdateCar(ctx, id, carReq)
+	updatedCar, err := s.store.UpdateCar(ctx, id, carReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package driver
 import (
 	"context"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/store"
 	"go.opentelemetry.io/otel"
@@ -20,16 +21,17 @@ func NewDriverService(store store.DriverStoreInterface) *DriverService {
 	}
 }
 
-func (s *DriverService) GetDrivers(ctx context.Context) ([]models.Driver, error) {
+func (s *DriverService) GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) ([]models.Driver, int, error) {
 	tracer := otel.Tracer("DriverService")
 	ctx, span := tracer.Start(ctx, "GetDrivers-Service")
 	defer span.End()
 
-	drivers, err := s.store.GetDrivers(ctx)
+	opts.Normalize()
+	drivers, total, err := s.store.GetDrivers(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return drivers, nil
+	return drivers, total, nil
 }
 
 func (s *DriverService) GetDriverById(ctx context.Context, id string) (*models.Driver, error) {
@@ -53,7 +55,7 @@ func (s *DriverService) CreateDriver(ctx context.Context, driverReq *models.Driv
 	// 	return nil, err
 	// }
 
-	createdDriver, err := s.store.CreateDriver(ctx, driverReq)
+	createdDriver, err := s.store.CreateDriver(ctx, driverReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +70,7 @@ func (s *DriverService) UpdateDriver(ctx context.Context, id string, driverReq *
 	// 	return nil, err
 	// }
 
-	updatedDriver, err := s.store.UpdateDriver(ctx, id, driverReq)
+	updatedDriver, err := s.store.UpdateDriver(ctx, id, driverReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +82,7 @@ func (s *DriverService) ToggleDriverStatus(ctx context.Context, id string, activ
 	ctx, span := tracer.Start(ctx, "ToggleDriverStatus-Service")
 	defer span.End()
 
-	deletedDriver, err := s.store.ToggleDriverStatus(ctx, id, active)
+	deletedDriver, err := s.store.ToggleDriverStatus(ctx, id, active, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +104,21 @@ func (s *DriverService) SoftDeleteDriver(ctx context.Context, id string) (*model
 	ctx, span := tracer.Start(ctx, "SoftDeleteDriver-Service")
 	defer span.End()
 
-	deletedDriver, err := s.store.SoftDeleteDriver(ctx, id)
+	deletedDriver, err := s.store.SoftDeleteDriver(ctx, id, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
 	return &deletedDriver, nil
 }
+
+func (s *DriverService) GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error) {
+	tracer := otel.Tracer("DriverService")
+	ctx, span := tracer.Start(ctx, "GetDriverLicenses-Service")
+	defer span.End()
+
+	licenses, err := s.store.GetDriverLicenses(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/store"
 	"go.opentelemetry.io/otel"
@@ -39,7 +40,7 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 		return nil, err
 	}
 
-	createdEngine, err := s.store.CreateEngine(ctx, engineReq)
+	createdEngine, err := s.store.CreateEngine(ctx, engineReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +56,7 @@ func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *
 		return nil, err
 	}
 
-	updatedEngine, err := s.store.UpdateEngine(ctx, id, engineReq)
+	updatedEngine, err := s.store.UpdateEngine(ctx, id, engineReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,196 @@
+package fuel
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultAnomalyThreshold = 0.25
+	defaultMinRefuels       = 3
+	defaultInterval         = "month"
+)
+
+type FuelService struct {
+	store     store.FuelStoreInterface
+	carStore  store.CarStoreInterface
+	tripStore store.TripStoreInterface
+	// anomalyThreshold is the relative deviation from a baseline that is flagged, minRefuels the number of
+	// refuels a baseline needs before it is trusted
+	anomalyThreshold float64
+	minRefuels       int
+	// cardMapping reads fuel card statements unless an import brings its own columns
+	cardMapping models.FuelCardMapping
+}
+
+func NewFuelService(store store.FuelStoreInterface, carStore store.CarStoreInterface, tripStore store.TripStoreInterface) *FuelService {
+	return &FuelService{
+		store:            store,
+		carStore:         carStore,
+		tripStore:        tripStore,
+		anomalyThreshold: anomalyThresholdFromEnv(),
+		minRefuels:       minRefuelsFromEnv(),
+		cardMapping:      cardMappingFromEnv(),
+	}
+}
+
+// anomalyThresholdFromEnv reads FUEL_ANOMALY_THRESHOLD, e.g. 0.3 for 30%, falling back to 25%
+func anomalyThresholdFromEnv() float64 {
+	value := os.Getenv("FUEL_ANOMALY_THRESHOLD")
+	if value == "" {
+		return defaultAnomalyThreshold
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 {
+		log.Printf("FUEL_ANOMALY_THRESHOLD: invalid value %q, using %v", value, defaultAnomalyThreshold)
+		return defaultAnomalyThreshold
+	}
+	return threshold
+}
+
+// minRefuelsFromEnv reads FUEL_BASELINE_MIN_REFUELS, falling back to 3
+func minRefuelsFromEnv() int {
+	value := os.Getenv("FUEL_BASELINE_MIN_REFUELS")
+	if value == "" {
+		return defaultMinRefuels
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("FUEL_BASELINE_MIN_REFUELS: invalid value %q, using %d", value, defaultMinRefuels)
+		return defaultMinRefuels
+	}
+	return n
+}
+
+func (s *FuelService) GetFuelEntries(ctx context.Context, filter models.FuelFilter, opts models.ListOptions) ([]models.FuelEntry, int, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "GetFuelEntries-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetFuelEntries(ctx, filter, opts)
+}
+
+func (s *FuelService) GetFuelEntryById(ctx context.Context, id string) (*models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "GetFuelEntryById-Service")
+	defer span.End()
+
+	entry, err := s.store.GetFuelEntryById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *FuelService) CreateFuelEntry(ctx context.Context, carID string, fuelReq *models.FuelEntryRequest) (*models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "CreateFuelEntry-Service")
+	defer span.End()
+
+	if err := models.ValidateFuelEntryRequest(*fuelReq); err != nil {
+		return nil, err
+	}
+	if fuelReq.TotalCost == 0 {
+		fuelReq.TotalCost = math.Round(fuelReq.Liters*fuelReq.PricePerLiter*100) / 100
+	}
+
+	entry, err := s.store.CreateFuelEntry(ctx, carID, fuelReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.RecordRefuelMetrics(entry.Liters, entry.TotalCost)
+	return &entry, nil
+}
+
+func (s *FuelService) DeleteFuelEntry(ctx context.Context, id string) (*models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "DeleteFuelEntry-Service")
+	defer span.End()
+
+	entry, err := s.store.DeleteFuelEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetFuelEfficiency reports the consumption and the cost per kilometer of a car or a driver per period, monthly by default
+func (s *FuelService) GetFuelEfficiency(ctx context.Context, filter models.FuelEfficiencyFilter) (*models.FuelEfficiencyReport, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "GetFuelEfficiency-Service")
+	defer span.End()
+
+	if filter.Interval == "" {
+		filter.Interval = defaultInterval
+	}
+	if err := models.ValidateFuelInterval(filter.Interval); err != nil {
+		return nil, err
+	}
+
+	periods, err := s.store.GetFuelEfficiency(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.FuelEfficiencyReport{
+		Interval: filter.Interval,
+		From:     filter.From,
+		To:       filter.To,
+		Periods:  periods,
+	}
+	if filter.CarID != uuid.Nil {
+		report.CarID = &filter.CarID
+	}
+	if filter.DriverID != uuid.Nil {
+		report.DriverID = &filter.DriverID
+	}
+	for i := range report.Periods {
+		period := &report.Periods[i]
+		period.Rates()
+		report.Total.Refuels += period.Refuels
+		report.Total.Liters += period.Liters
+		report.Total.Cost += period.Cost
+		report.Total.DistanceKM += period.DistanceKM
+	}
+	if len(report.Periods) > 0 {
+		report.Total.PeriodStart = report.Periods[0].PeriodStart
+	}
+	report.Total.Rates()
+	return &report, nil
+}
+
+// GetFuelAnomalies lists the refuels whose consumption deviates from the baselines by the configured threshold
+// unless the filter sets its own
+func (s *FuelService) GetFuelAnomalies(ctx context.Context, filter models.FuelAnomalyFilter, opts models.ListOptions) ([]models.FuelAnomaly, int, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "GetFuelAnomalies-Service")
+	defer span.End()
+
+	if filter.Threshold <= 0 {
+		filter.Threshold = s.anomalyThreshold
+	}
+	if filter.MinSamples <= 0 {
+		filter.MinSamples = s.minRefuels
+	}
+	opts.Normalize()
+
+	anomalies, total, err := s.store.GetFuelAnomalies(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range anomalies {
+		anomalies[i].Deviations()
+	}
+	return anomalies, total, nil
+}
@@ -0,0 +1,306 @@
+package fuel
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+// timeLayouts are tried in order when a mapping has no time layout
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05"}
+
+// cardMappingFromEnv reads FUEL_CARD_MAPPING, a JSON object with the columns that differ from the default mapping
+func cardMappingFromEnv() models.FuelCardMapping {
+	value := os.Getenv("FUEL_CARD_MAPPING")
+	if value == "" {
+		return models.DefaultFuelCardMapping
+	}
+	var override models.FuelCardMapping
+	if err := json.Unmarshal([]byte(value), &override); err != nil {
+		log.Printf("FUEL_CARD_MAPPING: invalid value %q, using the default mapping", value)
+		return models.DefaultFuelCardMapping
+	}
+	return models.DefaultFuelCardMapping.Merge(override)
+}
+
+// statementColumns is the position of every mapped column in a statement, -1 for optional columns it lacks
+type statementColumns struct {
+	reference          int
+	registrationNumber int
+	fueledAt           int
+	liters             int
+	pricePerLiter      int
+	totalCost          int
+	station            int
+	odometerKM         int
+}
+
+func findColumns(header []string, mapping models.FuelCardMapping) (statementColumns, error) {
+	positions := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var missing []string
+	find := func(name string, required bool) int {
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok && name != "" {
+			return i
+		}
+		if required {
+			missing = append(missing, name)
+		}
+		return -1
+	}
+
+	columns := statementColumns{
+		reference:          find(mapping.Reference, false),
+		registrationNumber: find(mapping.RegistrationNumber, true),
+		fueledAt:           find(mapping.FueledAt, true),
+		liters:             find(mapping.Liters, true),
+		pricePerLiter:      find(mapping.PricePerLiter, false),
+		totalCost:          find(mapping.TotalCost, false),
+		station:            find(mapping.Station, false),
+		odometerKM:         find(mapping.OdometerKM, true),
+	}
+	if len(missing) > 0 {
+		return columns, models.Validation("the statement has no %s column", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// ImportFuelCardStatement adds the transactions of a fuel card statement to the fuel log. Each row is matched to a
+// car by registration number and to the trip the car was on at the time, whose driver is taken as the buyer. Rows
+// are imported one at a time, a row that fails is reported and does not stop the import. An error that is not about
+// a row, e.g. a lost database connection, does stop it; the rows before it stay imported.
+func (s *FuelService) ImportFuelCardStatement(ctx context.Context, statement io.Reader, mapping models.FuelCardMapping) (*models.FuelImportReport, error) {
+	tracer := otel.Tracer("FuelService")
+	ctx, span := tracer.Start(ctx, "ImportFuelCardStatement-Service")
+	defer span.End()
+
+	mapping = s.cardMapping.Merge(mapping)
+	if err := models.ValidateFuelCardMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(statement)
+	reader.Comma, _ = utf8.DecodeRuneInString(mapping.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, models.Validation("the statement is empty")
+	}
+	if err != nil {
+		return nil, models.Validation("invalid statement header: %v", err)
+	}
+	columns, err := findColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.FuelImportReport{
+		Entries:    []models.FuelEntry{},
+		Exceptions: []models.FuelImportException{},
+	}
+	skip := func(exception models.FuelImportException, reason string, message string) {
+		exception.Reason = reason
+		exception.Message = message
+		report.Skipped++
+		report.Exceptions = append(report.Exceptions, exception)
+	}
+
+	// cars by registration key, nil for registration numbers without a car
+	cars := map[string]*models.Car{}
+	actor := middleware.Actor(ctx)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Rows++
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			skip(models.FuelImportException{Row: parseErr.StartLine}, models.FuelImportInvalidRow, parseErr.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		exception := models.FuelImportException{
+			Row:                line,
+			Reference:          field(columns.reference),
+			RegistrationNumber: field(columns.registrationNumber),
+		}
+
+		fuelReq, err := statementEntry(field, columns, mapping.TimeLayout)
+		if err != nil {
+			skip(exception, models.FuelImportInvalidRow, err.Error())
+			continue
+		}
+		exception.FueledAt = &fuelReq.FueledAt
+		exception.Liters = fuelReq.Liters
+		if exception.RegistrationNumber == "" {
+			skip(exception, models.FuelImportInvalidRow, "registration number is required")
+			continue
+		}
+		if err := models.ValidateFuelEntryRequest(fuelReq); err != nil {
+			skip(exception, models.FuelImportInvalidRow, err.Error())
+			continue
+		}
+
+		car, err := s.statementCar(ctx, cars, exception.RegistrationNumber)
+		if err != nil {
+			return nil, err
+		}
+		if car == nil {
+			skip(exception, models.FuelImportUnknownCar, fmt.Sprintf("no car has registration number %s", exception.RegistrationNumber))
+			continue
+		}
+
+		var flags []models.FuelImportException
+		trips, _, err := s.tripStore.GetTrips(ctx, models.TripFilter{CarID: car.ID, ActiveAt: fuelReq.FueledAt}, models.ListOptions{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(trips) == 0 {
+			flag := exception
+			flag.Reason = models.FuelImportNoActiveTrip
+			flag.Message = fmt.Sprintf("car %s was not on a trip at %s", car.RegistrationNumber, fuelReq.FueledAt.Format(time.RFC3339))
+			flags = append(flags, flag)
+		} else {
+			fuelReq.TripID = &trips[0].ID
+			fuelReq.DriverID = &trips[0].DriverID
+		}
+		if car.TankCapacityLiters > 0 && fuelReq.Liters > car.TankCapacityLiters {
+			flag := exception
+			flag.Reason = models.FuelImportOverTankCapacity
+			flag.Message = fmt.Sprintf("%.2f liters is more than the %.2f liter tank of car %s", fuelReq.Liters, car.TankCapacityLiters, car.RegistrationNumber)
+			flags = append(flags, flag)
+		}
+
+		if fuelReq.TotalCost == 0 {
+			fuelReq.TotalCost = math.Round(fuelReq.Liters*fuelReq.PricePerLiter*100) / 100
+		}
+
+		entry, err := s.store.CreateFuelEntry(ctx, car.ID.String(), &fuelReq, actor)
+		if err != nil {
+			var domainErr *models.Error
+			if !errors.As(err, &domainErr) {
+				return nil, err
+			}
+			skip(exception, models.FuelImportRejected, domainErr.Message)
+			continue
+		}
+
+		middleware.RecordRefuelMetrics(entry.Liters, entry.TotalCost)
+		report.Imported++
+		report.Entries = append(report.Entries, entry)
+		for _, flag := range flags {
+			flag.FuelEntryID = &entry.ID
+			report.Exceptions = append(report.Exceptions, flag)
+		}
+	}
+
+	return &report, nil
+}
+
+// statementCar looks up the car of a registration number once per import, it is nil when there is none
+func (s *FuelService) statementCar(ctx context.Context, cars map[string]*models.Car, registrationNumber string) (*models.Car, error) {
+	key := strings.ToUpper(strings.ReplaceAll(registrationNumber, " ", ""))
+	if car, ok := cars[key]; ok {
+		return car, nil
+	}
+
+	car, err := s.carStore.GetCarByRegistrationNumber(ctx, registrationNumber)
+	if errors.Is(err, models.ErrNotFound) {
+		cars[key] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cars[key] = &car
+	return &car, nil
+}
+
+// statementEntry reads the refuel of a statement row
+func statementEntry(field func(int) string, columns statementColumns, timeLayout string) (models.FuelEntryRequest, error) {
+	var fuelReq models.FuelEntryRequest
+	var err error
+
+	fuelReq.Reference = field(columns.reference)
+	fuelReq.Station = field(columns.station)
+	if fuelReq.FueledAt, err = parseStatementTime(field(columns.fueledAt), timeLayout); err != nil {
+		return fuelReq, err
+	}
+	if fuelReq.Liters, err = parseStatementNumber("liters", field(columns.liters)); err != nil {
+		return fuelReq, err
+	}
+	if fuelReq.PricePerLiter, err = parseStatementNumber("price per liter", field(columns.pricePerLiter)); err != nil {
+		return fuelReq, err
+	}
+	if fuelReq.TotalCost, err = parseStatementNumber("total cost", field(columns.totalCost)); err != nil {
+		return fuelReq, err
+	}
+	if fuelReq.OdometerKM, err = parseStatementNumber("odometer", field(columns.odometerKM)); err != nil {
+		return fuelReq, err
+	}
+	return fuelReq, nil
+}
+
+// parseStatementNumber reads an empty value as 0, required values are checked by ValidateFuelEntryRequest
+func parseStatementNumber(name string, value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, " ", ""), 64)
+	if err != nil {
+		return 0, models.Validation("invalid %s %q", name, value)
+	}
+	return number, nil
+}
+
+func parseStatementTime(value string, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, models.Validation("fueled_at is required")
+	}
+	layouts := timeLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, models.Validation("invalid time %q", value)
+}
@@ -0,0 +1,97 @@
+package geofence
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type GeofenceService struct {
+	store store.GeofenceStoreInterface
+}
+
+func NewGeofenceService(store store.GeofenceStoreInterface) *GeofenceService {
+	return &GeofenceService{
+		store: store,
+	}
+}
+
+func (s *GeofenceService) GetGeofences(ctx context.Context, filter models.GeofenceFilter, opts models.ListOptions) ([]models.Geofence, int, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "GetGeofences-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetGeofences(ctx, filter, opts)
+}
+
+func (s *GeofenceService) GetGeofenceById(ctx context.Context, id string) (*models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "GetGeofenceById-Service")
+	defer span.End()
+
+	geofence, err := s.store.GetGeofenceById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+func (s *GeofenceService) CreateGeofence(ctx context.Context, geofenceReq *models.GeofenceRequest) (*models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "CreateGeofence-Service")
+	defer span.End()
+
+	if err := models.ValidateGeofenceRequest(*geofenceReq); err != nil {
+		return nil, err
+	}
+
+	geofence, err := s.store.CreateGeofence(ctx, geofenceReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+func (s *GeofenceService) UpdateGeofence(ctx context.Context, id string, geofenceReq *models.GeofenceRequest) (*models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "UpdateGeofence-Service")
+	defer span.End()
+
+	if err := models.ValidateGeofenceRequest(*geofenceReq); err != nil {
+		return nil, err
+	}
+
+	geofence, err := s.store.UpdateGeofence(ctx, id, geofenceReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+func (s *GeofenceService) DeleteGeofence(ctx context.Context, id string) (*models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "DeleteGeofence-Service")
+	defer span.End()
+
+	geofence, err := s.store.DeleteGeofence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+func (s *GeofenceService) GetGeofenceEvents(ctx context.Context, filter models.GeofenceEventFilter, opts models.ListOptions) ([]models.GeofenceEvent, int, error) {
+	tracer := otel.Tracer("GeofenceService")
+	ctx, span := tracer.Start(ctx, "GetGeofenceEvents-Service")
+	defer span.End()
+
+	if filter.Event != "" && filter.Event != models.GeofenceEventEnter && filter.Event != models.GeofenceEventExit {
+		return nil, 0, models.Validation("invalid event %q, expected enter or exit", filter.Event)
+	}
+	opts.Normalize()
+	return s.store.GetGeofenceEvents(ctx, filter, opts)
+}
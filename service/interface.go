@@ -2,13 +2,16 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 )
 
 type CarServiceInterface interface {
 	GetCarById(ctx context.Context, id string) (*models.Car, error)
-	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) ([]models.Car, int, error)
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error)
 	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error)
 	DeleteCar(ctx context.Context, id string) (*models.Car, error)
@@ -28,26 +31,89 @@ type UserServiceInterface interface {
 	UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest) (*models.User, error)
 	DeleteUser(ctx context.Context, id string) (*models.User, error)
 	ToggleUserStatus(ctx context.Context, id string, active bool) (*models.User, error)
-	GetUsers(ctx context.Context) ([]models.User, error)
+	GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]models.User, int, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 }
 
 type DriverServiceInterface interface {
-	GetDrivers(ctx context.Context) ([]models.Driver, error)
+	GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) ([]models.Driver, int, error)
 	GetDriverById(ctx context.Context, id string) (*models.Driver, error)
 	CreateDriver(ctx context.Context, driverReq *models.DriverRequest) (*models.Driver, error)
 	UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest) (*models.Driver, error)
 	DeleteDriver(ctx context.Context, id string) (*models.Driver, error)
 	SoftDeleteDriver(ctx context.Context, id string) (*models.Driver, error)
 	ToggleDriverStatus(ctx context.Context, id string, active bool) (*models.Driver, error)
+	GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error)
 }
 type TripServiceInterface interface {
-	GetTrips(ctx context.Context) ([]models.Trip, error)
-	GetTripsByDriverID(ctx context.Context, id string) ([]models.Trip, error)
-	GetTripsByCarID(ctx context.Context, id string) ([]models.Trip, error)
+	GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) ([]models.Trip, int, error)
 	GetTripById(ctx context.Context, id string) (*models.Trip, error)
 	CreateTrip(ctx context.Context, tripReq *models.TripRequest) (*models.Trip, error)
 	UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest) (*models.Trip, error)
-	UpdateTripStatus(ctx context.Context, id string, status string) (*models.Trip, error)
+	UpdateTripStatus(ctx context.Context, id string, statusReq *models.TripStatusRequest) (*models.Trip, error)
+	GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error)
 	DeleteTrip(ctx context.Context, id string) (*models.Trip, error)
+	GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) ([]models.RouteStats, int, error)
+}
+
+type MaintenanceServiceInterface interface {
+	GetMaintenanceRecords(ctx context.Context, carID string, opts models.ListOptions) ([]models.MaintenanceRecord, int, error)
+	GetMaintenanceRecordById(ctx context.Context, id string) (*models.MaintenanceRecord, error)
+	CreateMaintenanceRecord(ctx context.Context, carID string, recordReq *models.MaintenanceRecordRequest) (*models.MaintenanceRecord, error)
+	UpdateMaintenanceRecord(ctx context.Context, id string, recordReq *models.MaintenanceRecordRequest) (*models.MaintenanceRecord, error)
+	DeleteMaintenanceRecord(ctx context.Context, id string) (*models.MaintenanceRecord, error)
+	GetMaintenancePlans(ctx context.Context, carID string) ([]models.MaintenancePlan, error)
+	CreateMaintenancePlan(ctx context.Context, carID string, planReq *models.MaintenancePlanRequest) (*models.MaintenancePlan, error)
+	UpdateMaintenancePlan(ctx context.Context, id string, planReq *models.MaintenancePlanRequest) (*models.MaintenancePlan, error)
+	DeleteMaintenancePlan(ctx context.Context, id string) (*models.MaintenancePlan, error)
+	GetDueMaintenance(ctx context.Context, window time.Duration, withinKM float64, opts models.ListOptions) ([]models.MaintenancePlan, int, error)
+}
+
+type OdometerServiceInterface interface {
+	GetOdometerReadings(ctx context.Context, carID string, filter models.OdometerFilter, opts models.ListOptions) ([]models.OdometerReading, int, error)
+	CreateOdometerReading(ctx context.Context, carID string, readingReq *models.OdometerReadingRequest) (*models.OdometerReading, error)
+}
+
+type FuelServiceInterface interface {
+	GetFuelEntries(ctx context.Context, filter models.FuelFilter, opts models.ListOptions) ([]models.FuelEntry, int, error)
+	GetFuelEntryById(ctx context.Context, id string) (*models.FuelEntry, error)
+	CreateFuelEntry(ctx context.Context, carID string, fuelReq *models.FuelEntryRequest) (*models.FuelEntry, error)
+	DeleteFuelEntry(ctx context.Context, id string) (*models.FuelEntry, error)
+	GetFuelEfficiency(ctx context.Context, filter models.FuelEfficiencyFilter) (*models.FuelEfficiencyReport, error)
+	GetFuelAnomalies(ctx context.Context, filter models.FuelAnomalyFilter, opts models.ListOptions) ([]models.FuelAnomaly, int, error)
+	ImportFuelCardStatement(ctx context.Context, statement io.Reader, mapping models.FuelCardMapping) (*models.FuelImportReport, error)
+}
+
+type PositionServiceInterface interface {
+	CreatePositions(ctx context.Context, carID string, batchReq *models.PositionBatchRequest) (*models.PositionBatch, error)
+	GetPositions(ctx context.Context, filter models.PositionFilter, opts models.ListOptions) ([]models.Position, int, error)
+	GetTripRoute(ctx context.Context, tripID string) (*models.TripRoute, error)
+}
+
+type GeofenceServiceInterface interface {
+	GetGeofences(ctx context.Context, filter models.GeofenceFilter, opts models.ListOptions) ([]models.Geofence, int, error)
+	GetGeofenceById(ctx context.Context, id string) (*models.Geofence, error)
+	CreateGeofence(ctx context.Context, geofenceReq *models.GeofenceRequest) (*models.Geofence, error)
+	UpdateGeofence(ctx context.Context, id string, geofenceReq *models.GeofenceRequest) (*models.Geofence, error)
+	DeleteGeofence(ctx context.Context, id string) (*models.Geofence, error)
+	GetGeofenceEvents(ctx context.Context, filter models.GeofenceEventFilter, opts models.ListOptions) ([]models.GeofenceEvent, int, error)
+}
+
+type LocationServiceInterface interface {
+	GetLocations(ctx context.Context, filter models.LocationFilter, opts models.ListOptions) ([]models.Location, int, error)
+	GetLocationById(ctx context.Context, id string) (*models.Location, error)
+	CreateLocation(ctx context.Context, locationReq *models.LocationRequest) (*models.Location, error)
+	UpdateLocation(ctx context.Context, id string, locationReq *models.LocationRequest) (*models.Location, error)
+	DeleteLocation(ctx context.Context, id string) (*models.Location, error)
+}
+
+type TokenServiceInterface interface {
+	IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, claims *middleware.Claims, refreshToken string) error
+	ValidateSession(ctx context.Context, claims *middleware.Claims) error
+}
+
+type AuditServiceInterface interface {
+	GetHistory(ctx context.Context, resource string, resourceID string, opts models.ListOptions) ([]models.AuditEntry, int, error)
 }
@@ -0,0 +1,85 @@
+package location
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type LocationService struct {
+	store store.LocationStoreInterface
+}
+
+func NewLocationService(store store.LocationStoreInterface) *LocationService {
+	return &LocationService{
+		store: store,
+	}
+}
+
+func (s *LocationService) GetLocations(ctx context.Context, filter models.LocationFilter, opts models.ListOptions) ([]models.Location, int, error) {
+	tracer := otel.Tracer("LocationService")
+	ctx, span := tracer.Start(ctx, "GetLocations-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetLocations(ctx, filter, opts)
+}
+
+func (s *LocationService) GetLocationById(ctx context.Context, id string) (*models.Location, error) {
+	tracer := otel.Tracer("LocationService")
+	ctx, span := tracer.Start(ctx, "GetLocationById-Service")
+	defer span.End()
+
+	location, err := s.store.GetLocationById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (s *LocationService) CreateLocation(ctx context.Context, locationReq *models.LocationRequest) (*models.Location, error) {
+	tracer := otel.Tracer("LocationService")
+	ctx, span := tracer.Start(ctx, "CreateLocation-Service")
+	defer span.End()
+
+	if err := models.ValidateLocationRequest(*locationReq); err != nil {
+		return nil, err
+	}
+
+	location, err := s.store.CreateLocation(ctx, locationReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (s *LocationService) UpdateLocation(ctx context.Context, id string, locationReq *models.LocationRequest) (*models.Location, error) {
+	tracer := otel.Tracer("LocationService")
+	ctx, span := tracer.Start(ctx, "UpdateLocation-Service")
+	defer span.End()
+
+	if err := models.ValidateLocationRequest(*locationReq); err != nil {
+		return nil, err
+	}
+
+	location, err := s.store.UpdateLocation(ctx, id, locationReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (s *LocationService) DeleteLocation(ctx context.Context, id string) (*models.Location, error) {
+	tracer := otel.Tracer("LocationService")
+	ctx, span := tracer.Start(ctx, "DeleteLocation-Service")
+	defer span.End()
+
+	location, err := s.store.DeleteLocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
@@ -0,0 +1,220 @@
+package maintenance
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultDueWindow = 30 * 24 * time.Hour
+	defaultDueKM     = 1000
+)
+
+type MaintenanceService struct {
+	store store.MaintenanceStoreInterface
+	// dueWindow and dueKM tell how early a plan shows as due soon
+	dueWindow time.Duration
+	dueKM     float64
+}
+
+func NewMaintenanceService(store store.MaintenanceStoreInterface) *MaintenanceService {
+	return &MaintenanceService{
+		store:     store,
+		dueWindow: dueWindowFromEnv(),
+		dueKM:     dueKMFromEnv(),
+	}
+}
+
+// dueWindowFromEnv reads MAINTENANCE_DUE_WINDOW, e.g. 14d, falling back to 30 days
+func dueWindowFromEnv() time.Duration {
+	value := os.Getenv("MAINTENANCE_DUE_WINDOW")
+	if value == "" {
+		return defaultDueWindow
+	}
+	window, err := models.ParseWindow(value)
+	if err != nil {
+		log.Printf("MAINTENANCE_DUE_WINDOW: %v, using %s", err, defaultDueWindow)
+		return defaultDueWindow
+	}
+	return window
+}
+
+// dueKMFromEnv reads MAINTENANCE_DUE_KM, falling back to 1000 km
+func dueKMFromEnv() float64 {
+	value := os.Getenv("MAINTENANCE_DUE_KM")
+	if value == "" {
+		return defaultDueKM
+	}
+	km, err := strconv.ParseFloat(value, 64)
+	if err != nil || km < 0 {
+		log.Printf("MAINTENANCE_DUE_KM: invalid value %q, using %d", value, defaultDueKM)
+		return defaultDueKM
+	}
+	return km
+}
+
+func (s *MaintenanceService) GetMaintenanceRecords(ctx context.Context, carID string, opts models.ListOptions) ([]models.MaintenanceRecord, int, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "GetMaintenanceRecords-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetMaintenanceRecords(ctx, carID, opts)
+}
+
+func (s *MaintenanceService) GetMaintenanceRecordById(ctx context.Context, id string) (*models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "GetMaintenanceRecordById-Service")
+	defer span.End()
+
+	record, err := s.store.GetMaintenanceRecordById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *MaintenanceService) CreateMaintenanceRecord(ctx context.Context, carID string, recordReq *models.MaintenanceRecordRequest) (*models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "CreateMaintenanceRecord-Service")
+	defer span.End()
+
+	if err := models.ValidateMaintenanceRecordRequest(*recordReq); err != nil {
+		return nil, err
+	}
+
+	createdRecord, err := s.store.CreateMaintenanceRecord(ctx, carID, recordReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &createdRecord, nil
+}
+
+func (s *MaintenanceService) UpdateMaintenanceRecord(ctx context.Context, id string, recordReq *models.MaintenanceRecordRequest) (*models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "UpdateMaintenanceRecord-Service")
+	defer span.End()
+
+	if err := models.ValidateMaintenanceRecordRequest(*recordReq); err != nil {
+		return nil, err
+	}
+
+	updatedRecord, err := s.store.UpdateMaintenanceRecord(ctx, id, recordReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &updatedRecord, nil
+}
+
+func (s *MaintenanceService) DeleteMaintenanceRecord(ctx context.Context, id string) (*models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "DeleteMaintenanceRecord-Service")
+	defer span.End()
+
+	deletedRecord, err := s.store.DeleteMaintenanceRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &deletedRecord, nil
+}
+
+func (s *MaintenanceService) GetMaintenancePlans(ctx context.Context, carID string) ([]models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "GetMaintenancePlans-Service")
+	defer span.End()
+
+	plans, err := s.store.GetMaintenancePlans(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	s.setStatus(plans, s.dueWindow, s.dueKM)
+	return plans, nil
+}
+
+func (s *MaintenanceService) CreateMaintenancePlan(ctx context.Context, carID string, planReq *models.MaintenancePlanRequest) (*models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "CreateMaintenancePlan-Service")
+	defer span.End()
+
+	if err := models.ValidateMaintenancePlanRequest(*planReq); err != nil {
+		return nil, err
+	}
+
+	createdPlan, err := s.store.CreateMaintenancePlan(ctx, carID, planReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	createdPlan.Status = createdPlan.DueStatus(time.Now(), s.dueWindow, s.dueKM)
+	return &createdPlan, nil
+}
+
+func (s *MaintenanceService) UpdateMaintenancePlan(ctx context.Context, id string, planReq *models.MaintenancePlanRequest) (*models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "UpdateMaintenancePlan-Service")
+	defer span.End()
+
+	if err := models.ValidateMaintenancePlanRequest(*planReq); err != nil {
+		return nil, err
+	}
+
+	updatedPlan, err := s.store.UpdateMaintenancePlan(ctx, id, planReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	updatedPlan.Status = updatedPlan.DueStatus(time.Now(), s.dueWindow, s.dueKM)
+	return &updatedPlan, nil
+}
+
+func (s *MaintenanceService) DeleteMaintenancePlan(ctx context.Context, id string) (*models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "DeleteMaintenancePlan-Service")
+	defer span.End()
+
+	deletedPlan, err := s.store.DeleteMaintenancePlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &deletedPlan, nil
+}
+
+// GetDueMaintenance returns the plans of all cars that are overdue or come due within the window or the kilometers.
+// A zero window or distance falls back to the configured one.
+func (s *MaintenanceService) GetDueMaintenance(ctx context.Context, window time.Duration, withinKM float64, opts models.ListOptions) ([]models.MaintenancePlan, int, error) {
+	tracer := otel.Tracer("MaintenanceService")
+	ctx, span := tracer.Start(ctx, "GetDueMaintenance-Service")
+	defer span.End()
+
+	if window <= 0 {
+		window = s.dueWindow
+	}
+	if withinKM <= 0 {
+		withinKM = s.dueKM
+	}
+	opts.Normalize()
+
+	filter := models.MaintenanceDueFilter{
+		DueBy:    time.Now().Add(window),
+		WithinKM: withinKM,
+	}
+	plans, total, err := s.store.GetDueMaintenance(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.setStatus(plans, window, withinKM)
+	return plans, total, nil
+}
+
+func (s *MaintenanceService) setStatus(plans []models.MaintenancePlan, window time.Duration, withinKM float64) {
+	now := time.Now()
+	for i := range plans {
+		plans[i].Status = plans[i].DueStatus(now, window, withinKM)
+	}
+}
@@ -0,0 +1,45 @@
+package odometer
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type OdometerService struct {
+	store store.OdometerStoreInterface
+}
+
+func NewOdometerService(store store.OdometerStoreInterface) *OdometerService {
+	return &OdometerService{
+		store: store,
+	}
+}
+
+func (s *OdometerService) GetOdometerReadings(ctx context.Context, carID string, filter models.OdometerFilter, opts models.ListOptions) ([]models.OdometerReading, int, error) {
+	tracer := otel.Tracer("OdometerService")
+	ctx, span := tracer.Start(ctx, "GetOdometerReadings-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetOdometerReadings(ctx, carID, filter, opts)
+}
+
+func (s *OdometerService) CreateOdometerReading(ctx context.Context, carID string, readingReq *models.OdometerReadingRequest) (*models.OdometerReading, error) {
+	tracer := otel.Tracer("OdometerService")
+	ctx, span := tracer.Start(ctx, "CreateOdometerReading-Service")
+	defer span.End()
+
+	if err := models.ValidateOdometerReadingRequest(*readingReq); err != nil {
+		return nil, err
+	}
+
+	reading, err := s.store.CreateOdometerReading(ctx, carID, readingReq, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &reading, nil
+}
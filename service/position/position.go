@@ -0,0 +1,85 @@
+package position
+
+import (
+	"context"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type PositionService struct {
+	store     store.PositionStoreInterface
+	tripStore store.TripStoreInterface
+}
+
+func NewPositionService(store store.PositionStoreInterface, tripStore store.TripStoreInterface) *PositionService {
+	return &PositionService{
+		store:     store,
+		tripStore: tripStore,
+	}
+}
+
+// CreatePositions stores a batch of points reported by the tracker of a car. The store checks the new ones against
+// the geofences in the same transaction, so a batch whose check fails is not kept and can be sent again.
+func (s *PositionService) CreatePositions(ctx context.Context, carID string, batchReq *models.PositionBatchRequest) (*models.PositionBatch, error) {
+	tracer := otel.Tracer("PositionService")
+	ctx, span := tracer.Start(ctx, "CreatePositions-Service")
+	defer span.End()
+
+	if err := models.ValidatePositionBatch(*batchReq); err != nil {
+		return nil, err
+	}
+
+	positions, events, err := s.store.CreatePositions(ctx, carID, batchReq.Points)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := models.PositionBatch{
+		Received:   len(batchReq.Points),
+		Stored:     len(positions),
+		Duplicates: len(batchReq.Points) - len(positions),
+	}
+	for _, position := range positions {
+		if position.TripID == nil {
+			batch.Unassigned++
+		}
+	}
+
+	for _, event := range events {
+		middleware.RecordGeofenceEvent(event.GeofenceKind, event.Event)
+	}
+	batch.GeofenceEvents = len(events)
+	return &batch, nil
+}
+
+func (s *PositionService) GetPositions(ctx context.Context, filter models.PositionFilter, opts models.ListOptions) ([]models.Position, int, error) {
+	tracer := otel.Tracer("PositionService")
+	ctx, span := tracer.Start(ctx, "GetPositions-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetPositions(ctx, filter, opts)
+}
+
+// GetTripRoute returns the GPS track of a trip with its length
+func (s *PositionService) GetTripRoute(ctx context.Context, tripID string) (*models.TripRoute, error) {
+	tracer := otel.Tracer("PositionService")
+	ctx, span := tracer.Start(ctx, "GetTripRoute-Service")
+	defer span.End()
+
+	trip, err := s.tripStore.GetTripById(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := s.store.GetTripTrack(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	route := models.NewTripRoute(trip, track)
+	return &route, nil
+}
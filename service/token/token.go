@@ -0,0 +1,225 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// TokenService issues short-lived access tokens together with long-lived refresh tokens.
+// Refresh tokens are single use: every refresh rotates them, and presenting one that was
+// already rotated revokes the whole family since it means the token has leaked.
+type TokenService struct {
+	store     store.TokenStoreInterface
+	userStore store.UserStoreInterface
+}
+
+func NewTokenService(store store.TokenStoreInterface, userStore store.UserStoreInterface) *TokenService {
+	return &TokenService{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	tracer := otel.Tracer("TokenService")
+	ctx, span := tracer.Start(ctx, "IssueTokens-Service")
+	defer span.End()
+
+	rawToken, tokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(middleware.RefreshTokenTTL()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenPair(user, rawToken)
+}
+
+func (s *TokenService) RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	tracer := otel.Tracer("TokenService")
+	ctx, span := tracer.Start(ctx, "RefreshTokens-Service")
+	defer span.End()
+
+	current, err := s.store.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	// an already rotated token is being replayed, so kill every session that descends from it
+	if !current.RevokedAt.IsZero() {
+		if err := s.store.RevokeTokenFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	user, err := s.userStore.GetUserProfile(ctx, current.UserID.String())
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if !user.Active {
+		if err := s.store.RevokeUserTokens(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrUserInactive
+	}
+
+	rawToken, tokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.RotateRefreshToken(ctx, current.ID, &models.RefreshToken{
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(middleware.RefreshTokenTTL()),
+	})
+	if err != nil {
+		// lost a race against another refresh with the same token
+		if errors.Is(err, models.ErrRefreshTokenReused) {
+			if rvErr := s.store.RevokeTokenFamily(ctx, current.FamilyID); rvErr != nil {
+				return nil, rvErr
+			}
+		}
+		return nil, err
+	}
+
+	return tokenPair(&user, rawToken)
+}
+
+// Logout revokes the access token the request was made with and, when given, the refresh token family
+func (s *TokenService) Logout(ctx context.Context, claims *middleware.Claims, refreshToken string) error {
+	tracer := otel.Tracer("TokenService")
+	ctx, span := tracer.Start(ctx, "Logout-Service")
+	defer span.End()
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return models.ErrTokenRevoked
+	}
+
+	if refreshToken != "" {
+		current, err := s.store.GetRefreshToken(ctx, hashToken(refreshToken))
+		if err != nil && !errors.Is(err, models.ErrInvalidRefreshToken) {
+			return err
+		}
+		// never let one user revoke somebody else's session
+		if err == nil && current.UserID == userID {
+			if err := s.store.RevokeTokenFamily(ctx, current.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if claims.Id == "" {
+		return nil
+	}
+	return s.store.RevokeAccessToken(ctx, claims.Id, userID, time.Unix(claims.ExpiresAt, 0))
+}
+
+// ValidateSession implements middleware.SessionValidator
+func (s *TokenService) ValidateSession(ctx context.Context, claims *middleware.Claims) error {
+	tracer := otel.Tracer("TokenService")
+	ctx, span := tracer.Start(ctx, "ValidateSession-Service")
+	defer span.End()
+
+	if claims.Id != "" {
+		revoked, err := s.store.IsAccessTokenRevoked(ctx, claims.Id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return models.ErrTokenRevoked
+		}
+	}
+
+	// tokens issued before the user id claim existed only carry the username
+	var user models.User
+	var err error
+	if claims.UserID != "" {
+		user, err = s.userStore.GetUserProfile(ctx, claims.UserID)
+	} else {
+		user, err = s.userStore.GetUserByUsername(ctx, claims.UserName)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrUserInactive
+		}
+		return err
+	}
+	if user.ID == uuid.Nil || !user.Active {
+		return models.ErrUserInactive
+	}
+	return nil
+}
+
+func tokenPair(user *models.User, refreshToken string) (*models.TokenPair, error) {
+	now := time.Now()
+	claims := &middleware.Claims{
+		UserID:   user.ID.String(),
+		UserName: user.UserName,
+		Role:     user.Role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			ExpiresAt: now.Add(middleware.TokenTTL()).Unix(),
+			Subject:   user.UserName,
+			IssuedAt:  now.Unix(),
+		},
+	}
+
+	accessToken, err := middleware.SignToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		Token:        accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(middleware.TokenTTL().Seconds()),
+	}, nil
+}
+
+// newRefreshToken returns an opaque random token and the hash that is persisted in its place
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
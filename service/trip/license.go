@@ -0,0 +1,69 @@
+package trip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+const defaultLicenseGrace = 7 * 24 * time.Hour
+
+// licenseGraceFromEnv reads LICENSE_GRACE_WINDOW, e.g. 14d, falling back to 7 days
+func licenseGraceFromEnv() time.Duration {
+	value := os.Getenv("LICENSE_GRACE_WINDOW")
+	if value == "" {
+		return defaultLicenseGrace
+	}
+	grace, err := models.ParseWindow(value)
+	if err != nil {
+		log.Printf("LICENSE_GRACE_WINDOW: %v, using %s", err, defaultLicenseGrace)
+		return defaultLicenseGrace
+	}
+	return grace
+}
+
+// checkDriverLicense rejects a trip whose driver's license expires before the trip ends plus the grace window,
+// so licenses about to expire are caught while the trip is planned. Trips without an end time are checked
+// against their start.
+func (s *TripService) checkDriverLicense(ctx context.Context, driverID uuid.UUID, start time.Time, end time.Time) error {
+	driver, err := s.driverStore.GetDriverById(ctx, driverID.String())
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Validation("driver %s does not exist", driverID)
+		}
+		return err
+	}
+
+	now := time.Now()
+	until := end
+	if until.IsZero() {
+		until = start
+	}
+	if until.Before(now) {
+		until = now
+	}
+	if !driver.LicenseExpiry.Before(until.Add(s.licenseGrace)) {
+		return nil
+	}
+
+	expiry := driver.LicenseExpiry.Format(time.DateOnly)
+	message := fmt.Sprintf("the license of driver %s expires on %s, too close to the trip", driverID, expiry)
+	if driver.LicenseExpiry.Before(now) {
+		message = fmt.Sprintf("the license of driver %s expired on %s", driverID, expiry)
+	}
+	return &models.Error{
+		Kind:    models.ErrConflict,
+		Message: message,
+		Details: models.DriverLicenseExpiry{
+			DriverID:        driver.ID,
+			DriverLicenseNo: driver.DriverLicenseNo,
+			LicenseExpiry:   driver.LicenseExpiry,
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package trip
+
+import (
+	"slices"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// transitions lists the statuses a trip may move to from each status. Completed and Cancelled are final.
+var transitions = map[string][]string{
+	models.TripStatusDraft:      {models.TripStatusScheduled, models.TripStatusCancelled},
+	models.TripStatusScheduled:  {models.TripStatusInProgress, models.TripStatusCancelled},
+	models.TripStatusInProgress: {models.TripStatusCompleted, models.TripStatusCancelled},
+}
+
+// initialStatuses are the statuses a trip may be created with
+var initialStatuses = []string{models.TripStatusDraft, models.TripStatusScheduled}
+
+// planTransition checks that the trip may move to the requested status and works out what the store has to write.
+// Starting a trip stamps the actual start time, completing it requires the end time and fuel used. The distance
+// may be left out, the store derives it from the odometer readings or the GPS track of the trip.
+func planTransition(trip models.Trip, statusReq *models.TripStatusRequest, now time.Time) (models.TripStatusChange, error) {
+	if err := models.ValidateTripStatus(statusReq.Status); err != nil {
+		return models.TripStatusChange{}, err
+	}
+	if err := models.ValidateOdometerReading(statusReq.OdometerKM); err != nil {
+		return models.TripStatusChange{}, err
+	}
+	if !slices.Contains(transitions[trip.Status], statusReq.Status) {
+		return models.TripStatusChange{}, models.Conflict("cannot move trip from %s to %s", trip.Status, statusReq.Status)
+	}
+
+	change := models.TripStatusChange{
+		From:   trip.Status,
+		To:     statusReq.Status,
+		Reason: statusReq.Reason,
+	}
+
+	switch statusReq.Status {
+	case models.TripStatusInProgress:
+		change.StartTime = now
+		change.OdometerKM = statusReq.OdometerKM
+	case models.TripStatusCompleted:
+		if statusReq.EndTime.IsZero() {
+			return models.TripStatusChange{}, models.Validation("end_time is required to complete a trip")
+		}
+		if !statusReq.EndTime.After(trip.StartTime) {
+			return models.TripStatusChange{}, models.Validation("end_time must be after the start time %s", trip.StartTime.Format(time.RFC3339))
+		}
+		if statusReq.DistanceKM < 0 {
+			return models.TripStatusChange{}, models.Validation("distance_km cannot be negative")
+		}
+		if statusReq.FuelConsumedLiters <= 0 {
+			return models.TripStatusChange{}, models.Validation("fuel_consumed_liters is required to complete a trip")
+		}
+		change.EndTime = statusReq.EndTime
+		change.DistanceKM = statusReq.DistanceKM
+		change.FuelConsumedLiters = statusReq.FuelConsumedLiters
+		change.OdometerKM = statusReq.OdometerKM
+	}
+	return change, nil
+}
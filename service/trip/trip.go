@@ -2,55 +2,43 @@ package trip
 
 import (
 	"context"
+	"slices"
+	"time"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/store"
 	"go.opentelemetry.io/otel"
 )
 
 type TripService struct {
-	store store.TripStoreInterface
+	store        store.TripStoreInterface
+	driverStore  store.DriverStoreInterface
+	licenseGrace time.Duration
 }
 
-func NewTripService(store store.TripStoreInterface) *TripService {
+// NewTripService reads the license grace window from LICENSE_GRACE_WINDOW, see licenseGraceFromEnv
+func NewTripService(store store.TripStoreInterface, driverStore store.DriverStoreInterface) *TripService {
 	return &TripService{
-		store: store,
+		store:        store,
+		driverStore:  driverStore,
+		licenseGrace: licenseGraceFromEnv(),
 	}
 }
 
-func (s *TripService) GetTrips(ctx context.Context) ([]models.Trip, error) {
+func (s *TripService) GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) ([]models.Trip, int, error) {
 	tracer := otel.Tracer("TripService")
 	ctx, span := tracer.Start(ctx, "GetTrips-Service")
 	defer span.End()
 
-	trips, err := s.store.GetTrips(ctx)
+	opts.Normalize()
+	trips, total, err := s.store.GetTrips(ctx, filter, opts)
 	if err != nil {
-		return nil, err
-	}
-	return trips, nil
-}
-func (s *TripService) GetTripsByCarID(ctx context.Context, id string) ([]models.Trip, error) {
-	tracer := otel.Tracer("TripService")
-	ctx, span := tracer.Start(ctx, "GetTrips-Service")
-	defer span.End()
-
-	trips, err := s.store.GetTripsByCarID(ctx, id)
-	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return trips, nil
+	return trips, total, nil
 }
-func (s *TripService) GetTripsByDriverID(ctx context.Context, id string) ([]models.Trip, error) {
-	tracer := otel.Tracer("TripService")
-	ctx, span := tracer.Start(ctx, "GetTrips-Service")
-	defer span.End()
 
-	trips, err := s.store.GetTripsByDriverID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	return trips, nil
-}
 func (s *TripService) GetTripById(ctx context.Context, id string) (*models.Trip, error) {
 	tracer := otel.Tracer("TripService")
 	ctx, span := tracer.Start(ctx, "GetTripById-Service")
@@ -68,11 +56,21 @@ func (s *TripService) CreateTrip(ctx context.Context, tripReq *models.TripReques
 	ctx, span := tracer.Start(ctx, "CreateTrip-Service")
 	defer span.End()
 
+	// new trips start as drafts unless they are scheduled right away
+	if tripReq.Status == "" {
+		tripReq.Status = models.TripStatusDraft
+	}
 	if err := models.ValidateTripRequest(*tripReq); err != nil {
 		return nil, err
 	}
+	if !slices.Contains(initialStatuses, tripReq.Status) {
+		return nil, models.Validation("a new trip must be %s or %s", models.TripStatusDraft, models.TripStatusScheduled)
+	}
+	if err := s.checkDriverLicense(ctx, tripReq.DriverID, tripReq.StartTime, tripReq.EndTime); err != nil {
+		return nil, err
+	}
 
-	createdTrip, err := s.store.CreateTrip(ctx, tripReq)
+	createdTrip, err := s.store.CreateTrip(ctx, tripReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -84,37 +82,99 @@ func (s *TripService) UpdateTrip(ctx context.Context, id string, tripReq *models
 	ctx, span := tracer.Start(ctx, "UpdateTrip-Service")
 	defer span.End()
 
+	// the status only changes through UpdateTripStatus so the transitions are checked and recorded
+	current, err := s.store.GetTripById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tripReq.Status == "" {
+		tripReq.Status = current.Status
+	}
+	if tripReq.Status != current.Status {
+		return nil, models.Conflict("trip status cannot be changed from %s to %s with an update, use the update-status endpoint", current.Status, tripReq.Status)
+	}
 	if err := models.ValidateTripRequest(*tripReq); err != nil {
 		return nil, err
 	}
+	// completed and cancelled trips are history and can be corrected whatever the license
+	if _, open := transitions[tripReq.Status]; open {
+		if err := s.checkDriverLicense(ctx, tripReq.DriverID, tripReq.StartTime, tripReq.EndTime); err != nil {
+			return nil, err
+		}
+	}
 
-	updatedTrip, err := s.store.UpdateTrip(ctx, id, tripReq)
+	updatedTrip, err := s.store.UpdateTrip(ctx, id, tripReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
 	return &updatedTrip, nil
 }
 
-func (s *TripService) UpdateTripStatus(ctx context.Context, id string, status string) (*models.Trip, error) {
+func (s *TripService) UpdateTripStatus(ctx context.Context, id string, statusReq *models.TripStatusRequest) (*models.Trip, error) {
 	tracer := otel.Tracer("TripService")
-	ctx, span := tracer.Start(ctx, "UpdateTrip-Service")
+	ctx, span := tracer.Start(ctx, "UpdateTripStatus-Service")
 	defer span.End()
 
-	updatedTrip, err := s.store.UpdateTripStatus(ctx, id, status)
+	trip, err := s.store.GetTripById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+
+	change, err := planTransition(trip, statusReq, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if change.To == models.TripStatusScheduled || change.To == models.TripStatusInProgress {
+		if err := s.checkDriverLicense(ctx, trip.DriverID, trip.StartTime, trip.EndTime); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedTrip, err := s.store.UpdateTripStatus(ctx, id, change, middleware.Actor(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if updatedTrip.Status == models.TripStatusCompleted {
+		middleware.RecordTripMetrics(updatedTrip.FuelConsumedLiters, updatedTrip.DistanceKM, updatedTrip.EndTime.Sub(updatedTrip.StartTime))
+	}
 	return &updatedTrip, nil
 }
 
+func (s *TripService) GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error) {
+	tracer := otel.Tracer("TripService")
+	ctx, span := tracer.Start(ctx, "GetTripTransitions-Service")
+	defer span.End()
+
+	// answer 404 rather than an empty list for unknown trips
+	if _, err := s.store.GetTripById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	history, err := s.store.GetTripTransitions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
 func (s *TripService) DeleteTrip(ctx context.Context, id string) (*models.Trip, error) {
 	tracer := otel.Tracer("TripService")
 	ctx, span := tracer.Start(ctx, "DeleteTrip-Service")
 	defer span.End()
 
-	deletedTrip, err := s.store.DeleteTrip(ctx, id)
+	deletedTrip, err := s.store.DeleteTrip(ctx, id, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
 	return &deletedTrip, nil
 }
+
+func (s *TripService) GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) ([]models.RouteStats, int, error) {
+	tracer := otel.Tracer("TripService")
+	ctx, span := tracer.Start(ctx, "GetRouteStats-Service")
+	defer span.End()
+
+	opts.Normalize()
+	return s.store.GetRouteStats(ctx, filter, opts)
+}
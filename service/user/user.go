@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 
+	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
 	"github.com/JulianaSau/carzone/store"
 	"go.opentelemetry.io/otel"
@@ -20,16 +21,17 @@ func NewUserService(store store.UserStoreInterface) *UserService {
 	}
 }
 
-func (s *UserService) GetUsers(ctx context.Context) ([]models.User, error) {
+func (s *UserService) GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]models.User, int, error) {
 	tracer := otel.Tracer("UserService")
 	ctx, span := tracer.Start(ctx, "GetUsers-Service")
 	defer span.End()
 
-	users, err := s.store.GetUsers(ctx)
+	opts.Normalize()
+	users, total, err := s.store.GetUsers(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return users, nil
+	return users, total, nil
 }
 
 func (s *UserService) GetUserProfile(ctx context.Context, id string) (*models.User, error) {
@@ -53,7 +55,7 @@ func (s *UserService) CreateUser(ctx context.Context, userReq *models.UserReques
 	// 	return nil, err
 	// }
 
-	createdUser, err := s.store.CreateUser(ctx, userReq)
+	createdUser, err := s.store.CreateUser(ctx, userReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +70,7 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, id string, userReq
 	// 	return nil, err
 	// }
 
-	updatedUser, err := s.store.UpdateUserProfile(ctx, id, userReq)
+	updatedUser, err := s.store.UpdateUserProfile(ctx, id, userReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +84,7 @@ func (s *UserService) UpdateUserPassword(ctx context.Context, id string, userReq
 	// 	return nil, err
 	// }
 
-	updatedUser, err := s.store.UpdateUserPassword(ctx, id, userReq)
+	updatedUser, err := s.store.UpdateUserPassword(ctx, id, userReq, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +96,7 @@ func (s *UserService) ToggleUserStatus(ctx context.Context, id string, active bo
 	ctx, span := tracer.Start(ctx, "ToggleUserStatus-Service")
 	defer span.End()
 
-	deletedUser, err := s.store.ToggleUserStatus(ctx, id, active)
+	deletedUser, err := s.store.ToggleUserStatus(ctx, id, active, middleware.Actor(ctx))
 	if err != nil {
 		return nil, err
 	}
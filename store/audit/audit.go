@@ -0,0 +1,274 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/JulianaSau/carzone/middleware"
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// tables maps the audited resources to the table holding them
+var tables = map[string]string{
+	models.AuditResourceCar:    "car",
+	models.AuditResourceDriver: "driver",
+	models.AuditResourceTrip:   "trip",
+	models.AuditResourceUser:   `"user"`,
+}
+
+// ignoredFields change on every write and are already covered by the audit entry itself
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+	"updated_by": true,
+}
+
+// redactedFields are recorded as changed without storing their values
+var redactedFields = map[string]bool{
+	"password": true,
+}
+
+const redacted = "[redacted]"
+
+// Snapshot is the state of a row as column name to value
+type Snapshot map[string]interface{}
+
+// Capture reads the current state of a row inside tx and locks it until the transaction ends,
+// so the diff written by Record cannot interleave with a concurrent change. It returns a nil
+// snapshot when the row does not exist.
+func Capture(ctx context.Context, tx *sql.Tx, dialect store.Dialect, resource string, id string) (Snapshot, error) {
+	table, ok := tables[resource]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit resource %q", resource)
+	}
+	if dialect == store.SQLite {
+		return captureRow(ctx, tx, table, id)
+	}
+
+	var raw []byte
+	err := tx.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE t.id = $1 FOR UPDATE`, table),
+		id).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// captureRow reads a row the way to_jsonb renders it, for SQLite which has no JSON of whole rows: timestamps
+// without a time zone, dates without a time and every number as a JSON number
+func captureRow(ctx context.Context, tx *sql.Tx, table string, id string) (Snapshot, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM %s t WHERE t.id = $1`, table), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := map[string]interface{}{}
+	for i, column := range columns {
+		switch value := values[i].(type) {
+		case time.Time:
+			if column.DatabaseTypeName() == "DATE" {
+				row[column.Name()] = value.Format(time.DateOnly)
+			} else {
+				row[column.Name()] = value.Format("2006-01-02T15:04:05.999999")
+			}
+		case []byte:
+			row[column.Name()] = string(value)
+		default:
+			row[column.Name()] = value
+		}
+	}
+
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Record writes the difference between two snapshots of a row to the audit log inside tx, so the
+// entry is committed or rolled back together with the change. Nothing is written when no audited
+// field changed.
+func Record(ctx context.Context, tx *sql.Tx, resource string, id string, action string, before, after Snapshot) error {
+	entry, ok := NewEntry(ctx, resource, id, action, before, after)
+	if !ok {
+		return nil
+	}
+
+	body, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO audit_log (id, resource, resource_id, action, changes, actor_id, actor_name, ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`,
+		entry.ID, entry.Resource, entry.ResourceID, entry.Action, body, entry.ActorID, entry.ActorName, entry.IP, entry.CreatedAt)
+	return err
+}
+
+// NewEntry builds the audit entry for a change between two snapshots of a row. The actor and client ip
+// are taken from the request identity. It reports false when no audited field changed.
+func NewEntry(ctx context.Context, resource string, id string, action string, before, after Snapshot) (models.AuditEntry, bool) {
+	if before == nil && after == nil {
+		return models.AuditEntry{}, false
+	}
+
+	changes := diff(before, after)
+	if len(changes) == 0 {
+		return models.AuditEntry{}, false
+	}
+
+	identity, _ := middleware.IdentityFromContext(ctx)
+	return models.AuditEntry{
+		ID:         uuid.New(),
+		Resource:   resource,
+		ResourceID: id,
+		Action:     action,
+		Changes:    changes,
+		ActorID:    identity.UserID,
+		ActorName:  identity.UserName,
+		IP:         identity.IP,
+		CreatedAt:  time.Now(),
+	}, true
+}
+
+func diff(before, after Snapshot) map[string]models.FieldChange {
+	changes := map[string]models.FieldChange{}
+
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	for field := range fields {
+		if ignoredFields[field] {
+			continue
+		}
+		oldValue, newValue := before[field], after[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if redactedFields[field] {
+			oldValue, newValue = redacted, redacted
+		}
+		changes[field] = models.FieldChange{Old: oldValue, New: newValue}
+	}
+	return changes
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// auditSortColumns are the fields the history can be sorted by
+var auditSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+// GetHistory returns a page of the audit entries of a resource and the total number of entries
+func (a *AuditStore) GetHistory(ctx context.Context, resource string, resourceID string, opts models.ListOptions) ([]models.AuditEntry, int, error) {
+	tracer := otel.Tracer("AuditStore")
+	ctx, span := tracer.Start(ctx, "GetHistory-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	q.Where("resource = ?", resource)
+	q.Where("resource_id = ?", resourceID)
+
+	orderBy, err := q.OrderBy(opts, auditSortColumns, "created_at", "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = a.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log `+q.WhereClause(), q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := a.db.QueryContext(ctx,
+		`
+		SELECT id, resource, resource_id, action, changes, actor_id, actor_name, ip, created_at
+		FROM audit_log
+		`+q.WhereClause()+" "+orderBy+" "+page,
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var changes []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Resource,
+			&entry.ResourceID,
+			&entry.Action,
+			&changes,
+			&entry.ActorID,
+			&entry.ActorName,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -4,19 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
+// likeEscaper escapes the ILIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type Store struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect store.Dialect
 }
 
 func New(db *sql.DB) Store {
-	return Store{db: db}
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+// odometerKM and serviceDue select car_odometer_km and car_service_due of the car c. The SQLite schema has no
+// functions, it keeps both in the car_maintenance view.
+func (s Store) odometerKM() string {
+	if s.dialect == store.SQLite {
+		return "(SELECT m.odometer_km FROM car_maintenance m WHERE m.car_id = c.id)"
+	}
+	return "car_odometer_km(c.id)"
+}
+
+func (s Store) serviceDue() string {
+	if s.dialect == store.SQLite {
+		return "(SELECT m.service_due FROM car_maintenance m WHERE m.car_id = c.id)"
+	}
+	return "car_service_due(c.id)"
 }
 
 func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
@@ -27,10 +50,17 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 	// create car model
 	var car models.Car
 
+	// Parse the car ID
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.Validation("invalid car id %q", id)
+	}
+
 	// using left join operator to get (RIGHT SIDE)engine details matching the cars we are querying
 	query := `
-		SELECT c.id, c.registration_number, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at,
-		c.updated_at, e.id, e.displacement, e.no_of_cylinders, e.car_range 
+		SELECT c.id, c.registration_number, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.tank_capacity_liters, c.status, ` + s.odometerKM() + `, ` + s.serviceDue() + `,
+		COALESCE(c.created_by, ''), COALESCE(c.updated_by, ''), c.created_at, c.updated_at,
+		e.id, e.displacement, e.no_of_cylinders, e.car_range 
 		FROM car c 
 		LEFT JOIN engine e 
 		ON c.engine_id = e.id 
@@ -38,10 +68,10 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 	`
 
 	// returns at most one row
-	row := s.db.QueryRowContext(ctx, query, id)
+	row := s.db.QueryRowContext(ctx, query, carID)
 
 	// scan the row and assign the values to the car model'
-	err := row.Scan(
+	err = row.Scan(
 		&car.ID,
 		&car.RegistrationNumber,
 		&car.Name,
@@ -50,6 +80,12 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 		&car.FuelType,
 		&car.Engine.EngineID,
 		&car.Price,
+		&car.TankCapacityLiters,
+		&car.Status,
+		&car.OdometerKM,
+		&car.ServiceDue,
+		&car.CreatedBy,
+		&car.UpdatedBy,
 		&car.CreatedAt,
 		&car.UpdatedAt,
 		&car.Engine.EngineID,
@@ -58,98 +94,172 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 		&car.Engine.CarRange)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return car, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return car, models.NotFound("car %s not found", id)
 		}
-		return car, err
+		return car, store.DBError(err)
 	}
 
 	return car, nil
 }
 
-func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+// GetCarByRegistrationNumber finds a car by its registration number, ignoring case and spaces
+func (s Store) GetCarByRegistrationNumber(ctx context.Context, registrationNumber string) (models.Car, error) {
 	tracer := otel.Tracer("CarStore")
-	ctx, span := tracer.Start(ctx, "GetCarByBrand-Store")
+	ctx, span := tracer.Start(ctx, "GetCarByRegistrationNumber-Store")
 	defer span.End()
 
-	var cars []models.Car
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id
+		FROM car
+		WHERE UPPER(REPLACE(registration_number, ' ', '')) = UPPER(REPLACE($1, ' ', ''))
+		ORDER BY created_at
+		LIMIT 1
+	`, registrationNumber).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Car{}, models.NotFound("car with registration number %s not found", registrationNumber)
+		}
+		return models.Car{}, store.DBError(err)
+	}
 
-	var query string
+	return s.GetCarById(ctx, id.String())
+}
 
-	if isEngine {
-		query = `
-			SELECT c.id, c.registration_number, c.name, c.brand, c.fuel_type, c.engine_id, c.price,
-			c.created_at, c.updated_at, e.id, e.displacement, e.no_of_cylinders, e.car_range 
-			FROM car c 
-			LEFT JOIN engine e ON c.engine_id=e.id 
-			WHERE c.brand = $1
-		`
-	} else {
-		query = `
-			SELECT id, registration_number, name, brand, fuel_type, engine_id, price, created_at, updated_at 
-			FROM car 
-			WHERE brand = $1
-		`
+// carSortColumns are the fields cars can be sorted by
+var carSortColumns = map[string]string{
+	"name":                "c.name",
+	"registration_number": "c.registration_number",
+	"brand":               "c.brand",
+	"year":                "c.year",
+	"price":               "c.price",
+	"status":              "c.status",
+	"odometer_km":         "odometer_km",
+	"created_at":          "c.created_at",
+	"updated_at":          "c.updated_at",
+}
+
+func (s Store) SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) ([]models.Car, int, error) {
+	tracer := otel.Tracer("CarStore")
+	ctx, span := tracer.Start(ctx, "SearchCars-Store")
+	defer span.End()
+
+	cars := []models.Car{}
+
+	var q store.ListQuery
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		q.Where("("+s.dialect.ILike("c.name")+" OR "+s.dialect.ILike("c.registration_number")+")", pattern, pattern)
+	}
+	if filter.Brand != "" {
+		q.Where("LOWER(c.brand) = LOWER(?)", filter.Brand)
+	}
+	if filter.FuelType != "" {
+		q.Where("c.fuel_type = ?", filter.FuelType)
+	}
+	if filter.Status != "" {
+		q.Where("c.status = ?", filter.Status)
+	}
+	if filter.YearFrom != 0 {
+		q.Where("CAST(c.year AS INT) >= ?", filter.YearFrom)
+	}
+	if filter.YearTo != 0 {
+		q.Where("CAST(c.year AS INT) <= ?", filter.YearTo)
+	}
+	if filter.PriceMin != 0 {
+		q.Where("c.price >= ?", filter.PriceMin)
+	}
+	if filter.PriceMax != 0 {
+		q.Where("c.price <= ?", filter.PriceMax)
+	}
+	if filter.DisplacementMin != 0 {
+		q.Where("e.displacement >= ?", filter.DisplacementMin)
+	}
+	if filter.DisplacementMax != 0 {
+		q.Where("e.displacement <= ?", filter.DisplacementMax)
+	}
+	if filter.Cylinders != 0 {
+		q.Where("e.no_of_cylinders = ?", filter.Cylinders)
+	}
+	if filter.RangeMin != 0 {
+		q.Where("e.car_range >= ?", filter.RangeMin)
+	}
+	if filter.ServiceDue != nil {
+		q.Where(s.serviceDue()+" = ?", *filter.ServiceDue)
 	}
 
-	//  executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
-	rows, err := s.db.QueryContext(ctx, query, brand)
+	orderBy, err := q.OrderBy(opts, carSortColumns, "c.name", "c.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the engine is always joined so its specs can be filtered on, it is only returned when asked for
+	from := `
+		FROM car c
+		LEFT JOIN engine e ON c.engine_id = e.id
+	` + q.WhereClause()
 
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
+	page, args := q.Page(opts)
+	query := `
+		SELECT c.id, c.registration_number, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.tank_capacity_liters, c.status, ` + s.odometerKM() + ` AS odometer_km, ` + s.serviceDue() + `,
+		COALESCE(c.created_by, ''), COALESCE(c.updated_by, ''), c.created_at, c.updated_at,
+		COALESCE(e.displacement, 0), COALESCE(e.no_of_cylinders, 0), COALESCE(e.car_range, 0)
+	` + from + " " + orderBy + " " + page
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer rows.Close()
+
 	for rows.Next() {
 		var car models.Car
-		if isEngine {
-			var engine models.Engine
-			err := rows.Scan(
-				&car.ID,
-				&car.RegistrationNumber,
-				&car.Name,
-				&car.Year,
-				&car.Brand,
-				&car.FuelType,
-				&car.Engine.EngineID,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-				&car.Engine.EngineID,
-				&car.Engine.Displacement,
-				&car.Engine.NoOfCylinders,
-				&car.Engine.CarRange)
-
-			if err != nil {
-				return nil, err
-			}
-			car.Engine = engine
-		} else {
-			err := rows.Scan(
-				&car.ID,
-				&car.RegistrationNumber,
-				&car.Name,
-				&car.Year,
-				&car.Brand,
-				&car.FuelType,
-				// &car.Engine.EngineID,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-			)
-			if err != nil {
-				return nil, err
-			}
+		var engine models.Engine
+		err := rows.Scan(
+			&car.ID,
+			&car.RegistrationNumber,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.TankCapacityLiters,
+			&car.Status,
+			&car.OdometerKM,
+			&car.ServiceDue,
+			&car.CreatedBy,
+			&car.UpdatedBy,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		if filter.WithEngine {
+			car.Engine.Displacement = engine.Displacement
+			car.Engine.NoOfCylinders = engine.NoOfCylinders
+			car.Engine.CarRange = engine.CarRange
 		}
 		cars = append(cars, car)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
 	}
-	return cars, nil
+	return cars, total, nil
 }
 
-func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest, actor string) (models.Car, error) {
 	tracer := otel.Tracer("CarStore")
 	ctx, span := tracer.Start(ctx, "CreateCar-Store")
 	defer span.End()
@@ -170,7 +280,7 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return createdCar, errors.New("engine_id doesnt exist in the engine table")
+			return createdCar, models.Validation("engine %s does not exist", carReq.Engine.EngineID)
 		}
 		return createdCar, err
 	}
@@ -189,6 +299,10 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		FuelType:           carReq.FuelType,
 		Engine:             carReq.Engine,
 		Price:              carReq.Price,
+		TankCapacityLiters: carReq.TankCapacityLiters,
+		Status:             carReq.Status,
+		CreatedBy:          actor,
+		UpdatedBy:          actor,
 		CreatedAt:          createdAt,
 		UpdatedAt:          updatedAt,
 	}
@@ -211,9 +325,9 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 
 	// insert car into the car table
 	query := `
-		INSERT INTO car (id, registration_number, name, year, brand, fuel_type, engine_id, price, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
-		RETURNING id, registration_number, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
+		INSERT INTO car (id, registration_number, name, year, brand, fuel_type, engine_id, price, status, created_by, updated_by, created_at, updated_at, tank_capacity_liters) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) 
+		RETURNING id, registration_number, name, year, brand, fuel_type, engine_id, price, status, created_by, updated_by, created_at, updated_at, tank_capacity_liters
 	`
 
 	err = tx.QueryRowContext(ctx, query,
@@ -225,8 +339,12 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		newCar.FuelType,
 		newCar.Engine.EngineID,
 		newCar.Price,
+		newCar.Status,
+		newCar.CreatedBy,
+		newCar.UpdatedBy,
 		newCar.CreatedAt,
 		newCar.UpdatedAt,
+		newCar.TankCapacityLiters,
 	).Scan(
 		&createdCar.ID,
 		&createdCar.RegistrationNumber,
@@ -236,23 +354,33 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		&createdCar.FuelType,
 		&createdCar.Engine.EngineID,
 		&createdCar.Price,
+		&createdCar.Status,
+		&createdCar.CreatedBy,
+		&createdCar.UpdatedBy,
 		&createdCar.CreatedAt,
 		&createdCar.UpdatedAt,
+		&createdCar.TankCapacityLiters,
 	)
 	if err != nil {
-		return createdCar, err
+		return createdCar, store.DBError(err)
 	}
 
 	return createdCar, nil
 }
 
-func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
+func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, actor string) (models.Car, error) {
 	tracer := otel.Tracer("CarStore")
 	ctx, span := tracer.Start(ctx, "UpdateCar-Store")
 	defer span.End()
 
 	var updatedCar models.Car
 
+	// Parse the car ID
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.Validation("invalid car id %q", id)
+	}
+
 	updatedAt := time.Now()
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -268,15 +396,20 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		err = tx.Commit()
 	}()
 
+	before, err := audit.Capture(ctx, tx, s.dialect, models.AuditResourceCar, carID.String())
+	if err != nil {
+		return updatedCar, err
+	}
+
 	query := `
 		UPDATE car 
-		SET name=$2, year=$3, brand = $4, fuel_type=$5, engine_id=$6, price=$7, updated_at=$8, registration_number=$9
+		SET name=$2, year=$3, brand = $4, fuel_type=$5, engine_id=$6, price=$7, updated_at=$8, registration_number=$9, status=$10, updated_by=$11, tank_capacity_liters=$12
 		WHERE id=$1
-		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, registration_number
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, status, COALESCE(created_by, ''), updated_by, created_at, updated_at, registration_number, tank_capacity_liters
 	`
 
 	err = tx.QueryRowContext(ctx, query,
-		id,
+		carID,
 		carReq.Name,
 		carReq.Year,
 		carReq.Brand,
@@ -285,6 +418,9 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		carReq.Price,
 		updatedAt,
 		carReq.RegistrationNumber,
+		carReq.Status,
+		actor,
+		carReq.TankCapacityLiters,
 	).Scan(
 		&updatedCar.ID,
 		&updatedCar.Name,
@@ -293,10 +429,26 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		&updatedCar.FuelType,
 		&updatedCar.Engine.EngineID,
 		&updatedCar.Price,
+		&updatedCar.Status,
+		&updatedCar.CreatedBy,
+		&updatedCar.UpdatedBy,
 		&updatedCar.CreatedAt,
 		&updatedCar.UpdatedAt,
 		&updatedCar.RegistrationNumber,
+		&updatedCar.TankCapacityLiters,
 	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return updatedCar, models.NotFound("car %s not found", id)
+		}
+		return updatedCar, store.DBError(err)
+	}
+
+	after, err := audit.Capture(ctx, tx, s.dialect, models.AuditResourceCar, carID.String())
+	if err != nil {
+		return updatedCar, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceCar, carID.String(), models.AuditActionUpdate, before, after)
 	if err != nil {
 		return updatedCar, err
 	}
@@ -311,6 +463,12 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 
 	var deletedCar models.Car
 
+	// Parse the car ID
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.Validation("invalid car id %q", id)
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return deletedCar, err
@@ -324,13 +482,13 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 		err = tx.Commit()
 	}()
 
-	tx.QueryRowContext(ctx,
+	err = tx.QueryRowContext(ctx,
 		`
 			SELECT id, registration_number, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
 			FROM car 
 			WHERE id=$1
 		`,
-		id).Scan(
+		carID).Scan(
 		&deletedCar.ID,
 		&deletedCar.RegistrationNumber,
 		&deletedCar.Name,
@@ -344,22 +502,33 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.Car{}, errors.New("car not found")
+			return models.Car{}, models.NotFound("car %s not found", id)
 		}
-		return models.Car{}, err
+		return models.Car{}, store.DBError(err)
 	}
 
-	result, err := tx.ExecContext(ctx, `DELETE FROM car WHERE id=$1`, id)
+	before, err := audit.Capture(ctx, tx, s.dialect, models.AuditResourceCar, carID.String())
 	if err != nil {
 		return models.Car{}, err
 	}
 
+	result, err := tx.ExecContext(ctx, `DELETE FROM car WHERE id=$1`, carID)
+	if err != nil {
+		return models.Car{}, store.DBError(err)
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return models.Car{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Car{}, errors.New("no rows were deleted")
+		err = models.NotFound("car %s not found", id)
+		return models.Car{}, err
+	}
+
+	err = audit.Record(ctx, tx, models.AuditResourceCar, carID.String(), models.AuditActionDelete, before, nil)
+	if err != nil {
+		return models.Car{}, err
 	}
 	return deletedCar, nil
 }
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Dialect is the SQL dialect of the database the stores run on. The stores write postgres and branch on the
+// dialect only where SQLite has no equivalent syntax.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+// DialectOf returns the dialect of a database opened with lib/pq or OpenSQLite
+func DialectOf(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(sqliteDriver); ok {
+		return SQLite
+	}
+	return Postgres
+}
+
+func (d Dialect) String() string {
+	if d == SQLite {
+		return "sqlite"
+	}
+	return "postgres"
+}
+
+// ForUpdate returns the row lock clause of a SELECT. SQLite has none: its transactions take the database's write
+// lock when they begin, so the rows read cannot change before the transaction ends.
+func (d Dialect) ForUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return "FOR UPDATE"
+}
+
+// ILike returns a case-insensitive match of column against a ? pattern escaped with backslashes. SQLite's LIKE
+// ignores the case of ASCII letters only.
+func (d Dialect) ILike(column string) string {
+	if d == SQLite {
+		return column + ` LIKE ? ESCAPE '\'`
+	}
+	return column + " ILIKE ?"
+}
+
+// Returning returns the RETURNING clause of columns qualified with the alias of the table written to. SQLite
+// only resolves unqualified columns there, so the alias is dropped.
+func (d Dialect) Returning(alias string, columns string) string {
+	if d == SQLite {
+		columns = regexp.MustCompile(`\b`+regexp.QuoteMeta(alias)+`\.`).ReplaceAllString(columns, "")
+	}
+	return "RETURNING " + columns
+}
+
+// TripWindowEnd is the end of trip_window(start_time, end_time) in SQLite, where there are no ranges: a trip without
+// an end time, or one started after its planned end time, runs forever
+const TripWindowEnd = `CASE WHEN end_time IS NULL OR end_time <= start_time THEN '9999-12-31' ELSE end_time END`
+
+// sqliteTimeFormat is how timestamps are stored in SQLite. It sorts as text in time order and, like postgres's
+// TIMESTAMP, keeps the wall clock without a time zone.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// NullTime is a sql.NullTime that also scans the text of timestamps SQLite computes, which come back as strings
+// rather than times
+type NullTime struct {
+	sql.NullTime
+}
+
+func (t *NullTime) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return t.NullTime.Scan(value)
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", time.DateOnly} {
+		parsed, err := time.Parse(layout, text)
+		if err == nil {
+			t.Time, t.Valid = parsed, true
+			return nil
+		}
+	}
+	return fmt.Errorf("store: cannot parse %q as a timestamp", text)
+}
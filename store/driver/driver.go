@@ -8,37 +8,74 @@ import (
 	"time"
 
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
 type DriverStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect store.Dialect
 }
 
 func New(db *sql.DB) *DriverStore {
-	return &DriverStore{db: db}
+	return &DriverStore{db: db, dialect: store.DialectOf(db)}
 }
 
-func (d DriverStore) GetDrivers(ctx context.Context) ([]models.Driver, error) {
+// driverSortColumns are the fields drivers can be sorted by
+var driverSortColumns = map[string]string{
+	"driver_license_number": "d.driver_license_number",
+	"license_expiry":        "d.license_expiry",
+	"username":              "u.username",
+	"created_at":            "d.created_at",
+	"updated_at":            "d.updated_at",
+}
+
+func (d DriverStore) GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) ([]models.Driver, int, error) {
 	tracer := otel.Tracer("DriverStore")
 	ctx, span := tracer.Start(ctx, "GetDrivers-Store")
 	defer span.End()
 
 	drivers := []models.Driver{}
 
+	var q store.ListQuery
+	q.Where("d.deleted_at IS NULL")
+	if filter.Active != nil {
+		q.Where("d.active = ?", *filter.Active)
+	}
+	if !filter.LicenseExpiresBefore.IsZero() {
+		q.Where("d.license_expiry < ?", filter.LicenseExpiresBefore)
+	}
+	if !filter.LicenseExpiresAfter.IsZero() {
+		q.Where("d.license_expiry >= ?", filter.LicenseExpiresAfter)
+	}
+
+	orderBy, err := q.OrderBy(opts, driverSortColumns, "d.created_at", "d.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = d.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM driver d JOIN "user" u ON d.user_id = u.id `+q.WhereClause(),
+		q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
 	query := `
 		SELECT 
 			d.id, d.user_id, d.driver_license_number, d.license_expiry, d.active,
-			d.created_at, d.updated_at, d.created_by, d.deleted_at,
+			d.created_at, d.updated_at, COALESCE(d.created_by, ''), COALESCE(d.updated_by, ''),
 			u.id AS user_id, u.username, u.first_name, u.last_name, u.email
 		FROM driver d
-		JOIN user u ON d.user_id = user_id
-		WHERE d.deleted_at IS NULL
-	`
-	rows, err := d.db.QueryContext(ctx, query)
+		JOIN "user" u ON d.user_id = u.id
+	` + q.WhereClause() + " " + orderBy + " " + page
+	rows, err := d.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -53,7 +90,7 @@ func (d DriverStore) GetDrivers(ctx context.Context) ([]models.Driver, error) {
 			&driver.CreatedAt,
 			&driver.UpdatedAt,
 			&driver.CreatedBy,
-			&driver.DeletedAt,
+			&driver.UpdatedBy,
 			&driver.User.ID,
 			&driver.User.UserName,
 			&driver.User.FirstName,
@@ -61,14 +98,17 @@ func (d DriverStore) GetDrivers(ctx context.Context) ([]models.Driver, error) {
 			&driver.User.Email,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		drivers = append(drivers, driver)
 	}
-	return drivers, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return drivers, total, nil
 }
 
-func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverRequest) (models.Driver, error) {
+func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverRequest, actor string) (models.Driver, error) {
 	tracer := otel.Tracer("DriverStore")
 	ctx, span := tracer.Start(ctx, "CreateDriver-Store")
 	defer span.End()
@@ -86,14 +126,14 @@ func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverR
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.Driver{}, errors.New("user_id doesnt exist in the user table")
+			return models.Driver{}, models.Validation("user %s does not exist", driverReq.UserID)
 		}
 		return models.Driver{}, err
 	}
 
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Driver{}, nil
+		return models.Driver{}, err
 	}
 
 	defer func() {
@@ -110,8 +150,8 @@ func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverR
 
 	// Insert driver into the database
 	query := `
-		INSERT INTO driver (id, user_id, driver_license_number, license_expiry, active, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO driver (id, user_id, driver_license_number, license_expiry, active, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, user_id, driver_license_number, license_expiry, created_by, created_at, updated_at
 	`
 	driverID := uuid.New()
@@ -121,28 +161,36 @@ func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverR
 		driverReq.DriverLicenseNo,
 		driverReq.LicenseExpiry,
 		true,
-		"d3b07384-d9a1-4c4b-8a0d-4b1b1b1b1b1b",
+		actor,
+		actor,
 		time.Now(),
 		time.Now(),
 	)
 
+	if err != nil {
+		return models.Driver{}, store.DBError(err)
+	}
+
+	err = recordLicense(ctx, tx, d.dialect, driverID, driverReq.DriverLicenseNo, driverReq.LicenseExpiry, actor)
 	if err != nil {
 		return models.Driver{}, err
 	}
+
 	driver := models.Driver{
 		ID:              driverID,
 		UserID:          userId,
 		DriverLicenseNo: driverReq.DriverLicenseNo,
 		LicenseExpiry:   driverReq.LicenseExpiry,
 		Active:          true,
-		CreatedBy:       "d3b07384-d9a1-4c4b-8a0d-4b1b1b1b1b1b",
+		CreatedBy:       actor,
+		UpdatedBy:       actor,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 	return driver, nil
 }
 
-func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest) (models.Driver, error) {
+func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest, actor string) (models.Driver, error) {
 	tracer := otel.Tracer("DriverStore")
 	ctx, span := tracer.Start(ctx, "UpdateDriver-Store")
 	defer span.End()
@@ -150,12 +198,12 @@ func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *mod
 	// Parse the driver ID
 	driverID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Driver{}, fmt.Errorf("invalid Driver ID : %w", err)
+		return models.Driver{}, models.Validation("invalid driver id %q", id)
 	}
 
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Driver{}, nil
+		return models.Driver{}, err
 	}
 
 	// Defer the rollback or commit
@@ -173,21 +221,27 @@ func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *mod
 		}
 	}()
 
+	before, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
+	}
+
 	// Update driver profile in the database
 	query := `
 		UPDATE driver
-		SET licence_expiry=$1, driver_license_no=$2, updated_at = $3
-		WHERE uuid = $4
+		SET license_expiry=$1, driver_license_number=$2, updated_at = $3, updated_by = $4
+		WHERE id = $5
 	`
 	results, err := tx.ExecContext(ctx, query,
 		driverReq.LicenseExpiry,
 		driverReq.DriverLicenseNo,
 		time.Now(),
+		actor,
 		driverID,
 	)
 
 	if err != nil {
-		return models.Driver{}, err
+		return models.Driver{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -195,7 +249,22 @@ func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *mod
 		return models.Driver{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Driver{}, errors.New("no rows updated")
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+
+	// keep the previous license on record
+	err = recordLicense(ctx, tx, d.dialect, driverID, driverReq.DriverLicenseNo, driverReq.LicenseExpiry, actor)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
+	after, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceDriver, driverID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.Driver{}, err
 	}
 
 	// Return the updated driver
@@ -203,6 +272,7 @@ func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *mod
 		ID:              driverID,
 		DriverLicenseNo: driverReq.DriverLicenseNo,
 		LicenseExpiry:   driverReq.LicenseExpiry,
+		UpdatedBy:       actor,
 		UpdatedAt:       time.Now(),
 	}
 
@@ -217,17 +287,17 @@ func (d DriverStore) GetDriverById(ctx context.Context, id string) (models.Drive
 	// Parse the driver ID
 	driverID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Driver{}, fmt.Errorf("invalid driver ID : %w", err)
+		return models.Driver{}, models.Validation("invalid driver id %q", id)
 	}
 
 	// Query the database
 	query := `
 	SELECT 
 			d.id, d.user_id, d.driver_license_number, d.license_expiry, d.active,
-			d.created_at, d.updated_at, d.created_by, d.deleted_at,
+			d.created_at, d.updated_at, COALESCE(d.created_by, ''), COALESCE(d.updated_by, ''),
 			u.id AS user_id, u.username, u.first_name, u.last_name, u.email
 		FROM driver d
-		JOIN user u ON d.user_id = user_id
+		JOIN "user" u ON d.user_id = u.id
 		WHERE d.deleted_at IS NULL AND d.id = $1
 	`
 	row := d.db.QueryRowContext(ctx, query, driverID)
@@ -242,7 +312,7 @@ func (d DriverStore) GetDriverById(ctx context.Context, id string) (models.Drive
 		&driver.CreatedAt,
 		&driver.UpdatedAt,
 		&driver.CreatedBy,
-		&driver.DeletedAt,
+		&driver.UpdatedBy,
 		&driver.User.ID,
 		&driver.User.UserName,
 		&driver.User.FirstName,
@@ -250,12 +320,15 @@ func (d DriverStore) GetDriverById(ctx context.Context, id string) (models.Drive
 		&driver.User.Email,
 	)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Driver{}, models.NotFound("driver %s not found", id)
+		}
 		return models.Driver{}, err
 	}
 	return driver, nil
 }
 
-func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active bool) (models.Driver, error) {
+func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active bool, actor string) (models.Driver, error) {
 	tracer := otel.Tracer("DriverStore")
 	ctx, span := tracer.Start(ctx, "ToggleDriverStatus-Store")
 	defer span.End()
@@ -263,12 +336,12 @@ func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active b
 	// Parse the user ID
 	driverID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Driver{}, fmt.Errorf("invalid driver ID : %w", err)
+		return models.Driver{}, models.Validation("invalid driver id %q", id)
 	}
 
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Driver{}, nil
+		return models.Driver{}, err
 	}
 
 	// Defer the rollback or commit
@@ -286,18 +359,24 @@ func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active b
 		}
 	}()
 
+	before, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
+	}
+
 	query := `
 	    UPDATE driver
-		SET active = $1, updated_at = $2
-		WHERE uuid = $3
+		SET active = $1, updated_at = $2, updated_by = $3
+		WHERE id = $4
 	`
 	results, err := tx.ExecContext(ctx, query,
 		active,
 		time.Now(),
+		actor,
 		driverID,
 	)
 	if err != nil {
-		return models.Driver{}, err
+		return models.Driver{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -305,12 +384,23 @@ func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active b
 		return models.Driver{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Driver{}, errors.New("no rows updated")
+		return models.Driver{}, models.NotFound("driver %s not found", id)
 	}
+
+	after, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceDriver, driverID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
 	// Return the updated user
 	driver := models.Driver{
 		ID:        driverID,
 		Active:    active,
+		UpdatedBy: actor,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -326,12 +416,12 @@ func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver
 	// Parse the user ID
 	driverID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Driver{}, fmt.Errorf("invalid Driver ID : %w", err)
+		return models.Driver{}, models.Validation("invalid driver id %q", id)
 	}
 
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Driver{}, nil
+		return models.Driver{}, err
 	}
 
 	// Defer the rollback or commit
@@ -349,15 +439,14 @@ func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver
 		}
 	}()
 
-	// check if the user exists
-	err = tx.QueryRowContext(ctx, `SELECT id FROM driver WHERE id = $1`, driverID).Scan()
-
+	// check if the driver exists and keep its state for the audit log
+	before, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.Driver{}, nil
-		}
 		return models.Driver{}, err
 	}
+	if before == nil {
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
 
 	query := `
 		DELETE FROM driver
@@ -365,7 +454,7 @@ func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver
 
 	results, err := tx.ExecContext(ctx, query, driverID)
 	if err != nil {
-		return models.Driver{}, err
+		return models.Driver{}, store.DBError(err)
 	}
 	// Check if the row was actually deleted
 	rowsAffected, err := results.RowsAffected()
@@ -373,7 +462,12 @@ func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver
 		return models.Driver{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Driver{}, errors.New("no rows deleted")
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+
+	err = audit.Record(ctx, tx, models.AuditResourceDriver, driverID.String(), models.AuditActionDelete, before, nil)
+	if err != nil {
+		return models.Driver{}, err
 	}
 	// Return the deleted driver
 	driver := models.Driver{
@@ -384,7 +478,7 @@ func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver
 	return driver, nil
 }
 
-func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string) (models.Driver, error) {
+func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string, actor string) (models.Driver, error) {
 	tracer := otel.Tracer("DriverStore")
 	ctx, span := tracer.Start(ctx, "SoftDeleteDriver-Store")
 	defer span.End()
@@ -392,12 +486,12 @@ func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string) (models.Dr
 	// Parse the user ID
 	driverID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Driver{}, fmt.Errorf("invalid driver ID : %w", err)
+		return models.Driver{}, models.Validation("invalid driver id %q", id)
 	}
 
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Driver{}, nil
+		return models.Driver{}, err
 	}
 
 	// Defer the rollback or commit
@@ -414,17 +508,24 @@ func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string) (models.Dr
 			}
 		}
 	}()
+
+	before, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
+	}
+
 	query := `
 	    UPDATE driver
-		SET deleted_at = $1
-		WHERE uuid = $2
+		SET deleted_at = $1, updated_at = $1, updated_by = $2
+		WHERE id = $3
 	`
 	results, err := tx.ExecContext(ctx, query,
 		time.Now(),
+		actor,
 		driverID,
 	)
 	if err != nil {
-		return models.Driver{}, err
+		return models.Driver{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -432,11 +533,22 @@ func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string) (models.Dr
 		return models.Driver{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Driver{}, errors.New("no rows updated")
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+
+	after, err := audit.Capture(ctx, tx, d.dialect, models.AuditResourceDriver, driverID.String())
+	if err != nil {
+		return models.Driver{}, err
 	}
+	err = audit.Record(ctx, tx, models.AuditResourceDriver, driverID.String(), models.AuditActionDelete, before, after)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
 	// Return the updated driver
 	driver := models.Driver{
 		ID:        driverID,
+		UpdatedBy: actor,
 		DeletedAt: time.Now(),
 	}
 	return driver, nil
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// recordLicense keeps the license history of a driver: unless the license is unchanged, the current
+// entry is closed and a new one opened for the given license.
+func recordLicense(ctx context.Context, tx *sql.Tx, dialect store.Dialect, driverID uuid.UUID, licenseNo string, licenseExpiry time.Time, actor string) error {
+	now := time.Now()
+
+	var currentNo string
+	var currentExpiry time.Time
+	err := tx.QueryRowContext(ctx, `
+		SELECT driver_license_number, license_expiry
+		FROM driver_license_history
+		WHERE driver_id = $1 AND valid_to IS NULL
+		`+dialect.ForUpdate(), driverID).Scan(&currentNo, &currentExpiry)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	default:
+		if currentNo == licenseNo && currentExpiry.Format(time.DateOnly) == licenseExpiry.Format(time.DateOnly) {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE driver_license_history SET valid_to = $1, replaced_by = $2
+			WHERE driver_id = $3 AND valid_to IS NULL
+		`, now, actor, driverID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO driver_license_history (id, driver_id, driver_license_number, license_expiry, valid_from, recorded_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, uuid.New(), driverID, licenseNo, licenseExpiry, now, actor)
+	return err
+}
+
+func (d DriverStore) GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error) {
+	tracer := otel.Tracer("DriverStore")
+	ctx, span := tracer.Start(ctx, "GetDriverLicenses-Store")
+	defer span.End()
+
+	// Parse the driver ID
+	driverID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.Validation("invalid driver id %q", id)
+	}
+
+	// the history is kept for deleted drivers too
+	var exists bool
+	err = d.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM driver WHERE id = $1)`, driverID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.NotFound("driver %s not found", id)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, driver_id, driver_license_number, license_expiry, valid_from, valid_to, recorded_by, COALESCE(replaced_by, '')
+		FROM driver_license_history
+		WHERE driver_id = $1
+		ORDER BY valid_from DESC
+	`, driverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	licenses := []models.DriverLicense{}
+	for rows.Next() {
+		var license models.DriverLicense
+		var validTo sql.NullTime
+		err := rows.Scan(
+			&license.ID,
+			&license.DriverID,
+			&license.DriverLicenseNo,
+			&license.LicenseExpiry,
+			&license.ValidFrom,
+			&validTo,
+			&license.RecordedBy,
+			&license.ReplacedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		license.ValidTo = validTo.Time
+		licenses = append(licenses, license)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
@@ -26,9 +28,15 @@ func (e *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engi
 
 	var engine models.Engine
 
+	// Parse the engine ID
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.Validation("invalid engine id %q", id)
+	}
+
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return engine, nil
+		return engine, err
 	}
 
 	defer func() {
@@ -43,29 +51,29 @@ func (e *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engi
 		}
 	}()
 
-	err = tx.QueryRowContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range
+	err = tx.QueryRowContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range, COALESCE(created_by, ''), COALESCE(updated_by, ''), created_at, updated_at
 	from engine 
 	WHERE id=$1`,
-		id).Scan(&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange)
+		engineID).Scan(&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange, &engine.CreatedBy, &engine.UpdatedBy, &engine.CreatedAt, &engine.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return engine, nil
+			return engine, models.NotFound("engine %s not found", id)
 		}
-		return engine, err
+		return engine, store.DBError(err)
 	}
-	return engine, err
+	return engine, nil
 
 }
 
-func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest, actor string) (models.Engine, error) {
 	tracer := otel.Tracer("EngineStore")
 	ctx, span := tracer.Start(ctx, "CreateEngine-Store")
 	defer span.End()
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, nil
+		return models.Engine{}, err
 	}
 
 	defer func() {
@@ -81,14 +89,15 @@ func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.Engine
 	}()
 
 	engineID := uuid.New()
+	createdAt := time.Now()
 	_, err = tx.ExecContext(ctx,
 		`
-		INSERT INTO engine (id, displacement, no_of_cylinders, car_range)
-		VALUES ($1, $2, $3, $4)
-	`, engineID, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+		INSERT INTO engine (id, displacement, no_of_cylinders, car_range, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, engineID, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, actor, actor, createdAt, createdAt)
 
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.DBError(err)
 	}
 
 	engine := models.Engine{
@@ -96,12 +105,16 @@ func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.Engine
 		Displacement:  engineReq.Displacement,
 		NoOfCylinders: engineReq.NoOfCylinders,
 		CarRange:      engineReq.CarRange,
+		CreatedBy:     actor,
+		UpdatedBy:     actor,
+		CreatedAt:     createdAt,
+		UpdatedAt:     createdAt,
 	}
 
 	return engine, nil
 }
 
-func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error) {
+func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, actor string) (models.Engine, error) {
 	tracer := otel.Tracer("EngineStore")
 	ctx, span := tracer.Start(ctx, "UpdateEngine-Store")
 	defer span.End()
@@ -109,12 +122,12 @@ func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *mo
 	// Parse the engine ID
 	engineID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Engine{}, fmt.Errorf("invalid Engine ID : %w", err)
+		return models.Engine{}, models.Validation("invalid engine id %q", id)
 	}
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, nil
+		return models.Engine{}, err
 	}
 
 	// Defer the rollback or commit
@@ -133,15 +146,16 @@ func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *mo
 	}()
 
 	// Update the engine
+	updatedAt := time.Now()
 	results, err := tx.ExecContext(ctx,
 		`
-	    UPDATE engine SET displacement=$1, no_of_cylinders=$2, car_range=$3
-		WHERE id=$4
+	    UPDATE engine SET displacement=$1, no_of_cylinders=$2, car_range=$3, updated_by=$4, updated_at=$5
+		WHERE id=$6
 		`,
-		engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, engineID)
+		engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, actor, updatedAt, engineID)
 
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.DBError(err)
 	}
 
 	// Check if the row was actually updated
@@ -150,7 +164,7 @@ func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *mo
 		return models.Engine{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Engine{}, errors.New("no rows updated")
+		return models.Engine{}, models.NotFound("engine %s not found", id)
 	}
 
 	// Return the updated engine
@@ -159,6 +173,8 @@ func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *mo
 		Displacement:  engineReq.Displacement,
 		NoOfCylinders: engineReq.NoOfCylinders,
 		CarRange:      engineReq.CarRange,
+		UpdatedBy:     actor,
+		UpdatedAt:     updatedAt,
 	}
 
 	return engine, nil
@@ -174,12 +190,12 @@ func (s *EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engin
 	// Parse the engine ID
 	engineID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Engine{}, fmt.Errorf("invalid Engine ID: %w", err)
+		return models.Engine{}, models.Validation("invalid engine id %q", id)
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, nil
+		return models.Engine{}, err
 	}
 
 	// Defer the rollback or commit
@@ -201,11 +217,11 @@ func (s *EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engin
 	err = tx.QueryRowContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range
 	from engine 
 	WHERE id=$1`,
-		id).Scan(&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange)
+		engineID).Scan(&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return engine, nil
+			return engine, models.NotFound("engine %s not found", id)
 		}
 		return engine, err
 	}
@@ -215,7 +231,7 @@ func (s *EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engin
 		`DELETE FROM  engine WHERE id=$1`, engineID)
 
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := result.RowsAffected()
@@ -223,7 +239,7 @@ func (s *EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engin
 		return models.Engine{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Engine{}, errors.New("no rows were deleted")
+		return models.Engine{}, models.NotFound("engine %s not found", id)
 	}
 
 	return engine, nil
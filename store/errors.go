@@ -0,0 +1,95 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/lib/pq"
+)
+
+// constraintMessages are what clients are told when a write runs into a constraint, by the constraint's postgres
+// name. They name the field but never the values the database reports, those only go to the log.
+var constraintMessages = map[string]string{
+	"user_username_key":                   "username is already taken",
+	"user_email_key":                      "email is already registered",
+	"user_role_check":                     "role must be one of admin, manager, driver, tracker",
+	"refresh_token_user_id_fkey":          "user_id does not refer to an existing user",
+	"refresh_token_token_hash_key":        "token is already issued",
+	"fk_engine_id":                        "engine_id does not refer to an existing engine",
+	"car_status_check":                    "status must be one of Available, In Use, Maintenance, Decommissioned",
+	"fk_user_id":                          "user_id does not refer to an existing user",
+	"driver_driver_license_number_key":    "driver_license_number is already registered to another driver",
+	"fk_driver_id":                        "driver_id does not refer to an existing driver",
+	"fk_car_id":                           "car_id does not refer to an existing car",
+	"trip_status_check":                   "status must be one of Draft, Scheduled, In Progress, Completed, Cancelled",
+	"trip_start_location_id_fkey":         "start_location_id does not refer to an existing location",
+	"trip_end_location_id_fkey":           "end_location_id does not refer to an existing location",
+	"trip_car_no_overlap":                 "the car is already booked for an overlapping trip",
+	"trip_driver_no_overlap":              "the driver is already booked for an overlapping trip",
+	"idx_maintenance_plan_car_type":       "the car already has a maintenance plan for this service_type",
+	"odometer_reading_source_check":       "source must be one of manual, trip_start, trip_end, refuel",
+	"idx_fuel_entry_reference":            "reference is already imported",
+	"car_position_car_id_recorded_at_key": "a position of the car is already stored for this timestamp",
+	"idx_geofence_name":                   "name is already used by another geofence",
+	"idx_location_name":                   "name is already used by another location",
+}
+
+// what clients are told about a constraint that has no message of its own
+const (
+	duplicateFallback = "the request conflicts with an existing record"
+	referenceFallback = "a referenced record does not exist"
+	checkFallback     = "a field is outside its allowed values"
+)
+
+// ConstraintError is the domain error of a write that runs into the named constraint
+func ConstraintError(kind error, constraint string) error {
+	fallback := duplicateFallback
+	if kind == models.ErrValidation {
+		fallback = checkFallback
+	}
+	return constraintError(kind, constraint, fallback, nil)
+}
+
+func constraintError(kind error, constraint string, fallback string, err error) error {
+	message, ok := constraintMessages[constraint]
+	if !ok {
+		message = fallback
+	}
+	return &models.Error{Kind: kind, Message: message, Err: err}
+}
+
+// DBError turns constraint violations and malformed values rejected by postgres or SQLite into domain errors,
+// any other error is returned unchanged. What the database says about the rejected values is logged, clients only
+// get the fixed message of the constraint.
+func DBError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return sqliteError(err)
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation", "exclusion_violation":
+		log.Println("Database rejected a write: ", pqErr.Message, pqErr.Detail)
+		return constraintError(models.ErrConflict, pqErr.Constraint, duplicateFallback, err)
+	case "foreign_key_violation":
+		log.Println("Database rejected a write: ", pqErr.Message, pqErr.Detail)
+		return constraintError(models.ErrConflict, pqErr.Constraint, referenceFallback, err)
+	case "check_violation":
+		log.Println("Database rejected a write: ", pqErr.Message, pqErr.Detail)
+		return constraintError(models.ErrValidation, pqErr.Constraint, checkFallback, err)
+	case "not_null_violation":
+		return &models.Error{Kind: models.ErrValidation, Message: fmt.Sprintf("%s is required", pqErr.Column), Err: err}
+	case "invalid_text_representation", "invalid_datetime_format":
+		log.Println("Database rejected a value: ", pqErr.Message)
+		return &models.Error{Kind: models.ErrValidation, Message: "a field is not in the expected format", Err: err}
+	case "datetime_field_overflow", "numeric_value_out_of_range":
+		log.Println("Database rejected a value: ", pqErr.Message)
+		return &models.Error{Kind: models.ErrValidation, Message: "a field is out of range", Err: err}
+	case "string_data_right_truncation":
+		log.Println("Database rejected a value: ", pqErr.Message)
+		return &models.Error{Kind: models.ErrValidation, Message: "a field is too long", Err: err}
+	}
+	return err
+}
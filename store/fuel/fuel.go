@@ -0,0 +1,442 @@
+package fuel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/odometer"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+const entryColumns = `
+	f.id, f.car_id, f.driver_id, f.trip_id, f.fueled_at, f.liters, f.price_per_liter, f.total_cost, f.station, f.odometer_km, f.notes,
+	COALESCE(f.reference, ''), COALESCE(f.created_by, ''), f.created_at
+`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row scanner) (models.FuelEntry, error) {
+	var entry models.FuelEntry
+	var driverID, tripID uuid.NullUUID
+	err := row.Scan(
+		&entry.ID,
+		&entry.CarID,
+		&driverID,
+		&tripID,
+		&entry.FueledAt,
+		&entry.Liters,
+		&entry.PricePerLiter,
+		&entry.TotalCost,
+		&entry.Station,
+		&entry.OdometerKM,
+		&entry.Notes,
+		&entry.Reference,
+		&entry.CreatedBy,
+		&entry.CreatedAt,
+	)
+	if driverID.Valid {
+		entry.DriverID = &driverID.UUID
+	}
+	if tripID.Valid {
+		entry.TripID = &tripID.UUID
+	}
+	return entry, err
+}
+
+// fuelSortColumns are the fields fuel entries can be sorted by
+var fuelSortColumns = map[string]string{
+	"fueled_at":   "f.fueled_at",
+	"liters":      "f.liters",
+	"total_cost":  "f.total_cost",
+	"odometer_km": "f.odometer_km",
+	"created_at":  "f.created_at",
+}
+
+func (s Store) GetFuelEntries(ctx context.Context, filter models.FuelFilter, opts models.ListOptions) ([]models.FuelEntry, int, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "GetFuelEntries-Store")
+	defer span.End()
+
+	entries := []models.FuelEntry{}
+
+	var q store.ListQuery
+	if filter.CarID != uuid.Nil {
+		q.Where("f.car_id = ?", filter.CarID)
+	}
+	if filter.DriverID != uuid.Nil {
+		q.Where("f.driver_id = ?", filter.DriverID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("f.fueled_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("f.fueled_at < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, fuelSortColumns, "f.fueled_at", "f.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM fuel_entry f ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+entryColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+func (s Store) GetFuelEntryById(ctx context.Context, id string) (models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "GetFuelEntryById-Store")
+	defer span.End()
+
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return models.FuelEntry{}, models.Validation("invalid fuel entry id %q", id)
+	}
+
+	entry, err := scanEntry(s.db.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM fuel_entry f WHERE f.id = $1`, entryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entry, models.NotFound("fuel entry %s not found", id)
+		}
+		return entry, store.DBError(err)
+	}
+	return entry, nil
+}
+
+// CreateFuelEntry records a refuel together with its odometer reading, which has to fit the car's readings
+func (s Store) CreateFuelEntry(ctx context.Context, carID string, fuelReq *models.FuelEntryRequest, actor string) (models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "CreateFuelEntry-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return models.FuelEntry{}, models.Validation("invalid car id %q", carID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.FuelEntry{}, err
+	}
+
+	// Defer the rollback or commit
+	defer func() {
+		// if we find any problem with the transaction, we rollback
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			// if everything is fine, we commit the transaction
+			if cmErr := tx.Commit(); cmErr != nil {
+				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	entry, err := insertEntry(ctx, tx, s.dialect, id, fuelReq, actor)
+	if err != nil {
+		return models.FuelEntry{}, err
+	}
+	return entry, nil
+}
+
+// insertEntry writes a refuel and its odometer reading inside tx
+func insertEntry(ctx context.Context, tx *sql.Tx, dialect store.Dialect, carID uuid.UUID, fuelReq *models.FuelEntryRequest, actor string) (models.FuelEntry, error) {
+	entry, err := scanEntry(tx.QueryRowContext(ctx, `
+		INSERT INTO fuel_entry AS f (id, car_id, driver_id, trip_id, fueled_at, liters, price_per_liter, total_cost, station, odometer_km, notes, reference, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14)
+		`+dialect.Returning("f", entryColumns),
+		uuid.New(),
+		carID,
+		nullUUID(fuelReq.DriverID),
+		nullUUID(fuelReq.TripID),
+		fuelReq.FueledAt,
+		fuelReq.Liters,
+		fuelReq.PricePerLiter,
+		fuelReq.TotalCost,
+		fuelReq.Station,
+		fuelReq.OdometerKM,
+		fuelReq.Notes,
+		fuelReq.Reference,
+		actor,
+		time.Now(),
+	))
+	if err != nil {
+		return models.FuelEntry{}, store.DBError(err)
+	}
+
+	err = odometer.Record(ctx, tx, dialect, &models.OdometerReading{
+		CarID:       entry.CarID,
+		TripID:      entry.TripID,
+		FuelEntryID: &entry.ID,
+		ReadingKM:   entry.OdometerKM,
+		Source:      models.OdometerSourceRefuel,
+		RecordedAt:  entry.FueledAt,
+		RecordedBy:  actor,
+	})
+	if err != nil {
+		return models.FuelEntry{}, err
+	}
+	return entry, nil
+}
+
+// DeleteFuelEntry removes a refuel, its odometer reading goes with it
+func (s Store) DeleteFuelEntry(ctx context.Context, id string) (models.FuelEntry, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "DeleteFuelEntry-Store")
+	defer span.End()
+
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return models.FuelEntry{}, models.Validation("invalid fuel entry id %q", id)
+	}
+
+	entry, err := scanEntry(s.db.QueryRowContext(ctx, `DELETE FROM fuel_entry AS f WHERE f.id = $1 `+s.dialect.Returning("f", entryColumns), entryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entry, models.NotFound("fuel entry %s not found", id)
+		}
+		return entry, store.DBError(err)
+	}
+	return entry, nil
+}
+
+// sqlitePeriods are date_trunc of the refuel time to each of models.FuelIntervals in SQLite, weeks start on Monday
+var sqlitePeriods = map[string]string{
+	"day":   "strftime('%Y-%m-%d 00:00:00', s.fueled_at)",
+	"week":  "date(s.fueled_at, '-6 days', 'weekday 1') || ' 00:00:00'",
+	"month": "strftime('%Y-%m-01 00:00:00', s.fueled_at)",
+	"year":  "strftime('%Y-01-01 00:00:00', s.fueled_at)",
+}
+
+// GetFuelEfficiency sums the refuels of a car or a driver per period. Refuels without a previous refuel of the
+// same car have no distance and are left out.
+func (s Store) GetFuelEfficiency(ctx context.Context, filter models.FuelEfficiencyFilter) ([]models.FuelEfficiency, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "GetFuelEfficiency-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	q.Where("s.distance_km > 0")
+	if filter.CarID != uuid.Nil {
+		q.Where("s.car_id = ?", filter.CarID)
+	}
+	if filter.DriverID != uuid.Nil {
+		q.Where("s.driver_id = ?", filter.DriverID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("s.fueled_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("s.fueled_at < ?", filter.To)
+	}
+
+	// the interval is one of models.FuelIntervals
+	args := append(q.Args(), filter.Interval)
+	period := fmt.Sprintf("date_trunc($%d, s.fueled_at)", len(args))
+	if s.dialect == store.SQLite {
+		var ok bool
+		period, ok = sqlitePeriods[filter.Interval]
+		if !ok {
+			return nil, models.Validation("unknown interval %q", filter.Interval)
+		}
+		args = q.Args()
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s AS period, COUNT(*), SUM(s.liters), SUM(s.total_cost), SUM(s.distance_km)
+		FROM fuel_consumption s
+		%s
+		GROUP BY period
+		ORDER BY period
+	`, period, q.WhereClause()), args...)
+	if err != nil {
+		return nil, store.DBError(err)
+	}
+	defer rows.Close()
+
+	periods := []models.FuelEfficiency{}
+	for rows.Next() {
+		var period models.FuelEfficiency
+		var periodStart store.NullTime
+		err := rows.Scan(&periodStart, &period.Refuels, &period.Liters, &period.Cost, &period.DistanceKM)
+		if err != nil {
+			return nil, err
+		}
+		period.PeriodStart = periodStart.Time
+		periods = append(periods, period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
+
+// anomalySortColumns are the fields fuel anomalies can be sorted by
+var anomalySortColumns = map[string]string{
+	"fueled_at":        "a.fueled_at",
+	"liters_per_100km": "a.rate",
+	"liters":           "a.liters",
+}
+
+// GetFuelAnomalies returns the refuels whose consumption deviates by at least the threshold from the car's other
+// refuels, or from the refuels of the other cars with the same engine. A baseline is only compared against once it
+// has MinSamples refuels.
+func (s Store) GetFuelAnomalies(ctx context.Context, filter models.FuelAnomalyFilter, opts models.ListOptions) ([]models.FuelAnomaly, int, error) {
+	tracer := otel.Tracer("FuelStore")
+	ctx, span := tracer.Start(ctx, "GetFuelAnomalies-Store")
+	defer span.End()
+
+	anomalies := []models.FuelAnomaly{}
+
+	var q store.ListQuery
+	q.Where(`((a.car_refuels >= ? AND ABS(a.rate / a.car_baseline - 1) >= ?)
+		OR (a.engine_refuels >= ? AND ABS(a.rate / a.engine_baseline - 1) >= ?))`,
+		filter.MinSamples, filter.Threshold, filter.MinSamples, filter.Threshold)
+	if filter.CarID != uuid.Nil {
+		q.Where("a.car_id = ?", filter.CarID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("a.fueled_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("a.fueled_at < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, anomalySortColumns, "a.fueled_at", "a.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// every refuel is compared against the car without it, and against the other cars with the same engine
+	from := `
+		FROM (
+			SELECT seg.*,
+				ct.refuels - 1 AS car_refuels,
+				CASE WHEN ct.distance > seg.distance_km THEN (ct.liters - seg.liters) / (ct.distance - seg.distance_km) * 100 END AS car_baseline,
+				et.refuels - ct.refuels AS engine_refuels,
+				CASE WHEN et.distance > ct.distance THEN (et.liters - ct.liters) / (et.distance - ct.distance) * 100 END AS engine_baseline
+			FROM (
+				SELECT s.id, s.car_id, c.engine_id, s.driver_id, s.fueled_at, s.liters, s.distance_km, s.liters / s.distance_km * 100 AS rate
+				FROM fuel_consumption s
+				JOIN car c ON c.id = s.car_id
+				WHERE s.distance_km > 0
+			) seg
+			JOIN (
+				SELECT s.car_id, COUNT(*) AS refuels, SUM(s.liters) AS liters, SUM(s.distance_km) AS distance
+				FROM fuel_consumption s
+				WHERE s.distance_km > 0
+				GROUP BY s.car_id
+			) ct ON ct.car_id = seg.car_id
+			JOIN (
+				SELECT c.engine_id, COUNT(*) AS refuels, SUM(s.liters) AS liters, SUM(s.distance_km) AS distance
+				FROM fuel_consumption s
+				JOIN car c ON c.id = s.car_id
+				WHERE s.distance_km > 0
+				GROUP BY c.engine_id
+			) et ON et.engine_id = seg.engine_id
+		) a
+	` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.car_id, a.engine_id, a.driver_id, a.fueled_at, a.liters, a.distance_km, a.rate,
+			a.car_refuels, a.car_baseline, a.engine_refuels, a.engine_baseline
+	`+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var anomaly models.FuelAnomaly
+		var driverID uuid.NullUUID
+		var carRefuels, engineRefuels int
+		var carBaseline, engineBaseline sql.NullFloat64
+		err := rows.Scan(
+			&anomaly.FuelEntryID,
+			&anomaly.CarID,
+			&anomaly.EngineID,
+			&driverID,
+			&anomaly.FueledAt,
+			&anomaly.Liters,
+			&anomaly.DistanceKM,
+			&anomaly.LitersPer100KM,
+			&carRefuels,
+			&carBaseline,
+			&engineRefuels,
+			&engineBaseline,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		if driverID.Valid {
+			anomaly.DriverID = &driverID.UUID
+		}
+		if carRefuels >= filter.MinSamples {
+			anomaly.CarBaseline = carBaseline.Float64
+		}
+		if engineRefuels >= filter.MinSamples {
+			anomaly.EngineBaseline = engineBaseline.Float64
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return anomalies, total, nil
+}
+
+// nullUUID stores a missing id as NULL
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
@@ -0,0 +1,435 @@
+package geofence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+// geofenceColumns and eventColumns are selected in the order scanGeofence and scanEvent read them
+const geofenceColumns = `
+	g.id, g.name, g.kind, g.shape, g.center_lat, g.center_lon, g.radius_m, g.vertices,
+	COALESCE(g.created_by, ''), COALESCE(g.updated_by, ''), g.created_at, g.updated_at
+`
+
+const eventColumns = `
+	e.id, e.geofence_id, g.name, g.kind, e.car_id, e.trip_id, e.position_id, e.event, e.latitude, e.longitude, e.occurred_at
+`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGeofence(row scanner) (models.Geofence, error) {
+	var geofence models.Geofence
+	var centerLat, centerLon sql.NullFloat64
+	var vertices []byte
+	err := row.Scan(
+		&geofence.ID,
+		&geofence.Name,
+		&geofence.Kind,
+		&geofence.Shape,
+		&centerLat,
+		&centerLon,
+		&geofence.RadiusM,
+		&vertices,
+		&geofence.CreatedBy,
+		&geofence.UpdatedBy,
+		&geofence.CreatedAt,
+		&geofence.UpdatedAt,
+	)
+	if err != nil {
+		return geofence, err
+	}
+	if centerLat.Valid && centerLon.Valid {
+		geofence.Center = &models.Coordinate{Latitude: centerLat.Float64, Longitude: centerLon.Float64}
+	}
+	if err := json.Unmarshal(vertices, &geofence.Vertices); err != nil {
+		return geofence, err
+	}
+	return geofence, nil
+}
+
+func scanEvent(row scanner) (models.GeofenceEvent, error) {
+	var event models.GeofenceEvent
+	var tripID uuid.NullUUID
+	err := row.Scan(
+		&event.ID,
+		&event.GeofenceID,
+		&event.GeofenceName,
+		&event.GeofenceKind,
+		&event.CarID,
+		&tripID,
+		&event.PositionID,
+		&event.Event,
+		&event.Latitude,
+		&event.Longitude,
+		&event.OccurredAt,
+	)
+	if tripID.Valid {
+		event.TripID = &tripID.UUID
+	}
+	return event, err
+}
+
+// shapeArgs are the center, radius and vertices columns of a geofence request, the ones a shape does not use are
+// NULL, 0 and an empty array
+func shapeArgs(geofenceReq *models.GeofenceRequest) (sql.NullFloat64, sql.NullFloat64, float64, []byte, error) {
+	var centerLat, centerLon sql.NullFloat64
+	if geofenceReq.Center != nil {
+		centerLat = sql.NullFloat64{Float64: geofenceReq.Center.Latitude, Valid: true}
+		centerLon = sql.NullFloat64{Float64: geofenceReq.Center.Longitude, Valid: true}
+	}
+	vertices := geofenceReq.Vertices
+	if vertices == nil {
+		vertices = []models.Coordinate{}
+	}
+	encoded, err := json.Marshal(vertices)
+	return centerLat, centerLon, geofenceReq.RadiusM, encoded, err
+}
+
+// geofenceSortColumns are the fields geofences can be sorted by
+var geofenceSortColumns = map[string]string{
+	"name":       "g.name",
+	"kind":       "g.kind",
+	"created_at": "g.created_at",
+	"updated_at": "g.updated_at",
+}
+
+func (s Store) GetGeofences(ctx context.Context, filter models.GeofenceFilter, opts models.ListOptions) ([]models.Geofence, int, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "GetGeofences-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	if filter.Kind != "" {
+		q.Where("g.kind = ?", filter.Kind)
+	}
+
+	orderBy, err := q.OrderBy(opts, geofenceSortColumns, "g.name", "g.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM geofence g ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	geofences, err := queryGeofences(ctx, s.db, `SELECT `+geofenceColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return geofences, total, nil
+}
+
+// GetAllGeofences returns every geofence, positions are checked against all of them
+func (s Store) GetAllGeofences(ctx context.Context) ([]models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "GetAllGeofences-Store")
+	defer span.End()
+
+	return queryGeofences(ctx, s.db, `SELECT `+geofenceColumns+` FROM geofence g ORDER BY g.name, g.id`)
+}
+
+// queryer is a *sql.DB or a *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryGeofences(ctx context.Context, q queryer, query string, args ...interface{}) ([]models.Geofence, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	geofences := []models.Geofence{}
+	for rows.Next() {
+		geofence, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		geofences = append(geofences, geofence)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return geofences, nil
+}
+
+func (s Store) GetGeofenceById(ctx context.Context, id string) (models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "GetGeofenceById-Store")
+	defer span.End()
+
+	geofenceID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Geofence{}, models.Validation("invalid geofence id %q", id)
+	}
+
+	geofence, err := scanGeofence(s.db.QueryRowContext(ctx, `SELECT `+geofenceColumns+` FROM geofence g WHERE g.id = $1`, geofenceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return geofence, models.NotFound("geofence %s not found", id)
+		}
+		return geofence, store.DBError(err)
+	}
+	return geofence, nil
+}
+
+func (s Store) CreateGeofence(ctx context.Context, geofenceReq *models.GeofenceRequest, actor string) (models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "CreateGeofence-Store")
+	defer span.End()
+
+	centerLat, centerLon, radius, vertices, err := shapeArgs(geofenceReq)
+	if err != nil {
+		return models.Geofence{}, err
+	}
+
+	now := time.Now()
+	geofence, err := scanGeofence(s.db.QueryRowContext(ctx, `
+		INSERT INTO geofence AS g (id, name, kind, shape, center_lat, center_lon, radius_m, vertices, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9, $10, $10)
+		`+s.dialect.Returning("g", geofenceColumns),
+		uuid.New(),
+		geofenceReq.Name,
+		geofenceReq.Kind,
+		geofenceReq.Shape,
+		centerLat,
+		centerLon,
+		radius,
+		vertices,
+		actor,
+		now,
+	))
+	if err != nil {
+		return models.Geofence{}, store.DBError(err)
+	}
+	return geofence, nil
+}
+
+func (s Store) UpdateGeofence(ctx context.Context, id string, geofenceReq *models.GeofenceRequest, actor string) (models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "UpdateGeofence-Store")
+	defer span.End()
+
+	geofenceID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Geofence{}, models.Validation("invalid geofence id %q", id)
+	}
+
+	centerLat, centerLon, radius, vertices, err := shapeArgs(geofenceReq)
+	if err != nil {
+		return models.Geofence{}, err
+	}
+
+	geofence, err := scanGeofence(s.db.QueryRowContext(ctx, `
+		UPDATE geofence AS g
+		SET name = $1, kind = $2, shape = $3, center_lat = $4, center_lon = $5, radius_m = $6, vertices = $7, updated_by = $8, updated_at = $9
+		WHERE g.id = $10
+		`+s.dialect.Returning("g", geofenceColumns),
+		geofenceReq.Name,
+		geofenceReq.Kind,
+		geofenceReq.Shape,
+		centerLat,
+		centerLon,
+		radius,
+		vertices,
+		actor,
+		time.Now(),
+		geofenceID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return geofence, models.NotFound("geofence %s not found", id)
+		}
+		return geofence, store.DBError(err)
+	}
+	return geofence, nil
+}
+
+// DeleteGeofence removes a geofence, its events go with it
+func (s Store) DeleteGeofence(ctx context.Context, id string) (models.Geofence, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "DeleteGeofence-Store")
+	defer span.End()
+
+	geofenceID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Geofence{}, models.Validation("invalid geofence id %q", id)
+	}
+
+	geofence, err := scanGeofence(s.db.QueryRowContext(ctx, `DELETE FROM geofence AS g WHERE g.id = $1 `+s.dialect.Returning("g", geofenceColumns), geofenceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return geofence, models.NotFound("geofence %s not found", id)
+		}
+		return geofence, store.DBError(err)
+	}
+	return geofence, nil
+}
+
+// RecordEvents checks newly stored positions of a car against every geofence and stores an event each time the car
+// crosses a boundary. It runs in the transaction that stores the positions, which holds the car row locked, so a
+// batch is only kept along with its events and batches of the same car are evaluated one after the other against
+// the state the previous one left. Positions older than the car's last event arrived too late to tell where the car
+// went and are skipped.
+func RecordEvents(ctx context.Context, tx *sql.Tx, carID uuid.UUID, positions []models.Position) ([]models.GeofenceEvent, error) {
+	geofences, err := queryGeofences(ctx, tx, `SELECT `+geofenceColumns+` FROM geofence g ORDER BY g.name, g.id`)
+	if err != nil || len(geofences) == 0 {
+		return nil, err
+	}
+
+	inside, lastEvent, err := insideGeofences(ctx, tx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	track := []models.Position{}
+	for _, position := range positions {
+		if !position.RecordedAt.Before(lastEvent) {
+			track = append(track, position)
+		}
+	}
+
+	events := models.DetectGeofenceEvents(carID, geofences, inside, track)
+	for _, event := range events {
+		var tripID uuid.NullUUID
+		if event.TripID != nil {
+			tripID = uuid.NullUUID{UUID: *event.TripID, Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO geofence_event (id, geofence_id, car_id, trip_id, position_id, event, latitude, longitude, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, event.ID, event.GeofenceID, event.CarID, tripID, event.PositionID, event.Event, event.Latitude, event.Longitude, event.OccurredAt)
+		if err != nil {
+			return nil, store.DBError(err)
+		}
+	}
+	return events, nil
+}
+
+// insideGeofences returns the geofences a car was last seen entering and has not left since, and the time of its
+// last event in any geofence
+func insideGeofences(ctx context.Context, tx *sql.Tx, carID uuid.UUID) (map[uuid.UUID]bool, time.Time, error) {
+	var lastEvent store.NullTime
+	err := tx.QueryRowContext(ctx, `SELECT MAX(occurred_at) FROM geofence_event WHERE car_id = $1`, carID).Scan(&lastEvent)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT geofence_id
+		FROM (
+			SELECT geofence_id, event,
+				ROW_NUMBER() OVER (PARTITION BY geofence_id ORDER BY occurred_at DESC, position_id DESC) AS n
+			FROM geofence_event
+			WHERE car_id = $1
+		) last_event
+		WHERE n = 1 AND event = $2
+	`, carID, models.GeofenceEventEnter)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	inside := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var geofenceID uuid.UUID
+		if err := rows.Scan(&geofenceID); err != nil {
+			return nil, time.Time{}, err
+		}
+		inside[geofenceID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return inside, lastEvent.Time, nil
+}
+
+// eventSortColumns are the fields geofence events can be sorted by
+var eventSortColumns = map[string]string{
+	"occurred_at": "e.occurred_at",
+	"event":       "e.event",
+}
+
+func (s Store) GetGeofenceEvents(ctx context.Context, filter models.GeofenceEventFilter, opts models.ListOptions) ([]models.GeofenceEvent, int, error) {
+	tracer := otel.Tracer("GeofenceStore")
+	ctx, span := tracer.Start(ctx, "GetGeofenceEvents-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	if filter.GeofenceID != uuid.Nil {
+		q.Where("e.geofence_id = ?", filter.GeofenceID)
+	}
+	if filter.CarID != uuid.Nil {
+		q.Where("e.car_id = ?", filter.CarID)
+	}
+	if filter.TripID != uuid.Nil {
+		q.Where("e.trip_id = ?", filter.TripID)
+	}
+	if filter.Event != "" {
+		q.Where("e.event = ?", filter.Event)
+	}
+	if !filter.From.IsZero() {
+		q.Where("e.occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("e.occurred_at < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, eventSortColumns, "e.occurred_at", "e.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM geofence_event e JOIN geofence g ON g.id = e.geofence_id ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []models.GeofenceEvent{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
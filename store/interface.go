@@ -2,53 +2,123 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
 )
 
 type CarStoreInterface interface {
 	GetCarById(ctx context.Context, id string) (models.Car, error)
-	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
-	CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error)
-	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error)
+	GetCarByRegistrationNumber(ctx context.Context, registrationNumber string) (models.Car, error)
+	SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) ([]models.Car, int, error)
+	CreateCar(ctx context.Context, carReq *models.CarRequest, actor string) (models.Car, error)
+	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, actor string) (models.Car, error)
 	DeleteCar(ctx context.Context, id string) (models.Car, error)
 }
 
 type EngineStoreInterface interface {
 	GetEngineById(ctx context.Context, id string) (models.Engine, error)
-	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error)
-	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error)
+	CreateEngine(ctx context.Context, engineReq *models.EngineRequest, actor string) (models.Engine, error)
+	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, actor string) (models.Engine, error)
 	DeleteEngine(ctx context.Context, id string) (models.Engine, error)
 }
 
 type UserStoreInterface interface {
 	GetUserProfile(ctx context.Context, id string) (models.User, error)
-	CreateUser(ctx context.Context, userReq *models.UserRequest) (models.User, error)
-	UpdateUserProfile(ctx context.Context, id string, userReq *models.UserRequest) (models.User, error)
-	UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest) (models.User, error)
+	CreateUser(ctx context.Context, userReq *models.UserRequest, actor string) (models.User, error)
+	UpdateUserProfile(ctx context.Context, id string, userReq *models.UserRequest, actor string) (models.User, error)
+	UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest, actor string) (models.User, error)
 	DeleteUser(ctx context.Context, id string) (models.User, error)
-	ToggleUserStatus(ctx context.Context, id string, active bool) (models.User, error)
-	GetUsers(ctx context.Context) ([]models.User, error)
+	ToggleUserStatus(ctx context.Context, id string, active bool, actor string) (models.User, error)
+	GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]models.User, int, error)
 	GetUserByUsername(ctx context.Context, username string) (models.User, error)
 }
 
 type DriverStoreInterface interface {
-	GetDrivers(ctx context.Context) ([]models.Driver, error)
+	GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) ([]models.Driver, int, error)
 	GetDriverById(ctx context.Context, id string) (models.Driver, error)
-	CreateDriver(ctx context.Context, driverReq *models.DriverRequest) (models.Driver, error)
-	UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest) (models.Driver, error)
+	CreateDriver(ctx context.Context, driverReq *models.DriverRequest, actor string) (models.Driver, error)
+	UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest, actor string) (models.Driver, error)
 	DeleteDriver(ctx context.Context, id string) (models.Driver, error)
-	SoftDeleteDriver(ctx context.Context, id string) (models.Driver, error)
-	ToggleDriverStatus(ctx context.Context, id string, active bool) (models.Driver, error)
+	SoftDeleteDriver(ctx context.Context, id string, actor string) (models.Driver, error)
+	ToggleDriverStatus(ctx context.Context, id string, active bool, actor string) (models.Driver, error)
+	GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error)
 }
 
 type TripStoreInterface interface {
-	GetTrips(ctx context.Context) ([]models.Trip, error)
-	GetTripsByDriverID(ctx context.Context, id string) ([]models.Trip, error)
-	GetTripsByCarID(ctx context.Context, id string) ([]models.Trip, error)
+	GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) ([]models.Trip, int, error)
 	GetTripById(ctx context.Context, id string) (models.Trip, error)
-	CreateTrip(ctx context.Context, tripReq *models.TripRequest) (models.Trip, error)
-	UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest) (models.Trip, error)
-	UpdateTripStatus(ctx context.Context, id string, status string) (models.Trip, error)
-	DeleteTrip(ctx context.Context, id string) (models.Trip, error)
+	CreateTrip(ctx context.Context, tripReq *models.TripRequest, actor string) (models.Trip, error)
+	UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest, actor string) (models.Trip, error)
+	UpdateTripStatus(ctx context.Context, id string, change models.TripStatusChange, actor string) (models.Trip, error)
+	GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error)
+	DeleteTrip(ctx context.Context, id string, actor string) (models.Trip, error)
+	GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) ([]models.RouteStats, int, error)
+}
+
+type MaintenanceStoreInterface interface {
+	GetMaintenanceRecords(ctx context.Context, carID string, opts models.ListOptions) ([]models.MaintenanceRecord, int, error)
+	GetMaintenanceRecordById(ctx context.Context, id string) (models.MaintenanceRecord, error)
+	CreateMaintenanceRecord(ctx context.Context, carID string, recordReq *models.MaintenanceRecordRequest, actor string) (models.MaintenanceRecord, error)
+	UpdateMaintenanceRecord(ctx context.Context, id string, recordReq *models.MaintenanceRecordRequest, actor string) (models.MaintenanceRecord, error)
+	DeleteMaintenanceRecord(ctx context.Context, id string) (models.MaintenanceRecord, error)
+	GetMaintenancePlans(ctx context.Context, carID string) ([]models.MaintenancePlan, error)
+	GetMaintenancePlanById(ctx context.Context, id string) (models.MaintenancePlan, error)
+	CreateMaintenancePlan(ctx context.Context, carID string, planReq *models.MaintenancePlanRequest, actor string) (models.MaintenancePlan, error)
+	UpdateMaintenancePlan(ctx context.Context, id string, planReq *models.MaintenancePlanRequest, actor string) (models.MaintenancePlan, error)
+	DeleteMaintenancePlan(ctx context.Context, id string) (models.MaintenancePlan, error)
+	GetDueMaintenance(ctx context.Context, filter models.MaintenanceDueFilter, opts models.ListOptions) ([]models.MaintenancePlan, int, error)
+}
+
+type OdometerStoreInterface interface {
+	GetOdometerReadings(ctx context.Context, carID string, filter models.OdometerFilter, opts models.ListOptions) ([]models.OdometerReading, int, error)
+	CreateOdometerReading(ctx context.Context, carID string, readingReq *models.OdometerReadingRequest, actor string) (models.OdometerReading, error)
+}
+
+type FuelStoreInterface interface {
+	GetFuelEntries(ctx context.Context, filter models.FuelFilter, opts models.ListOptions) ([]models.FuelEntry, int, error)
+	GetFuelEntryById(ctx context.Context, id string) (models.FuelEntry, error)
+	CreateFuelEntry(ctx context.Context, carID string, fuelReq *models.FuelEntryRequest, actor string) (models.FuelEntry, error)
+	DeleteFuelEntry(ctx context.Context, id string) (models.FuelEntry, error)
+	GetFuelEfficiency(ctx context.Context, filter models.FuelEfficiencyFilter) ([]models.FuelEfficiency, error)
+	GetFuelAnomalies(ctx context.Context, filter models.FuelAnomalyFilter, opts models.ListOptions) ([]models.FuelAnomaly, int, error)
+}
+
+type PositionStoreInterface interface {
+	CreatePositions(ctx context.Context, carID string, points []models.PositionRequest) ([]models.Position, []models.GeofenceEvent, error)
+	GetPositions(ctx context.Context, filter models.PositionFilter, opts models.ListOptions) ([]models.Position, int, error)
+	GetTripTrack(ctx context.Context, tripID string) ([]models.Position, error)
+}
+
+type GeofenceStoreInterface interface {
+	GetGeofences(ctx context.Context, filter models.GeofenceFilter, opts models.ListOptions) ([]models.Geofence, int, error)
+	GetAllGeofences(ctx context.Context) ([]models.Geofence, error)
+	GetGeofenceById(ctx context.Context, id string) (models.Geofence, error)
+	CreateGeofence(ctx context.Context, geofenceReq *models.GeofenceRequest, actor string) (models.Geofence, error)
+	UpdateGeofence(ctx context.Context, id string, geofenceReq *models.GeofenceRequest, actor string) (models.Geofence, error)
+	DeleteGeofence(ctx context.Context, id string) (models.Geofence, error)
+	GetGeofenceEvents(ctx context.Context, filter models.GeofenceEventFilter, opts models.ListOptions) ([]models.GeofenceEvent, int, error)
+}
+
+type LocationStoreInterface interface {
+	GetLocations(ctx context.Context, filter models.LocationFilter, opts models.ListOptions) ([]models.Location, int, error)
+	GetLocationById(ctx context.Context, id string) (models.Location, error)
+	CreateLocation(ctx context.Context, locationReq *models.LocationRequest, actor string) (models.Location, error)
+	UpdateLocation(ctx context.Context, id string, locationReq *models.LocationRequest, actor string) (models.Location, error)
+	DeleteLocation(ctx context.Context, id string) (models.Location, error)
+}
+
+type TokenStoreInterface interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (models.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) (models.RefreshToken, error)
+	RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeAccessToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type AuditStoreInterface interface {
+	GetHistory(ctx context.Context, resource string, resourceID string, opts models.ListOptions) ([]models.AuditEntry, int, error)
 }
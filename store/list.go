@@ -0,0 +1,61 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JulianaSau/carzone/models"
+)
+
+// ListQuery builds the WHERE, ORDER BY and LIMIT clauses of a list query with numbered placeholders
+type ListQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// Where adds a condition, every ? in it is bound to the next argument
+func (q *ListQuery) Where(condition string, args ...interface{}) {
+	for _, arg := range args {
+		q.args = append(q.args, arg)
+		condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(q.args)), 1)
+	}
+	q.conditions = append(q.conditions, condition)
+}
+
+// WhereClause returns the accumulated conditions joined with AND, or an empty string
+func (q *ListQuery) WhereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// Args returns the arguments bound by Where
+func (q *ListQuery) Args() []interface{} {
+	return q.args
+}
+
+// OrderBy maps opts.Sort to a column through columns, which doubles as the allow list, and falls back to
+// fallback when no sort was requested. tiebreak keeps pages stable when the sort column has duplicates.
+func (q *ListQuery) OrderBy(opts models.ListOptions, columns map[string]string, fallback string, tiebreak string) (string, error) {
+	column := fallback
+	if opts.Sort != "" {
+		var ok bool
+		column, ok = columns[opts.Sort]
+		if !ok {
+			return "", fmt.Errorf("%w %q", models.ErrInvalidSort, opts.Sort)
+		}
+	}
+	// postgres sorts NULLs as the largest values, SQLite as the smallest; both are told to do what postgres does
+	direction, nulls := "ASC", "NULLS LAST"
+	if opts.Desc {
+		direction, nulls = "DESC", "NULLS FIRST"
+	}
+	return fmt.Sprintf("ORDER BY %s %s %s, %s %s", column, direction, nulls, tiebreak, direction), nil
+}
+
+// Page returns the LIMIT and OFFSET clause and binds both values. Call it after the last Where.
+func (q *ListQuery) Page(opts models.ListOptions) (string, []interface{}) {
+	args := append(append([]interface{}{}, q.args...), opts.Limit, opts.Offset)
+	return fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args
+}
@@ -0,0 +1,239 @@
+package location
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// likeEscaper escapes the ILIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+// locationColumns are selected in the order scanLocation reads them
+const locationColumns = `
+	l.id, l.name, COALESCE(l.address, ''), l.latitude, l.longitude, l.type,
+	COALESCE(l.created_by, ''), COALESCE(l.updated_by, ''), l.created_at, l.updated_at
+`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLocation(row scanner) (models.Location, error) {
+	var location models.Location
+	err := row.Scan(
+		&location.ID,
+		&location.Name,
+		&location.Address,
+		&location.Latitude,
+		&location.Longitude,
+		&location.Type,
+		&location.CreatedBy,
+		&location.UpdatedBy,
+		&location.CreatedAt,
+		&location.UpdatedAt,
+	)
+	return location, err
+}
+
+// locationSortColumns are the fields locations can be sorted by
+var locationSortColumns = map[string]string{
+	"name":       "l.name",
+	"type":       "l.type",
+	"created_at": "l.created_at",
+	"updated_at": "l.updated_at",
+}
+
+func (s Store) GetLocations(ctx context.Context, filter models.LocationFilter, opts models.ListOptions) ([]models.Location, int, error) {
+	tracer := otel.Tracer("LocationStore")
+	ctx, span := tracer.Start(ctx, "GetLocations-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	if filter.Type != "" {
+		q.Where("l.type = ?", filter.Type)
+	}
+	if filter.Name != "" {
+		q.Where(s.dialect.ILike("l.name"), "%"+likeEscaper.Replace(filter.Name)+"%")
+	}
+
+	orderBy, err := q.OrderBy(opts, locationSortColumns, "l.name", "l.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM location l ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+locationColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	locations := []models.Location{}
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return locations, total, nil
+}
+
+func (s Store) GetLocationById(ctx context.Context, id string) (models.Location, error) {
+	tracer := otel.Tracer("LocationStore")
+	ctx, span := tracer.Start(ctx, "GetLocationById-Store")
+	defer span.End()
+
+	locationID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Location{}, models.Validation("invalid location id %q", id)
+	}
+
+	location, err := scanLocation(s.db.QueryRowContext(ctx, `SELECT `+locationColumns+` FROM location l WHERE l.id = $1`, locationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return location, models.NotFound("location %s not found", id)
+		}
+		return location, store.DBError(err)
+	}
+	return location, nil
+}
+
+func (s Store) CreateLocation(ctx context.Context, locationReq *models.LocationRequest, actor string) (models.Location, error) {
+	tracer := otel.Tracer("LocationStore")
+	ctx, span := tracer.Start(ctx, "CreateLocation-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Location{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	now := time.Now()
+	location, err := scanLocation(tx.QueryRowContext(ctx, `
+		INSERT INTO location AS l (id, name, address, latitude, longitude, type, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8, $8)
+		`+s.dialect.Returning("l", locationColumns),
+		uuid.New(),
+		strings.TrimSpace(locationReq.Name),
+		locationReq.Address,
+		locationReq.Latitude,
+		locationReq.Longitude,
+		locationReq.Type,
+		actor,
+		now,
+	))
+	if err != nil {
+		err = store.DBError(err)
+		return models.Location{}, err
+	}
+
+	// trips recorded with the name as text before the location existed are linked to it
+	for _, end := range []string{"start", "end"} {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE trip SET %[1]s_location_id = $1
+			WHERE %[1]s_location_id IS NULL AND LOWER(TRIM(%[1]s_location)) = LOWER($2)`, end),
+			location.ID, location.Name)
+		if err != nil {
+			return models.Location{}, err
+		}
+	}
+	return location, nil
+}
+
+// UpdateLocation changes a location. The start_location and end_location text of the trips linked to it is left
+// as it was recorded.
+func (s Store) UpdateLocation(ctx context.Context, id string, locationReq *models.LocationRequest, actor string) (models.Location, error) {
+	tracer := otel.Tracer("LocationStore")
+	ctx, span := tracer.Start(ctx, "UpdateLocation-Store")
+	defer span.End()
+
+	locationID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Location{}, models.Validation("invalid location id %q", id)
+	}
+
+	location, err := scanLocation(s.db.QueryRowContext(ctx, `
+		UPDATE location AS l
+		SET name = $1, address = $2, latitude = $3, longitude = $4, type = $5, updated_by = $6, updated_at = $7
+		WHERE l.id = $8
+		`+s.dialect.Returning("l", locationColumns),
+		strings.TrimSpace(locationReq.Name),
+		locationReq.Address,
+		locationReq.Latitude,
+		locationReq.Longitude,
+		locationReq.Type,
+		actor,
+		time.Now(),
+		locationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return location, models.NotFound("location %s not found", id)
+		}
+		return location, store.DBError(err)
+	}
+	return location, nil
+}
+
+// DeleteLocation removes a location no trip starts or ends at
+func (s Store) DeleteLocation(ctx context.Context, id string) (models.Location, error) {
+	tracer := otel.Tracer("LocationStore")
+	ctx, span := tracer.Start(ctx, "DeleteLocation-Store")
+	defer span.End()
+
+	locationID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Location{}, models.Validation("invalid location id %q", id)
+	}
+
+	location, err := scanLocation(s.db.QueryRowContext(ctx, `DELETE FROM location AS l WHERE l.id = $1 `+s.dialect.Returning("l", locationColumns), locationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return location, models.NotFound("location %s not found", id)
+		}
+		return location, store.DBError(err)
+	}
+	return location, nil
+}
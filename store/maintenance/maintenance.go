@@ -0,0 +1,474 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+// recordColumns and planColumns are selected in the order scanRecord and scanPlan read them
+const recordColumns = `
+	r.id, r.car_id, r.service_date, r.odometer_km, r.service_type, r.cost, r.workshop, r.notes, r.parts,
+	COALESCE(r.created_by, ''), COALESCE(r.updated_by, ''), r.created_at, r.updated_at
+`
+
+const planColumns = `
+	p.id, p.car_id, p.service_type, p.interval_km, p.interval_months, p.baseline_date, p.baseline_odometer_km,
+	p.last_service_date, p.last_service_odometer_km, p.current_odometer_km, p.next_due_date, p.next_due_odometer_km,
+	COALESCE(p.created_by, ''), COALESCE(p.updated_by, ''), p.created_at, p.updated_at
+`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row scanner) (models.MaintenanceRecord, error) {
+	var record models.MaintenanceRecord
+	var parts []byte
+	err := row.Scan(
+		&record.ID,
+		&record.CarID,
+		&record.ServiceDate,
+		&record.OdometerKM,
+		&record.ServiceType,
+		&record.Cost,
+		&record.Workshop,
+		&record.Notes,
+		&parts,
+		&record.CreatedBy,
+		&record.UpdatedBy,
+		&record.CreatedAt,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return record, err
+	}
+	record.Parts = []models.MaintenancePart{}
+	if err := json.Unmarshal(parts, &record.Parts); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+func scanPlan(row scanner) (models.MaintenancePlan, error) {
+	var plan models.MaintenancePlan
+	// the dates the view computes come back as text from SQLite
+	var lastServiceDate, nextDueDate store.NullTime
+	var nextDueOdometer sql.NullFloat64
+	err := row.Scan(
+		&plan.ID,
+		&plan.CarID,
+		&plan.ServiceType,
+		&plan.IntervalKM,
+		&plan.IntervalMonths,
+		&plan.BaselineDate,
+		&plan.BaselineOdometerKM,
+		&lastServiceDate,
+		&plan.LastServiceOdometerKM,
+		&plan.CurrentOdometerKM,
+		&nextDueDate,
+		&nextDueOdometer,
+		&plan.CreatedBy,
+		&plan.UpdatedBy,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	plan.LastServiceDate = lastServiceDate.Time
+	plan.NextDueDate = nextDueDate.Time
+	plan.NextDueOdometerKM = nextDueOdometer.Float64
+	return plan, err
+}
+
+// recordSortColumns are the fields maintenance records can be sorted by
+var recordSortColumns = map[string]string{
+	"service_date": "r.service_date",
+	"odometer_km":  "r.odometer_km",
+	"service_type": "r.service_type",
+	"cost":         "r.cost",
+	"created_at":   "r.created_at",
+}
+
+func (s Store) GetMaintenanceRecords(ctx context.Context, carID string, opts models.ListOptions) ([]models.MaintenanceRecord, int, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "GetMaintenanceRecords-Store")
+	defer span.End()
+
+	records := []models.MaintenanceRecord{}
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, 0, models.Validation("invalid car id %q", carID)
+	}
+
+	var q store.ListQuery
+	q.Where("r.car_id = ?", id)
+
+	orderBy, err := q.OrderBy(opts, recordSortColumns, "r.service_date", "r.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM maintenance_record r ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+recordColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+func (s Store) GetMaintenanceRecordById(ctx context.Context, id string) (models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "GetMaintenanceRecordById-Store")
+	defer span.End()
+
+	recordID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MaintenanceRecord{}, models.Validation("invalid maintenance record id %q", id)
+	}
+
+	record, err := scanRecord(s.db.QueryRowContext(ctx, `SELECT `+recordColumns+` FROM maintenance_record r WHERE r.id = $1`, recordID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return record, models.NotFound("maintenance record %s not found", id)
+		}
+		return record, store.DBError(err)
+	}
+	return record, nil
+}
+
+func (s Store) CreateMaintenanceRecord(ctx context.Context, carID string, recordReq *models.MaintenanceRecordRequest, actor string) (models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "CreateMaintenanceRecord-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return models.MaintenanceRecord{}, models.Validation("invalid car id %q", carID)
+	}
+
+	parts, err := json.Marshal(partsOrEmpty(recordReq.Parts))
+	if err != nil {
+		return models.MaintenanceRecord{}, err
+	}
+
+	now := time.Now()
+	record, err := scanRecord(s.db.QueryRowContext(ctx, `
+		INSERT INTO maintenance_record AS r (id, car_id, service_date, odometer_km, service_type, cost, workshop, notes, parts, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $11)
+		`+s.dialect.Returning("r", recordColumns),
+		uuid.New(),
+		id,
+		recordReq.ServiceDate,
+		recordReq.OdometerKM,
+		recordReq.ServiceType,
+		recordReq.Cost,
+		recordReq.Workshop,
+		recordReq.Notes,
+		parts,
+		actor,
+		now,
+	))
+	if err != nil {
+		return models.MaintenanceRecord{}, store.DBError(err)
+	}
+	return record, nil
+}
+
+func (s Store) UpdateMaintenanceRecord(ctx context.Context, id string, recordReq *models.MaintenanceRecordRequest, actor string) (models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "UpdateMaintenanceRecord-Store")
+	defer span.End()
+
+	recordID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MaintenanceRecord{}, models.Validation("invalid maintenance record id %q", id)
+	}
+
+	parts, err := json.Marshal(partsOrEmpty(recordReq.Parts))
+	if err != nil {
+		return models.MaintenanceRecord{}, err
+	}
+
+	record, err := scanRecord(s.db.QueryRowContext(ctx, `
+		UPDATE maintenance_record AS r
+		SET service_date = $1, odometer_km = $2, service_type = $3, cost = $4, workshop = $5, notes = $6, parts = $7, updated_by = $8, updated_at = $9
+		WHERE r.id = $10
+		`+s.dialect.Returning("r", recordColumns),
+		recordReq.ServiceDate,
+		recordReq.OdometerKM,
+		recordReq.ServiceType,
+		recordReq.Cost,
+		recordReq.Workshop,
+		recordReq.Notes,
+		parts,
+		actor,
+		time.Now(),
+		recordID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return record, models.NotFound("maintenance record %s not found", id)
+		}
+		return record, store.DBError(err)
+	}
+	return record, nil
+}
+
+func (s Store) DeleteMaintenanceRecord(ctx context.Context, id string) (models.MaintenanceRecord, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "DeleteMaintenanceRecord-Store")
+	defer span.End()
+
+	recordID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MaintenanceRecord{}, models.Validation("invalid maintenance record id %q", id)
+	}
+
+	record, err := scanRecord(s.db.QueryRowContext(ctx, `DELETE FROM maintenance_record AS r WHERE r.id = $1 `+s.dialect.Returning("r", recordColumns), recordID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return record, models.NotFound("maintenance record %s not found", id)
+		}
+		return record, store.DBError(err)
+	}
+	return record, nil
+}
+
+func (s Store) GetMaintenancePlans(ctx context.Context, carID string) ([]models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "GetMaintenancePlans-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, models.Validation("invalid car id %q", carID)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+planColumns+`
+		FROM maintenance_plan_status p
+		WHERE p.car_id = $1
+		ORDER BY p.service_type, p.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []models.MaintenancePlan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (s Store) GetMaintenancePlanById(ctx context.Context, id string) (models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "GetMaintenancePlanById-Store")
+	defer span.End()
+
+	planID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MaintenancePlan{}, models.Validation("invalid maintenance plan id %q", id)
+	}
+
+	plan, err := scanPlan(s.db.QueryRowContext(ctx, `SELECT `+planColumns+` FROM maintenance_plan_status p WHERE p.id = $1`, planID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return plan, models.NotFound("maintenance plan %s not found", id)
+		}
+		return plan, store.DBError(err)
+	}
+	return plan, nil
+}
+
+func (s Store) CreateMaintenancePlan(ctx context.Context, carID string, planReq *models.MaintenancePlanRequest, actor string) (models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "CreateMaintenancePlan-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return models.MaintenancePlan{}, models.Validation("invalid car id %q", carID)
+	}
+
+	now := time.Now()
+	baselineDate := planReq.BaselineDate
+	if baselineDate.IsZero() {
+		baselineDate = now
+	}
+
+	planID := uuid.New()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO maintenance_plan (id, car_id, service_type, interval_km, interval_months, baseline_date, baseline_odometer_km, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $9)
+	`, planID, id, planReq.ServiceType, planReq.IntervalKM, planReq.IntervalMonths, baselineDate, planReq.BaselineOdometerKM, actor, now)
+	if err != nil {
+		return models.MaintenancePlan{}, store.DBError(err)
+	}
+
+	return s.GetMaintenancePlanById(ctx, planID.String())
+}
+
+func (s Store) UpdateMaintenancePlan(ctx context.Context, id string, planReq *models.MaintenancePlanRequest, actor string) (models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "UpdateMaintenancePlan-Store")
+	defer span.End()
+
+	planID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MaintenancePlan{}, models.Validation("invalid maintenance plan id %q", id)
+	}
+
+	// the baseline is kept when none is given
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE maintenance_plan
+		SET service_type = $1, interval_km = $2, interval_months = $3,
+			baseline_date = COALESCE($4, baseline_date), baseline_odometer_km = CASE WHEN CAST($4 AS TIMESTAMP) IS NULL THEN baseline_odometer_km ELSE $5 END,
+			updated_by = $6, updated_at = $7
+		WHERE id = $8
+	`, planReq.ServiceType, planReq.IntervalKM, planReq.IntervalMonths, nullTime(planReq.BaselineDate), planReq.BaselineOdometerKM, actor, time.Now(), planID)
+	if err != nil {
+		return models.MaintenancePlan{}, store.DBError(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.MaintenancePlan{}, err
+	}
+	if rowsAffected == 0 {
+		return models.MaintenancePlan{}, models.NotFound("maintenance plan %s not found", id)
+	}
+
+	return s.GetMaintenancePlanById(ctx, id)
+}
+
+func (s Store) DeleteMaintenancePlan(ctx context.Context, id string) (models.MaintenancePlan, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "DeleteMaintenancePlan-Store")
+	defer span.End()
+
+	plan, err := s.GetMaintenancePlanById(ctx, id)
+	if err != nil {
+		return plan, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM maintenance_plan WHERE id = $1`, plan.ID)
+	if err != nil {
+		return plan, store.DBError(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return plan, err
+	}
+	if rowsAffected == 0 {
+		return plan, models.NotFound("maintenance plan %s not found", id)
+	}
+	return plan, nil
+}
+
+// duePlanSortColumns are the fields due plans can be sorted by
+var duePlanSortColumns = map[string]string{
+	"next_due_date":        "p.next_due_date",
+	"next_due_odometer_km": "p.next_due_odometer_km",
+	"service_type":         "p.service_type",
+	"car_id":               "p.car_id",
+}
+
+func (s Store) GetDueMaintenance(ctx context.Context, filter models.MaintenanceDueFilter, opts models.ListOptions) ([]models.MaintenancePlan, int, error) {
+	tracer := otel.Tracer("MaintenanceStore")
+	ctx, span := tracer.Start(ctx, "GetDueMaintenance-Store")
+	defer span.End()
+
+	plans := []models.MaintenancePlan{}
+
+	var q store.ListQuery
+	q.Where("(p.next_due_date <= ? OR p.current_odometer_km + ? >= p.next_due_odometer_km)", filter.DueBy, filter.WithinKM)
+
+	orderBy, err := q.OrderBy(opts, duePlanSortColumns, "p.next_due_date", "p.id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM maintenance_plan_status p ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+planColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return plans, total, nil
+}
+
+// partsOrEmpty stores a record without parts as an empty list rather than null
+func partsOrEmpty(parts []models.MaintenancePart) []models.MaintenancePart {
+	if parts == nil {
+		return []models.MaintenancePart{}
+	}
+	return parts
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// record adds the difference between two snapshots of a row to the audit log, nothing is added when no audited
+// field changed. The caller holds the lock.
+func (db *DB) record(ctx context.Context, resource string, id string, action string, before, after audit.Snapshot) {
+	if entry, ok := audit.NewEntry(ctx, resource, id, action, before, after); ok {
+		db.auditLog = append(db.auditLog, entry)
+	}
+}
+
+type AuditStore struct {
+	db *DB
+}
+
+func NewAuditStore(db *DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// auditSortFields are the fields the history can be sorted by
+var auditSortFields = map[string]order[models.AuditEntry]{
+	"created_at": byTime(func(e models.AuditEntry) time.Time { return e.CreatedAt }),
+}
+
+// GetHistory returns a page of the audit entries of a resource and the total number of entries
+func (a *AuditStore) GetHistory(ctx context.Context, resource string, resourceID string, opts models.ListOptions) ([]models.AuditEntry, int, error) {
+	tracer := otel.Tracer("AuditStore")
+	_, span := tracer.Start(ctx, "GetHistory-Store")
+	defer span.End()
+
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	entries := []models.AuditEntry{}
+	for _, entry := range a.db.auditLog {
+		if entry.Resource == resource && entry.ResourceID == resourceID {
+			entries = append(entries, entry)
+		}
+	}
+
+	page, err := listPage(entries, opts, auditSortFields, "created_at", byID(func(e models.AuditEntry) uuid.UUID { return e.ID }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(entries), nil
+}
@@ -0,0 +1,314 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// carStatuses are the values the car_status_check constraint allows
+var carStatuses = []string{models.CarStatusAvailable, models.CarStatusInUse, models.CarStatusMaintenance, models.CarStatusDecommissioned}
+
+type CarStore struct {
+	db *DB
+}
+
+func NewCarStore(db *DB) CarStore {
+	return CarStore{db: db}
+}
+
+func (s CarStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	_, span := tracer.Start(ctx, "GetCarById-Store")
+	defer span.End()
+
+	carID, err := parseID("car", id)
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	car, ok := s.db.cars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car %s not found", id)
+	}
+	return s.db.carView(car), nil
+}
+
+// GetCarByRegistrationNumber finds a car by its registration number, ignoring case and spaces
+func (s CarStore) GetCarByRegistrationNumber(ctx context.Context, registrationNumber string) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	_, span := tracer.Start(ctx, "GetCarByRegistrationNumber-Store")
+	defer span.End()
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := registrationKey(registrationNumber)
+	var found *models.Car
+	for _, car := range s.db.cars {
+		if registrationKey(car.RegistrationNumber) != key {
+			continue
+		}
+		if found == nil || car.CreatedAt.Before(found.CreatedAt) {
+			found = &car
+		}
+	}
+	if found == nil {
+		return models.Car{}, models.NotFound("car with registration number %s not found", registrationNumber)
+	}
+	return s.db.carView(*found), nil
+}
+
+// registrationKey is the registration number as the car store matches it, in upper case without spaces
+func registrationKey(registrationNumber string) string {
+	return strings.ToUpper(strings.ReplaceAll(registrationNumber, " ", ""))
+}
+
+// carSortFields are the fields cars can be sorted by
+var carSortFields = map[string]order[models.Car]{
+	"name":                byString(func(c models.Car) string { return c.Name }),
+	"registration_number": byString(func(c models.Car) string { return c.RegistrationNumber }),
+	"brand":               byString(func(c models.Car) string { return c.Brand }),
+	"year":                byString(func(c models.Car) string { return c.Year }),
+	"price":               byNumber(func(c models.Car) float64 { return c.Price }),
+	"status":              byString(func(c models.Car) string { return c.Status }),
+	"odometer_km":         byNumber(func(c models.Car) float64 { return c.OdometerKM }),
+	"created_at":          byTime(func(c models.Car) time.Time { return c.CreatedAt }),
+	"updated_at":          byTime(func(c models.Car) time.Time { return c.UpdatedAt }),
+}
+
+func (s CarStore) SearchCars(ctx context.Context, filter models.CarFilter, opts models.ListOptions) ([]models.Car, int, error) {
+	tracer := otel.Tracer("CarStore")
+	_, span := tracer.Start(ctx, "SearchCars-Store")
+	defer span.End()
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	cars := []models.Car{}
+	for _, car := range s.db.cars {
+		car = s.db.carView(car)
+		if matchCar(car, filter) {
+			if !filter.WithEngine {
+				car.Engine = models.Engine{EngineID: car.Engine.EngineID}
+			}
+			cars = append(cars, car)
+		}
+	}
+
+	page, err := listPage(cars, opts, carSortFields, "name", byID(func(c models.Car) uuid.UUID { return c.ID }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(cars), nil
+}
+
+// matchCar applies the filter of SearchCars to a car joined with its engine
+func matchCar(car models.Car, filter models.CarFilter) bool {
+	if filter.Query != "" {
+		query := strings.ToLower(filter.Query)
+		if !strings.Contains(strings.ToLower(car.Name), query) && !strings.Contains(strings.ToLower(car.RegistrationNumber), query) {
+			return false
+		}
+	}
+	if filter.Brand != "" && !strings.EqualFold(car.Brand, filter.Brand) {
+		return false
+	}
+	if filter.FuelType != "" && car.FuelType != filter.FuelType {
+		return false
+	}
+	if filter.Status != "" && car.Status != filter.Status {
+		return false
+	}
+	if filter.YearFrom != 0 || filter.YearTo != 0 {
+		year, err := strconv.Atoi(car.Year)
+		if err != nil {
+			return false
+		}
+		if filter.YearFrom != 0 && year < filter.YearFrom {
+			return false
+		}
+		if filter.YearTo != 0 && year > filter.YearTo {
+			return false
+		}
+	}
+	if filter.PriceMin != 0 && car.Price < filter.PriceMin {
+		return false
+	}
+	if filter.PriceMax != 0 && car.Price > filter.PriceMax {
+		return false
+	}
+	if filter.DisplacementMin != 0 && car.Engine.Displacement < int64(filter.DisplacementMin) {
+		return false
+	}
+	if filter.DisplacementMax != 0 && car.Engine.Displacement > int64(filter.DisplacementMax) {
+		return false
+	}
+	if filter.Cylinders != 0 && car.Engine.NoOfCylinders != int64(filter.Cylinders) {
+		return false
+	}
+	if filter.RangeMin != 0 && car.Engine.CarRange < int64(filter.RangeMin) {
+		return false
+	}
+	if filter.ServiceDue != nil && car.ServiceDue != *filter.ServiceDue {
+		return false
+	}
+	return true
+}
+
+func (s CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest, actor string) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	_, span := tracer.Start(ctx, "CreateCar-Store")
+	defer span.End()
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.engines[carReq.Engine.EngineID]; !ok {
+		return models.Car{}, models.Validation("engine %s does not exist", carReq.Engine.EngineID)
+	}
+	if !slices.Contains(carStatuses, carReq.Status) {
+		return models.Car{}, violates("car_status_check")
+	}
+
+	createdAt := time.Now()
+	car := models.Car{
+		ID:                 uuid.New(),
+		RegistrationNumber: carReq.RegistrationNumber,
+		Name:               carReq.Name,
+		Year:               carReq.Year,
+		Brand:              carReq.Brand,
+		FuelType:           carReq.FuelType,
+		Engine:             models.Engine{EngineID: carReq.Engine.EngineID},
+		Price:              carReq.Price,
+		TankCapacityLiters: carReq.TankCapacityLiters,
+		Status:             carReq.Status,
+		CreatedBy:          actor,
+		UpdatedBy:          actor,
+		CreatedAt:          createdAt,
+		UpdatedAt:          createdAt,
+	}
+	s.db.cars[car.ID] = car
+	return car, nil
+}
+
+func (s CarStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, actor string) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	ctx, span := tracer.Start(ctx, "UpdateCar-Store")
+	defer span.End()
+
+	carID, err := parseID("car", id)
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	car, ok := s.db.cars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car %s not found", id)
+	}
+	if _, ok := s.db.engines[carReq.Engine.EngineID]; !ok {
+		return models.Car{}, missing("fk_engine_id")
+	}
+	if !slices.Contains(carStatuses, carReq.Status) {
+		return models.Car{}, violates("car_status_check")
+	}
+
+	before := carSnapshot(car)
+	car.RegistrationNumber = carReq.RegistrationNumber
+	car.Name = carReq.Name
+	car.Year = carReq.Year
+	car.Brand = carReq.Brand
+	car.FuelType = carReq.FuelType
+	car.Engine = models.Engine{EngineID: carReq.Engine.EngineID}
+	car.Price = carReq.Price
+	car.TankCapacityLiters = carReq.TankCapacityLiters
+	car.Status = carReq.Status
+	car.UpdatedBy = actor
+	car.UpdatedAt = time.Now()
+	s.db.cars[carID] = car
+
+	s.db.record(ctx, models.AuditResourceCar, id, models.AuditActionUpdate, before, carSnapshot(car))
+	return car, nil
+}
+
+// DeleteCar deletes the car along with its trips and odometer readings, as their foreign keys cascade
+func (s CarStore) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	ctx, span := tracer.Start(ctx, "DeleteCar-Store")
+	defer span.End()
+
+	carID, err := parseID("car", id)
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	car, ok := s.db.cars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car %s not found", id)
+	}
+	s.db.deleteCar(carID)
+
+	s.db.record(ctx, models.AuditResourceCar, id, models.AuditActionDelete, carSnapshot(car), nil)
+	return car, nil
+}
+
+func (db *DB) deleteCar(id uuid.UUID) {
+	for tripID, trip := range db.trips {
+		if trip.CarID == id {
+			db.deleteTrip(tripID)
+		}
+	}
+	db.readings = slices.DeleteFunc(db.readings, func(r models.OdometerReading) bool { return r.CarID == id })
+	delete(db.cars, id)
+}
+
+// carView joins a stored car with its engine and its odometer, the way the car store selects it. Service plans are
+// not kept in memory, so no car is ever due for service.
+func (db *DB) carView(car models.Car) models.Car {
+	if engine, ok := db.engines[car.Engine.EngineID]; ok {
+		car.Engine = models.Engine{
+			EngineID:      engine.EngineID,
+			Displacement:  engine.Displacement,
+			NoOfCylinders: engine.NoOfCylinders,
+			CarRange:      engine.CarRange,
+		}
+	}
+	car.OdometerKM = db.odometerKM(car.ID)
+	car.ServiceDue = false
+	return car
+}
+
+func carSnapshot(car models.Car) audit.Snapshot {
+	return snapshot(map[string]interface{}{
+		"id":                   car.ID,
+		"registration_number":  car.RegistrationNumber,
+		"name":                 car.Name,
+		"year":                 car.Year,
+		"brand":                car.Brand,
+		"fuel_type":            car.FuelType,
+		"engine_id":            car.Engine.EngineID,
+		"price":                car.Price,
+		"tank_capacity_liters": car.TankCapacityLiters,
+		"status":               car.Status,
+		"created_by":           nullable(car.CreatedBy),
+		"updated_by":           nullable(car.UpdatedBy),
+		"created_at":           car.CreatedAt,
+		"updated_at":           car.UpdatedAt,
+	})
+}
@@ -0,0 +1,300 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type DriverStore struct {
+	db *DB
+}
+
+func NewDriverStore(db *DB) *DriverStore {
+	return &DriverStore{db: db}
+}
+
+// driverSortFields are the fields drivers can be sorted by
+var driverSortFields = map[string]order[models.Driver]{
+	"driver_license_number": byString(func(d models.Driver) string { return d.DriverLicenseNo }),
+	"license_expiry":        byTime(func(d models.Driver) time.Time { return d.LicenseExpiry }),
+	"username":              byString(func(d models.Driver) string { return d.User.UserName }),
+	"created_at":            byTime(func(d models.Driver) time.Time { return d.CreatedAt }),
+	"updated_at":            byTime(func(d models.Driver) time.Time { return d.UpdatedAt }),
+}
+
+func (d DriverStore) GetDrivers(ctx context.Context, filter models.DriverFilter, opts models.ListOptions) ([]models.Driver, int, error) {
+	tracer := otel.Tracer("DriverStore")
+	_, span := tracer.Start(ctx, "GetDrivers-Store")
+	defer span.End()
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	drivers := []models.Driver{}
+	for _, driver := range d.db.drivers {
+		if !driver.DeletedAt.IsZero() {
+			continue
+		}
+		if filter.Active != nil && driver.Active != *filter.Active {
+			continue
+		}
+		if !filter.LicenseExpiresBefore.IsZero() && !driver.LicenseExpiry.Before(filter.LicenseExpiresBefore) {
+			continue
+		}
+		if !filter.LicenseExpiresAfter.IsZero() && driver.LicenseExpiry.Before(filter.LicenseExpiresAfter) {
+			continue
+		}
+		drivers = append(drivers, d.db.driverView(driver))
+	}
+
+	page, err := listPage(drivers, opts, driverSortFields, "created_at", byID(func(d models.Driver) uuid.UUID { return d.ID }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(drivers), nil
+}
+
+func (d DriverStore) CreateDriver(ctx context.Context, driverReq *models.DriverRequest, actor string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	_, span := tracer.Start(ctx, "CreateDriver-Store")
+	defer span.End()
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	if _, ok := d.db.users[driverReq.UserID]; !ok {
+		return models.Driver{}, models.Validation("user %s does not exist", driverReq.UserID)
+	}
+
+	createdAt := time.Now()
+	driver := models.Driver{
+		ID:              uuid.New(),
+		UserID:          driverReq.UserID,
+		DriverLicenseNo: driverReq.DriverLicenseNo,
+		LicenseExpiry:   dateOnly(driverReq.LicenseExpiry),
+		Active:          true,
+		CreatedBy:       actor,
+		UpdatedBy:       actor,
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+	}
+	if err := d.db.checkDriver(driver); err != nil {
+		return models.Driver{}, err
+	}
+	d.db.drivers[driver.ID] = driver
+	d.db.recordLicense(driver, actor)
+	return driver, nil
+}
+
+func (d DriverStore) UpdateDriver(ctx context.Context, id string, driverReq *models.DriverUpdateRequest, actor string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	ctx, span := tracer.Start(ctx, "UpdateDriver-Store")
+	defer span.End()
+
+	return d.update(ctx, id, actor, models.AuditActionUpdate, func(driver *models.Driver) {
+		driver.DriverLicenseNo = driverReq.DriverLicenseNo
+		driver.LicenseExpiry = dateOnly(driverReq.LicenseExpiry)
+	})
+}
+
+func (d DriverStore) GetDriverById(ctx context.Context, id string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	_, span := tracer.Start(ctx, "GetDriverById-Store")
+	defer span.End()
+
+	driverID, err := parseID("driver", id)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	driver, ok := d.db.drivers[driverID]
+	if !ok || !driver.DeletedAt.IsZero() {
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+	return d.db.driverView(driver), nil
+}
+
+func (d DriverStore) ToggleDriverStatus(ctx context.Context, id string, active bool, actor string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	ctx, span := tracer.Start(ctx, "ToggleDriverStatus-Store")
+	defer span.End()
+
+	return d.update(ctx, id, actor, models.AuditActionUpdate, func(driver *models.Driver) {
+		driver.Active = active
+	})
+}
+
+// SoftDeleteDriver hides the driver from the lists and lookups, its trips and license history are kept
+func (d DriverStore) SoftDeleteDriver(ctx context.Context, id string, actor string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	ctx, span := tracer.Start(ctx, "SoftDeleteDriver-Store")
+	defer span.End()
+
+	return d.update(ctx, id, actor, models.AuditActionDelete, func(driver *models.Driver) {
+		driver.DeletedAt = time.Now()
+	})
+}
+
+// update applies a change to a driver, soft deleted or not as the driver store does, and records it in the license
+// history and the audit log
+func (d DriverStore) update(ctx context.Context, id string, actor string, action string, change func(*models.Driver)) (models.Driver, error) {
+	driverID, err := parseID("driver", id)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	driver, ok := d.db.drivers[driverID]
+	if !ok {
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+
+	before := driverSnapshot(driver)
+	change(&driver)
+	driver.UpdatedBy = actor
+	driver.UpdatedAt = time.Now()
+	if err := d.db.checkDriver(driver); err != nil {
+		return models.Driver{}, err
+	}
+	d.db.drivers[driverID] = driver
+	d.db.recordLicense(driver, actor)
+
+	d.db.record(ctx, models.AuditResourceDriver, driverID.String(), action, before, driverSnapshot(driver))
+	return driver, nil
+}
+
+// checkDriver enforces the unique license numbers of the driver table, soft deleted drivers included
+func (db *DB) checkDriver(driver models.Driver) error {
+	for _, other := range db.drivers {
+		if other.ID != driver.ID && other.DriverLicenseNo == driver.DriverLicenseNo {
+			return duplicate("driver_driver_license_number_key")
+		}
+	}
+	return nil
+}
+
+// DeleteDriver deletes the driver along with their trips and license history, as the foreign keys cascade
+func (d DriverStore) DeleteDriver(ctx context.Context, id string) (models.Driver, error) {
+	tracer := otel.Tracer("DriverStore")
+	ctx, span := tracer.Start(ctx, "DeleteDriver-Store")
+	defer span.End()
+
+	driverID, err := parseID("driver", id)
+	if err != nil {
+		return models.Driver{}, err
+	}
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	driver, ok := d.db.drivers[driverID]
+	if !ok {
+		return models.Driver{}, models.NotFound("driver %s not found", id)
+	}
+	d.db.deleteDriver(driverID)
+
+	d.db.record(ctx, models.AuditResourceDriver, driverID.String(), models.AuditActionDelete, driverSnapshot(driver), nil)
+	return models.Driver{ID: driverID, CreatedAt: driver.CreatedAt}, nil
+}
+
+func (db *DB) deleteDriver(id uuid.UUID) {
+	for tripID, trip := range db.trips {
+		if trip.DriverID == id {
+			db.deleteTrip(tripID)
+		}
+	}
+	db.licenses = slices.DeleteFunc(db.licenses, func(l models.DriverLicense) bool { return l.DriverID == id })
+	delete(db.drivers, id)
+}
+
+// recordLicense keeps the license history of a driver: unless the license is unchanged, the current entry is closed
+// and a new one opened for the driver's license
+func (db *DB) recordLicense(driver models.Driver, actor string) {
+	now := time.Now()
+	for i, license := range db.licenses {
+		if license.DriverID != driver.ID || !license.ValidTo.IsZero() {
+			continue
+		}
+		if license.DriverLicenseNo == driver.DriverLicenseNo && license.LicenseExpiry.Equal(driver.LicenseExpiry) {
+			return
+		}
+		db.licenses[i].ValidTo = now
+		db.licenses[i].ReplacedBy = actor
+	}
+
+	db.licenses = append(db.licenses, models.DriverLicense{
+		ID:              uuid.New(),
+		DriverID:        driver.ID,
+		DriverLicenseNo: driver.DriverLicenseNo,
+		LicenseExpiry:   driver.LicenseExpiry,
+		ValidFrom:       now,
+		RecordedBy:      actor,
+	})
+}
+
+func (d DriverStore) GetDriverLicenses(ctx context.Context, id string) ([]models.DriverLicense, error) {
+	tracer := otel.Tracer("DriverStore")
+	_, span := tracer.Start(ctx, "GetDriverLicenses-Store")
+	defer span.End()
+
+	driverID, err := parseID("driver", id)
+	if err != nil {
+		return nil, err
+	}
+
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	// the history is kept for deleted drivers too
+	if _, ok := d.db.drivers[driverID]; !ok {
+		return nil, models.NotFound("driver %s not found", id)
+	}
+
+	licenses := []models.DriverLicense{}
+	for _, license := range d.db.licenses {
+		if license.DriverID == driverID {
+			licenses = append(licenses, license)
+		}
+	}
+	slices.SortStableFunc(licenses, func(a, b models.DriverLicense) int { return b.ValidFrom.Compare(a.ValidFrom) })
+	return licenses, nil
+}
+
+// driverView joins a stored driver with their user, the way the driver store selects it
+func (db *DB) driverView(driver models.Driver) models.Driver {
+	user := db.users[driver.UserID]
+	driver.User = models.User{
+		ID:        user.ID,
+		UserName:  user.UserName,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+	return driver
+}
+
+func driverSnapshot(driver models.Driver) audit.Snapshot {
+	return snapshot(map[string]interface{}{
+		"id":                    driver.ID,
+		"user_id":               driver.UserID,
+		"driver_license_number": driver.DriverLicenseNo,
+		"license_expiry":        driver.LicenseExpiry.Format(time.DateOnly),
+		"active":                driver.Active,
+		"created_by":            nullable(driver.CreatedBy),
+		"updated_by":            nullable(driver.UpdatedBy),
+		"deleted_at":            nullable(driver.DeletedAt),
+		"created_at":            driver.CreatedAt,
+		"updated_at":            driver.UpdatedAt,
+	})
+}
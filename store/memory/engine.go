@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type EngineStore struct {
+	db *DB
+}
+
+func NewEngineStore(db *DB) *EngineStore {
+	return &EngineStore{db: db}
+}
+
+func (e *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("EngineStore")
+	_, span := tracer.Start(ctx, "GetEngineById-Store")
+	defer span.End()
+
+	engineID, err := parseID("engine", id)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine %s not found", id)
+	}
+	return engine, nil
+}
+
+func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest, actor string) (models.Engine, error) {
+	tracer := otel.Tracer("EngineStore")
+	_, span := tracer.Start(ctx, "CreateEngine-Store")
+	defer span.End()
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	createdAt := time.Now()
+	engine := models.Engine{
+		EngineID:      uuid.New(),
+		Displacement:  engineReq.Displacement,
+		NoOfCylinders: engineReq.NoOfCylinders,
+		CarRange:      engineReq.CarRange,
+		CreatedBy:     actor,
+		UpdatedBy:     actor,
+		CreatedAt:     createdAt,
+		UpdatedAt:     createdAt,
+	}
+	e.db.engines[engine.EngineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, actor string) (models.Engine, error) {
+	tracer := otel.Tracer("EngineStore")
+	_, span := tracer.Start(ctx, "UpdateEngine-Store")
+	defer span.End()
+
+	engineID, err := parseID("engine", id)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine %s not found", id)
+	}
+	engine.Displacement = engineReq.Displacement
+	engine.NoOfCylinders = engineReq.NoOfCylinders
+	engine.CarRange = engineReq.CarRange
+	engine.UpdatedBy = actor
+	engine.UpdatedAt = time.Now()
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+// DeleteEngine deletes the engine along with its cars, as the foreign key of the car table cascades
+func (e *EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("EngineStore")
+	_, span := tracer.Start(ctx, "DeleteEngine-Store")
+	defer span.End()
+
+	engineID, err := parseID("engine", id)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine %s not found", id)
+	}
+	e.db.deleteEngine(engineID)
+	return engine, nil
+}
+
+func (db *DB) deleteEngine(id uuid.UUID) {
+	for carID, car := range db.cars {
+		if car.Engine.EngineID == id {
+			db.deleteCar(carID)
+		}
+	}
+	delete(db.engines, id)
+}
@@ -0,0 +1,167 @@
+// Package memory keeps the cars, engines, users, drivers, trips and sessions of a server in process, for demos that
+// run without a database. The stores follow the rules the postgres schema enforces: unique usernames, emails and
+// license numbers, foreign keys, cascading deletes and soft deleted drivers, and they record the same audit entries.
+// Maintenance, fuel, locations, geofences and positions are not kept; odometer readings are only kept as far as
+// trips record them.
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+)
+
+// DB holds the data of every memory store. Each store method takes the lock for its whole run, the way the
+// postgres stores run in one transaction, so a change and its cascades and audit entries are seen together.
+type DB struct {
+	mu sync.Mutex
+
+	engines       map[uuid.UUID]models.Engine
+	cars          map[uuid.UUID]models.Car
+	users         map[uuid.UUID]models.User
+	drivers       map[uuid.UUID]models.Driver
+	licenses      []models.DriverLicense
+	trips         map[uuid.UUID]models.Trip
+	transitions   []models.TripTransition
+	readings      []models.OdometerReading
+	refreshTokens map[uuid.UUID]models.RefreshToken
+	revokedTokens map[string]time.Time
+	auditLog      []models.AuditEntry
+}
+
+func New() *DB {
+	return &DB{
+		engines:       map[uuid.UUID]models.Engine{},
+		cars:          map[uuid.UUID]models.Car{},
+		users:         map[uuid.UUID]models.User{},
+		drivers:       map[uuid.UUID]models.Driver{},
+		trips:         map[uuid.UUID]models.Trip{},
+		refreshTokens: map[uuid.UUID]models.RefreshToken{},
+		revokedTokens: map[string]time.Time{},
+	}
+}
+
+// parseID reads the id of a resource, rejecting malformed ids the way postgres rejects them for a UUID column
+func parseID(resource string, id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, models.Validation("invalid %s id %q", resource, id)
+	}
+	return parsed, nil
+}
+
+// duplicate is the conflict of a unique constraint, given by its postgres name so the message matches the database
+// stores
+func duplicate(constraint string) error {
+	return store.ConstraintError(models.ErrConflict, constraint)
+}
+
+// missing is the conflict of a foreign key, given by its postgres name
+func missing(constraint string) error {
+	return store.ConstraintError(models.ErrConflict, constraint)
+}
+
+// violates is a failed check constraint, given by its postgres name
+func violates(constraint string) error {
+	return store.ConstraintError(models.ErrValidation, constraint)
+}
+
+// order compares two rows by one field
+type order[T any] func(a, b T) int
+
+// listPage sorts rows by the field opts.Sort names in fields, which doubles as the allow list like the sort columns of
+// the postgres stores, or by fallback when no sort was requested, with tiebreak keeping pages stable. It returns the
+// page opts selects.
+func listPage[T any](rows []T, opts models.ListOptions, fields map[string]order[T], fallback string, tiebreak order[T]) ([]T, error) {
+	compare := fields[fallback]
+	if opts.Sort != "" {
+		var ok bool
+		compare, ok = fields[opts.Sort]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", models.ErrInvalidSort, opts.Sort)
+		}
+	}
+
+	slices.SortStableFunc(rows, func(a, b T) int {
+		c := compare(a, b)
+		if c == 0 {
+			c = tiebreak(a, b)
+		}
+		if opts.Desc {
+			return -c
+		}
+		return c
+	})
+
+	start := min(max(opts.Offset, 0), len(rows))
+	end := min(start+max(opts.Limit, 0), len(rows))
+	return rows[start:end], nil
+}
+
+func byString[T any](field func(T) string) order[T] {
+	return func(a, b T) int { return cmp.Compare(field(a), field(b)) }
+}
+
+func byNumber[T any, N cmp.Ordered](field func(T) N) order[T] {
+	return func(a, b T) int { return cmp.Compare(field(a), field(b)) }
+}
+
+func byTime[T any](field func(T) time.Time) order[T] {
+	return func(a, b T) int { return compareTime(field(a), field(b)) }
+}
+
+func byID[T any](field func(T) uuid.UUID) order[T] {
+	return func(a, b T) int {
+		x, y := field(a), field(b)
+		return bytes.Compare(x[:], y[:])
+	}
+}
+
+// compareTime orders zero times, NULL in postgres, after every other time as postgres does
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+	return a.Compare(b)
+}
+
+// dateOnly keeps the day of t, the way a DATE column stores it
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// snapshot turns a row into the column to value form audit.Capture reads from postgres
+func snapshot(row map[string]interface{}) audit.Snapshot {
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return nil
+	}
+	var s audit.Snapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// nullable maps the zero value to NULL
+func nullable[T comparable](value T) interface{} {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// odometerKM is the highest reading recorded for a car, zero when it has none
+func (db *DB) odometerKM(carID uuid.UUID) float64 {
+	var km float64
+	for _, reading := range db.readings {
+		if reading.CarID == carID {
+			km = max(km, reading.ReadingKM)
+		}
+	}
+	return km
+}
+
+// tripReading builds the odometer reading given when a trip starts or ends, checked against the other readings of
+// the car. It returns nil when the transition carries no reading.
+func (db *DB) tripReading(trip models.Trip, change models.TripStatusChange, actor string) (*models.OdometerReading, error) {
+	if change.OdometerKM == 0 {
+		return nil, nil
+	}
+
+	reading := models.OdometerReading{
+		ID:         uuid.New(),
+		CarID:      trip.CarID,
+		TripID:     &trip.ID,
+		ReadingKM:  change.OdometerKM,
+		Source:     models.OdometerSourceTripStart,
+		RecordedAt: trip.StartTime,
+		RecordedBy: actor,
+	}
+	if change.To == models.TripStatusCompleted {
+		reading.Source = models.OdometerSourceTripEnd
+		reading.RecordedAt = trip.EndTime
+	}
+	if err := db.checkReading(reading); err != nil {
+		return nil, err
+	}
+	return &reading, nil
+}
+
+// checkReading makes sure a reading fits between the readings of the car recorded before and after it
+func (db *DB) checkReading(reading models.OdometerReading) error {
+	var previous, next *models.OdometerReading
+	for _, other := range db.readings {
+		if other.CarID != reading.CarID {
+			continue
+		}
+		if !other.RecordedAt.After(reading.RecordedAt) {
+			if previous == nil || other.RecordedAt.After(previous.RecordedAt) ||
+				(other.RecordedAt.Equal(previous.RecordedAt) && other.ReadingKM > previous.ReadingKM) {
+				previous = &other
+			}
+			continue
+		}
+		if next == nil || other.RecordedAt.Before(next.RecordedAt) ||
+			(other.RecordedAt.Equal(next.RecordedAt) && other.ReadingKM < next.ReadingKM) {
+			next = &other
+		}
+	}
+
+	if previous != nil && previous.ReadingKM > reading.ReadingKM {
+		return backwards(reading, *previous, "below")
+	}
+	if next != nil && next.ReadingKM < reading.ReadingKM {
+		return backwards(reading, *next, "above")
+	}
+	return nil
+}
+
+func backwards(reading models.OdometerReading, other models.OdometerReading, relation string) error {
+	return &models.Error{
+		Kind: models.ErrConflict,
+		Message: fmt.Sprintf("odometer reading of %.1f km at %s is %s the %.1f km recorded at %s",
+			reading.ReadingKM, reading.RecordedAt.Format(time.RFC3339), relation, other.ReadingKM, other.RecordedAt.Format(time.RFC3339)),
+		Details: other,
+	}
+}
+
+// tripStartReading returns the latest reading taken when a trip started, found is false when there is none
+func (db *DB) tripStartReading(tripID uuid.UUID) (models.OdometerReading, bool) {
+	var start models.OdometerReading
+	found := false
+	for _, reading := range db.readings {
+		if reading.TripID == nil || *reading.TripID != tripID || reading.Source != models.OdometerSourceTripStart {
+			continue
+		}
+		if !found || reading.RecordedAt.After(start.RecordedAt) {
+			start, found = reading, true
+		}
+	}
+	return start, found
+}
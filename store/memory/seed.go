@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// demoPassword is the bcrypt hash the demo users of seed.sql share
+const demoPassword = "$2a$14$mvWNjPutN.zuLr9GyLft0uLOgZdX2msNBq2ELbExc9.bKi09dPXoC"
+
+// Seed loads the demo data of store/migrations/postgres/seed.sql. Rows that already exist are left alone, so it can be loaded
+// again.
+func Seed(db *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	admin := "d3b07384-d9a1-4c4b-8a0d-4b1b1b1b1b1b"
+
+	for _, engine := range []models.Engine{
+		{EngineID: uuid.MustParse("e1f86b1a-0873-4c19-bae2-fc60329d0140"), Displacement: 2000, NoOfCylinders: 4, CarRange: 600},
+		{EngineID: uuid.MustParse("f4a9c66b-8e38-419b-93c4-215d5cefb318"), Displacement: 1600, NoOfCylinders: 4, CarRange: 550},
+		{EngineID: uuid.MustParse("cc2c2a7d-2e21-4f59-b7b8-bd9e5e4cf04c"), Displacement: 3000, NoOfCylinders: 6, CarRange: 700},
+		{EngineID: uuid.MustParse("9746be12-07b7-42a3-b8ab-7d1f209b63d7"), Displacement: 1800, NoOfCylinders: 4, CarRange: 500},
+	} {
+		if _, ok := db.engines[engine.EngineID]; !ok {
+			engine.CreatedAt, engine.UpdatedAt = now, now
+			db.engines[engine.EngineID] = engine
+		}
+	}
+
+	for _, user := range []models.User{
+		{ID: uuid.MustParse(admin), UserName: "admin", FirstName: "System", LastName: "Admin", Email: "admin@carmanagement.com", PhoneNumber: "244707070707", Role: models.RoleAdmin},
+		{ID: uuid.MustParse("e4c2f3a5-e5b2-4d5c-9b2e-5c2c2c2c2c2c"), UserName: "manager", FirstName: "System", LastName: "Manager", Email: "manager@carmanagement.com", PhoneNumber: "244707070706", Role: models.RoleManager},
+		{ID: uuid.MustParse("f5d3e4b6-f6c3-4e6d-ac3f-6d3d3d3d3d3d"), UserName: "driver", FirstName: "System", LastName: "Driver", Email: "driver@carmanagement.com", PhoneNumber: "244707070708", Role: models.RoleDriver},
+	} {
+		if _, ok := db.users[user.ID]; !ok {
+			user.Password = demoPassword
+			user.Active = true
+			user.CreatedBy = admin
+			user.CreatedAt, user.UpdatedAt = now, now
+			db.users[user.ID] = user
+		}
+	}
+
+	for _, car := range []models.Car{
+		{ID: uuid.MustParse("c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"), RegistrationNumber: "KCX 786T", Name: "Honda Civic", Year: "2023", Brand: "Honda", FuelType: "Gasoline", Engine: models.Engine{EngineID: uuid.MustParse("e1f86b1a-0873-4c19-bae2-fc60329d0140")}, Price: 25000},
+		{ID: uuid.MustParse("9d6a56f8-79c3-4931-a5c0-6b290c84ba2f"), RegistrationNumber: "KCZ 883J", Name: "Toyota Corolla", Year: "2022", Brand: "Toyota", FuelType: "Gasoline", Engine: models.Engine{EngineID: uuid.MustParse("f4a9c66b-8e38-419b-93c4-215d5cefb318")}, Price: 22000},
+		{ID: uuid.MustParse("9b9437c4-3ed1-45a5-b240-0fe3e24e0e4e"), RegistrationNumber: "KBX 284P", Name: "Ford Mustang", Year: "2024", Brand: "Ford", FuelType: "Gasoline", Engine: models.Engine{EngineID: uuid.MustParse("cc2c2a7d-2e21-4f59-b7b8-bd9e5e4cf04c")}, Price: 40000},
+		{ID: uuid.MustParse("5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06"), RegistrationNumber: "KDC 376C", Name: "BMW 3 Series", Year: "2023", Brand: "BMW", FuelType: "Gasoline", Engine: models.Engine{EngineID: uuid.MustParse("9746be12-07b7-42a3-b8ab-7d1f209b63d7")}, Price: 35000},
+	} {
+		if _, ok := db.cars[car.ID]; !ok {
+			car.Status = models.CarStatusAvailable
+			car.CreatedAt, car.UpdatedAt = now, now
+			db.cars[car.ID] = car
+		}
+	}
+
+	expiry := time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+	for _, driver := range []models.Driver{
+		{ID: uuid.MustParse("a1b2c3d4-e5f6-7a8b-9c0d-e1f2a3b4c5d6"), UserID: uuid.MustParse("f5d3e4b6-f6c3-4e6d-ac3f-6d3d3d3d3d3d"), DriverLicenseNo: "DL123456"},
+		{ID: uuid.MustParse("b2c3d4e5-f6c3-4e6d-ac3f-6d3d3d3d3d3d"), UserID: uuid.MustParse("e4c2f3a5-e5b2-4d5c-9b2e-5c2c2c2c2c2c"), DriverLicenseNo: "DL789101"},
+	} {
+		if _, ok := db.drivers[driver.ID]; !ok {
+			driver.LicenseExpiry = expiry
+			driver.Active = true
+			driver.CreatedAt, driver.UpdatedAt = now, now
+			db.drivers[driver.ID] = driver
+			// the seeded drivers start their license history with their current license
+			db.recordLicense(driver, "")
+		}
+	}
+
+	for _, trip := range []models.Trip{
+		{ID: uuid.MustParse("05c938c5-48d9-4148-82a3-934646464646"), Description: "Nairobi To Mombasa Route", DriverID: uuid.MustParse("a1b2c3d4-e5f6-7a8b-9c0d-e1f2a3b4c5d6"), CarID: uuid.MustParse("c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"), StartLocation: "Nairobi", EndLocation: "Mombasa", StartTime: time.Date(2023, time.December, 31, 8, 0, 0, 0, time.UTC), Status: models.TripStatusCompleted},
+		{ID: uuid.MustParse("b5c6d7e8-f9a0-1b2c-3d4e-f5a6b7c8d9e0"), Description: "Kisumu To Mombasa Route", DriverID: uuid.MustParse("b2c3d4e5-f6c3-4e6d-ac3f-6d3d3d3d3d3d"), CarID: uuid.MustParse("5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06"), StartLocation: "Kisumu", EndLocation: "Mombasa", StartTime: time.Date(2024, time.January, 1, 10, 54, 0, 0, time.UTC), Status: models.TripStatusCompleted},
+		{ID: uuid.MustParse("d1e2f3a4-b5c6-7d8e-9f0a-b1c2d3e4f5a6"), Description: "Eldoret To Mombasa Route", DriverID: uuid.MustParse("b2c3d4e5-f6c3-4e6d-ac3f-6d3d3d3d3d3d"), CarID: uuid.MustParse("9b9437c4-3ed1-45a5-b240-0fe3e24e0e4e"), StartLocation: "Eldoret", EndLocation: "Mombasa", StartTime: time.Date(2025, time.January, 27, 9, 0, 0, 0, time.UTC), Status: models.TripStatusInProgress},
+		{ID: uuid.MustParse("c3d4e5f6-a7b8-9c0d-1e2f-3a4b5c6d7e8f"), Description: "Kisii To Nairobi Route", DriverID: uuid.MustParse("a1b2c3d4-e5f6-7a8b-9c0d-e1f2a3b4c5d6"), CarID: uuid.MustParse("5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06"), StartLocation: "Kisii", EndLocation: "Nairobi", StartTime: time.Date(2025, time.January, 27, 6, 0, 0, 0, time.UTC), Status: models.TripStatusInProgress},
+	} {
+		if _, ok := db.trips[trip.ID]; !ok {
+			trip.CreatedAt, trip.UpdatedAt = now, now
+			db.trips[trip.ID] = trip
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type TokenStore struct {
+	db *DB
+}
+
+func NewTokenStore(db *DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+func (t *TokenStore) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "CreateRefreshToken-Store")
+	defer span.End()
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	if _, ok := t.db.users[token.UserID]; !ok {
+		return models.RefreshToken{}, missing("refresh_token_user_id_fkey")
+	}
+	if err := t.db.checkToken(*token); err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+	t.db.refreshTokens[token.ID] = *token
+	return *token, nil
+}
+
+func (t *TokenStore) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "GetRefreshToken-Store")
+	defer span.End()
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	for _, token := range t.db.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, models.ErrInvalidRefreshToken
+}
+
+// checkToken enforces the unique token hashes of the refresh_token table
+func (db *DB) checkToken(token models.RefreshToken) error {
+	for _, other := range db.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			return duplicate("refresh_token_token_hash_key")
+		}
+	}
+	return nil
+}
+
+// RotateRefreshToken revokes the presented token and stores its replacement. Only a token that is still active is
+// rotated, so two concurrent refreshes cannot both win.
+func (t *TokenStore) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "RotateRefreshToken-Store")
+	defer span.End()
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	old, ok := t.db.refreshTokens[oldID]
+	if !ok || !old.RevokedAt.IsZero() {
+		return models.RefreshToken{}, models.ErrRefreshTokenReused
+	}
+	if _, ok := t.db.users[next.UserID]; !ok {
+		return models.RefreshToken{}, missing("refresh_token_user_id_fkey")
+	}
+	if err := t.db.checkToken(*next); err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	next.ID = uuid.New()
+	next.CreatedAt = time.Now()
+	old.RevokedAt = next.CreatedAt
+	old.ReplacedBy = next.ID
+	t.db.refreshTokens[oldID] = old
+	t.db.refreshTokens[next.ID] = *next
+	return *next, nil
+}
+
+// RevokeTokenFamily revokes every refresh token that descends from the same login
+func (t *TokenStore) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "RevokeTokenFamily-Store")
+	defer span.End()
+
+	t.db.revokeTokens(func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+// RevokeUserTokens revokes every refresh token of the user, e.g. when the account is deactivated
+func (t *TokenStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "RevokeUserTokens-Store")
+	defer span.End()
+
+	t.db.revokeTokens(func(token models.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+// revokeTokens revokes the active refresh tokens match selects
+func (db *DB) revokeTokens(match func(models.RefreshToken) bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range db.refreshTokens {
+		if token.RevokedAt.IsZero() && match(token) {
+			token.RevokedAt = now
+			db.refreshTokens[id] = token
+		}
+	}
+}
+
+func (t *TokenStore) RevokeAccessToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "RevokeAccessToken-Store")
+	defer span.End()
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	if _, ok := t.db.revokedTokens[jti]; !ok {
+		t.db.revokedTokens[jti] = expiresAt
+	}
+
+	// expired tokens are rejected anyway, so there is no need to keep them on the deny list
+	now := time.Now()
+	for revoked, expiry := range t.db.revokedTokens {
+		if expiry.Before(now) {
+			delete(t.db.revokedTokens, revoked)
+		}
+	}
+	return nil
+}
+
+func (t *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	tracer := otel.Tracer("TokenStore")
+	_, span := tracer.Start(ctx, "IsAccessTokenRevoked-Store")
+	defer span.End()
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	_, revoked := t.db.revokedTokens[jti]
+	return revoked, nil
+}
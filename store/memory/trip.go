@@ -0,0 +1,620 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// tripStatuses are the values the trip_status_check constraint allows
+var tripStatuses = []string{models.TripStatusDraft, models.TripStatusScheduled, models.TripStatusInProgress, models.TripStatusCompleted, models.TripStatusCancelled}
+
+// bookingStatuses are the trip statuses that hold the car and the driver for the trip's time window
+var bookingStatuses = []string{models.TripStatusScheduled, models.TripStatusInProgress}
+
+// unavailableCarStatuses are the car statuses that cannot take trips
+var unavailableCarStatuses = []string{models.CarStatusMaintenance, models.CarStatusDecommissioned}
+
+type TripStore struct {
+	db *DB
+}
+
+func NewTripStore(db *DB) *TripStore {
+	return &TripStore{db: db}
+}
+
+// tripSortFields are the fields trips can be sorted by
+var tripSortFields = map[string]order[models.Trip]{
+	"start_time":  byTime(func(t models.Trip) time.Time { return t.StartTime }),
+	"end_time":    byTime(func(t models.Trip) time.Time { return t.EndTime }),
+	"status":      byString(func(t models.Trip) string { return t.Status }),
+	"distance_km": byNumber(func(t models.Trip) float64 { return t.DistanceKM }),
+	"created_at":  byTime(func(t models.Trip) time.Time { return t.CreatedAt }),
+	"updated_at":  byTime(func(t models.Trip) time.Time { return t.UpdatedAt }),
+}
+
+func (e *TripStore) GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) ([]models.Trip, int, error) {
+	tracer := otel.Tracer("TripStore")
+	_, span := tracer.Start(ctx, "GetTrips-Store")
+	defer span.End()
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	trips := []models.Trip{}
+	for _, trip := range e.db.trips {
+		if matchTrip(trip, filter) {
+			trips = append(trips, trip)
+		}
+	}
+
+	page, err := listPage(trips, opts, tripSortFields, "start_time", byID(func(t models.Trip) uuid.UUID { return t.ID }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(trips), nil
+}
+
+func matchTrip(trip models.Trip, filter models.TripFilter) bool {
+	if filter.Status != "" && trip.Status != filter.Status {
+		return false
+	}
+	if filter.CarID != uuid.Nil && trip.CarID != filter.CarID {
+		return false
+	}
+	if filter.DriverID != uuid.Nil && trip.DriverID != filter.DriverID {
+		return false
+	}
+	if filter.StartLocationID != uuid.Nil && (trip.StartLocationID == nil || *trip.StartLocationID != filter.StartLocationID) {
+		return false
+	}
+	if filter.EndLocationID != uuid.Nil && (trip.EndLocationID == nil || *trip.EndLocationID != filter.EndLocationID) {
+		return false
+	}
+	if !filter.From.IsZero() && trip.StartTime.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !trip.StartTime.Before(filter.To) {
+		return false
+	}
+	if !filter.ActiveAt.IsZero() {
+		// a trip in progress runs until it is completed, even past its planned end time
+		switch trip.Status {
+		case models.TripStatusInProgress:
+			if trip.StartTime.After(filter.ActiveAt) {
+				return false
+			}
+		case models.TripStatusCompleted:
+			start, end := tripWindow(trip)
+			if filter.ActiveAt.Before(start) || !filter.ActiveAt.Before(end) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// openEnded stands in for the unbounded upper end of a trip window
+var openEnded = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// tripWindow is the time a trip holds its car and driver, from its start up to its end. A trip without an end time,
+// or one started after its planned end time, is open ended.
+func tripWindow(trip models.Trip) (time.Time, time.Time) {
+	end := trip.EndTime
+	if end.IsZero() || !end.After(trip.StartTime) {
+		end = openEnded
+	}
+	return trip.StartTime, end
+}
+
+func (e *TripStore) GetTripById(ctx context.Context, id string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	_, span := tracer.Start(ctx, "GetTripById-Store")
+	defer span.End()
+
+	tripID, err := parseID("trip", id)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	trip, ok := e.db.trips[tripID]
+	if !ok {
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
+	return trip, nil
+}
+
+func (e *TripStore) CreateTrip(ctx context.Context, tripReq *models.TripRequest, actor string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	_, span := tracer.Start(ctx, "CreateTrip-Store")
+	defer span.End()
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	if err := resolveLocations(tripReq); err != nil {
+		return models.Trip{}, err
+	}
+
+	createdAt := time.Now()
+	trip := models.Trip{
+		ID:        uuid.New(),
+		CreatedAt: createdAt,
+		CreatedBy: actor,
+	}
+	applyTripRequest(&trip, tripReq, actor, createdAt)
+
+	if err := e.db.checkTrip(trip); err != nil {
+		return models.Trip{}, err
+	}
+	e.db.trips[trip.ID] = trip
+	return trip, nil
+}
+
+func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest, actor string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "UpdateTrip-Store")
+	defer span.End()
+
+	tripID, err := parseID("trip", id)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	trip, ok := e.db.trips[tripID]
+	if !ok {
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
+	if err := resolveLocations(tripReq); err != nil {
+		return models.Trip{}, err
+	}
+
+	previous := trip
+	before := tripSnapshot(trip)
+	applyTripRequest(&trip, tripReq, actor, time.Now())
+	if err := e.db.checkTrip(trip); err != nil {
+		return models.Trip{}, err
+	}
+	e.db.trips[tripID] = trip
+
+	e.db.record(ctx, models.AuditResourceTrip, tripID.String(), models.AuditActionUpdate, before, tripSnapshot(trip))
+
+	// a trip in progress moved to another car hands the car over
+	if trip.CarID != previous.CarID {
+		e.db.releaseCar(ctx, previous, actor)
+		if trip.Status == models.TripStatusInProgress {
+			e.db.syncCarStatus(ctx, trip, actor)
+		}
+	}
+	return trip, nil
+}
+
+func applyTripRequest(trip *models.Trip, tripReq *models.TripRequest, actor string, updatedAt time.Time) {
+	trip.Description = tripReq.Description
+	trip.DriverID = tripReq.DriverID
+	trip.CarID = tripReq.CarID
+	trip.StartLocation = tripReq.StartLocation
+	trip.EndLocation = tripReq.EndLocation
+	trip.StartLocationID = tripReq.StartLocationID
+	trip.EndLocationID = tripReq.EndLocationID
+	trip.StartTime = tripReq.StartTime
+	trip.EndTime = tripReq.EndTime
+	trip.DistanceKM = tripReq.DistanceKM
+	trip.FuelConsumedLiters = tripReq.FuelConsumedLiters
+	trip.Status = tripReq.Status
+	trip.UpdatedAt = updatedAt
+	trip.UpdatedBy = actor
+}
+
+// resolveLocations keeps the text of the endpoints of a trip. Locations are not kept in memory, so an endpoint given
+// by location does not exist and one given as text is not linked to any.
+func resolveLocations(tripReq *models.TripRequest) error {
+	if tripReq.StartLocationID != nil {
+		return models.Validation("start location %s does not exist", *tripReq.StartLocationID)
+	}
+	if tripReq.EndLocationID != nil {
+		return models.Validation("end location %s does not exist", *tripReq.EndLocationID)
+	}
+	return nil
+}
+
+// checkTrip enforces the foreign keys and the allowed statuses of the trip table, then checks the booking
+func (db *DB) checkTrip(trip models.Trip) error {
+	if _, ok := db.drivers[trip.DriverID]; !ok {
+		return missing("fk_driver_id")
+	}
+	if _, ok := db.cars[trip.CarID]; !ok {
+		return missing("fk_car_id")
+	}
+	if !slices.Contains(tripStatuses, trip.Status) {
+		return violates("trip_status_check")
+	}
+	return db.checkBooking(trip)
+}
+
+// checkBooking makes sure the car of a scheduled or running trip can take it and that neither the car nor the driver
+// is booked on another trip whose time window overlaps
+func (db *DB) checkBooking(trip models.Trip) error {
+	if !slices.Contains(bookingStatuses, trip.Status) {
+		return nil
+	}
+
+	car, ok := db.cars[trip.CarID]
+	if !ok {
+		return models.Validation("car %s does not exist", trip.CarID)
+	}
+	if slices.Contains(unavailableCarStatuses, car.Status) {
+		return models.Conflict("car %s is in %s and cannot take trips", trip.CarID, car.Status)
+	}
+
+	start, end := tripWindow(trip)
+	var other *models.Trip
+	for _, t := range db.trips {
+		if t.ID == trip.ID || (t.CarID != trip.CarID && t.DriverID != trip.DriverID) || !slices.Contains(bookingStatuses, t.Status) {
+			continue
+		}
+		otherStart, otherEnd := tripWindow(t)
+		if !otherStart.Before(end) || !start.Before(otherEnd) {
+			continue
+		}
+		if other == nil || t.StartTime.Before(other.StartTime) {
+			other = &t
+		}
+	}
+	if other == nil {
+		return nil
+	}
+
+	booked := fmt.Sprintf("driver %s", trip.DriverID)
+	if other.CarID == trip.CarID {
+		booked = fmt.Sprintf("car %s", trip.CarID)
+	}
+	return &models.Error{
+		Kind:    models.ErrConflict,
+		Message: fmt.Sprintf("%s is already booked on trip %s at that time", booked, other.ID),
+		Details: booking(*other),
+	}
+}
+
+// booking is the part of a trip a booking conflict reports
+func booking(trip models.Trip) models.TripBooking {
+	b := models.TripBooking{ID: trip.ID, StartTime: trip.StartTime}
+	if !trip.EndTime.IsZero() {
+		b.EndTime = &trip.EndTime
+	}
+	return b
+}
+
+func (e *TripStore) UpdateTripStatus(ctx context.Context, id string, change models.TripStatusChange, actor string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "UpdateTripStatus-Store")
+	defer span.End()
+
+	tripID, err := parseID("trip", id)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	// only move the trip if nobody moved it out of the status the transition was checked against
+	trip, ok := e.db.trips[tripID]
+	if !ok {
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
+	if trip.Status != change.From {
+		return models.Trip{}, models.Conflict("trip %s is no longer %s", id, change.From)
+	}
+	if !slices.Contains(tripStatuses, change.To) {
+		return models.Trip{}, violates("trip_status_check")
+	}
+
+	before := tripSnapshot(trip)
+	now := time.Now()
+	trip.Status = change.To
+	if !change.StartTime.IsZero() {
+		trip.StartTime = change.StartTime
+	}
+	if !change.EndTime.IsZero() {
+		trip.EndTime = change.EndTime
+	}
+	if change.DistanceKM != 0 {
+		trip.DistanceKM = change.DistanceKM
+	}
+	if change.FuelConsumedLiters != 0 {
+		trip.FuelConsumedLiters = change.FuelConsumedLiters
+	}
+	trip.UpdatedAt = now
+	trip.UpdatedBy = actor
+
+	// every check runs before anything is written, so a failed change leaves no trace
+	reading, err := e.db.tripReading(trip, change, actor)
+	if err != nil {
+		return models.Trip{}, err
+	}
+	if err := e.db.tripDistance(&trip, change); err != nil {
+		return models.Trip{}, err
+	}
+	if err := e.db.checkBooking(trip); err != nil {
+		return models.Trip{}, err
+	}
+
+	if reading != nil {
+		e.db.readings = append(e.db.readings, *reading)
+	}
+	e.db.trips[tripID] = trip
+	e.db.syncCarStatus(ctx, trip, actor)
+	e.db.transitions = append(e.db.transitions, models.TripTransition{
+		ID:         uuid.New(),
+		TripID:     tripID,
+		FromStatus: change.From,
+		ToStatus:   change.To,
+		Reason:     change.Reason,
+		Actor:      actor,
+		CreatedAt:  now,
+	})
+
+	e.db.record(ctx, models.AuditResourceTrip, tripID.String(), models.AuditActionUpdate, before, tripSnapshot(trip))
+	return trip, nil
+}
+
+// tripDistance settles the distance of a trip that is being completed. When the trip has start and end odometer
+// readings its distance is the difference between them, and a distance given with the transition must agree with
+// it. Otherwise a given distance is kept. GPS tracks are not kept in memory. A trip with neither keeps the distance
+// it was created with, if any.
+func (db *DB) tripDistance(trip *models.Trip, change models.TripStatusChange) error {
+	if change.To != models.TripStatusCompleted {
+		return nil
+	}
+
+	if change.OdometerKM != 0 {
+		if start, found := db.tripStartReading(trip.ID); found {
+			distanceKM := change.OdometerKM - start.ReadingKM
+			if err := models.CheckOdometerDistance(trip.ID, change.DistanceKM, distanceKM); err != nil {
+				return err
+			}
+			trip.DistanceKM = distanceKM
+			return nil
+		}
+	}
+
+	if change.DistanceKM > 0 {
+		return nil
+	}
+
+	if trip.DistanceKM <= 0 {
+		return models.Validation("distance_km is required, trip %s has neither a start odometer reading nor a GPS track", trip.ID)
+	}
+	return nil
+}
+
+// syncCarStatus keeps the status of the trip's car in step with the trip: the car is In Use while the trip is In
+// Progress and Available again once it is completed or cancelled. A car in any other status, or still on another
+// trip in progress, is left alone.
+func (db *DB) syncCarStatus(ctx context.Context, trip models.Trip, actor string) {
+	switch trip.Status {
+	case models.TripStatusInProgress:
+		db.setCarStatus(ctx, trip, models.CarStatusAvailable, models.CarStatusInUse, actor)
+	case models.TripStatusCompleted, models.TripStatusCancelled:
+		db.setCarStatus(ctx, trip, models.CarStatusInUse, models.CarStatusAvailable, actor)
+	}
+}
+
+// releaseCar makes the car of a trip in progress Available again when the trip leaves it, because the trip is
+// deleted or moved to another car
+func (db *DB) releaseCar(ctx context.Context, trip models.Trip, actor string) {
+	if trip.Status == models.TripStatusInProgress {
+		db.setCarStatus(ctx, trip, models.CarStatusInUse, models.CarStatusAvailable, actor)
+	}
+}
+
+// setCarStatus moves the trip's car from one status to another, unless it is in another status or on another trip
+// in progress
+func (db *DB) setCarStatus(ctx context.Context, trip models.Trip, from, to string, actor string) {
+	car, ok := db.cars[trip.CarID]
+	if !ok || car.Status != from {
+		return
+	}
+	for _, other := range db.trips {
+		if other.CarID == trip.CarID && other.ID != trip.ID && other.Status == models.TripStatusInProgress {
+			return
+		}
+	}
+
+	before := carSnapshot(car)
+	car.Status = to
+	car.UpdatedAt = time.Now()
+	car.UpdatedBy = actor
+	db.cars[car.ID] = car
+	db.record(ctx, models.AuditResourceCar, car.ID.String(), models.AuditActionUpdate, before, carSnapshot(car))
+}
+
+func (e *TripStore) GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error) {
+	tracer := otel.Tracer("TripStore")
+	_, span := tracer.Start(ctx, "GetTripTransitions-Store")
+	defer span.End()
+
+	tripID, err := parseID("trip", id)
+	if err != nil {
+		return nil, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	transitions := []models.TripTransition{}
+	for _, transition := range e.db.transitions {
+		if transition.TripID == tripID {
+			transitions = append(transitions, transition)
+		}
+	}
+	// transitions are appended as they happen, so they are already in order
+	return transitions, nil
+}
+
+// DeleteTrip deletes the trip along with its transitions, odometer readings taken on it are kept without the trip
+func (e *TripStore) DeleteTrip(ctx context.Context, id string, actor string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "DeleteTrip-Store")
+	defer span.End()
+
+	tripID, err := parseID("trip", id)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	trip, ok := e.db.trips[tripID]
+	if !ok {
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
+	e.db.deleteTrip(tripID)
+
+	e.db.record(ctx, models.AuditResourceTrip, tripID.String(), models.AuditActionDelete, tripSnapshot(trip), nil)
+
+	// a deleted trip in progress no longer holds its car
+	e.db.releaseCar(ctx, trip, actor)
+	return trip, nil
+}
+
+func (db *DB) deleteTrip(id uuid.UUID) {
+	db.transitions = slices.DeleteFunc(db.transitions, func(t models.TripTransition) bool { return t.TripID == id })
+	for i, reading := range db.readings {
+		if reading.TripID != nil && *reading.TripID == id {
+			db.readings[i].TripID = nil
+		}
+	}
+	delete(db.trips, id)
+}
+
+// routeSortFields are the fields route stats can be sorted by
+var routeSortFields = map[string]order[models.RouteStats]{
+	"origin":       byString(func(r models.RouteStats) string { return r.Origin }),
+	"destination":  byString(func(r models.RouteStats) string { return r.Destination }),
+	"trips":        byNumber(func(r models.RouteStats) int { return r.Trips }),
+	"avg_duration": byNumber(func(r models.RouteStats) float64 { return r.AvgDurationMinutes }),
+	"avg_distance": byNumber(func(r models.RouteStats) float64 { return r.AvgDistanceKM }),
+	"avg_fuel":     byNumber(func(r models.RouteStats) float64 { return r.AvgFuelLiters }),
+}
+
+// GetRouteStats groups the completed trips by origin and destination. Locations are not kept in memory, so every
+// endpoint is grouped by its text.
+func (e *TripStore) GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) ([]models.RouteStats, int, error) {
+	tracer := otel.Tracer("TripStore")
+	_, span := tracer.Start(ctx, "GetRouteStats-Store")
+	defer span.End()
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	type route struct{ origin, destination string }
+	type sums struct {
+		trips, timed            int
+		minutes, distance, fuel float64
+	}
+	routes := map[route]*sums{}
+	for _, trip := range e.db.trips {
+		if trip.Status != models.TripStatusCompleted || !matchRoute(trip, filter) {
+			continue
+		}
+		key := route{trip.StartLocation, trip.EndLocation}
+		s, ok := routes[key]
+		if !ok {
+			s = &sums{}
+			routes[key] = s
+		}
+		s.trips++
+		s.distance += trip.DistanceKM
+		s.fuel += trip.FuelConsumedLiters
+		if !trip.EndTime.IsZero() {
+			s.timed++
+			s.minutes += trip.EndTime.Sub(trip.StartTime).Minutes()
+		}
+	}
+
+	stats := []models.RouteStats{}
+	for key, s := range routes {
+		stat := models.RouteStats{
+			Origin:        key.origin,
+			Destination:   key.destination,
+			Trips:         s.trips,
+			AvgDistanceKM: round2(s.distance / float64(s.trips)),
+			AvgFuelLiters: round2(s.fuel / float64(s.trips)),
+		}
+		if s.timed > 0 {
+			stat.AvgDurationMinutes = round2(s.minutes / float64(s.timed))
+		}
+		stats = append(stats, stat)
+	}
+
+	page, err := listPage(stats, opts, routeSortFields, "origin", byString(func(r models.RouteStats) string { return r.Destination }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(stats), nil
+}
+
+func matchRoute(trip models.Trip, filter models.RouteStatsFilter) bool {
+	if filter.CarID != uuid.Nil && trip.CarID != filter.CarID {
+		return false
+	}
+	if filter.DriverID != uuid.Nil && trip.DriverID != filter.DriverID {
+		return false
+	}
+	// no trip is linked to a location
+	if filter.OriginID != uuid.Nil || filter.DestinationID != uuid.Nil {
+		return false
+	}
+	if !filter.From.IsZero() && trip.StartTime.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !trip.StartTime.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// round2 rounds to two decimals, like the ROUND(..., 2) of the route stats query
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+func tripSnapshot(trip models.Trip) audit.Snapshot {
+	return snapshot(map[string]interface{}{
+		"id":                   trip.ID,
+		"description":          trip.Description,
+		"driver_id":            trip.DriverID,
+		"car_id":               trip.CarID,
+		"start_location":       trip.StartLocation,
+		"end_location":         trip.EndLocation,
+		"start_location_id":    trip.StartLocationID,
+		"end_location_id":      trip.EndLocationID,
+		"start_time":           trip.StartTime,
+		"end_time":             nullable(trip.EndTime),
+		"distance_km":          trip.DistanceKM,
+		"fuel_consumed_liters": trip.FuelConsumedLiters,
+		"status":               trip.Status,
+		"created_by":           nullable(trip.CreatedBy),
+		"updated_by":           nullable(trip.UpdatedBy),
+		"created_at":           trip.CreatedAt,
+		"updated_at":           trip.UpdatedAt,
+	})
+}
@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// userRoles are the values the user_role_check constraint allows
+var userRoles = []string{models.RoleAdmin, models.RoleManager, models.RoleDriver, models.RoleTracker}
+
+type UserStore struct {
+	db *DB
+}
+
+func NewUserStore(db *DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// userSortFields are the fields users can be sorted by
+var userSortFields = map[string]order[models.User]{
+	"username":   byString(func(u models.User) string { return u.UserName }),
+	"first_name": byString(func(u models.User) string { return u.FirstName }),
+	"last_name":  byString(func(u models.User) string { return u.LastName }),
+	"email":      byString(func(u models.User) string { return u.Email }),
+	"role":       byString(func(u models.User) string { return u.Role }),
+	"created_at": byTime(func(u models.User) time.Time { return u.CreatedAt }),
+	"updated_at": byTime(func(u models.User) time.Time { return u.UpdatedAt }),
+}
+
+func (u UserStore) GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]models.User, int, error) {
+	tracer := otel.Tracer("UserStore")
+	_, span := tracer.Start(ctx, "GetUsers-Store")
+	defer span.End()
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range u.db.users {
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if filter.Active != nil && user.Active != *filter.Active {
+			continue
+		}
+		users = append(users, userView(user))
+	}
+
+	page, err := listPage(users, opts, userSortFields, "username", byID(func(u models.User) uuid.UUID { return u.ID }))
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(users), nil
+}
+
+func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest, actor string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	_, span := tracer.Start(ctx, "CreateUser-Store")
+	defer span.End()
+
+	if userReq.Password != userReq.ConfirmPassword {
+		return models.User{}, models.Validation("password and confirm password do not match")
+	}
+	if err := userReq.HashPassword(userReq.Password); err != nil {
+		return models.User{}, err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	createdAt := time.Now()
+	user := models.User{
+		ID:          uuid.New(),
+		UserName:    userReq.UserName,
+		Password:    userReq.Password,
+		FirstName:   userReq.FirstName,
+		LastName:    userReq.LastName,
+		Email:       userReq.Email,
+		PhoneNumber: userReq.PhoneNumber,
+		Role:        userReq.Role,
+		Active:      true,
+		CreatedBy:   actor,
+		UpdatedBy:   actor,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+	if err := u.db.checkUser(user); err != nil {
+		return models.User{}, err
+	}
+	u.db.users[user.ID] = user
+	return userView(user), nil
+}
+
+func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *models.UserRequest, actor string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	ctx, span := tracer.Start(ctx, "UpdateUserProfile-Store")
+	defer span.End()
+
+	return u.update(ctx, id, actor, func(user *models.User) {
+		user.FirstName = userReq.FirstName
+		user.LastName = userReq.LastName
+		user.UserName = userReq.UserName
+		user.Email = userReq.Email
+		user.PhoneNumber = userReq.PhoneNumber
+	})
+}
+
+func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest, actor string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	ctx, span := tracer.Start(ctx, "UpdateUserPassword-Store")
+	defer span.End()
+
+	if _, err := parseID("user", id); err != nil {
+		return models.User{}, err
+	}
+	if err := userReq.HashPassword(userReq.Password); err != nil {
+		return models.User{}, err
+	}
+
+	return u.update(ctx, id, actor, func(user *models.User) {
+		user.Password = userReq.Password
+	})
+}
+
+func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool, actor string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	ctx, span := tracer.Start(ctx, "ToggleUserStatus-Store")
+	defer span.End()
+
+	return u.update(ctx, id, actor, func(user *models.User) {
+		user.Active = active
+	})
+}
+
+// update applies a change to a user, checks the constraints of the user table and records the change in the audit log
+func (u UserStore) update(ctx context.Context, id string, actor string, change func(*models.User)) (models.User, error) {
+	userID, err := parseID("user", id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	user, ok := u.db.users[userID]
+	if !ok {
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+
+	before := userSnapshot(user)
+	change(&user)
+	user.UpdatedBy = actor
+	user.UpdatedAt = time.Now()
+	if err := u.db.checkUser(user); err != nil {
+		return models.User{}, err
+	}
+	u.db.users[userID] = user
+
+	u.db.record(ctx, models.AuditResourceUser, userID.String(), models.AuditActionUpdate, before, userSnapshot(user))
+	return userView(user), nil
+}
+
+// checkUser enforces the unique usernames and emails and the allowed roles of the user table
+func (db *DB) checkUser(user models.User) error {
+	if !slices.Contains(userRoles, user.Role) {
+		return violates("user_role_check")
+	}
+	for _, other := range db.users {
+		if other.ID == user.ID {
+			continue
+		}
+		if other.UserName == user.UserName {
+			return duplicate("user_username_key")
+		}
+		if other.Email == user.Email {
+			return duplicate("user_email_key")
+		}
+	}
+	return nil
+}
+
+func (u UserStore) GetUserProfile(ctx context.Context, id string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	_, span := tracer.Start(ctx, "GetUserProfile-Store")
+	defer span.End()
+
+	userID, err := parseID("user", id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	user, ok := u.db.users[userID]
+	if !ok {
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+	return userView(user), nil
+}
+
+// DeleteUser deletes the user along with their drivers and sessions, as the foreign keys cascade
+func (u UserStore) DeleteUser(ctx context.Context, id string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	ctx, span := tracer.Start(ctx, "DeleteUser-Store")
+	defer span.End()
+
+	userID, err := parseID("user", id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	user, ok := u.db.users[userID]
+	if !ok {
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+	u.db.deleteUser(userID)
+
+	u.db.record(ctx, models.AuditResourceUser, userID.String(), models.AuditActionDelete, userSnapshot(user), nil)
+	return models.User{ID: userID, CreatedAt: user.CreatedAt}, nil
+}
+
+func (db *DB) deleteUser(id uuid.UUID) {
+	for driverID, driver := range db.drivers {
+		if driver.UserID == id {
+			db.deleteDriver(driverID)
+		}
+	}
+	for tokenID, token := range db.refreshTokens {
+		if token.UserID == id {
+			delete(db.refreshTokens, tokenID)
+		}
+	}
+	delete(db.users, id)
+}
+
+// GetUserByUsername returns the user with the password hash, for logins
+func (u UserStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	tracer := otel.Tracer("UserStore")
+	_, span := tracer.Start(ctx, "GetUserByUsername-Store")
+	defer span.End()
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	for _, user := range u.db.users {
+		if user.UserName == username && user.DeletedAt.IsZero() {
+			return user, nil
+		}
+	}
+	return models.User{}, models.NotFound("user %s not found", username)
+}
+
+// userView is a user as the user store returns it, without the password hash
+func userView(user models.User) models.User {
+	user.Password = ""
+	return user
+}
+
+func userSnapshot(user models.User) audit.Snapshot {
+	return snapshot(map[string]interface{}{
+		"id":           user.ID,
+		"username":     user.UserName,
+		"password":     user.Password,
+		"first_name":   user.FirstName,
+		"last_name":    user.LastName,
+		"email":        user.Email,
+		"phone_number": user.PhoneNumber,
+		"role":         user.Role,
+		"active":       user.Active,
+		"created_by":   nullable(user.CreatedBy),
+		"updated_by":   nullable(user.UpdatedBy),
+		"deleted_at":   nullable(user.DeletedAt),
+		"created_at":   user.CreatedAt,
+		"updated_at":   user.UpdatedAt,
+	})
+}
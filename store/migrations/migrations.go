@@ -0,0 +1,293 @@
+// Package migrations versions the database schema. Migrations are numbered pairs of files, NNNN_name.up.sql and
+// NNNN_name.down.sql, embedded in the binary; the applied versions are kept in the schema_migrations table. Postgres
+// and SQLite have migrations of their own, in the directory named after the dialect, next to its seed.sql.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/JulianaSau/carzone/store"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// advisoryLockID is the postgres advisory lock held while migrating, so replicas starting together take turns
+const advisoryLockID int64 = 7_304_021_955_202_101
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, AppliedAt is nil while it is pending. Missing marks a version
+// recorded in the database that this build does not know.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+// Load reads the migrations in dir of fsys ordered by version. Every version needs both an up and a down file.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    store.Dialect
+	migrations []Migration
+	seed       string
+}
+
+// New returns a migrator for the migrations embedded in the binary for the dialect of db
+func New(db *sql.DB) (*Migrator, error) {
+	dialect := store.DialectOf(db)
+	migrations, err := Load(files, dialect.String())
+	if err != nil {
+		return nil, err
+	}
+	seed, err := fs.ReadFile(files, path.Join(dialect.String(), "seed.sql"))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, seed: string(seed)}, nil
+}
+
+// Up applies every pending migration in order, each in a transaction of its own, and returns the ones it applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			err := run(ctx, conn, migration.Up, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, time.Now())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, latest first, and returns the ones it reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := []Migration{}
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		latest := make([]int, 0, len(versions))
+		for version := range versions {
+			latest = append(latest, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(latest)))
+		if steps < len(latest) {
+			latest = latest[:steps]
+		}
+
+		for _, version := range latest {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not part of this build, it cannot be reverted", version)
+			}
+			err := run(ctx, conn, migration.Down, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %04d_%s", migration.Version, migration.Name)
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every migration of this build and every version applied to the database, ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if applied, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &applied.at
+				delete(versions, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for version, applied := range versions {
+			statuses = append(statuses, Status{Version: version, Name: applied.name, AppliedAt: &applied.at, Missing: true})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// Seed loads the demo data, see seed.sql
+func (m *Migrator) Seed(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, m.seed)
+		return err
+	})
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked runs fn on a connection of its own holding the advisory lock. The lock belongs to the session, so
+// everything that needs it runs on that connection. SQLite serves a single node and takes no lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == store.Postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+			return fmt.Errorf("waiting for the migration lock: %w", err)
+		}
+		defer func() {
+			// a fresh context so the lock is released even when ctx is done
+			if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockID); err != nil {
+				log.Printf("Error releasing the migration lock: %v", err)
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type appliedVersion struct {
+	name string
+	at   time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]appliedVersion, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int]appliedVersion{}
+	for rows.Next() {
+		var version int
+		var applied appliedVersion
+		if err := rows.Scan(&version, &applied.name, &applied.at); err != nil {
+			return nil, err
+		}
+		versions[version] = applied
+	}
+	return versions, rows.Err()
+}
+
+// run executes the SQL of a migration and the statement recording it in one transaction
+func run(ctx context.Context, conn *sql.Conn, migrationSQL string, record string, args ...interface{}) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v", rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
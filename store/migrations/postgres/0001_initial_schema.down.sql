@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS trip;
+DROP TABLE IF EXISTS driver;
+DROP TABLE IF EXISTS car;
+DROP TABLE IF EXISTS engine;
+DROP TABLE IF EXISTS "user";
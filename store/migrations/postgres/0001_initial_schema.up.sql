@@ -0,0 +1,81 @@
+CREATE TABLE IF NOT EXISTS "user" (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    phone_number VARCHAR(20),
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'manager', 'driver')),
+    active BOOLEAN DEFAULT TRUE,
+    created_by VARCHAR(50) DEFAULT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS engine (
+    id UUID PRIMARY KEY,
+    displacement INT NOT NULL,
+    no_of_cylinders INT NOT NULL,
+    car_range INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS car (
+    id UUID PRIMARY KEY,
+    registration_number VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    year VARCHAR(4) NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    fuel_type VARCHAR(50) NOT NULL,
+    engine_id UUID NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('Available', 'In Use', 'Maintenance', 'Decommissioned')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS driver (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    driver_license_number VARCHAR(255) UNIQUE NOT NULL,
+    license_expiry DATE NOT NULL,
+    active BOOLEAN DEFAULT TRUE,
+    created_by VARCHAR(50) DEFAULT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS trip (
+    id UUID PRIMARY KEY,
+    description TEXT DEFAULT NULL,
+    driver_id UUID NOT NULL,
+    car_id UUID NOT NULL,
+    start_location VARCHAR(255) NOT NULL,
+    end_location VARCHAR(255) NOT NULL,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP DEFAULT NULL,
+    distance_km DECIMAL(10, 2) DEFAULT 0.00,
+    fuel_consumed_liters DECIMAL(10, 2) DEFAULT 0.00,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('In Progress', 'Completed', 'Cancelled', 'Scheduled', 'Draft')) DEFAULT 'Scheduled',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL
+);
+
+-- the foreign keys are dropped first so databases created from the old schema.sql, which has them, can be migrated
+ALTER TABLE car DROP CONSTRAINT IF EXISTS fk_engine_id;
+ALTER TABLE car ADD CONSTRAINT fk_engine_id FOREIGN KEY (engine_id) REFERENCES engine(id) ON DELETE CASCADE;
+
+ALTER TABLE driver DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE driver ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE;
+
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS fk_driver_id;
+ALTER TABLE trip ADD CONSTRAINT fk_driver_id FOREIGN KEY (driver_id) REFERENCES driver(id) ON DELETE CASCADE;
+
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS fk_car_id;
+ALTER TABLE trip ADD CONSTRAINT fk_car_id FOREIGN KEY (car_id) REFERENCES car(id) ON DELETE CASCADE;
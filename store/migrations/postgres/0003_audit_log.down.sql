@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+ALTER TABLE engine DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE engine DROP COLUMN IF EXISTS created_by;
+ALTER TABLE car DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE car DROP COLUMN IF EXISTS created_by;
+ALTER TABLE driver DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE "user" DROP COLUMN IF EXISTS updated_by;
@@ -0,0 +1,33 @@
+-- who created and who last changed each row
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS updated_by VARCHAR(50) DEFAULT NULL;
+ALTER TABLE driver ADD COLUMN IF NOT EXISTS updated_by VARCHAR(50) DEFAULT NULL;
+ALTER TABLE car ADD COLUMN IF NOT EXISTS created_by VARCHAR(50) DEFAULT NULL;
+ALTER TABLE car ADD COLUMN IF NOT EXISTS updated_by VARCHAR(50) DEFAULT NULL;
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS created_by VARCHAR(50) DEFAULT NULL;
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS updated_by VARCHAR(50) DEFAULT NULL;
+
+-- append-only change history of cars, drivers, trips and users; changes holds {"column": {"old": .., "new": ..}}
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    resource VARCHAR(20) NOT NULL,
+    resource_id VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    changes JSONB NOT NULL,
+    actor_id VARCHAR(50) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (resource, resource_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS trip_transition;
+DROP INDEX IF EXISTS idx_car_status;
+DROP INDEX IF EXISTS idx_car_brand;
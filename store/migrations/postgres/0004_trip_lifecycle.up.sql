@@ -0,0 +1,16 @@
+-- car search filters
+CREATE INDEX IF NOT EXISTS idx_car_brand ON car (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_car_status ON car (status);
+
+-- status changes of trips with the reason given and the user who made them
+CREATE TABLE IF NOT EXISTS trip_transition (
+    id UUID PRIMARY KEY,
+    trip_id UUID NOT NULL REFERENCES trip(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_transition_trip ON trip_transition (trip_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS trip_driver_no_overlap;
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS trip_car_no_overlap;
+DROP FUNCTION IF EXISTS trip_window(TIMESTAMP, TIMESTAMP);
@@ -0,0 +1,46 @@
+-- a car or driver cannot be on two scheduled or running trips at the same time. A trip without an end time, or one
+-- started after its planned end time, holds them from its start onwards.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE OR REPLACE FUNCTION trip_window(start_time TIMESTAMP, end_time TIMESTAMP) RETURNS tsrange AS $$
+    SELECT tsrange(start_time, CASE WHEN end_time IS NULL OR end_time <= start_time THEN 'infinity' ELSE end_time END)
+$$ LANGUAGE sql IMMUTABLE;
+
+-- trips booked twice before this migration would make adding the constraints fail on an opaque gist error, so they
+-- are listed first. Cancel or reschedule one trip of each pair and run the migration again.
+DO $$
+DECLARE
+    car_conflicts TEXT;
+    driver_conflicts TEXT;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'trip_car_no_overlap') THEN
+        SELECT string_agg(format('%s and %s (car %s)', a.id, b.id, a.car_id), ', ' ORDER BY a.id, b.id) INTO car_conflicts
+        FROM trip a
+        JOIN trip b ON b.car_id = a.car_id AND b.id > a.id
+        WHERE a.status IN ('Scheduled', 'In Progress') AND b.status IN ('Scheduled', 'In Progress')
+            AND trip_window(a.start_time, a.end_time) && trip_window(b.start_time, b.end_time);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'trip_driver_no_overlap') THEN
+        SELECT string_agg(format('%s and %s (driver %s)', a.id, b.id, a.driver_id), ', ' ORDER BY a.id, b.id) INTO driver_conflicts
+        FROM trip a
+        JOIN trip b ON b.driver_id = a.driver_id AND b.id > a.id
+        WHERE a.status IN ('Scheduled', 'In Progress') AND b.status IN ('Scheduled', 'In Progress')
+            AND trip_window(a.start_time, a.end_time) && trip_window(b.start_time, b.end_time);
+    END IF;
+    IF car_conflicts IS NOT NULL OR driver_conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'overlapping scheduled or in progress trips: %', concat_ws(', ', car_conflicts, driver_conflicts)
+            USING HINT = 'cancel or reschedule one trip of each pair, then run the migration again';
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'trip_car_no_overlap') THEN
+        ALTER TABLE trip ADD CONSTRAINT trip_car_no_overlap
+            EXCLUDE USING gist (car_id WITH =, trip_window(start_time, end_time) WITH &&)
+            WHERE (status IN ('Scheduled', 'In Progress'));
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'trip_driver_no_overlap') THEN
+        ALTER TABLE trip ADD CONSTRAINT trip_driver_no_overlap
+            EXCLUDE USING gist (driver_id WITH =, trip_window(start_time, end_time) WITH &&)
+            WHERE (status IN ('Scheduled', 'In Progress'));
+    END IF;
+END;
+$$;
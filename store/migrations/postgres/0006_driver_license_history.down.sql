@@ -0,0 +1 @@
+DROP TABLE IF EXISTS driver_license_history;
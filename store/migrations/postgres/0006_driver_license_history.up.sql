@@ -0,0 +1,19 @@
+-- every license a driver held and the period it was on record; valid_to is NULL for the current license
+CREATE TABLE IF NOT EXISTS driver_license_history (
+    id UUID PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES driver(id) ON DELETE CASCADE,
+    driver_license_number VARCHAR(255) NOT NULL,
+    license_expiry DATE NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP DEFAULT NULL,
+    recorded_by VARCHAR(50) NOT NULL DEFAULT '',
+    replaced_by VARCHAR(50) DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_driver_license_history_current ON driver_license_history (driver_id) WHERE valid_to IS NULL;
+
+-- drivers created before the history existed start with their current license
+INSERT INTO driver_license_history (id, driver_id, driver_license_number, license_expiry, valid_from, recorded_by)
+SELECT gen_random_uuid(), d.id, d.driver_license_number, d.license_expiry, d.created_at, COALESCE(d.created_by, '')
+FROM driver d
+WHERE NOT EXISTS (SELECT 1 FROM driver_license_history h WHERE h.driver_id = d.id);
@@ -0,0 +1,5 @@
+DROP FUNCTION IF EXISTS car_service_due(UUID);
+DROP VIEW IF EXISTS maintenance_plan_status;
+DROP FUNCTION IF EXISTS car_odometer_km(UUID);
+DROP TABLE IF EXISTS maintenance_plan;
+DROP TABLE IF EXISTS maintenance_record;
@@ -0,0 +1,74 @@
+-- services carried out on a car and the parts fitted
+CREATE TABLE IF NOT EXISTS maintenance_record (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    service_date TIMESTAMP NOT NULL,
+    odometer_km DECIMAL(10, 1) NOT NULL CHECK (odometer_km >= 0),
+    service_type VARCHAR(100) NOT NULL,
+    cost DECIMAL(10, 2) NOT NULL DEFAULT 0.00 CHECK (cost >= 0),
+    workshop VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    parts JSONB NOT NULL DEFAULT '[]',
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_record_car ON maintenance_record (car_id, LOWER(service_type), service_date);
+
+-- services that recur every interval_km kilometers or interval_months months, whichever comes first. The baseline
+-- is the last service before the car's records start.
+CREATE TABLE IF NOT EXISTS maintenance_plan (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    service_type VARCHAR(100) NOT NULL,
+    interval_km INT NOT NULL DEFAULT 0 CHECK (interval_km >= 0),
+    interval_months INT NOT NULL DEFAULT 0 CHECK (interval_months >= 0),
+    baseline_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    baseline_odometer_km DECIMAL(10, 1) NOT NULL DEFAULT 0 CHECK (baseline_odometer_km >= 0),
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (interval_km > 0 OR interval_months > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenance_plan_car_type ON maintenance_plan (car_id, LOWER(service_type));
+
+-- the highest odometer reading known for a car
+CREATE OR REPLACE FUNCTION car_odometer_km(car UUID) RETURNS DECIMAL AS $$
+    SELECT COALESCE(MAX(odometer_km), 0) FROM (
+        SELECT odometer_km FROM maintenance_record WHERE car_id = car
+        UNION ALL
+        SELECT baseline_odometer_km FROM maintenance_plan WHERE car_id = car
+    ) readings
+$$ LANGUAGE sql STABLE;
+
+-- every plan with its last service, counted from the latest record of the same type, and when it is next due
+CREATE OR REPLACE VIEW maintenance_plan_status AS
+SELECT p.id, p.car_id, p.service_type, p.interval_km, p.interval_months, p.baseline_date, p.baseline_odometer_km,
+    COALESCE(last.service_date, p.baseline_date) AS last_service_date,
+    COALESCE(last.odometer_km, p.baseline_odometer_km) AS last_service_odometer_km,
+    car_odometer_km(p.car_id) AS current_odometer_km,
+    CASE WHEN p.interval_months > 0
+        THEN COALESCE(last.service_date, p.baseline_date) + make_interval(months => p.interval_months) END AS next_due_date,
+    CASE WHEN p.interval_km > 0
+        THEN COALESCE(last.odometer_km, p.baseline_odometer_km) + p.interval_km END AS next_due_odometer_km,
+    p.created_by, p.updated_by, p.created_at, p.updated_at
+FROM maintenance_plan p
+LEFT JOIN LATERAL (
+    SELECT r.service_date, r.odometer_km
+    FROM maintenance_record r
+    WHERE r.car_id = p.car_id AND LOWER(r.service_type) = LOWER(p.service_type)
+    ORDER BY r.service_date DESC, r.odometer_km DESC
+    LIMIT 1
+) last ON TRUE;
+
+-- a car is due for service once any of its plans passed its next service date or odometer reading
+CREATE OR REPLACE FUNCTION car_service_due(car UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM maintenance_plan_status s
+        WHERE s.car_id = car AND (s.next_due_date <= now() OR s.current_odometer_km >= s.next_due_odometer_km)
+    )
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,10 @@
+-- the odometer of a car goes back to counting maintenance only
+CREATE OR REPLACE FUNCTION car_odometer_km(car UUID) RETURNS DECIMAL AS $$
+    SELECT COALESCE(MAX(odometer_km), 0) FROM (
+        SELECT odometer_km FROM maintenance_record WHERE car_id = car
+        UNION ALL
+        SELECT baseline_odometer_km FROM maintenance_plan WHERE car_id = car
+    ) readings
+$$ LANGUAGE sql STABLE;
+
+DROP TABLE IF EXISTS odometer_reading;
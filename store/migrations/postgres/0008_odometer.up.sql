@@ -0,0 +1,25 @@
+-- odometer readings per car, taken when trips start and end or entered by hand. A car's readings never go backwards.
+CREATE TABLE IF NOT EXISTS odometer_reading (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id UUID DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    reading_km DECIMAL(10, 1) NOT NULL CHECK (reading_km >= 0),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'trip_start', 'trip_end')),
+    notes TEXT NOT NULL DEFAULT '',
+    recorded_at TIMESTAMP NOT NULL,
+    recorded_by VARCHAR(50) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_odometer_reading_car ON odometer_reading (car_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_odometer_reading_trip ON odometer_reading (trip_id) WHERE trip_id IS NOT NULL;
+
+-- the odometer of a car now counts its readings too
+CREATE OR REPLACE FUNCTION car_odometer_km(car UUID) RETURNS DECIMAL AS $$
+    SELECT COALESCE(MAX(odometer_km), 0) FROM (
+        SELECT reading_km AS odometer_km FROM odometer_reading WHERE car_id = car
+        UNION ALL
+        SELECT odometer_km FROM maintenance_record WHERE car_id = car
+        UNION ALL
+        SELECT baseline_odometer_km FROM maintenance_plan WHERE car_id = car
+    ) readings
+$$ LANGUAGE sql STABLE;
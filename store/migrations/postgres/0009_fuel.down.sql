@@ -0,0 +1,8 @@
+DROP VIEW IF EXISTS fuel_consumption;
+
+DELETE FROM odometer_reading WHERE source = 'refuel';
+ALTER TABLE odometer_reading DROP CONSTRAINT IF EXISTS odometer_reading_source_check;
+ALTER TABLE odometer_reading ADD CONSTRAINT odometer_reading_source_check CHECK (source IN ('manual', 'trip_start', 'trip_end'));
+ALTER TABLE odometer_reading DROP COLUMN IF EXISTS fuel_entry_id;
+
+DROP TABLE IF EXISTS fuel_entry;
@@ -0,0 +1,29 @@
+-- refuels of a car, the odometer reading taken at each refuel is kept with the car's readings
+CREATE TABLE IF NOT EXISTS fuel_entry (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    driver_id UUID DEFAULT NULL REFERENCES driver(id) ON DELETE SET NULL,
+    trip_id UUID DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    fueled_at TIMESTAMP NOT NULL,
+    liters DECIMAL(10, 2) NOT NULL CHECK (liters > 0),
+    price_per_liter DECIMAL(10, 3) NOT NULL DEFAULT 0 CHECK (price_per_liter >= 0),
+    total_cost DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (total_cost >= 0),
+    station VARCHAR(255) NOT NULL DEFAULT '',
+    odometer_km DECIMAL(10, 1) NOT NULL CHECK (odometer_km >= 0),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fuel_entry_car ON fuel_entry (car_id, fueled_at);
+CREATE INDEX IF NOT EXISTS idx_fuel_entry_driver ON fuel_entry (driver_id, fueled_at) WHERE driver_id IS NOT NULL;
+
+ALTER TABLE odometer_reading ADD COLUMN IF NOT EXISTS fuel_entry_id UUID DEFAULT NULL REFERENCES fuel_entry(id) ON DELETE CASCADE;
+ALTER TABLE odometer_reading DROP CONSTRAINT IF EXISTS odometer_reading_source_check;
+ALTER TABLE odometer_reading ADD CONSTRAINT odometer_reading_source_check CHECK (source IN ('manual', 'trip_start', 'trip_end', 'refuel'));
+
+-- every refuel with the distance driven since the car's previous refuel, NULL for the first one
+CREATE OR REPLACE VIEW fuel_consumption AS
+SELECT f.id, f.car_id, f.driver_id, f.fueled_at, f.liters, f.total_cost, f.odometer_km,
+    f.odometer_km - LAG(f.odometer_km) OVER (PARTITION BY f.car_id ORDER BY f.fueled_at, f.odometer_km) AS distance_km
+FROM fuel_entry f;
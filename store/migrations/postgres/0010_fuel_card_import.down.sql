@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_fuel_entry_reference;
+ALTER TABLE fuel_entry DROP COLUMN IF EXISTS reference;
+DROP INDEX IF EXISTS idx_car_registration_key;
+ALTER TABLE car DROP COLUMN IF EXISTS tank_capacity_liters;
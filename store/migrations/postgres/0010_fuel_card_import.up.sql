@@ -0,0 +1,9 @@
+-- the fuel tank size of a car, 0 when unknown; fuel card imports flag refuels above it
+ALTER TABLE car ADD COLUMN IF NOT EXISTS tank_capacity_liters DECIMAL(6, 2) NOT NULL DEFAULT 0 CHECK (tank_capacity_liters >= 0);
+
+-- fuel card statements name cars by registration number, written with or without spaces
+CREATE INDEX IF NOT EXISTS idx_car_registration_key ON car (UPPER(REPLACE(registration_number, ' ', '')));
+
+-- the fuel card transaction a refuel was imported from, each transaction is imported once
+ALTER TABLE fuel_entry ADD COLUMN IF NOT EXISTS reference VARCHAR(255) DEFAULT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fuel_entry_reference ON fuel_entry (reference) WHERE reference IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS car_position;
+DROP FUNCTION IF EXISTS car_trip_at(UUID, TIMESTAMP);
+
+-- fails while tracker accounts exist, they have to be removed or given another role first
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_role_check;
+ALTER TABLE "user" ADD CONSTRAINT user_role_check CHECK (role IN ('admin', 'manager', 'driver'));
@@ -0,0 +1,29 @@
+-- trackers log in with accounts of their own that may only report positions
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_role_check;
+ALTER TABLE "user" ADD CONSTRAINT user_role_check CHECK (role IN ('admin', 'manager', 'driver', 'tracker'));
+
+-- the trip a car was under way on at a time; a trip in progress runs until it is completed, even past its planned end
+CREATE OR REPLACE FUNCTION car_trip_at(car UUID, at TIMESTAMP) RETURNS UUID AS $$
+    SELECT id FROM trip
+    WHERE car_id = car
+        AND ((status = 'In Progress' AND start_time <= at)
+            OR (status = 'Completed' AND trip_window(start_time, end_time) @> at))
+    ORDER BY start_time DESC
+    LIMIT 1
+$$ LANGUAGE sql STABLE;
+
+-- GPS points reported by the trackers, attached to the trip the car was on; a tracker resending a point is ignored
+CREATE TABLE IF NOT EXISTS car_position (
+    id BIGSERIAL PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id UUID DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    speed_kph DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (speed_kph >= 0),
+    heading DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (heading >= 0 AND heading < 360),
+    recorded_at TIMESTAMP NOT NULL,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (car_id, recorded_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_position_trip ON car_position (trip_id, recorded_at) WHERE trip_id IS NOT NULL;
@@ -0,0 +1,35 @@
+-- named areas cars are tracked in and out of: a circle around center_lat/center_lon or a polygon of vertices,
+-- a JSON array of {"lat", "lon"} objects
+CREATE TABLE IF NOT EXISTS geofence (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('depot', 'customer', 'restricted')),
+    shape VARCHAR(20) NOT NULL CHECK (shape IN ('circle', 'polygon')),
+    center_lat DOUBLE PRECISION DEFAULT NULL CHECK (center_lat BETWEEN -90 AND 90),
+    center_lon DOUBLE PRECISION DEFAULT NULL CHECK (center_lon BETWEEN -180 AND 180),
+    radius_m DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (radius_m >= 0),
+    vertices JSONB NOT NULL DEFAULT '[]',
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (shape <> 'circle' OR (center_lat IS NOT NULL AND center_lon IS NOT NULL AND radius_m > 0))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_geofence_name ON geofence (LOWER(name));
+
+-- a car crossing the boundary of a geofence, detected from the first position reported on the other side
+CREATE TABLE IF NOT EXISTS geofence_event (
+    id UUID PRIMARY KEY,
+    geofence_id UUID NOT NULL REFERENCES geofence(id) ON DELETE CASCADE,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id UUID DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    position_id BIGINT NOT NULL,
+    event VARCHAR(10) NOT NULL CHECK (event IN ('enter', 'exit')),
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_event_car ON geofence_event (car_id, geofence_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_geofence_event_geofence ON geofence_event (geofence_id, occurred_at);
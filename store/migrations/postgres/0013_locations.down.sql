@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_trip_end_location;
+DROP INDEX IF EXISTS idx_trip_start_location;
+ALTER TABLE trip DROP COLUMN IF EXISTS end_location_id;
+ALTER TABLE trip DROP COLUMN IF EXISTS start_location_id;
+DROP TABLE IF EXISTS location;
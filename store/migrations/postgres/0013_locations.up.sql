@@ -0,0 +1,29 @@
+-- places trips start and end at; names are unique regardless of case so trip text can be matched to them
+CREATE TABLE IF NOT EXISTS location (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    address TEXT DEFAULT NULL,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('depot', 'customer', 'supplier', 'other')),
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_name ON location (LOWER(name));
+
+-- the locations a trip starts and ends at; start_location and end_location keep the text for older clients
+ALTER TABLE trip ADD COLUMN IF NOT EXISTS start_location_id UUID DEFAULT NULL REFERENCES location(id);
+ALTER TABLE trip ADD COLUMN IF NOT EXISTS end_location_id UUID DEFAULT NULL REFERENCES location(id);
+CREATE INDEX IF NOT EXISTS idx_trip_start_location ON trip (start_location_id) WHERE start_location_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_trip_end_location ON trip (end_location_id) WHERE end_location_id IS NOT NULL;
+
+-- link the trips recorded before locations existed to the location their text names
+UPDATE trip t SET start_location_id = l.id
+FROM location l
+WHERE t.start_location_id IS NULL AND LOWER(TRIM(t.start_location)) = LOWER(l.name);
+UPDATE trip t SET end_location_id = l.id
+FROM location l
+WHERE t.end_location_id IS NULL AND LOWER(TRIM(t.end_location)) = LOWER(l.name);
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS geofence_event;
+DROP TABLE IF EXISTS geofence;
+DROP TABLE IF EXISTS car_position;
+DROP VIEW IF EXISTS car_maintenance;
+DROP VIEW IF EXISTS maintenance_plan_status;
+DROP VIEW IF EXISTS car_odometer;
+DROP VIEW IF EXISTS fuel_consumption;
+DROP TABLE IF EXISTS odometer_reading;
+DROP TABLE IF EXISTS fuel_entry;
+DROP TABLE IF EXISTS maintenance_plan;
+DROP TABLE IF EXISTS maintenance_record;
+DROP TABLE IF EXISTS driver_license_history;
+DROP TABLE IF EXISTS trip_transition;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS revoked_token;
+DROP TABLE IF EXISTS refresh_token;
+DROP TABLE IF EXISTS trip;
+DROP TABLE IF EXISTS location;
+DROP TABLE IF EXISTS driver;
+DROP TABLE IF EXISTS car;
+DROP TABLE IF EXISTS engine;
+DROP TABLE IF EXISTS "user";
@@ -0,0 +1,444 @@
+-- The schema of the postgres migrations up to 0013 for SQLite. UUIDs are stored as text, decimals as REAL and times
+-- as sortable text in the connection's format, 2006-01-02 15:04:05.000000; CURRENT_TIMESTAMP is spelled out in that
+-- format as the local time, like postgres's TIMESTAMP keeps it. Functions become views and triggers.
+
+CREATE TABLE IF NOT EXISTS "user" (
+    id TEXT PRIMARY KEY,
+    username VARCHAR(50) UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    phone_number VARCHAR(20),
+    role VARCHAR(20) NOT NULL CONSTRAINT user_role_check CHECK (role IN ('admin', 'manager', 'driver', 'tracker')),
+    active BOOLEAN DEFAULT TRUE,
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE TABLE IF NOT EXISTS engine (
+    id TEXT PRIMARY KEY,
+    displacement INTEGER NOT NULL,
+    no_of_cylinders INTEGER NOT NULL,
+    car_range INTEGER NOT NULL,
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+-- tank_capacity_liters is the fuel tank size, 0 when unknown; fuel card imports flag refuels above it
+CREATE TABLE IF NOT EXISTS car (
+    id TEXT PRIMARY KEY,
+    registration_number VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    year VARCHAR(4) NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    fuel_type VARCHAR(50) NOT NULL,
+    engine_id TEXT NOT NULL CONSTRAINT fk_engine_id REFERENCES engine(id) ON DELETE CASCADE,
+    price REAL NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('Available', 'In Use', 'Maintenance', 'Decommissioned')),
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    tank_capacity_liters REAL NOT NULL DEFAULT 0 CHECK (tank_capacity_liters >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_brand ON car (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_car_status ON car (status);
+-- fuel card statements name cars by registration number, written with or without spaces
+CREATE INDEX IF NOT EXISTS idx_car_registration_key ON car (UPPER(REPLACE(registration_number, ' ', '')));
+
+CREATE TABLE IF NOT EXISTS driver (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL CONSTRAINT fk_user_id REFERENCES "user"(id) ON DELETE CASCADE,
+    driver_license_number VARCHAR(255) UNIQUE NOT NULL,
+    license_expiry DATE NOT NULL,
+    active BOOLEAN DEFAULT TRUE,
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+-- places trips start and end at; names are unique regardless of case so trip text can be matched to them
+CREATE TABLE IF NOT EXISTS location (
+    id TEXT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    address TEXT DEFAULT NULL,
+    latitude REAL NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude REAL NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('depot', 'customer', 'supplier', 'other')),
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_name ON location (LOWER(name));
+
+-- start_location and end_location keep the text of the locations for older clients
+CREATE TABLE IF NOT EXISTS trip (
+    id TEXT PRIMARY KEY,
+    description TEXT DEFAULT NULL,
+    driver_id TEXT NOT NULL CONSTRAINT fk_driver_id REFERENCES driver(id) ON DELETE CASCADE,
+    car_id TEXT NOT NULL CONSTRAINT fk_car_id REFERENCES car(id) ON DELETE CASCADE,
+    start_location VARCHAR(255) NOT NULL,
+    end_location VARCHAR(255) NOT NULL,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP DEFAULT NULL,
+    distance_km REAL DEFAULT 0.00,
+    fuel_consumed_liters REAL DEFAULT 0.00,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('In Progress', 'Completed', 'Cancelled', 'Scheduled', 'Draft')) DEFAULT 'Scheduled',
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    start_location_id TEXT DEFAULT NULL REFERENCES location(id),
+    end_location_id TEXT DEFAULT NULL REFERENCES location(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_start_location ON trip (start_location_id) WHERE start_location_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_trip_end_location ON trip (end_location_id) WHERE end_location_id IS NOT NULL;
+
+-- a car or driver cannot be on two scheduled or running trips at the same time. A trip without an end time, or one
+-- started after its planned end time, holds them from its start onwards. These stand in for the trip_car_no_overlap
+-- and trip_driver_no_overlap exclusion constraints.
+CREATE TRIGGER IF NOT EXISTS trip_car_no_overlap_insert
+BEFORE INSERT ON trip
+WHEN NEW.status IN ('Scheduled', 'In Progress') AND EXISTS (
+    SELECT 1 FROM trip t
+    WHERE t.car_id = NEW.car_id AND t.id <> NEW.id AND t.status IN ('Scheduled', 'In Progress')
+        AND t.start_time < CASE WHEN NEW.end_time IS NULL OR NEW.end_time <= NEW.start_time THEN '9999-12-31' ELSE NEW.end_time END
+        AND NEW.start_time < CASE WHEN t.end_time IS NULL OR t.end_time <= t.start_time THEN '9999-12-31' ELSE t.end_time END
+)
+BEGIN
+    SELECT RAISE(ABORT, 'conflicting key value violates exclusion constraint "trip_car_no_overlap"');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trip_car_no_overlap_update
+BEFORE UPDATE ON trip
+WHEN NEW.status IN ('Scheduled', 'In Progress') AND EXISTS (
+    SELECT 1 FROM trip t
+    WHERE t.car_id = NEW.car_id AND t.id <> NEW.id AND t.status IN ('Scheduled', 'In Progress')
+        AND t.start_time < CASE WHEN NEW.end_time IS NULL OR NEW.end_time <= NEW.start_time THEN '9999-12-31' ELSE NEW.end_time END
+        AND NEW.start_time < CASE WHEN t.end_time IS NULL OR t.end_time <= t.start_time THEN '9999-12-31' ELSE t.end_time END
+)
+BEGIN
+    SELECT RAISE(ABORT, 'conflicting key value violates exclusion constraint "trip_car_no_overlap"');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trip_driver_no_overlap_insert
+BEFORE INSERT ON trip
+WHEN NEW.status IN ('Scheduled', 'In Progress') AND EXISTS (
+    SELECT 1 FROM trip t
+    WHERE t.driver_id = NEW.driver_id AND t.id <> NEW.id AND t.status IN ('Scheduled', 'In Progress')
+        AND t.start_time < CASE WHEN NEW.end_time IS NULL OR NEW.end_time <= NEW.start_time THEN '9999-12-31' ELSE NEW.end_time END
+        AND NEW.start_time < CASE WHEN t.end_time IS NULL OR t.end_time <= t.start_time THEN '9999-12-31' ELSE t.end_time END
+)
+BEGIN
+    SELECT RAISE(ABORT, 'conflicting key value violates exclusion constraint "trip_driver_no_overlap"');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trip_driver_no_overlap_update
+BEFORE UPDATE ON trip
+WHEN NEW.status IN ('Scheduled', 'In Progress') AND EXISTS (
+    SELECT 1 FROM trip t
+    WHERE t.driver_id = NEW.driver_id AND t.id <> NEW.id AND t.status IN ('Scheduled', 'In Progress')
+        AND t.start_time < CASE WHEN NEW.end_time IS NULL OR NEW.end_time <= NEW.start_time THEN '9999-12-31' ELSE NEW.end_time END
+        AND NEW.start_time < CASE WHEN t.end_time IS NULL OR t.end_time <= t.start_time THEN '9999-12-31' ELSE t.end_time END
+)
+BEGIN
+    SELECT RAISE(ABORT, 'conflicting key value violates exclusion constraint "trip_driver_no_overlap"');
+END;
+
+-- refresh tokens are stored hashed and rotated on every use; tokens issued from the same login share a family
+CREATE TABLE IF NOT EXISTS refresh_token (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NULL,
+    replaced_by TEXT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family_id ON refresh_token (family_id);
+
+-- access tokens revoked before their expiry, e.g. on logout
+CREATE TABLE IF NOT EXISTS revoked_token (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+-- append-only change history of cars, drivers, trips and users; changes holds {"column": {"old": .., "new": ..}}
+CREATE TABLE IF NOT EXISTS audit_log (
+    id TEXT PRIMARY KEY,
+    resource VARCHAR(20) NOT NULL,
+    resource_id VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    changes TEXT NOT NULL,
+    actor_id VARCHAR(50) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (resource, resource_id, created_at DESC);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_append_only_update
+BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_append_only_delete
+BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+-- status changes of trips with the reason given and the user who made them
+CREATE TABLE IF NOT EXISTS trip_transition (
+    id TEXT PRIMARY KEY,
+    trip_id TEXT NOT NULL REFERENCES trip(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_transition_trip ON trip_transition (trip_id, created_at);
+
+-- every license a driver held and the period it was on record; valid_to is NULL for the current license
+CREATE TABLE IF NOT EXISTS driver_license_history (
+    id TEXT PRIMARY KEY,
+    driver_id TEXT NOT NULL REFERENCES driver(id) ON DELETE CASCADE,
+    driver_license_number VARCHAR(255) NOT NULL,
+    license_expiry DATE NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP DEFAULT NULL,
+    recorded_by VARCHAR(50) NOT NULL DEFAULT '',
+    replaced_by VARCHAR(50) DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_driver_license_history_current ON driver_license_history (driver_id) WHERE valid_to IS NULL;
+
+-- license expiry dates drop the time of day, like postgres's DATE does
+CREATE TRIGGER IF NOT EXISTS driver_license_expiry_insert
+AFTER INSERT ON driver
+WHEN NEW.license_expiry <> strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry)
+BEGIN
+    UPDATE driver SET license_expiry = strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS driver_license_expiry_update
+AFTER UPDATE OF license_expiry ON driver
+WHEN NEW.license_expiry <> strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry)
+BEGIN
+    UPDATE driver SET license_expiry = strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS driver_license_history_expiry_insert
+AFTER INSERT ON driver_license_history
+WHEN NEW.license_expiry <> strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry)
+BEGIN
+    UPDATE driver_license_history SET license_expiry = strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS driver_license_history_expiry_update
+AFTER UPDATE OF license_expiry ON driver_license_history
+WHEN NEW.license_expiry <> strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry)
+BEGIN
+    UPDATE driver_license_history SET license_expiry = strftime('%Y-%m-%d 00:00:00.000000', NEW.license_expiry) WHERE id = NEW.id;
+END;
+
+-- services carried out on a car and the parts fitted
+CREATE TABLE IF NOT EXISTS maintenance_record (
+    id TEXT PRIMARY KEY,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    service_date TIMESTAMP NOT NULL,
+    odometer_km REAL NOT NULL CHECK (odometer_km >= 0),
+    service_type VARCHAR(100) NOT NULL,
+    cost REAL NOT NULL DEFAULT 0.00 CHECK (cost >= 0),
+    workshop VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    parts TEXT NOT NULL DEFAULT '[]',
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_record_car ON maintenance_record (car_id, LOWER(service_type), service_date);
+
+-- services that recur every interval_km kilometers or interval_months months, whichever comes first. The baseline
+-- is the last service before the car's records start.
+CREATE TABLE IF NOT EXISTS maintenance_plan (
+    id TEXT PRIMARY KEY,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    service_type VARCHAR(100) NOT NULL,
+    interval_km INTEGER NOT NULL DEFAULT 0 CHECK (interval_km >= 0),
+    interval_months INTEGER NOT NULL DEFAULT 0 CHECK (interval_months >= 0),
+    baseline_date TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    baseline_odometer_km REAL NOT NULL DEFAULT 0 CHECK (baseline_odometer_km >= 0),
+    created_by VARCHAR(50) DEFAULT NULL,
+    updated_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    CHECK (interval_km > 0 OR interval_months > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenance_plan_car_type ON maintenance_plan (car_id, LOWER(service_type));
+
+-- odometer readings per car, taken when trips start and end, at refuels or entered by hand. A car's readings never
+-- go backwards.
+CREATE TABLE IF NOT EXISTS odometer_reading (
+    id TEXT PRIMARY KEY,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id TEXT DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    reading_km REAL NOT NULL CHECK (reading_km >= 0),
+    source VARCHAR(20) NOT NULL CONSTRAINT odometer_reading_source_check CHECK (source IN ('manual', 'trip_start', 'trip_end', 'refuel')),
+    notes TEXT NOT NULL DEFAULT '',
+    recorded_at TIMESTAMP NOT NULL,
+    recorded_by VARCHAR(50) NOT NULL DEFAULT '',
+    fuel_entry_id TEXT DEFAULT NULL REFERENCES fuel_entry(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_odometer_reading_car ON odometer_reading (car_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_odometer_reading_trip ON odometer_reading (trip_id) WHERE trip_id IS NOT NULL;
+
+-- refuels of a car, the odometer reading taken at each refuel is kept with the car's readings. reference is the fuel
+-- card transaction a refuel was imported from, each transaction is imported once.
+CREATE TABLE IF NOT EXISTS fuel_entry (
+    id TEXT PRIMARY KEY,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    driver_id TEXT DEFAULT NULL REFERENCES driver(id) ON DELETE SET NULL,
+    trip_id TEXT DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    fueled_at TIMESTAMP NOT NULL,
+    liters REAL NOT NULL CHECK (liters > 0),
+    price_per_liter REAL NOT NULL DEFAULT 0 CHECK (price_per_liter >= 0),
+    total_cost REAL NOT NULL DEFAULT 0 CHECK (total_cost >= 0),
+    station VARCHAR(255) NOT NULL DEFAULT '',
+    odometer_km REAL NOT NULL CHECK (odometer_km >= 0),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    reference VARCHAR(255) DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fuel_entry_car ON fuel_entry (car_id, fueled_at);
+CREATE INDEX IF NOT EXISTS idx_fuel_entry_driver ON fuel_entry (driver_id, fueled_at) WHERE driver_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fuel_entry_reference ON fuel_entry (reference) WHERE reference IS NOT NULL;
+
+-- every refuel with the distance driven since the car's previous refuel, NULL for the first one
+CREATE VIEW IF NOT EXISTS fuel_consumption AS
+SELECT f.id, f.car_id, f.driver_id, f.fueled_at, f.liters, f.total_cost, f.odometer_km,
+    f.odometer_km - LAG(f.odometer_km) OVER (PARTITION BY f.car_id ORDER BY f.fueled_at, f.odometer_km) AS distance_km
+FROM fuel_entry f;
+
+-- the highest odometer reading known for each car, car_odometer_km in postgres
+CREATE VIEW IF NOT EXISTS car_odometer AS
+SELECT c.id AS car_id,
+    MAX(
+        COALESCE((SELECT MAX(o.reading_km) FROM odometer_reading o WHERE o.car_id = c.id), 0),
+        COALESCE((SELECT MAX(r.odometer_km) FROM maintenance_record r WHERE r.car_id = c.id), 0),
+        COALESCE((SELECT MAX(p.baseline_odometer_km) FROM maintenance_plan p WHERE p.car_id = c.id), 0)
+    ) AS odometer_km
+FROM car c;
+
+-- every plan with its last service, counted from the latest record of the same type, and when it is next due. Adding
+-- months keeps the time of day and, like postgres, stops at the end of a shorter month.
+CREATE VIEW IF NOT EXISTS maintenance_plan_status AS
+SELECT p.id, p.car_id, p.service_type, p.interval_km, p.interval_months, p.baseline_date, p.baseline_odometer_km,
+    COALESCE(last.service_date, p.baseline_date) AS last_service_date,
+    COALESCE(last.odometer_km, p.baseline_odometer_km) AS last_service_odometer_km,
+    (SELECT o.odometer_km FROM car_odometer o WHERE o.car_id = p.car_id) AS current_odometer_km,
+    CASE WHEN p.interval_months > 0
+        THEN strftime('%Y-%m-%d %H:%M:%S', COALESCE(last.service_date, p.baseline_date), '+' || p.interval_months || ' months', 'floor')
+            || substr(COALESCE(last.service_date, p.baseline_date), 20) END AS next_due_date,
+    CASE WHEN p.interval_km > 0
+        THEN COALESCE(last.odometer_km, p.baseline_odometer_km) + p.interval_km END AS next_due_odometer_km,
+    p.created_by, p.updated_by, p.created_at, p.updated_at
+FROM maintenance_plan p
+LEFT JOIN maintenance_record last ON last.id = (
+    SELECT r.id
+    FROM maintenance_record r
+    WHERE r.car_id = p.car_id AND LOWER(r.service_type) = LOWER(p.service_type)
+    ORDER BY r.service_date DESC, r.odometer_km DESC
+    LIMIT 1
+);
+
+-- the odometer of each car and whether any of its plans passed its next service date or odometer reading,
+-- car_odometer_km and car_service_due in postgres
+CREATE VIEW IF NOT EXISTS car_maintenance AS
+SELECT o.car_id, o.odometer_km,
+    EXISTS (
+        SELECT 1 FROM maintenance_plan_status s
+        WHERE s.car_id = o.car_id
+            AND (s.next_due_date <= strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')
+                OR s.current_odometer_km >= s.next_due_odometer_km)
+    ) AS service_due
+FROM car_odometer o;
+
+-- trackers log in with accounts of their own that may only report positions. GPS points reported by the trackers
+-- are attached to the trip the car was on; a tracker resending a point is ignored.
+CREATE TABLE IF NOT EXISTS car_position (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id TEXT DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    latitude REAL NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude REAL NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    speed_kph REAL NOT NULL DEFAULT 0 CHECK (speed_kph >= 0),
+    heading REAL NOT NULL DEFAULT 0 CHECK (heading >= 0 AND heading < 360),
+    recorded_at TIMESTAMP NOT NULL,
+    received_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    UNIQUE (car_id, recorded_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_position_trip ON car_position (trip_id, recorded_at) WHERE trip_id IS NOT NULL;
+
+-- named areas cars are tracked in and out of: a circle around center_lat/center_lon or a polygon of vertices,
+-- a JSON array of {"lat", "lon"} objects
+CREATE TABLE IF NOT EXISTS geofence (
+    id TEXT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('depot', 'customer', 'restricted')),
+    shape VARCHAR(20) NOT NULL CHECK (shape IN ('circle', 'polygon')),
+    center_lat REAL DEFAULT NULL CHECK (center_lat BETWEEN -90 AND 90),
+    center_lon REAL DEFAULT NULL CHECK (center_lon BETWEEN -180 AND 180),
+    radius_m REAL NOT NULL DEFAULT 0 CHECK (radius_m >= 0),
+    vertices TEXT NOT NULL DEFAULT '[]',
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now', 'localtime')),
+    CHECK (shape <> 'circle' OR (center_lat IS NOT NULL AND center_lon IS NOT NULL AND radius_m > 0))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_geofence_name ON geofence (LOWER(name));
+
+-- a car crossing the boundary of a geofence, detected from the first position reported on the other side
+CREATE TABLE IF NOT EXISTS geofence_event (
+    id TEXT PRIMARY KEY,
+    geofence_id TEXT NOT NULL REFERENCES geofence(id) ON DELETE CASCADE,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    trip_id TEXT DEFAULT NULL REFERENCES trip(id) ON DELETE SET NULL,
+    position_id BIGINT NOT NULL,
+    event VARCHAR(10) NOT NULL CHECK (event IN ('enter', 'exit')),
+    latitude REAL NOT NULL,
+    longitude REAL NOT NULL,
+    occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_event_car ON geofence_event (car_id, geofence_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_geofence_event_geofence ON geofence_event (geofence_id, occurred_at);
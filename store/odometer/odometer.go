@@ -0,0 +1,256 @@
+package odometer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+const readingColumns = `id, car_id, trip_id, fuel_entry_id, reading_km, source, notes, recorded_at, recorded_by`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReading(row scanner) (models.OdometerReading, error) {
+	var reading models.OdometerReading
+	var tripID, fuelEntryID uuid.NullUUID
+	err := row.Scan(
+		&reading.ID,
+		&reading.CarID,
+		&tripID,
+		&fuelEntryID,
+		&reading.ReadingKM,
+		&reading.Source,
+		&reading.Notes,
+		&reading.RecordedAt,
+		&reading.RecordedBy,
+	)
+	if tripID.Valid {
+		reading.TripID = &tripID.UUID
+	}
+	if fuelEntryID.Valid {
+		reading.FuelEntryID = &fuelEntryID.UUID
+	}
+	return reading, err
+}
+
+// Record adds a reading to the odometer of a car inside tx. The car row stays locked until the transaction
+// ends so readings of the same car are checked one after the other. A reading below an earlier one, or above
+// a later one, is a conflict with that reading in the details.
+func Record(ctx context.Context, tx *sql.Tx, dialect store.Dialect, reading *models.OdometerReading) error {
+	var carID uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM car WHERE id = $1 `+dialect.ForUpdate(), reading.CarID).Scan(&carID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NotFound("car %s not found", reading.CarID)
+		}
+		return err
+	}
+
+	previous, err := scanReading(tx.QueryRowContext(ctx, `
+		SELECT `+readingColumns+`
+		FROM odometer_reading
+		WHERE car_id = $1 AND recorded_at <= $2
+		ORDER BY recorded_at DESC, reading_km DESC
+		LIMIT 1
+	`, reading.CarID, reading.RecordedAt))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case previous.ReadingKM > reading.ReadingKM:
+		return backwards(*reading, previous, "below")
+	}
+
+	next, err := scanReading(tx.QueryRowContext(ctx, `
+		SELECT `+readingColumns+`
+		FROM odometer_reading
+		WHERE car_id = $1 AND recorded_at > $2
+		ORDER BY recorded_at, reading_km
+		LIMIT 1
+	`, reading.CarID, reading.RecordedAt))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case next.ReadingKM < reading.ReadingKM:
+		return backwards(*reading, next, "above")
+	}
+
+	reading.ID = uuid.New()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO odometer_reading (`+readingColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, reading.ID, reading.CarID, nullUUID(reading.TripID), nullUUID(reading.FuelEntryID), reading.ReadingKM, reading.Source, reading.Notes, reading.RecordedAt, reading.RecordedBy)
+	if err != nil {
+		return store.DBError(err)
+	}
+	return nil
+}
+
+func backwards(reading models.OdometerReading, other models.OdometerReading, relation string) error {
+	return &models.Error{
+		Kind: models.ErrConflict,
+		Message: fmt.Sprintf("odometer reading of %.1f km at %s is %s the %.1f km recorded at %s",
+			reading.ReadingKM, reading.RecordedAt.Format(time.RFC3339), relation, other.ReadingKM, other.RecordedAt.Format(time.RFC3339)),
+		Details: other,
+	}
+}
+
+// nullUUID stores a missing id as NULL
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+// TripReading returns the reading taken when a trip started or ended, found is false when there is none
+func TripReading(ctx context.Context, tx *sql.Tx, tripID uuid.UUID, source string) (models.OdometerReading, bool, error) {
+	reading, err := scanReading(tx.QueryRowContext(ctx, `
+		SELECT `+readingColumns+`
+		FROM odometer_reading
+		WHERE trip_id = $1 AND source = $2
+		ORDER BY recorded_at DESC
+		LIMIT 1
+	`, tripID, source))
+	if errors.Is(err, sql.ErrNoRows) {
+		return reading, false, nil
+	}
+	if err != nil {
+		return reading, false, err
+	}
+	return reading, true, nil
+}
+
+// readingSortColumns are the fields readings can be sorted by
+var readingSortColumns = map[string]string{
+	"recorded_at": "recorded_at",
+	"reading_km":  "reading_km",
+}
+
+func (s Store) GetOdometerReadings(ctx context.Context, carID string, filter models.OdometerFilter, opts models.ListOptions) ([]models.OdometerReading, int, error) {
+	tracer := otel.Tracer("OdometerStore")
+	ctx, span := tracer.Start(ctx, "GetOdometerReadings-Store")
+	defer span.End()
+
+	readings := []models.OdometerReading{}
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, 0, models.Validation("invalid car id %q", carID)
+	}
+
+	var q store.ListQuery
+	q.Where("car_id = ?", id)
+	if filter.TripID != uuid.Nil {
+		q.Where("trip_id = ?", filter.TripID)
+	}
+	if filter.Source != "" {
+		q.Where("source = ?", filter.Source)
+	}
+	if !filter.From.IsZero() {
+		q.Where("recorded_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("recorded_at < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, readingSortColumns, "recorded_at", "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM odometer_reading ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+readingColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		reading, err := scanReading(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		readings = append(readings, reading)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return readings, total, nil
+}
+
+func (s Store) CreateOdometerReading(ctx context.Context, carID string, readingReq *models.OdometerReadingRequest, actor string) (models.OdometerReading, error) {
+	tracer := otel.Tracer("OdometerStore")
+	ctx, span := tracer.Start(ctx, "CreateOdometerReading-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return models.OdometerReading{}, models.Validation("invalid car id %q", carID)
+	}
+
+	reading := models.OdometerReading{
+		CarID:      id,
+		ReadingKM:  readingReq.ReadingKM,
+		Source:     models.OdometerSourceManual,
+		Notes:      readingReq.Notes,
+		RecordedAt: readingReq.RecordedAt,
+		RecordedBy: actor,
+	}
+	if reading.RecordedAt.IsZero() {
+		reading.RecordedAt = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.OdometerReading{}, err
+	}
+
+	// Defer the rollback or commit
+	defer func() {
+		// if we find any problem with the transaction, we rollback
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			// if everything is fine, we commit the transaction
+			if cmErr := tx.Commit(); cmErr != nil {
+				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = Record(ctx, tx, s.dialect, &reading)
+	if err != nil {
+		return models.OdometerReading{}, err
+	}
+	return reading, nil
+}
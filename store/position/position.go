@@ -0,0 +1,282 @@
+package position
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/geofence"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect store.Dialect
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db, dialect: store.DialectOf(db)}
+}
+
+const positionColumns = `id, car_id, trip_id, latitude, longitude, speed_kph, heading, recorded_at, received_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryer is a *sql.DB or a *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func scanPosition(row scanner) (models.Position, error) {
+	var position models.Position
+	var tripID uuid.NullUUID
+	err := row.Scan(
+		&position.ID,
+		&position.CarID,
+		&tripID,
+		&position.Latitude,
+		&position.Longitude,
+		&position.Speed,
+		&position.Heading,
+		&position.RecordedAt,
+		&position.ReceivedAt,
+	)
+	if tripID.Valid {
+		position.TripID = &tripID.UUID
+	}
+	return position, err
+}
+
+func scanPositions(rows *sql.Rows) ([]models.Position, error) {
+	defer rows.Close()
+
+	positions := []models.Position{}
+	for rows.Next() {
+		position, err := scanPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// Track returns the points of a trip oldest first
+func Track(ctx context.Context, q queryer, tripID uuid.UUID) ([]models.Position, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+positionColumns+`
+		FROM car_position
+		WHERE trip_id = $1
+		ORDER BY recorded_at
+	`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPositions(rows)
+}
+
+// CreatePositions stores a batch of points of a car, each attached to the trip the car was on when it was taken,
+// and records the geofence events of the new points in the same transaction. Points already stored for the same
+// time are skipped, only the new ones are returned.
+func (s Store) CreatePositions(ctx context.Context, carID string, points []models.PositionRequest) (positions []models.Position, events []models.GeofenceEvent, err error) {
+	tracer := otel.Tracer("PositionStore")
+	ctx, span := tracer.Start(ctx, "CreatePositions-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, nil, models.Validation("invalid car id %q", carID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		// if we find any problem with the transaction, we rollback
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+			return
+		}
+		// if everything is fine, we commit the transaction
+		err = tx.Commit()
+	}()
+
+	// the car stays locked until its geofence events are stored, batches of a car are taken one at a time
+	err = tx.QueryRowContext(ctx, `SELECT id FROM car WHERE id = $1 `+s.dialect.ForUpdate(), id).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, models.NotFound("car %s not found", carID)
+		}
+		return nil, nil, err
+	}
+
+	if s.dialect == store.SQLite {
+		positions, err = insertEach(ctx, tx, id, points)
+	} else {
+		positions, err = insertBatch(ctx, tx, id, points)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(positions) == 0 {
+		return positions, nil, nil
+	}
+
+	events, err = geofence.RecordEvents(ctx, tx, id, positions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return positions, events, nil
+}
+
+// insertBatch stores the points in one statement
+func insertBatch(ctx context.Context, tx *sql.Tx, carID uuid.UUID, points []models.PositionRequest) ([]models.Position, error) {
+	latitudes := make([]float64, len(points))
+	longitudes := make([]float64, len(points))
+	speeds := make([]float64, len(points))
+	headings := make([]float64, len(points))
+	recordedAt := make([]string, len(points))
+	for i, point := range points {
+		latitudes[i] = point.Latitude
+		longitudes[i] = point.Longitude
+		speeds[i] = point.Speed
+		headings[i] = point.Heading
+		recordedAt[i] = point.Timestamp.Format(time.RFC3339Nano)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO car_position (car_id, trip_id, latitude, longitude, speed_kph, heading, recorded_at, received_at)
+		SELECT $1, car_trip_at($1, p.recorded_at), p.latitude, p.longitude, p.speed_kph, p.heading, p.recorded_at, $7
+		FROM unnest($2::float8[], $3::float8[], $4::float8[], $5::float8[], $6::timestamp[])
+			AS p(latitude, longitude, speed_kph, heading, recorded_at)
+		ON CONFLICT (car_id, recorded_at) DO NOTHING
+		RETURNING `+positionColumns,
+		carID,
+		pq.Array(latitudes),
+		pq.Array(longitudes),
+		pq.Array(speeds),
+		pq.Array(headings),
+		pq.Array(recordedAt),
+		time.Now(),
+	)
+	if err != nil {
+		return nil, store.DBError(err)
+	}
+	return scanPositions(rows)
+}
+
+// insertEach stores the points one statement at a time, SQLite has no arrays to unnest. The subquery is
+// car_trip_at.
+func insertEach(ctx context.Context, tx *sql.Tx, carID uuid.UUID, points []models.PositionRequest) ([]models.Position, error) {
+	receivedAt := time.Now()
+	positions := []models.Position{}
+	for _, point := range points {
+		position, err := scanPosition(tx.QueryRowContext(ctx, `
+			INSERT INTO car_position (car_id, trip_id, latitude, longitude, speed_kph, heading, recorded_at, received_at)
+			VALUES ($1, (
+				SELECT id FROM trip
+				WHERE car_id = $1
+					AND ((status = 'In Progress' AND start_time <= $6)
+						OR (status = 'Completed' AND start_time <= $6 AND $6 < `+store.TripWindowEnd+`))
+				ORDER BY start_time DESC
+				LIMIT 1
+			), $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (car_id, recorded_at) DO NOTHING
+			RETURNING `+positionColumns,
+			carID,
+			point.Latitude,
+			point.Longitude,
+			point.Speed,
+			point.Heading,
+			point.Timestamp,
+			receivedAt,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, store.DBError(err)
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// positionSortColumns are the fields positions can be sorted by
+var positionSortColumns = map[string]string{
+	"timestamp":   "recorded_at",
+	"received_at": "received_at",
+	"speed":       "speed_kph",
+}
+
+func (s Store) GetPositions(ctx context.Context, filter models.PositionFilter, opts models.ListOptions) ([]models.Position, int, error) {
+	tracer := otel.Tracer("PositionStore")
+	ctx, span := tracer.Start(ctx, "GetPositions-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	if filter.CarID != uuid.Nil {
+		q.Where("car_id = ?", filter.CarID)
+	}
+	if filter.TripID != uuid.Nil {
+		q.Where("trip_id = ?", filter.TripID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("recorded_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("recorded_at < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, positionSortColumns, "recorded_at", "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := ` FROM car_position ` + q.WhereClause()
+
+	var total int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+from, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+positionColumns+from+" "+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	positions, err := scanPositions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return positions, total, nil
+}
+
+// GetTripTrack returns the points of a trip oldest first
+func (s Store) GetTripTrack(ctx context.Context, tripID string) ([]models.Position, error) {
+	tracer := otel.Tracer("PositionStore")
+	ctx, span := tracer.Start(ctx, "GetTripTrack-Store")
+	defer span.End()
+
+	id, err := uuid.Parse(tripID)
+	if err != nil {
+		return nil, models.Validation("invalid trip id %q", tripID)
+	}
+	return Track(ctx, s.db, id)
+}
@@ -0,0 +1,198 @@
+//go:build cgo
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// OpenSQLite opens the SQLite database in the file at path, creating it if needed. The stores' queries run on it
+// unchanged: $1 placeholders are bound like postgres binds them and times are stored as sortable text.
+//
+// Foreign keys are enforced, and transactions take the write lock when they begin, which stands in for the
+// SELECT ... FOR UPDATE of the postgres stores.
+func OpenSQLite(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_foreign_keys": {"1"},
+		"_busy_timeout": {"5000"},
+		"_txlock":       {"immediate"},
+		"_journal_mode": {"WAL"},
+	}.Encode()
+
+	db := sql.OpenDB(sqliteConnector{dsn: dsn})
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type sqliteConnector struct {
+	dsn string
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return sqliteDriver{}.Open(c.dsn)
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return sqliteDriver{}
+}
+
+// sqliteDriver opens SQLite connections that accept the stores' postgres placeholders and times
+type sqliteDriver struct{}
+
+func (sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type sqliteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	return sqliteStmt{stmt.(*sqlite3.SQLiteStmt)}, nil
+}
+
+func (c sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.SQLiteConn.ExecContext(ctx, rebind(query), sqliteArgs(args))
+}
+
+func (c sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.SQLiteConn.QueryContext(ctx, rebind(query), sqliteArgs(args))
+}
+
+type sqliteStmt struct {
+	*sqlite3.SQLiteStmt
+}
+
+func (s sqliteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.SQLiteStmt.ExecContext(ctx, sqliteArgs(args))
+}
+
+func (s sqliteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.SQLiteStmt.QueryContext(ctx, sqliteArgs(args))
+}
+
+// rebind turns the $1 placeholders of a query into SQLite's ?1, which bind by position as well. SQLite reads $1 as
+// a named parameter and numbers those in the order they appear. Placeholders in quoted text and comments are left
+// alone.
+func rebind(query string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			quote = '\n'
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			c = '?'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// sqliteArgs stores times as their wall clock in sqliteTimeFormat, the way postgres stores a TIMESTAMP
+func sqliteArgs(args []driver.NamedValue) []driver.NamedValue {
+	for i, arg := range args {
+		if t, ok := arg.Value.(time.Time); ok {
+			args[i].Value = t.Format(sqliteTimeFormat)
+		}
+	}
+	return args
+}
+
+// sqliteError turns the constraint violations SQLite reports into domain errors, like DBError does for postgres.
+// The overlapping trips the exclusion constraints reject in postgres are rejected by triggers in SQLite.
+func sqliteError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintTrigger:
+		log.Println("Database rejected a write: ", sqliteErr.Error())
+		return constraintError(models.ErrConflict, sqliteConstraint(sqliteErr), duplicateFallback, err)
+	case sqlite3.ErrConstraintForeignKey:
+		log.Println("Database rejected a write: ", sqliteErr.Error())
+		return constraintError(models.ErrConflict, "", referenceFallback, err)
+	case sqlite3.ErrConstraintCheck:
+		log.Println("Database rejected a write: ", sqliteErr.Error())
+		return constraintError(models.ErrValidation, sqliteConstraint(sqliteErr), checkFallback, err)
+	case sqlite3.ErrConstraintNotNull:
+		_, column, _ := strings.Cut(sqliteErr.Error(), ".")
+		return &models.Error{Kind: models.ErrValidation, Message: column + " is required", Err: err}
+	}
+	return err
+}
+
+// sqliteUniqueIndexes are the unique indexes SQLite reports by their columns, by the postgres name of a constraint on
+// those columns
+var sqliteUniqueIndexes = map[string]string{
+	"fuel_entry_reference_key": "idx_fuel_entry_reference",
+}
+
+// sqliteConstraint returns the postgres name of the constraint a SQLite error reports. SQLite names unique
+// constraints by their columns, unique indexes on expressions and named checks by their name, and the booking
+// triggers quote the exclusion constraint they stand in for.
+func sqliteConstraint(sqliteErr sqlite3.Error) string {
+	message := sqliteErr.Error()
+	if _, quoted, found := strings.Cut(message, `"`); found {
+		constraint, _, _ := strings.Cut(quoted, `"`)
+		return constraint
+	}
+
+	_, failed, found := strings.Cut(message, "constraint failed: ")
+	if !found {
+		return ""
+	}
+	if index, found := strings.CutPrefix(failed, "index "); found {
+		return strings.Trim(index, "'")
+	}
+	if sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return failed
+	}
+
+	// table.a, table.b is the table_a_b_key postgres gives a unique constraint
+	var table string
+	var columns []string
+	for _, column := range strings.Split(failed, ", ") {
+		table, column, _ = strings.Cut(column, ".")
+		columns = append(columns, column)
+	}
+	constraint := table + "_" + strings.Join(columns, "_") + "_key"
+	if index, ok := sqliteUniqueIndexes[constraint]; ok {
+		return index
+	}
+	return constraint
+}
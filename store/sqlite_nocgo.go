@@ -0,0 +1,28 @@
+//go:build !cgo
+
+package store
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// errNoSQLite is returned for SQLite databases by builds without cgo, which the SQLite driver needs
+var errNoSQLite = errors.New("store: SQLite needs a build with cgo enabled")
+
+// OpenSQLite is not available without cgo, see the cgo build
+func OpenSQLite(path string) (*sql.DB, error) {
+	return nil, errNoSQLite
+}
+
+// sqliteDriver is never opened without cgo, it only exists for DialectOf
+type sqliteDriver struct{}
+
+func (sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	return nil, errNoSQLite
+}
+
+func sqliteError(err error) error {
+	return err
+}
@@ -0,0 +1,542 @@
+// Package storetest checks that a set of stores keeps the rules the postgres schema enforces: unique columns,
+// foreign keys, cascading deletes, soft deleted drivers, trip bookings and status transitions. Every backend runs
+// the same checks, so the stores stay interchangeable; storetest_test.go runs them with go test.
+//
+// The checks create their own rows under names unique to the run and delete them again, so they can run against a
+// database that holds other data.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+)
+
+// Stores are the stores under check
+type Stores struct {
+	Car    store.CarStoreInterface
+	Engine store.EngineStoreInterface
+	User   store.UserStoreInterface
+	Driver store.DriverStoreInterface
+	Trip   store.TripStoreInterface
+}
+
+// actor is recorded as the creator of every row the checks write
+const actor = "storetest"
+
+var checks = []struct {
+	name string
+	run  func(s *suite) error
+}{
+	{"users are unique by username and email", checkUsers},
+	{"cars need an engine and a known status", checkCars},
+	{"drivers are unique by license and soft deleted", checkDrivers},
+	{"trips are booked and moved through their statuses", checkTrips},
+	{"running trips hand their car over when moved or deleted", checkCarHandover},
+	{"lists reject unknown sort fields", checkSort},
+}
+
+// Run runs every check against stores as a subtest of t
+func Run(t *testing.T, stores Stores) {
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			s := &suite{Stores: stores, ctx: context.Background(), run: uuid.NewString()[:8]}
+			defer s.cleanup()
+			if err := check.run(s); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// suite is the state of one check: the rows it created, deleted again in reverse order once it is done
+type suite struct {
+	Stores
+	ctx      context.Context
+	run      string
+	seq      int
+	deferred []func()
+}
+
+func (s *suite) cleanup() {
+	for i := len(s.deferred) - 1; i >= 0; i-- {
+		s.deferred[i]()
+	}
+}
+
+// name returns a name unique to the run
+func (s *suite) name(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%s%d", prefix, s.run, s.seq)
+}
+
+func (s *suite) engine() (models.Engine, error) {
+	engine, err := s.Engine.CreateEngine(s.ctx, &models.EngineRequest{Displacement: 1600, NoOfCylinders: 4, CarRange: 500}, actor)
+	if err != nil {
+		return engine, fmt.Errorf("create engine: %w", err)
+	}
+	s.deferred = append(s.deferred, func() { s.Engine.DeleteEngine(s.ctx, engine.EngineID.String()) })
+	return engine, nil
+}
+
+func (s *suite) carRequest(engineID uuid.UUID, status string) *models.CarRequest {
+	return &models.CarRequest{
+		RegistrationNumber: s.name("KST"),
+		Name:               "Storetest Car",
+		Year:               "2024",
+		Brand:              "Toyota",
+		FuelType:           "Gasoline",
+		Engine:             models.Engine{EngineID: engineID},
+		Status:             status,
+		Price:              20000,
+	}
+}
+
+func (s *suite) car(engineID uuid.UUID) (models.Car, error) {
+	car, err := s.Car.CreateCar(s.ctx, s.carRequest(engineID, models.CarStatusAvailable), actor)
+	if err != nil {
+		return car, fmt.Errorf("create car: %w", err)
+	}
+	s.deferred = append(s.deferred, func() { s.Car.DeleteCar(s.ctx, car.ID.String()) })
+	return car, nil
+}
+
+func (s *suite) userRequest() *models.UserRequest {
+	username := s.name("storetest")
+	return &models.UserRequest{
+		UserName:        username,
+		Password:        "Storetest1!",
+		ConfirmPassword: "Storetest1!",
+		FirstName:       "Store",
+		LastName:        "Test",
+		Email:           username + "@example.com",
+		PhoneNumber:     "254700000000",
+		Role:            models.RoleDriver,
+	}
+}
+
+func (s *suite) user() (models.User, error) {
+	user, err := s.User.CreateUser(s.ctx, s.userRequest(), actor)
+	if err != nil {
+		return user, fmt.Errorf("create user: %w", err)
+	}
+	s.deferred = append(s.deferred, func() { s.User.DeleteUser(s.ctx, user.ID.String()) })
+	return user, nil
+}
+
+func (s *suite) driver(userID uuid.UUID) (models.Driver, error) {
+	driver, err := s.Driver.CreateDriver(s.ctx, &models.DriverRequest{
+		UserID:          userID,
+		DriverLicenseNo: s.name("DL"),
+		LicenseExpiry:   time.Now().AddDate(2, 0, 0),
+	}, actor)
+	if err != nil {
+		return driver, fmt.Errorf("create driver: %w", err)
+	}
+	return driver, nil
+}
+
+// expect returns an error unless err is of the given kind
+func expect(what string, err error, kind error) error {
+	if !errors.Is(err, kind) {
+		return fmt.Errorf("%s: got error %v, want %v", what, err, kind)
+	}
+	return nil
+}
+
+func checkUsers(s *suite) error {
+	user, err := s.user()
+	if err != nil {
+		return err
+	}
+
+	sameName := s.userRequest()
+	sameName.UserName = user.UserName
+	_, err = s.User.CreateUser(s.ctx, sameName, actor)
+	if err := expect("create user with a taken username", err, models.ErrConflict); err != nil {
+		return err
+	}
+
+	sameEmail := s.userRequest()
+	sameEmail.Email = user.Email
+	_, err = s.User.CreateUser(s.ctx, sameEmail, actor)
+	if err := expect("create user with a taken email", err, models.ErrConflict); err != nil {
+		return err
+	}
+	// clients are told which field clashed, not the value it clashed with
+	if strings.Contains(err.Error(), user.Email) {
+		return fmt.Errorf("create user with a taken email: got message %q, want it without the email", err.Error())
+	}
+
+	unknownRole := s.userRequest()
+	unknownRole.Role = "pilot"
+	_, err = s.User.CreateUser(s.ctx, unknownRole, actor)
+	if err := expect("create user with an unknown role", err, models.ErrValidation); err != nil {
+		return err
+	}
+
+	profile, err := s.User.GetUserProfile(s.ctx, user.ID.String())
+	if err != nil {
+		return fmt.Errorf("get user profile: %w", err)
+	}
+	if profile.Password != "" {
+		return errors.New("get user profile: the password hash is returned")
+	}
+	login, err := s.User.GetUserByUsername(s.ctx, user.UserName)
+	if err != nil {
+		return fmt.Errorf("get user by username: %w", err)
+	}
+	if login.Password == "" {
+		return errors.New("get user by username: the password hash is missing")
+	}
+
+	_, err = s.User.GetUserProfile(s.ctx, "not-a-uuid")
+	return expect("get user with a malformed id", err, models.ErrValidation)
+}
+
+func checkCars(s *suite) error {
+	_, err := s.Car.CreateCar(s.ctx, s.carRequest(uuid.New(), models.CarStatusAvailable), actor)
+	if err := expect("create car with an unknown engine", err, models.ErrValidation); err != nil {
+		return err
+	}
+
+	engine, err := s.engine()
+	if err != nil {
+		return err
+	}
+	_, err = s.Car.CreateCar(s.ctx, s.carRequest(engine.EngineID, "Flying"), actor)
+	if err := expect("create car with an unknown status", err, models.ErrValidation); err != nil {
+		return err
+	}
+
+	car, err := s.car(engine.EngineID)
+	if err != nil {
+		return err
+	}
+	got, err := s.Car.GetCarById(s.ctx, car.ID.String())
+	if err != nil {
+		return fmt.Errorf("get car: %w", err)
+	}
+	if got.Engine.EngineID != engine.EngineID || got.Engine.Displacement != engine.Displacement {
+		return fmt.Errorf("get car: got engine %+v, want %+v", got.Engine, engine)
+	}
+
+	_, err = s.Car.UpdateCar(s.ctx, car.ID.String(), s.carRequest(uuid.New(), models.CarStatusAvailable), actor)
+	if err := expect("update car to an unknown engine", err, models.ErrConflict); err != nil {
+		return err
+	}
+	_, err = s.Car.UpdateCar(s.ctx, uuid.NewString(), s.carRequest(engine.EngineID, models.CarStatusAvailable), actor)
+	if err := expect("update unknown car", err, models.ErrNotFound); err != nil {
+		return err
+	}
+
+	// deleting the engine deletes its cars
+	if _, err := s.Engine.DeleteEngine(s.ctx, engine.EngineID.String()); err != nil {
+		return fmt.Errorf("delete engine: %w", err)
+	}
+	_, err = s.Car.GetCarById(s.ctx, car.ID.String())
+	return expect("get car of a deleted engine", err, models.ErrNotFound)
+}
+
+func checkDrivers(s *suite) error {
+	_, err := s.Driver.CreateDriver(s.ctx, &models.DriverRequest{UserID: uuid.New(), DriverLicenseNo: s.name("DL"), LicenseExpiry: time.Now()}, actor)
+	if err := expect("create driver of an unknown user", err, models.ErrValidation); err != nil {
+		return err
+	}
+
+	user, err := s.user()
+	if err != nil {
+		return err
+	}
+	driver, err := s.driver(user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Driver.CreateDriver(s.ctx, &models.DriverRequest{UserID: user.ID, DriverLicenseNo: driver.DriverLicenseNo, LicenseExpiry: time.Now()}, actor)
+	if err := expect("create driver with a taken license", err, models.ErrConflict); err != nil {
+		return err
+	}
+
+	renewed := &models.DriverUpdateRequest{DriverLicenseNo: s.name("DL"), LicenseExpiry: time.Now().AddDate(5, 0, 0)}
+	if _, err := s.Driver.UpdateDriver(s.ctx, driver.ID.String(), renewed, actor); err != nil {
+		return fmt.Errorf("update driver: %w", err)
+	}
+	licenses, err := s.Driver.GetDriverLicenses(s.ctx, driver.ID.String())
+	if err != nil {
+		return fmt.Errorf("get driver licenses: %w", err)
+	}
+	if len(licenses) != 2 || licenses[0].DriverLicenseNo != renewed.DriverLicenseNo || licenses[1].ValidTo.IsZero() {
+		return fmt.Errorf("get driver licenses: got %+v, want the renewed license before the closed original", licenses)
+	}
+
+	// a soft deleted driver is hidden but keeps their license and history
+	if _, err := s.Driver.SoftDeleteDriver(s.ctx, driver.ID.String(), actor); err != nil {
+		return fmt.Errorf("soft delete driver: %w", err)
+	}
+	_, err = s.Driver.GetDriverById(s.ctx, driver.ID.String())
+	if err := expect("get soft deleted driver", err, models.ErrNotFound); err != nil {
+		return err
+	}
+	drivers, _, err := s.Driver.GetDrivers(s.ctx, models.DriverFilter{}, models.ListOptions{Limit: 1000})
+	if err != nil {
+		return fmt.Errorf("get drivers: %w", err)
+	}
+	for _, listed := range drivers {
+		if listed.ID == driver.ID {
+			return errors.New("get drivers: the soft deleted driver is listed")
+		}
+	}
+	if _, err := s.Driver.GetDriverLicenses(s.ctx, driver.ID.String()); err != nil {
+		return fmt.Errorf("get licenses of soft deleted driver: %w", err)
+	}
+	_, err = s.Driver.CreateDriver(s.ctx, &models.DriverRequest{UserID: user.ID, DriverLicenseNo: renewed.DriverLicenseNo, LicenseExpiry: time.Now()}, actor)
+	if err := expect("create driver with the license of a soft deleted driver", err, models.ErrConflict); err != nil {
+		return err
+	}
+
+	// deleting the user deletes their drivers
+	if _, err := s.User.DeleteUser(s.ctx, user.ID.String()); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	_, err = s.Driver.GetDriverLicenses(s.ctx, driver.ID.String())
+	return expect("get licenses of the driver of a deleted user", err, models.ErrNotFound)
+}
+
+func checkTrips(s *suite) error {
+	engine, err := s.engine()
+	if err != nil {
+		return err
+	}
+	car, err := s.car(engine.EngineID)
+	if err != nil {
+		return err
+	}
+	user, err := s.user()
+	if err != nil {
+		return err
+	}
+	driver, err := s.driver(user.ID)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	trip := func(carID uuid.UUID, status string, from time.Duration, to time.Duration) *models.TripRequest {
+		return &models.TripRequest{
+			Description:   "storetest trip",
+			DriverID:      driver.ID,
+			CarID:         carID,
+			StartLocation: s.name("Origin"),
+			EndLocation:   s.name("Destination"),
+			StartTime:     start.Add(from),
+			EndTime:       start.Add(to),
+			Status:        status,
+		}
+	}
+
+	_, err = s.Trip.CreateTrip(s.ctx, trip(uuid.New(), models.TripStatusDraft, 0, time.Hour), actor)
+	if err := expect("create trip with an unknown car", err, models.ErrConflict); err != nil {
+		return err
+	}
+
+	booked, err := s.Trip.CreateTrip(s.ctx, trip(car.ID, models.TripStatusScheduled, 0, 2*time.Hour), actor)
+	if err != nil {
+		return fmt.Errorf("create scheduled trip: %w", err)
+	}
+	_, err = s.Trip.CreateTrip(s.ctx, trip(car.ID, models.TripStatusScheduled, time.Hour, 3*time.Hour), actor)
+	if err := expect("create overlapping trip", err, models.ErrConflict); err != nil {
+		return err
+	}
+	var conflict *models.Error
+	if !errors.As(err, &conflict) {
+		return fmt.Errorf("create overlapping trip: got %T, want *models.Error", err)
+	}
+	if other, ok := conflict.Details.(models.TripBooking); !ok || other.ID != booked.ID || !other.StartTime.Equal(booked.StartTime) {
+		return fmt.Errorf("create overlapping trip: got details %+v, want the booking of trip %s", conflict.Details, booked.ID)
+	}
+	// drafts hold no booking
+	draft, err := s.Trip.CreateTrip(s.ctx, trip(car.ID, models.TripStatusDraft, time.Hour, 3*time.Hour), actor)
+	if err != nil {
+		return fmt.Errorf("create overlapping draft: %w", err)
+	}
+	stored, err := s.Trip.GetTripById(s.ctx, draft.ID.String())
+	if err != nil {
+		return fmt.Errorf("get draft: %w", err)
+	}
+	edit := trip(car.ID, models.TripStatusDraft, time.Hour, 4*time.Hour)
+	edit.Description = "storetest trip, edited"
+	updated, err := s.Trip.UpdateTrip(s.ctx, draft.ID.String(), edit, "storetest-editor")
+	if err != nil {
+		return fmt.Errorf("update draft: %w", err)
+	}
+	if updated.CreatedBy != actor || !updated.CreatedAt.Equal(stored.CreatedAt) || updated.UpdatedBy != "storetest-editor" {
+		return fmt.Errorf("update draft: got created by %q at %v, updated by %q, want created by %q at %v, updated by storetest-editor",
+			updated.CreatedBy, updated.CreatedAt, updated.UpdatedBy, actor, stored.CreatedAt)
+	}
+
+	// start the trip with an odometer reading, then complete it with another one
+	_, err = s.Trip.UpdateTripStatus(s.ctx, booked.ID.String(), models.TripStatusChange{
+		From: models.TripStatusScheduled, To: models.TripStatusInProgress, StartTime: start, OdometerKM: 1000,
+	}, actor)
+	if err != nil {
+		return fmt.Errorf("start trip: %w", err)
+	}
+	if got, err := s.Car.GetCarById(s.ctx, car.ID.String()); err != nil || got.Status != models.CarStatusInUse {
+		return fmt.Errorf("car of a started trip: got status %q (%v), want %q", got.Status, err, models.CarStatusInUse)
+	}
+	_, err = s.Trip.UpdateTripStatus(s.ctx, booked.ID.String(), models.TripStatusChange{
+		From: models.TripStatusScheduled, To: models.TripStatusInProgress,
+	}, actor)
+	if err := expect("start a trip twice", err, models.ErrConflict); err != nil {
+		return err
+	}
+	_, err = s.Trip.UpdateTripStatus(s.ctx, booked.ID.String(), models.TripStatusChange{
+		From: models.TripStatusInProgress, To: models.TripStatusCompleted, EndTime: start.Add(2 * time.Hour),
+	}, actor)
+	if err := expect("complete a trip without a distance", err, models.ErrValidation); err != nil {
+		return err
+	}
+	_, err = s.Trip.UpdateTripStatus(s.ctx, booked.ID.String(), models.TripStatusChange{
+		From: models.TripStatusInProgress, To: models.TripStatusCompleted, EndTime: start.Add(2 * time.Hour), OdometerKM: 1120, DistanceKM: 50,
+	}, actor)
+	if err := expect("complete a trip with a distance the odometer readings disagree with", err, models.ErrValidation); err != nil {
+		return err
+	}
+	completed, err := s.Trip.UpdateTripStatus(s.ctx, booked.ID.String(), models.TripStatusChange{
+		From: models.TripStatusInProgress, To: models.TripStatusCompleted, EndTime: start.Add(2 * time.Hour), OdometerKM: 1120,
+	}, actor)
+	if err != nil {
+		return fmt.Errorf("complete trip: %w", err)
+	}
+	if completed.DistanceKM != 120 {
+		return fmt.Errorf("complete trip: got distance %v, want the 120 km between the odometer readings", completed.DistanceKM)
+	}
+	got, err := s.Car.GetCarById(s.ctx, car.ID.String())
+	if err != nil || got.Status != models.CarStatusAvailable || got.OdometerKM != 1120 {
+		return fmt.Errorf("car of a completed trip: got status %q at %v km (%v), want %q at 1120 km", got.Status, got.OdometerKM, err, models.CarStatusAvailable)
+	}
+
+	transitions, err := s.Trip.GetTripTransitions(s.ctx, booked.ID.String())
+	if err != nil {
+		return fmt.Errorf("get trip transitions: %w", err)
+	}
+	if len(transitions) != 2 || transitions[0].ToStatus != models.TripStatusInProgress || transitions[1].ToStatus != models.TripStatusCompleted {
+		return fmt.Errorf("get trip transitions: got %+v, want the start then the completion", transitions)
+	}
+
+	// a car in maintenance takes no trips
+	if _, err := s.Car.UpdateCar(s.ctx, car.ID.String(), s.carRequest(engine.EngineID, models.CarStatusMaintenance), actor); err != nil {
+		return fmt.Errorf("update car: %w", err)
+	}
+	_, err = s.Trip.CreateTrip(s.ctx, trip(car.ID, models.TripStatusScheduled, 24*time.Hour, 25*time.Hour), actor)
+	if err := expect("schedule trip on a car in maintenance", err, models.ErrConflict); err != nil {
+		return err
+	}
+
+	// deleting the car deletes its trips
+	if _, err := s.Car.DeleteCar(s.ctx, car.ID.String()); err != nil {
+		return fmt.Errorf("delete car: %w", err)
+	}
+	_, err = s.Trip.GetTripById(s.ctx, draft.ID.String())
+	return expect("get trip of a deleted car", err, models.ErrNotFound)
+}
+
+func checkCarHandover(s *suite) error {
+	engine, err := s.engine()
+	if err != nil {
+		return err
+	}
+	first, err := s.car(engine.EngineID)
+	if err != nil {
+		return err
+	}
+	second, err := s.car(engine.EngineID)
+	if err != nil {
+		return err
+	}
+	user, err := s.user()
+	if err != nil {
+		return err
+	}
+	driver, err := s.driver(user.ID)
+	if err != nil {
+		return err
+	}
+	status := func(car models.Car, want string) error {
+		got, err := s.Car.GetCarById(s.ctx, car.ID.String())
+		if err != nil || got.Status != want {
+			return fmt.Errorf("car %s: got status %q (%v), want %q", car.ID, got.Status, err, want)
+		}
+		return nil
+	}
+
+	start := time.Now().Truncate(time.Second)
+	tripReq := &models.TripRequest{
+		Description:   "storetest trip",
+		DriverID:      driver.ID,
+		CarID:         first.ID,
+		StartLocation: s.name("Origin"),
+		EndLocation:   s.name("Destination"),
+		StartTime:     start,
+		EndTime:       start.Add(time.Hour),
+		Status:        models.TripStatusScheduled,
+	}
+	trip, err := s.Trip.CreateTrip(s.ctx, tripReq, actor)
+	if err != nil {
+		return fmt.Errorf("create trip: %w", err)
+	}
+	_, err = s.Trip.UpdateTripStatus(s.ctx, trip.ID.String(), models.TripStatusChange{
+		From: models.TripStatusScheduled, To: models.TripStatusInProgress, StartTime: start,
+	}, actor)
+	if err != nil {
+		return fmt.Errorf("start trip: %w", err)
+	}
+
+	// moving the running trip frees the first car and takes the second
+	tripReq.CarID = second.ID
+	tripReq.Status = models.TripStatusInProgress
+	if _, err := s.Trip.UpdateTrip(s.ctx, trip.ID.String(), tripReq, actor); err != nil {
+		return fmt.Errorf("move trip: %w", err)
+	}
+	if err := status(first, models.CarStatusAvailable); err != nil {
+		return fmt.Errorf("car a running trip left: %w", err)
+	}
+	if err := status(second, models.CarStatusInUse); err != nil {
+		return fmt.Errorf("car a running trip moved to: %w", err)
+	}
+
+	// deleting it frees the second car too
+	if _, err := s.Trip.DeleteTrip(s.ctx, trip.ID.String(), actor); err != nil {
+		return fmt.Errorf("delete trip: %w", err)
+	}
+	if err := status(second, models.CarStatusAvailable); err != nil {
+		return fmt.Errorf("car of a deleted running trip: %w", err)
+	}
+	return nil
+}
+
+func checkSort(s *suite) error {
+	opts := models.ListOptions{Limit: 10, Sort: "colour"}
+	_, _, err := s.Car.SearchCars(s.ctx, models.CarFilter{}, opts)
+	if err := expect("search cars by an unknown field", err, models.ErrInvalidSort); err != nil {
+		return err
+	}
+	_, _, err = s.User.GetUsers(s.ctx, models.UserFilter{}, opts)
+	if err := expect("get users by an unknown field", err, models.ErrInvalidSort); err != nil {
+		return err
+	}
+	_, _, err = s.Driver.GetDrivers(s.ctx, models.DriverFilter{}, opts)
+	if err := expect("get drivers by an unknown field", err, models.ErrInvalidSort); err != nil {
+		return err
+	}
+	_, _, err = s.Trip.GetTrips(s.ctx, models.TripFilter{}, opts)
+	return expect("get trips by an unknown field", err, models.ErrInvalidSort)
+}
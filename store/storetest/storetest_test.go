@@ -0,0 +1,94 @@
+package storetest_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JulianaSau/carzone/store"
+	carStore "github.com/JulianaSau/carzone/store/car"
+	driverStore "github.com/JulianaSau/carzone/store/driver"
+	engineStore "github.com/JulianaSau/carzone/store/engine"
+	"github.com/JulianaSau/carzone/store/memory"
+	"github.com/JulianaSau/carzone/store/migrations"
+	"github.com/JulianaSau/carzone/store/storetest"
+	tripStore "github.com/JulianaSau/carzone/store/trip"
+	userStore "github.com/JulianaSau/carzone/store/user"
+	_ "github.com/lib/pq"
+)
+
+func TestMemory(t *testing.T) {
+	db := memory.New()
+	storetest.Run(t, storetest.Stores{
+		Car:    memory.NewCarStore(db),
+		Engine: memory.NewEngineStore(db),
+		User:   memory.NewUserStore(db),
+		Driver: memory.NewDriverStore(db),
+		Trip:   memory.NewTripStore(db),
+	})
+}
+
+// TestSQLite checks the database stores on a scratch SQLite database with the current schema
+func TestSQLite(t *testing.T) {
+	db, err := store.OpenSQLite(filepath.Join(t.TempDir(), "storetest.db"))
+	if err != nil {
+		t.Skipf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db)
+	storetest.Run(t, databaseStores(db))
+}
+
+// TestPostgres checks the database stores on the postgres database of the DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and
+// DB_NAME environment variables, the ones the server connects with. It is skipped when DB_HOST is not set. The
+// database is migrated to the current schema; the checks delete the rows they create.
+func TestPostgres(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set")
+	}
+
+	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	))
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db)
+	storetest.Run(t, databaseStores(db))
+}
+
+func databaseStores(db *sql.DB) storetest.Stores {
+	return storetest.Stores{
+		Car:    carStore.New(db),
+		Engine: engineStore.New(db),
+		User:   userStore.New(db),
+		Driver: driverStore.New(db),
+		Trip:   tripStore.New(db),
+	}
+}
+
+// migrate gives the database the current schema
+func migrate(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
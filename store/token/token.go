@@ -0,0 +1,215 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type TokenStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+func (t *TokenStore) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "CreateRefreshToken-Store")
+	defer span.End()
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := t.db.ExecContext(ctx,
+		`
+		INSERT INTO refresh_token (id, user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`,
+		token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	return *token, nil
+}
+
+func (t *TokenStore) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "GetRefreshToken-Store")
+	defer span.End()
+
+	var token models.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy uuid.NullUUID
+
+	err := t.db.QueryRowContext(ctx,
+		`
+		SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+		FROM refresh_token
+		WHERE token_hash = $1
+		`,
+		tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&revokedAt,
+		&replacedBy,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, models.ErrInvalidRefreshToken
+		}
+		return models.RefreshToken{}, err
+	}
+	token.RevokedAt = revokedAt.Time
+	token.ReplacedBy = replacedBy.UUID
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes the presented token and stores its replacement in one transaction.
+// The update only matches a token that is still active, so two concurrent refreshes cannot both win.
+func (t *TokenStore) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) (models.RefreshToken, error) {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "RotateRefreshToken-Store")
+	defer span.End()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	next.ID = uuid.New()
+	next.CreatedAt = time.Now()
+
+	results, err := tx.ExecContext(ctx,
+		`
+		UPDATE refresh_token SET revoked_at = $1, replaced_by = $2
+		WHERE id = $3 AND revoked_at IS NULL
+		`,
+		next.CreatedAt, next.ID, oldID)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	if rowsAffected == 0 {
+		err = models.ErrRefreshTokenReused
+		return models.RefreshToken{}, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO refresh_token (id, user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`,
+		next.ID, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	return *next, nil
+}
+
+// RevokeTokenFamily revokes every refresh token that descends from the same login
+func (t *TokenStore) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "RevokeTokenFamily-Store")
+	defer span.End()
+
+	_, err := t.db.ExecContext(ctx,
+		`UPDATE refresh_token SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`,
+		time.Now(), familyID)
+	return err
+}
+
+// RevokeUserTokens revokes every refresh token of the user, e.g. when the account is deactivated
+func (t *TokenStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "RevokeUserTokens-Store")
+	defer span.End()
+
+	_, err := t.db.ExecContext(ctx,
+		`UPDATE refresh_token SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(), userID)
+	return err
+}
+
+func (t *TokenStore) RevokeAccessToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "RevokeAccessToken-Store")
+	defer span.End()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO revoked_token (jti, user_id, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (jti) DO NOTHING
+		`,
+		jti, userID, expiresAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// expired tokens are rejected anyway, so there is no need to keep them on the deny list
+	_, err = tx.ExecContext(ctx, `DELETE FROM revoked_token WHERE expires_at < $1`, time.Now())
+	return err
+}
+
+func (t *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	tracer := otel.Tracer("TokenStore")
+	ctx, span := tracer.Start(ctx, "IsAccessTokenRevoked-Store")
+	defer span.End()
+
+	var revoked bool
+	err := t.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_token WHERE jti = $1)`,
+		jti).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
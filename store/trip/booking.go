@@ -0,0 +1,93 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// bookingStatuses are the trip statuses that hold the car and the driver for the trip's time window
+var bookingStatuses = []string{models.TripStatusScheduled, models.TripStatusInProgress}
+
+// unavailableCarStatuses are the car statuses that cannot take trips
+var unavailableCarStatuses = []string{models.CarStatusMaintenance, models.CarStatusDecommissioned}
+
+// checkBooking makes sure the car of a scheduled or running trip can take it and that neither the car nor
+// the driver is booked on another trip whose time window overlaps. A trip without an end time, or one started
+// after its planned end time, is open ended. The car row stays locked until the transaction ends so bookings of the same
+// car are checked one after the other; the trip_*_no_overlap exclusion constraints back this up for drivers.
+func checkBooking(ctx context.Context, tx *sql.Tx, dialect store.Dialect, trip models.Trip) error {
+	if !slices.Contains(bookingStatuses, trip.Status) {
+		return nil
+	}
+
+	var carStatus string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM car WHERE id=$1 `+dialect.ForUpdate(), trip.CarID).Scan(&carStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Validation("car %s does not exist", trip.CarID)
+		}
+		return err
+	}
+	if slices.Contains(unavailableCarStatuses, carStatus) {
+		return models.Conflict("car %s is in %s and cannot take trips", trip.CarID, carStatus)
+	}
+
+	// SQLite has neither arrays nor ranges: the statuses are passed as a JSON array and the windows compared by
+	// their ends
+	holds, overlaps := "status = ANY($4)", "trip_window(start_time, end_time) && trip_window($5, $6)"
+	var statuses interface{} = pq.Array(bookingStatuses)
+	if dialect == store.SQLite {
+		holds = "status IN (SELECT value FROM json_each($4))"
+		overlaps = `start_time < CASE WHEN $6 IS NULL OR $6 <= $5 THEN '9999-12-31' ELSE $6 END AND $5 < ` + store.TripWindowEnd
+		list, err := json.Marshal(bookingStatuses)
+		if err != nil {
+			return err
+		}
+		statuses = string(list)
+	}
+
+	var other models.TripBooking
+	var otherCarID uuid.UUID
+	var endTime sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, car_id, start_time, end_time
+		FROM trip
+		WHERE id <> $1 AND (car_id = $2 OR driver_id = $3) AND `+holds+`
+			AND `+overlaps+`
+		ORDER BY start_time
+		LIMIT 1
+	`, trip.ID, trip.CarID, trip.DriverID, statuses, trip.StartTime, nullTime(trip.EndTime)).Scan(
+		&other.ID,
+		&otherCarID,
+		&other.StartTime,
+		&endTime,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if endTime.Valid {
+		other.EndTime = &endTime.Time
+	}
+
+	booked := fmt.Sprintf("driver %s", trip.DriverID)
+	if otherCarID == trip.CarID {
+		booked = fmt.Sprintf("car %s", trip.CarID)
+	}
+	return &models.Error{
+		Kind:    models.ErrConflict,
+		Message: fmt.Sprintf("%s is already booked on trip %s at that time", booked, other.ID),
+		Details: other,
+	}
+}
@@ -0,0 +1,65 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
+)
+
+// syncCarStatus keeps the status of the trip's car in step with the trip: the car is In Use while the trip is
+// In Progress and Available again once it is completed or cancelled. A car in any other status, or still on
+// another trip in progress, is left alone. It runs in the transaction of the trip status change.
+func syncCarStatus(ctx context.Context, tx *sql.Tx, dialect store.Dialect, trip models.Trip, actor string) error {
+	switch trip.Status {
+	case models.TripStatusInProgress:
+		return setCarStatus(ctx, tx, dialect, trip, models.CarStatusAvailable, models.CarStatusInUse, actor)
+	case models.TripStatusCompleted, models.TripStatusCancelled:
+		return setCarStatus(ctx, tx, dialect, trip, models.CarStatusInUse, models.CarStatusAvailable, actor)
+	}
+	return nil
+}
+
+// releaseCar makes the car of a trip in progress Available again when the trip leaves it, because the trip is
+// deleted or moved to another car. It runs in the transaction of that change.
+func releaseCar(ctx context.Context, tx *sql.Tx, dialect store.Dialect, trip models.Trip, actor string) error {
+	if trip.Status != models.TripStatusInProgress {
+		return nil
+	}
+	return setCarStatus(ctx, tx, dialect, trip, models.CarStatusInUse, models.CarStatusAvailable, actor)
+}
+
+// setCarStatus moves the trip's car from one status to another, unless it is in another status or on another trip
+// in progress
+func setCarStatus(ctx context.Context, tx *sql.Tx, dialect store.Dialect, trip models.Trip, from, to string, actor string) error {
+	carID := trip.CarID.String()
+	before, err := audit.Capture(ctx, tx, dialect, models.AuditResourceCar, carID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE car SET status=$1, updated_at=$2, updated_by=$3
+		WHERE id=$4 AND status=$5
+			AND NOT EXISTS (SELECT 1 FROM trip WHERE car_id=$4 AND id<>$6 AND status=$7)
+	`, to, time.Now(), actor, trip.CarID, from, trip.ID, models.TripStatusInProgress)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	after, err := audit.Capture(ctx, tx, dialect, models.AuditResourceCar, carID)
+	if err != nil {
+		return err
+	}
+	return audit.Record(ctx, tx, models.AuditResourceCar, carID, models.AuditActionUpdate, before, after)
+}
@@ -0,0 +1,63 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store/odometer"
+	"github.com/JulianaSau/carzone/store/position"
+)
+
+// tripDistance settles the distance of a trip that is being completed. When the trip has start and end odometer
+// readings its distance is the difference between them, and a distance given with the transition must agree with
+// it. Otherwise a given distance is kept, then the distance is taken from the GPS track. A trip with neither keeps
+// the distance it was created with, if any.
+func tripDistance(ctx context.Context, tx *sql.Tx, trip *models.Trip, change models.TripStatusChange) error {
+	if change.To != models.TripStatusCompleted {
+		return nil
+	}
+
+	// points reported after the trip ended belong to no trip
+	_, err := tx.ExecContext(ctx, `UPDATE car_position SET trip_id = NULL WHERE trip_id = $1 AND recorded_at > $2`, trip.ID, trip.EndTime)
+	if err != nil {
+		return err
+	}
+
+	if change.OdometerKM != 0 {
+		start, found, err := odometer.TripReading(ctx, tx, trip.ID, models.OdometerSourceTripStart)
+		if err != nil {
+			return err
+		}
+		if found {
+			distanceKM := change.OdometerKM - start.ReadingKM
+			if err := models.CheckOdometerDistance(trip.ID, change.DistanceKM, distanceKM); err != nil {
+				return err
+			}
+			return setDistance(ctx, tx, trip, distanceKM)
+		}
+	}
+
+	if change.DistanceKM > 0 {
+		return nil
+	}
+
+	track, err := position.Track(ctx, tx, trip.ID)
+	if err != nil {
+		return err
+	}
+	if len(track) >= 2 {
+		return setDistance(ctx, tx, trip, models.TrackDistanceKM(track))
+	}
+
+	if trip.DistanceKM <= 0 {
+		return models.Validation("distance_km is required, trip %s has neither a start odometer reading nor a GPS track", trip.ID)
+	}
+	return nil
+}
+
+func setDistance(ctx context.Context, tx *sql.Tx, trip *models.Trip, distanceKM float64) error {
+	trip.DistanceKM = distanceKM
+	_, err := tx.ExecContext(ctx, `UPDATE trip SET distance_km = $1 WHERE id = $2`, trip.DistanceKM, trip.ID)
+	return err
+}
@@ -0,0 +1,62 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/google/uuid"
+)
+
+// setLocations copies the location columns of a trip row, NULL when the endpoint is only known by its text
+func setLocations(trip *models.Trip, startLocationID uuid.NullUUID, endLocationID uuid.NullUUID) {
+	if startLocationID.Valid {
+		trip.StartLocationID = &startLocationID.UUID
+	}
+	if endLocationID.Valid {
+		trip.EndLocationID = &endLocationID.UUID
+	}
+}
+
+// resolveLocations links both endpoints of a trip to their locations, see resolveLocation
+func resolveLocations(ctx context.Context, tx *sql.Tx, tripReq *models.TripRequest) error {
+	var err error
+	tripReq.StartLocationID, tripReq.StartLocation, err = resolveLocation(ctx, tx, "start", tripReq.StartLocationID, tripReq.StartLocation)
+	if err != nil {
+		return err
+	}
+	tripReq.EndLocationID, tripReq.EndLocation, err = resolveLocation(ctx, tx, "end", tripReq.EndLocationID, tripReq.EndLocation)
+	return err
+}
+
+// resolveLocation keeps the text of an endpoint filled in for clients that predate locations. A location given by
+// ID must exist and lends its name to an endpoint without text; an endpoint given only as text is linked to the
+// location of that name, if there is one.
+func resolveLocation(ctx context.Context, tx *sql.Tx, endpoint string, id *uuid.UUID, text string) (*uuid.UUID, string, error) {
+	if id != nil {
+		var name string
+		err := tx.QueryRowContext(ctx, `SELECT name FROM location WHERE id = $1`, *id).Scan(&name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, "", models.Validation("%s location %s does not exist", endpoint, *id)
+			}
+			return nil, "", err
+		}
+		if text == "" {
+			text = name
+		}
+		return id, text, nil
+	}
+
+	var locationID uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM location WHERE LOWER(name) = LOWER($1)`, strings.TrimSpace(text)).Scan(&locationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, text, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &locationID, text, nil
+}
@@ -0,0 +1,31 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/odometer"
+)
+
+// recordOdometer records the odometer reading given when a trip starts or ends
+func recordOdometer(ctx context.Context, tx *sql.Tx, dialect store.Dialect, trip *models.Trip, change models.TripStatusChange, actor string) error {
+	if change.OdometerKM == 0 {
+		return nil
+	}
+
+	reading := models.OdometerReading{
+		CarID:      trip.CarID,
+		TripID:     &trip.ID,
+		ReadingKM:  change.OdometerKM,
+		Source:     models.OdometerSourceTripStart,
+		RecordedAt: trip.StartTime,
+		RecordedBy: actor,
+	}
+	if change.To == models.TripStatusCompleted {
+		reading.Source = models.OdometerSourceTripEnd
+		reading.RecordedAt = trip.EndTime
+	}
+	return odometer.Record(ctx, tx, dialect, &reading)
+}
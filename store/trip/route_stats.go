@@ -0,0 +1,128 @@
+package trip
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// routeSortColumns are the fields route stats can be sorted by
+var routeSortColumns = map[string]string{
+	"origin":       "r.origin",
+	"destination":  "r.destination",
+	"trips":        "r.trips",
+	"avg_duration": "r.avg_duration_minutes",
+	"avg_distance": "r.avg_distance_km",
+	"avg_fuel":     "r.avg_fuel_liters",
+}
+
+// GetRouteStats groups the completed trips by origin and destination. An endpoint linked to a location is grouped
+// by the location and named after it, one that is not by its text.
+func (e *TripStore) GetRouteStats(ctx context.Context, filter models.RouteStatsFilter, opts models.ListOptions) ([]models.RouteStats, int, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "GetRouteStats-Store")
+	defer span.End()
+
+	var q store.ListQuery
+	q.Where("t.status = ?", models.TripStatusCompleted)
+	if filter.CarID != uuid.Nil {
+		q.Where("t.car_id = ?", filter.CarID)
+	}
+	if filter.DriverID != uuid.Nil {
+		q.Where("t.driver_id = ?", filter.DriverID)
+	}
+	if filter.OriginID != uuid.Nil {
+		q.Where("t.start_location_id = ?", filter.OriginID)
+	}
+	if filter.DestinationID != uuid.Nil {
+		q.Where("t.end_location_id = ?", filter.DestinationID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("t.start_time >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("t.start_time < ?", filter.To)
+	}
+
+	orderBy, err := q.OrderBy(opts, routeSortColumns, "r.origin", "r.destination")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	averages := `
+				ROUND(AVG(EXTRACT(EPOCH FROM t.end_time - t.start_time) / 60)::numeric, 2) AS avg_duration_minutes,
+				ROUND(AVG(t.distance_km)::numeric, 2) AS avg_distance_km,
+				ROUND(AVG(t.fuel_consumed_liters)::numeric, 2) AS avg_fuel_liters`
+	if e.dialect == store.SQLite {
+		// a day is 1440 minutes
+		averages = `
+				ROUND(AVG((julianday(t.end_time) - julianday(t.start_time)) * 1440), 2) AS avg_duration_minutes,
+				ROUND(AVG(t.distance_km), 2) AS avg_distance_km,
+				ROUND(AVG(t.fuel_consumed_liters), 2) AS avg_fuel_liters`
+	}
+
+	routes := `
+		WITH r AS (
+			SELECT t.start_location_id AS origin_id, COALESCE(o.name, t.start_location) AS origin,
+				t.end_location_id AS destination_id, COALESCE(d.name, t.end_location) AS destination,
+				COUNT(*) AS trips,` + averages + `
+			FROM trip t
+			LEFT JOIN location o ON o.id = t.start_location_id
+			LEFT JOIN location d ON d.id = t.end_location_id
+			` + q.WhereClause() + `
+			GROUP BY 1, 2, 3, 4
+		)
+	`
+
+	var total int
+	err = e.db.QueryRowContext(ctx, routes+`SELECT COUNT(*) FROM r`, q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
+	rows, err := e.db.QueryContext(ctx, routes+`
+		SELECT r.origin_id, r.origin, r.destination_id, r.destination, r.trips, r.avg_duration_minutes, r.avg_distance_km, r.avg_fuel_liters
+		FROM r
+	`+orderBy+" "+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stats := []models.RouteStats{}
+	for rows.Next() {
+		var route models.RouteStats
+		var originID, destinationID uuid.NullUUID
+		var avgDuration sql.NullFloat64
+		err := rows.Scan(
+			&originID,
+			&route.Origin,
+			&destinationID,
+			&route.Destination,
+			&route.Trips,
+			&avgDuration,
+			&route.AvgDistanceKM,
+			&route.AvgFuelLiters,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		if originID.Valid {
+			route.OriginID = &originID.UUID
+		}
+		if destinationID.Valid {
+			route.DestinationID = &destinationID.UUID
+		}
+		route.AvgDurationMinutes = avgDuration.Float64
+		stats = append(stats, route)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return stats, total, nil
+}
@@ -7,139 +7,98 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/JulianaSau/carzone/middleware"
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
 type TripStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect store.Dialect
 }
 
 func New(db *sql.DB) *TripStore {
-	return &TripStore{db: db}
+	return &TripStore{db: db, dialect: store.DialectOf(db)}
 }
 
-func (u TripStore) GetTrips(ctx context.Context) ([]models.Trip, error) {
+// tripSortColumns are the fields trips can be sorted by
+var tripSortColumns = map[string]string{
+	"start_time":  "start_time",
+	"end_time":    "end_time",
+	"status":      "status",
+	"distance_km": "distance_km",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+func (u TripStore) GetTrips(ctx context.Context, filter models.TripFilter, opts models.ListOptions) ([]models.Trip, int, error) {
 	tracer := otel.Tracer("TripStore")
 	ctx, span := tracer.Start(ctx, "GetTrips-Store")
 	defer span.End()
 
 	trips := []models.Trip{}
 
-	query := `
-		SELECT id, description, driver_id, car_id, start_location, end_location, start_time, distance_km, fuel_consumed_liters, status, created_at, updated_at
-		FROM trip
-	`
-	rows, err := u.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+	var q store.ListQuery
+	if filter.Status != "" {
+		q.Where("status = ?", filter.Status)
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var trip models.Trip
-		err := rows.Scan(
-			&trip.ID,
-			&trip.Description,
-			&trip.DriverID,
-			&trip.CarID,
-			&trip.StartLocation,
-			&trip.EndLocation,
-			&trip.StartTime,
-			// &trip.EndTime,
-			&trip.DistanceKM,
-			&trip.FuelConsumedLiters,
-			&trip.Status,
-			&trip.CreatedAt,
-			&trip.UpdatedAt,
-			// &trip.CreatedBy,
-			// &trip.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
-		trips = append(trips, trip)
+	if filter.CarID != uuid.Nil {
+		q.Where("car_id = ?", filter.CarID)
 	}
-	return trips, nil
-}
-func (u TripStore) GetTripsByCarID(ctx context.Context, id string) ([]models.Trip, error) {
-	tracer := otel.Tracer("TripStore")
-	ctx, span := tracer.Start(ctx, "GetTrips-Store")
-	defer span.End()
-
-	// Parse the car ID
-	carID, err := uuid.Parse(id)
-	if err != nil {
-		return nil, fmt.Errorf("invalid Car ID : %w", err)
+	if filter.DriverID != uuid.Nil {
+		q.Where("driver_id = ?", filter.DriverID)
 	}
-
-	trips := []models.Trip{}
-
-	query := `
-		SELECT id, description, driver_id, car_id, start_location, end_location, start_time, distance_km, fuel_consumed_liters, status, created_at, updated_at
-		FROM trip
-		WHERE car_id = $1
-	`
-	rows, err := u.db.QueryContext(ctx, query, carID)
-	if err != nil {
-		return nil, err
+	if filter.StartLocationID != uuid.Nil {
+		q.Where("start_location_id = ?", filter.StartLocationID)
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var trip models.Trip
-		err := rows.Scan(
-			&trip.ID,
-			&trip.Description,
-			&trip.DriverID,
-			&trip.CarID,
-			&trip.StartLocation,
-			&trip.EndLocation,
-			&trip.StartTime,
-			// &trip.EndTime,
-			&trip.DistanceKM,
-			&trip.FuelConsumedLiters,
-			&trip.Status,
-			&trip.CreatedAt,
-			&trip.UpdatedAt,
-			// &trip.CreatedBy,
-			// &trip.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
+	if filter.EndLocationID != uuid.Nil {
+		q.Where("end_location_id = ?", filter.EndLocationID)
+	}
+	if !filter.From.IsZero() {
+		q.Where("start_time >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.Where("start_time < ?", filter.To)
+	}
+	if !filter.ActiveAt.IsZero() {
+		// a trip in progress runs until it is completed, even past its planned end time
+		if u.dialect == store.SQLite {
+			q.Where(`((status = 'In Progress' AND start_time <= ?)
+				OR (status = 'Completed' AND start_time <= ? AND ? < `+store.TripWindowEnd+`))`, filter.ActiveAt, filter.ActiveAt, filter.ActiveAt)
+		} else {
+			q.Where(`((status = 'In Progress' AND start_time <= ?)
+				OR (status = 'Completed' AND trip_window(start_time, end_time) @> CAST(? AS TIMESTAMP)))`, filter.ActiveAt, filter.ActiveAt)
 		}
-		trips = append(trips, trip)
 	}
-	return trips, nil
-}
-func (u TripStore) GetTripsByDriverID(ctx context.Context, id string) ([]models.Trip, error) {
-	tracer := otel.Tracer("TripStore")
-	ctx, span := tracer.Start(ctx, "GetTripsByDriverID-Store")
-	defer span.End()
 
-	// Parse the driver ID
-	driverID, err := uuid.Parse(id)
+	orderBy, err := q.OrderBy(opts, tripSortColumns, "start_time", "id")
 	if err != nil {
-		return nil, fmt.Errorf("invalid Driver ID : %w", err)
+		return nil, 0, err
 	}
 
-	trips := []models.Trip{}
+	var total int
+	err = u.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM trip `+q.WhereClause(), q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
 
+	page, args := q.Page(opts)
 	query := `
-		SELECT id, description, driver_id, car_id, start_location, end_location, start_time, distance_km, fuel_consumed_liters, status, created_at, updated_at
+		SELECT id, description, driver_id, car_id, start_location, end_location, start_location_id, end_location_id, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 		FROM trip
-		WHERE driver_id = $1
-	`
-	rows, err := u.db.QueryContext(ctx, query, driverID)
+	` + q.WhereClause() + " " + orderBy + " " + page
+	rows, err := u.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var trip models.Trip
+		var endTime sql.NullTime
+		var startLocationID, endLocationID uuid.NullUUID
 		err := rows.Scan(
 			&trip.ID,
 			&trip.Description,
@@ -147,22 +106,29 @@ func (u TripStore) GetTripsByDriverID(ctx context.Context, id string) ([]models.
 			&trip.CarID,
 			&trip.StartLocation,
 			&trip.EndLocation,
+			&startLocationID,
+			&endLocationID,
 			&trip.StartTime,
-			// &trip.EndTime,
+			&endTime,
 			&trip.DistanceKM,
 			&trip.FuelConsumedLiters,
 			&trip.Status,
 			&trip.CreatedAt,
 			&trip.UpdatedAt,
-			// &trip.CreatedBy,
-			// &trip.UpdatedBy,
+			&trip.CreatedBy,
+			&trip.UpdatedBy,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
+		trip.EndTime = endTime.Time
+		setLocations(&trip, startLocationID, endLocationID)
 		trips = append(trips, trip)
 	}
-	return trips, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return trips, total, nil
 }
 
 func (e *TripStore) GetTripById(ctx context.Context, id string) (models.Trip, error) {
@@ -171,10 +137,18 @@ func (e *TripStore) GetTripById(ctx context.Context, id string) (models.Trip, er
 	defer span.End()
 
 	var trip models.Trip
+	var endTime sql.NullTime
+	var startLocationID, endLocationID uuid.NullUUID
+
+	// Parse the trip ID
+	tripID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Trip{}, models.Validation("invalid trip id %q", id)
+	}
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return trip, nil
+		return trip, err
 	}
 
 	defer func() {
@@ -189,17 +163,19 @@ func (e *TripStore) GetTripById(ctx context.Context, id string) (models.Trip, er
 		}
 	}()
 
-	err = tx.QueryRowContext(ctx, `SELECT id, description, driver_id, car_id, start_location, end_location, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, created_by, updated_by
+	err = tx.QueryRowContext(ctx, `SELECT id, description, driver_id, car_id, start_location, end_location, start_location_id, end_location_id, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 	from trip 
 	WHERE id=$1`,
-		id).Scan(&trip.ID,
+		tripID).Scan(&trip.ID,
 		&trip.Description,
 		&trip.DriverID,
 		&trip.CarID,
 		&trip.StartLocation,
 		&trip.EndLocation,
+		&startLocationID,
+		&endLocationID,
 		&trip.StartTime,
-		&trip.EndTime,
+		&endTime,
 		&trip.DistanceKM,
 		&trip.FuelConsumedLiters,
 		&trip.Status,
@@ -211,22 +187,24 @@ func (e *TripStore) GetTripById(ctx context.Context, id string) (models.Trip, er
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return trip, nil
+			return trip, models.NotFound("trip %s not found", id)
 		}
-		return trip, err
+		return trip, store.DBError(err)
 	}
-	return trip, err
+	trip.EndTime = endTime.Time
+	setLocations(&trip, startLocationID, endLocationID)
+	return trip, nil
 
 }
 
-func (e *TripStore) CreateTrip(ctx context.Context, tripReq *models.TripRequest) (models.Trip, error) {
+func (e *TripStore) CreateTrip(ctx context.Context, tripReq *models.TripRequest, actor string) (models.Trip, error) {
 	tracer := otel.Tracer("TripStore")
 	ctx, span := tracer.Start(ctx, "CreateTrip-Store")
 	defer span.End()
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Trip{}, nil
+		return models.Trip{}, err
 	}
 
 	defer func() {
@@ -241,11 +219,43 @@ func (e *TripStore) CreateTrip(ctx context.Context, tripReq *models.TripRequest)
 		}
 	}()
 
+	err = resolveLocations(ctx, tx, tripReq)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
 	tripID := uuid.New()
+	trip := models.Trip{
+		ID:                 tripID,
+		Description:        tripReq.Description,
+		DriverID:           tripReq.DriverID,
+		CarID:              tripReq.CarID,
+		StartLocation:      tripReq.StartLocation,
+		EndLocation:        tripReq.EndLocation,
+		StartLocationID:    tripReq.StartLocationID,
+		EndLocationID:      tripReq.EndLocationID,
+		StartTime:          tripReq.StartTime,
+		EndTime:            tripReq.EndTime,
+		DistanceKM:         tripReq.DistanceKM,
+		FuelConsumedLiters: tripReq.FuelConsumedLiters,
+		Status:             tripReq.Status,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+		CreatedBy:          actor,
+		UpdatedBy:          actor,
+	}
+
+	// the booking is checked before the write so an overlap is reported with the trip it overlaps, before the
+	// trip_*_no_overlap constraints refuse it
+	err = checkBooking(ctx, tx, e.dialect, trip)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
 	_, err = tx.ExecContext(ctx,
 		`
-		INSERT INTO trip (id, description, driver_id, car_id, start_location, end_location, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, created_by, updated_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9 , $10, $11, $12, $13, $14, $15)
+		INSERT INTO trip (id, description, driver_id, car_id, start_location, end_location, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, created_by, updated_by, start_location_id, end_location_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9 , $10, $11, $12, $13, $14, $15, $16, $17)
 	`, tripID,
 		tripReq.Description,
 		tripReq.DriverID,
@@ -259,36 +269,20 @@ func (e *TripStore) CreateTrip(ctx context.Context, tripReq *models.TripRequest)
 		tripReq.Status,
 		time.Now(),
 		time.Now(),
-		"",
-		"",
+		actor,
+		actor,
+		tripReq.StartLocationID,
+		tripReq.EndLocationID,
 	)
 
 	if err != nil {
-		return models.Trip{}, err
-	}
-
-	trip := models.Trip{
-		ID:                 tripID,
-		Description:        tripReq.Description,
-		DriverID:           tripReq.DriverID,
-		CarID:              tripReq.CarID,
-		StartLocation:      tripReq.StartLocation,
-		EndLocation:        tripReq.EndLocation,
-		StartTime:          tripReq.StartTime,
-		EndTime:            tripReq.EndTime,
-		DistanceKM:         tripReq.DistanceKM,
-		FuelConsumedLiters: tripReq.FuelConsumedLiters,
-		Status:             tripReq.Status,
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
-		CreatedBy:          "",
-		UpdatedBy:          "",
+		return models.Trip{}, store.DBError(err)
 	}
 
 	return trip, nil
 }
 
-func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest) (models.Trip, error) {
+func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.TripRequest, actor string) (models.Trip, error) {
 	tracer := otel.Tracer("TripStore")
 	ctx, span := tracer.Start(ctx, "UpdateTrip-Store")
 	defer span.End()
@@ -296,12 +290,12 @@ func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.T
 	// Parse the trip ID
 	tripID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Trip{}, fmt.Errorf("invalid Trip ID : %w", err)
+		return models.Trip{}, models.Validation("invalid trip id %q", id)
 	}
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Trip{}, nil
+		return models.Trip{}, err
 	}
 
 	// Defer the rollback or commit
@@ -319,28 +313,26 @@ func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.T
 		}
 	}()
 
-	// Update the trip
-	results, err := tx.ExecContext(ctx,
-		`
-	    UPDATE trip SET description=$1, driver_id=$2, car_id=$3, start_location=$4, end_location=$5, start_time=$6, end_time=$7, distance_km=$8, fuel_consumed_liters=$9, status=$10
-		WHERE id=$11
-		`,
-		tripReq.Description, tripReq.DriverID, tripReq.CarID, tripReq.StartLocation, tripReq.EndLocation, tripReq.StartTime, tripReq.EndTime, tripReq.DistanceKM, tripReq.FuelConsumedLiters, tripReq.Status, tripID)
-
+	before, err := audit.Capture(ctx, tx, e.dialect, models.AuditResourceTrip, tripID.String())
 	if err != nil {
 		return models.Trip{}, err
 	}
+	if before == nil {
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
 
-	// Check if the row was actually updated
-	rowsAffected, err := results.RowsAffected()
+	// the car and status the trip had, the capture locked its row
+	previous := models.Trip{ID: tripID}
+	err = tx.QueryRowContext(ctx, `SELECT car_id, status FROM trip WHERE id = $1`, tripID).Scan(&previous.CarID, &previous.Status)
 	if err != nil {
 		return models.Trip{}, err
 	}
-	if rowsAffected == 0 {
-		return models.Trip{}, errors.New("no rows updated")
+
+	err = resolveLocations(ctx, tx, tripReq)
+	if err != nil {
+		return models.Trip{}, err
 	}
 
-	// Return the updated trip
 	trip := models.Trip{
 		ID:                 tripID,
 		Description:        tripReq.Description,
@@ -348,48 +340,103 @@ func (e *TripStore) UpdateTrip(ctx context.Context, id string, tripReq *models.T
 		CarID:              tripReq.CarID,
 		StartLocation:      tripReq.StartLocation,
 		EndLocation:        tripReq.EndLocation,
+		StartLocationID:    tripReq.StartLocationID,
+		EndLocationID:      tripReq.EndLocationID,
 		StartTime:          tripReq.StartTime,
 		EndTime:            tripReq.EndTime,
 		DistanceKM:         tripReq.DistanceKM,
 		FuelConsumedLiters: tripReq.FuelConsumedLiters,
 		Status:             tripReq.Status,
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
-		CreatedBy:          "",
-		UpdatedBy:          "",
 	}
 
-	return trip, nil
-}
-func (e *TripStore) UpdateTripStatus(ctx context.Context, id string, status string) (models.Trip, error) {
-	tracer := otel.Tracer("TripStore")
-	ctx, span := tracer.Start(ctx, "UpdateTripStatus-Store")
-	defer span.End()
-
-	// get the trip details
-	trip, err := e.GetTripById(ctx, id)
+	// the booking is checked before the write so an overlap is reported with the trip it overlaps, before the
+	// trip_*_no_overlap constraints refuse it
+	err = checkBooking(ctx, tx, e.dialect, trip)
 	if err != nil {
 		return models.Trip{}, err
 	}
 
-	// Record trip metrics
+	// Update the trip, the creation columns of the returned trip come from the row
+	var endTime sql.NullTime
+	var startLocationID, endLocationID uuid.NullUUID
+	err = tx.QueryRowContext(ctx,
+		`
+	    UPDATE trip SET description=$1, driver_id=$2, car_id=$3, start_location=$4, end_location=$5, start_time=$6, end_time=$7, distance_km=$8, fuel_consumed_liters=$9, status=$10, updated_at=$11, updated_by=$12,
+			start_location_id=$13, end_location_id=$14
+		WHERE id=$15
+		RETURNING id, description, driver_id, car_id, start_location, end_location, start_location_id, end_location_id, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
+		`,
+		tripReq.Description, tripReq.DriverID, tripReq.CarID, tripReq.StartLocation, tripReq.EndLocation, tripReq.StartTime, tripReq.EndTime, tripReq.DistanceKM, tripReq.FuelConsumedLiters, tripReq.Status, time.Now(), actor,
+		tripReq.StartLocationID, tripReq.EndLocationID, tripID,
+	).Scan(
+		&trip.ID,
+		&trip.Description,
+		&trip.DriverID,
+		&trip.CarID,
+		&trip.StartLocation,
+		&trip.EndLocation,
+		&startLocationID,
+		&endLocationID,
+		&trip.StartTime,
+		&endTime,
+		&trip.DistanceKM,
+		&trip.FuelConsumedLiters,
+		&trip.Status,
+		&trip.CreatedAt,
+		&trip.UpdatedAt,
+		&trip.CreatedBy,
+		&trip.UpdatedBy,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Trip{}, models.NotFound("trip %s not found", id)
+		}
+		return models.Trip{}, store.DBError(err)
+	}
+	trip.EndTime = endTime.Time
+	trip.StartLocationID, trip.EndLocationID = nil, nil
+	setLocations(&trip, startLocationID, endLocationID)
+
+	after, err := audit.Capture(ctx, tx, e.dialect, models.AuditResourceTrip, tripID.String())
+	if err != nil {
+		return models.Trip{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceTrip, tripID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.Trip{}, err
+	}
 
-	if status == "Completed" {
-		// fuelConsumed float64, distanceTraveled float64, tripDuration time.Duration
-		// calculate the trip duration
-		tripDuration := trip.EndTime.Sub(trip.StartTime)
-		middleware.RecordTripMetrics(trip.FuelConsumedLiters, trip.DistanceKM, tripDuration)
+	// a trip in progress moved to another car hands the car over
+	if trip.CarID != previous.CarID {
+		err = releaseCar(ctx, tx, e.dialect, previous, actor)
+		if err != nil {
+			return models.Trip{}, err
+		}
+		if trip.Status == models.TripStatusInProgress {
+			err = syncCarStatus(ctx, tx, e.dialect, trip, actor)
+			if err != nil {
+				return models.Trip{}, err
+			}
+		}
 	}
 
+	// Return the updated trip
+	return trip, nil
+}
+func (e *TripStore) UpdateTripStatus(ctx context.Context, id string, change models.TripStatusChange, actor string) (models.Trip, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "UpdateTripStatus-Store")
+	defer span.End()
+
 	// Parse the trip ID
 	tripID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Trip{}, fmt.Errorf("invalid Trip ID : %w", err)
+		return models.Trip{}, models.Validation("invalid trip id %q", id)
 	}
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Trip{}, nil
+		return models.Trip{}, err
 	}
 
 	// Defer the rollback or commit
@@ -407,53 +454,172 @@ func (e *TripStore) UpdateTripStatus(ctx context.Context, id string, status stri
 		}
 	}()
 
-	// Update the trip
-	results, err := tx.ExecContext(ctx,
+	before, err := audit.Capture(ctx, tx, e.dialect, models.AuditResourceTrip, tripID.String())
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	// Update the trip, only if nobody moved it out of the status the transition was checked against
+	var trip models.Trip
+	var endTime sql.NullTime
+	var startLocationID, endLocationID uuid.NullUUID
+	now := time.Now()
+	err = tx.QueryRowContext(ctx,
 		`
-	    UPDATE trip SET status=$1
-		WHERE id=$2
+	    UPDATE trip SET status=$1, start_time=COALESCE($2, start_time), end_time=COALESCE($3, end_time),
+			distance_km=COALESCE($4, distance_km), fuel_consumed_liters=COALESCE($5, fuel_consumed_liters), updated_at=$6, updated_by=$7
+		WHERE id=$8 AND status=$9
+		RETURNING id, description, driver_id, car_id, start_location, end_location, start_location_id, end_location_id, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 		`,
-		status, tripID)
+		change.To, nullTime(change.StartTime), nullTime(change.EndTime), nullFloat(change.DistanceKM), nullFloat(change.FuelConsumedLiters),
+		now, actor, tripID, change.From,
+	).Scan(
+		&trip.ID,
+		&trip.Description,
+		&trip.DriverID,
+		&trip.CarID,
+		&trip.StartLocation,
+		&trip.EndLocation,
+		&startLocationID,
+		&endLocationID,
+		&trip.StartTime,
+		&endTime,
+		&trip.DistanceKM,
+		&trip.FuelConsumedLiters,
+		&trip.Status,
+		&trip.CreatedAt,
+		&trip.UpdatedAt,
+		&trip.CreatedBy,
+		&trip.UpdatedBy,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if before == nil {
+				return models.Trip{}, models.NotFound("trip %s not found", id)
+			}
+			return models.Trip{}, models.Conflict("trip %s is no longer %s", id, change.From)
+		}
+		return models.Trip{}, store.DBError(err)
+	}
+	trip.EndTime = endTime.Time
+	setLocations(&trip, startLocationID, endLocationID)
 
+	err = recordOdometer(ctx, tx, e.dialect, &trip, change, actor)
 	if err != nil {
 		return models.Trip{}, err
 	}
 
-	// Check if the row was actually updated
-	rowsAffected, err := results.RowsAffected()
+	err = tripDistance(ctx, tx, &trip, change)
 	if err != nil {
 		return models.Trip{}, err
 	}
-	if rowsAffected == 0 {
-		return models.Trip{}, errors.New("no rows updated")
+
+	err = checkBooking(ctx, tx, e.dialect, trip)
+	if err != nil {
+		return models.Trip{}, err
 	}
 
-	// Return the updated trip
-	trip = models.Trip{
-		ID:        tripID,
-		Status:    status,
-		UpdatedAt: time.Now(),
+	err = syncCarStatus(ctx, tx, e.dialect, trip, actor)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	// Record the transition
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO trip_transition (id, trip_id, from_status, to_status, reason, actor, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`,
+		uuid.New(), tripID, change.From, change.To, change.Reason, actor, now)
+	if err != nil {
+		return models.Trip{}, store.DBError(err)
+	}
+
+	after, err := audit.Capture(ctx, tx, e.dialect, models.AuditResourceTrip, tripID.String())
+	if err != nil {
+		return models.Trip{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceTrip, tripID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.Trip{}, err
 	}
 
 	return trip, nil
 }
 
-func (s *TripStore) DeleteTrip(ctx context.Context, id string) (models.Trip, error) {
+func (e *TripStore) GetTripTransitions(ctx context.Context, id string) ([]models.TripTransition, error) {
+	tracer := otel.Tracer("TripStore")
+	ctx, span := tracer.Start(ctx, "GetTripTransitions-Store")
+	defer span.End()
+
+	// Parse the trip ID
+	tripID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.Validation("invalid trip id %q", id)
+	}
+
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT id, trip_id, from_status, to_status, reason, actor, created_at
+		FROM trip_transition
+		WHERE trip_id = $1
+		ORDER BY created_at, id
+	`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []models.TripTransition{}
+	for rows.Next() {
+		var transition models.TripTransition
+		err := rows.Scan(
+			&transition.ID,
+			&transition.TripID,
+			&transition.FromStatus,
+			&transition.ToStatus,
+			&transition.Reason,
+			&transition.Actor,
+			&transition.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// nullTime maps the zero time to NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// nullFloat maps zero to NULL
+func nullFloat(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: f != 0}
+}
+
+func (s *TripStore) DeleteTrip(ctx context.Context, id string, actor string) (models.Trip, error) {
 	tracer := otel.Tracer("TripStore")
 	ctx, span := tracer.Start(ctx, "DeleteTrip-Store")
 	defer span.End()
 
 	var trip models.Trip
+	var endTime sql.NullTime
+	var startLocationID, endLocationID uuid.NullUUID
 
 	// Parse the trip ID
 	tripID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Trip{}, fmt.Errorf("invalid Trip ID: %w", err)
+		return models.Trip{}, models.Validation("invalid trip id %q", id)
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Trip{}, nil
+		return models.Trip{}, err
 	}
 
 	// Defer the rollback or commit
@@ -472,18 +638,20 @@ func (s *TripStore) DeleteTrip(ctx context.Context, id string) (models.Trip, err
 	}()
 
 	// check if the trip exists
-	err = tx.QueryRowContext(ctx, `SELECT id, description, driver_id, car_id, start_location, end_location, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, created_by, updated_by
+	err = tx.QueryRowContext(ctx, `SELECT id, description, driver_id, car_id, start_location, end_location, start_location_id, end_location_id, start_time, end_time, distance_km, fuel_consumed_liters, status, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')
 	from trip 
 	WHERE id=$1`,
-		id).Scan(
+		tripID).Scan(
 		&trip.ID,
 		&trip.Description,
 		&trip.DriverID,
 		&trip.CarID,
 		&trip.StartLocation,
 		&trip.EndLocation,
+		&startLocationID,
+		&endLocationID,
 		&trip.StartTime,
-		&trip.EndTime,
+		&endTime,
 		&trip.DistanceKM,
 		&trip.FuelConsumedLiters,
 		&trip.Status,
@@ -495,17 +663,24 @@ func (s *TripStore) DeleteTrip(ctx context.Context, id string) (models.Trip, err
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return trip, nil
+			return trip, models.NotFound("trip %s not found", id)
 		}
 		return trip, err
 	}
+	trip.EndTime = endTime.Time
+	setLocations(&trip, startLocationID, endLocationID)
+
+	before, err := audit.Capture(ctx, tx, s.dialect, models.AuditResourceTrip, tripID.String())
+	if err != nil {
+		return models.Trip{}, err
+	}
 
 	// Delete the trip
 	result, err := tx.ExecContext(ctx,
 		`DELETE FROM trip WHERE id=$1`, tripID)
 
 	if err != nil {
-		return models.Trip{}, err
+		return models.Trip{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := result.RowsAffected()
@@ -513,7 +688,18 @@ func (s *TripStore) DeleteTrip(ctx context.Context, id string) (models.Trip, err
 		return models.Trip{}, err
 	}
 	if rowsAffected == 0 {
-		return models.Trip{}, errors.New("no rows were deleted")
+		return models.Trip{}, models.NotFound("trip %s not found", id)
+	}
+
+	err = audit.Record(ctx, tx, models.AuditResourceTrip, tripID.String(), models.AuditActionDelete, before, nil)
+	if err != nil {
+		return models.Trip{}, err
+	}
+
+	// a deleted trip in progress no longer holds its car
+	err = releaseCar(ctx, tx, s.dialect, trip, actor)
+	if err != nil {
+		return models.Trip{}, err
 	}
 
 	return trip, nil
@@ -8,32 +8,66 @@ import (
 	"time"
 
 	"github.com/JulianaSau/carzone/models"
+	"github.com/JulianaSau/carzone/store"
+	"github.com/JulianaSau/carzone/store/audit"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
 type UserStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect store.Dialect
 }
 
 func New(db *sql.DB) *UserStore {
-	return &UserStore{db: db}
+	return &UserStore{db: db, dialect: store.DialectOf(db)}
 }
 
-func (u UserStore) GetUsers(ctx context.Context) ([]models.User, error) {
+// userSortColumns are the fields users can be sorted by
+var userSortColumns = map[string]string{
+	"username":   "username",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"role":       "role",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func (u UserStore) GetUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]models.User, int, error) {
 	tracer := otel.Tracer("UserStore")
 	ctx, span := tracer.Start(ctx, "GetUsers-Store")
 	defer span.End()
 
 	users := []models.User{}
 
+	var q store.ListQuery
+	if filter.Role != "" {
+		q.Where("role = ?", filter.Role)
+	}
+	if filter.Active != nil {
+		q.Where("active = ?", *filter.Active)
+	}
+
+	orderBy, err := q.OrderBy(opts, userSortColumns, "username", "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = u.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "user" `+q.WhereClause(), q.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := q.Page(opts)
 	query := `
-		SELECT username, first_name, last_name, email, phone_number, role, id, active, created_at, updated_at
+		SELECT username, first_name, last_name, email, phone_number, role, id, active, COALESCE(created_by, ''), COALESCE(updated_by, ''), created_at, updated_at
 		FROM "user"
-	`
-	rows, err := u.db.QueryContext(ctx, query)
+	` + q.WhereClause() + " " + orderBy + " " + page
+	rows, err := u.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -48,26 +82,30 @@ func (u UserStore) GetUsers(ctx context.Context) ([]models.User, error) {
 			&user.Role,
 			&user.ID,
 			&user.Active,
-			// &user.CreatedBy,
+			&user.CreatedBy,
+			&user.UpdatedBy,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
 }
 
-func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest) (models.User, error) {
+func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest, actor string) (models.User, error) {
 	tracer := otel.Tracer("UserStore")
 	ctx, span := tracer.Start(ctx, "CreateUser-Store")
 	defer span.End()
 
 	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, nil
+		return models.User{}, err
 	}
 
 	defer func() {
@@ -84,7 +122,7 @@ func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest)
 
 	// compare password and compare password
 	if userReq.Password != userReq.ConfirmPassword {
-		return models.User{}, errors.New("password and confirm password do not match")
+		return models.User{}, models.Validation("password and confirm password do not match")
 	}
 
 	// Hash the user's password before saving
@@ -95,8 +133,8 @@ func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest)
 
 	// Insert user into the database
 	query := `
-		INSERT INTO "user" (id, username, password, first_name, last_name, email, phone_number, role, active, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO "user" (id, username, password, first_name, last_name, email, phone_number, role, active, created_by, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, username, first_name, last_name, email, phone_number, role, active, created_at, updated_at
 	`
 	userID := uuid.New()
@@ -110,13 +148,14 @@ func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest)
 		userReq.PhoneNumber,
 		userReq.Role,
 		true,
-		"d3b07384-d9a1-4c4b-8a0d-4b1b1b1b1b1b",
+		actor,
+		actor,
 		time.Now(),
 		time.Now(),
 	)
 
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, store.DBError(err)
 	}
 	user := models.User{
 		UserName:    userReq.UserName,
@@ -127,13 +166,15 @@ func (u UserStore) CreateUser(ctx context.Context, userReq *models.UserRequest)
 		Role:        userReq.Role,
 		ID:          userID,
 		Active:      true,
+		CreatedBy:   actor,
+		UpdatedBy:   actor,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 	return user, nil
 }
 
-func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *models.UserRequest) (models.User, error) {
+func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *models.UserRequest, actor string) (models.User, error) {
 	tracer := otel.Tracer("UserStore")
 	ctx, span := tracer.Start(ctx, "UpdateUserProfile-Store")
 	defer span.End()
@@ -141,12 +182,12 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 	// Parse the user ID
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("invalid User ID : %w", err)
+		return models.User{}, models.Validation("invalid user id %q", id)
 	}
 
 	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, nil
+		return models.User{}, err
 	}
 
 	// Defer the rollback or commit
@@ -164,11 +205,16 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 		}
 	}()
 
+	before, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+
 	// Update user profile in the database
 	query := `
 		UPDATE "user"
-		SET first_name = $1, last_name = $2, username = $3, email = $4, phone_number = $5, updated_at = $6
-		WHERE id = $6
+		SET first_name = $1, last_name = $2, username = $3, email = $4, phone_number = $5, updated_at = $6, updated_by = $7
+		WHERE id = $8
 	`
 	results, err := tx.ExecContext(ctx, query,
 		userReq.FirstName,
@@ -177,10 +223,12 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 		userReq.Email,
 		userReq.PhoneNumber,
 		time.Now(),
+		actor,
+		userID,
 	)
 
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -188,7 +236,16 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 		return models.User{}, err
 	}
 	if rowsAffected == 0 {
-		return models.User{}, errors.New("no rows updated")
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+
+	after, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceUser, userID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.User{}, err
 	}
 
 	// Return the updated user
@@ -200,6 +257,7 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 		PhoneNumber: userReq.PhoneNumber,
 		ID:          userID,
 		Active:      true,
+		UpdatedBy:   actor,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 		Role:        userReq.Role,
@@ -208,7 +266,7 @@ func (u UserStore) UpdateUserProfile(ctx context.Context, id string, userReq *mo
 	return user, nil
 }
 
-func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest) (models.User, error) {
+func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *models.UpdatePasswordRequest, actor string) (models.User, error) {
 	tracer := otel.Tracer("UserStore")
 	ctx, span := tracer.Start(ctx, "UpdateUserPassword-Store")
 	defer span.End()
@@ -216,12 +274,12 @@ func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *m
 	// Parse the user ID
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("invalid User ID : %w", err)
+		return models.User{}, models.Validation("invalid user id %q", id)
 	}
 
 	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, nil
+		return models.User{}, err
 	}
 	// Defer the rollback or commit
 	defer func() {
@@ -242,19 +300,25 @@ func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *m
 		return models.User{}, err
 	}
 
+	before, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+
 	// Update user password in the database
 	query := `
 		UPDATE "user"
-		SET password = $1, updated_at = $2
-		WHERE id = $3
+		SET password = $1, updated_at = $2, updated_by = $3
+		WHERE id = $4
 	`
 	results, err := tx.ExecContext(ctx, query,
 		userReq.Password,
 		time.Now(),
+		actor,
 		userID,
 	)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -262,12 +326,22 @@ func (u UserStore) UpdateUserPassword(ctx context.Context, id string, userReq *m
 		return models.User{}, err
 	}
 	if rowsAffected == 0 {
-		return models.User{}, errors.New("no rows updated")
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+
+	after, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceUser, userID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.User{}, err
 	}
 	// Return the updated user
 	user := models.User{
 		ID:        userID,
 		Active:    true,
+		UpdatedBy: actor,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -282,12 +356,12 @@ func (u UserStore) GetUserProfile(ctx context.Context, id string) (models.User,
 	// Parse the user ID
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("invalid User ID : %w", err)
+		return models.User{}, models.Validation("invalid user id %q", id)
 	}
 
 	// Query the database
 	query := `
-		SELECT username, first_name, last_name, email, phone_number, role, id, active, created_by, created_at, updated_at
+		SELECT username, first_name, last_name, email, phone_number, role, id, active, COALESCE(created_by, ''), COALESCE(updated_by, ''), created_at, updated_at
 		FROM "user"
 		WHERE id = $1
 	`
@@ -304,16 +378,20 @@ func (u UserStore) GetUserProfile(ctx context.Context, id string) (models.User,
 		&user.ID,
 		&user.Active,
 		&user.CreatedBy,
+		&user.UpdatedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, models.NotFound("user %s not found", id)
+		}
 		return models.User{}, err
 	}
 	return user, nil
 }
 
-func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool) (models.User, error) {
+func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool, actor string) (models.User, error) {
 	tracer := otel.Tracer("UserStore")
 	ctx, span := tracer.Start(ctx, "ToggleUserStatus-Store")
 	defer span.End()
@@ -321,12 +399,12 @@ func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool)
 	// Parse the user ID
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("invalid User ID : %w", err)
+		return models.User{}, models.Validation("invalid user id %q", id)
 	}
 
 	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, nil
+		return models.User{}, err
 	}
 
 	// Defer the rollback or commit
@@ -344,18 +422,24 @@ func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool)
 		}
 	}()
 
+	before, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+
 	query := `
 	    UPDATE "user"
-		SET active = $1, updated_at = $2
-		WHERE id = $3
+		SET active = $1, updated_at = $2, updated_by = $3
+		WHERE id = $4
 	`
 	results, err := tx.ExecContext(ctx, query,
 		active,
 		time.Now(),
+		actor,
 		userID,
 	)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, store.DBError(err)
 	}
 	// Check if the row was actually updated
 	rowsAffected, err := results.RowsAffected()
@@ -363,12 +447,22 @@ func (u UserStore) ToggleUserStatus(ctx context.Context, id string, active bool)
 		return models.User{}, err
 	}
 	if rowsAffected == 0 {
-		return models.User{}, errors.New("no rows updated")
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+
+	after, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+	err = audit.Record(ctx, tx, models.AuditResourceUser, userID.String(), models.AuditActionUpdate, before, after)
+	if err != nil {
+		return models.User{}, err
 	}
 	// Return the updated user
 	user := models.User{
 		ID:        userID,
 		Active:    active,
+		UpdatedBy: actor,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -384,12 +478,12 @@ func (u UserStore) DeleteUser(ctx context.Context, id string) (models.User, erro
 	// Parse the user ID
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("invalid User ID : %w", err)
+		return models.User{}, models.Validation("invalid user id %q", id)
 	}
 
 	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, nil
+		return models.User{}, err
 	}
 
 	// Defer the rollback or commit
@@ -407,15 +501,14 @@ func (u UserStore) DeleteUser(ctx context.Context, id string) (models.User, erro
 		}
 	}()
 
-	// check if the user exists
-	err = tx.QueryRowContext(ctx, `SELECT id, username FROM "user" WHERE id = $1`, userID).Scan()
-
+	// check if the user exists and keep its state for the audit log
+	before, err := audit.Capture(ctx, tx, u.dialect, models.AuditResourceUser, userID.String())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, nil
-		}
 		return models.User{}, err
 	}
+	if before == nil {
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
 
 	query := `
 		DELETE FROM "user"
@@ -423,7 +516,7 @@ func (u UserStore) DeleteUser(ctx context.Context, id string) (models.User, erro
 
 	results, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, store.DBError(err)
 	}
 	// Check if the row was actually deleted
 	rowsAffected, err := results.RowsAffected()
@@ -431,7 +524,12 @@ func (u UserStore) DeleteUser(ctx context.Context, id string) (models.User, erro
 		return models.User{}, err
 	}
 	if rowsAffected == 0 {
-		return models.User{}, errors.New("no rows deleted")
+		return models.User{}, models.NotFound("user %s not found", id)
+	}
+
+	err = audit.Record(ctx, tx, models.AuditResourceUser, userID.String(), models.AuditActionDelete, before, nil)
+	if err != nil {
+		return models.User{}, err
 	}
 	// Return the deleted user
 	user := models.User{
@@ -475,8 +573,8 @@ func (u UserStore) GetUserByUsername(ctx context.Context, username string) (mode
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return models.User{}, nil
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, models.NotFound("user %s not found", username)
 	}
 
 	if err != nil {
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/JulianaSau/carzone/store"
+	auditStore "github.com/JulianaSau/carzone/store/audit"
+	carStore "github.com/JulianaSau/carzone/store/car"
+	driverStore "github.com/JulianaSau/carzone/store/driver"
+	engineStore "github.com/JulianaSau/carzone/store/engine"
+	fuelStore "github.com/JulianaSau/carzone/store/fuel"
+	geofenceStore "github.com/JulianaSau/carzone/store/geofence"
+	locationStore "github.com/JulianaSau/carzone/store/location"
+	maintenanceStore "github.com/JulianaSau/carzone/store/maintenance"
+	"github.com/JulianaSau/carzone/store/memory"
+	odometerStore "github.com/JulianaSau/carzone/store/odometer"
+	positionStore "github.com/JulianaSau/carzone/store/position"
+	tokenStore "github.com/JulianaSau/carzone/store/token"
+	tripStore "github.com/JulianaSau/carzone/store/trip"
+	userStore "github.com/JulianaSau/carzone/store/user"
+)
+
+// stores are the stores the server runs on. The stores of features a backend does not keep are nil and the routes
+// of those features are left out.
+type stores struct {
+	car    store.CarStoreInterface
+	engine store.EngineStoreInterface
+	user   store.UserStoreInterface
+	driver store.DriverStoreInterface
+	trip   store.TripStoreInterface
+	token  store.TokenStoreInterface
+	audit  store.AuditStoreInterface
+
+	maintenance store.MaintenanceStoreInterface
+	odometer    store.OdometerStoreInterface
+	fuel        store.FuelStoreInterface
+	location    store.LocationStoreInterface
+	geofence    store.GeofenceStoreInterface
+	position    store.PositionStoreInterface
+}
+
+// databaseStores keeps every feature in the postgres or SQLite database
+func databaseStores(db *sql.DB) stores {
+	return stores{
+		car:         carStore.New(db),
+		engine:      engineStore.New(db),
+		user:        userStore.New(db),
+		driver:      driverStore.New(db),
+		trip:        tripStore.New(db),
+		token:       tokenStore.New(db),
+		audit:       auditStore.New(db),
+		maintenance: maintenanceStore.New(db),
+		odometer:    odometerStore.New(db),
+		fuel:        fuelStore.New(db),
+		location:    locationStore.New(db),
+		geofence:    geofenceStore.New(db),
+		position:    positionStore.New(db),
+	}
+}
+
+// memoryStores keeps the cars, engines, users, drivers and trips in process, loaded with the demo data, so the
+// server runs without a database. Everything is lost when the server stops.
+func memoryStores() stores {
+	db := memory.New()
+	memory.Seed(db)
+	return stores{
+		car:    memory.NewCarStore(db),
+		engine: memory.NewEngineStore(db),
+		user:   memory.NewUserStore(db),
+		driver: memory.NewDriverStore(db),
+		trip:   memory.NewTripStore(db),
+		token:  memory.NewTokenStore(db),
+		audit:  memory.NewAuditStore(db),
+	}
+}